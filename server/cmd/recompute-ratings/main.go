@@ -0,0 +1,50 @@
+// Command recompute-ratings replays the full games history in the order the
+// games ended and rebuilds every player's rating under the configured rating
+// system. Run it with the same environment as the server, ideally while the
+// server is stopped, after changing RATING_SYSTEM or its parameters.
+package main
+
+import (
+	"context"
+	"log"
+
+	"battle-wordle/server/config"
+	"battle-wordle/server/repositories"
+	"battle-wordle/server/services"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	gameRepository, err := repositories.NewGameRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create game repository: %v", err)
+	}
+	playerRepository, err := repositories.NewPlayerRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create player repository: %v", err)
+	}
+
+	ratingSystem, err := services.NewRatingSystem(cfg.RatingSystem, services.EloConfig{
+		InitialRating:      cfg.EloInitialRating,
+		KFactor:            cfg.EloKFactor,
+		ProvisionalKFactor: cfg.EloProvisionalKFactor,
+		ProvisionalGames:   cfg.EloProvisionalGames,
+	}, services.Glicko2Config{
+		Tau:          cfg.Glicko2Tau,
+		RatingPeriod: cfg.Glicko2RatingPeriod,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create rating system: %v", err)
+	}
+	ratingService := services.NewRatingService(playerRepository, gameRepository, ratingSystem)
+
+	rated, err := ratingService.RecomputeAll(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to recompute ratings: %v", err)
+	}
+	log.Printf("Recomputed %s ratings from %d rated games", ratingSystem.Name(), rated)
+}
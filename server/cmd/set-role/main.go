@@ -0,0 +1,49 @@
+// Command set-role gives a registered player a role, e.g. to appoint the first
+// admin, who can then manage roles through the admin API:
+//
+//	set-role <player name> <player|moderator|admin>
+//
+// Run it with the same environment as the server.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"battle-wordle/server/config"
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatalf("Usage: %s <player name> <player|moderator|admin>", os.Args[0])
+	}
+	name, role := os.Args[1], os.Args[2]
+	if !models.ValidRole(role) {
+		log.Fatalf("Unknown role %q", role)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	playerRepository, err := repositories.NewPlayerRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create player repository: %v", err)
+	}
+
+	ctx := context.Background()
+	player, err := playerRepository.GetByName(ctx, name)
+	if err != nil {
+		log.Fatalf("Failed to find player: %v", err)
+	}
+	if player == nil || !player.Registered {
+		log.Fatalf("No registered player is named %q", name)
+	}
+	if err := playerRepository.SetRole(ctx, player.ID, role); err != nil {
+		log.Fatalf("Failed to set role: %v", err)
+	}
+	log.Printf("%s is now %s", player.Name, role)
+}
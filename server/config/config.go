@@ -2,13 +2,71 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	DBPath    string
 	JWTSecret string
 	Port      string
+	// RatingSystem selects the rating system: "elo" or "glicko2".
+	RatingSystem string
+	// Elo rating parameters.
+	EloInitialRating      int
+	EloKFactor            int
+	EloProvisionalKFactor int
+	EloProvisionalGames   int
+	// Glicko-2 rating parameters.
+	Glicko2Tau          float64
+	Glicko2RatingPeriod time.Duration
+	// AnalyticsInterval is how often global statistics are recomputed.
+	AnalyticsInterval time.Duration
+	// PublicURL is the address of the UI, used for links in emails and share previews.
+	PublicURL string
+	// Mail settings. Without an SMTP host, mail is written to MailOutboxDir.
+	SMTPHost      string
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	MailFrom      string
+	MailOutboxDir string
+	// OIDCProviders are the OpenID Connect providers players can log in with.
+	OIDCProviders []OIDCProvider
+	// Rate limits in requests per minute: for each IP address and each player
+	// across the API, for each IP address on login, registration and password
+	// reset, and for player search.
+	RateLimitIP     int
+	RateLimitPlayer int
+	RateLimitAuth   int
+	RateLimitSearch int
+	// TrustedProxies are the proxies, such as nginx, whose X-Real-IP header
+	// gives the client's address. Other clients' headers are ignored.
+	TrustedProxies []*net.IPNet
+	// NameBlocklistFile lists words, one per line, that player names may not
+	// contain (optional).
+	NameBlocklistFile string
+	// ChatBlocklistFile lists words, one per line, that chat messages may not
+	// contain (optional).
+	ChatBlocklistFile string
+	// ChatSpectators lets players watching a game read its chat.
+	ChatSpectators bool
+	// ChallengeTTL is how long a challenge waits for an answer.
+	ChallengeTTL time.Duration
+}
+
+// OIDCProvider configures an OpenID Connect provider. Providers are listed by
+// ID in OIDC_PROVIDERS and configured with OIDC_<ID>_ISSUER, _CLIENT_ID,
+// _CLIENT_SECRET and _NAME.
+type OIDCProvider struct {
+	ID           string
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
 }
 
 // Load reads configuration from environment variables and returns a Config struct.
@@ -17,16 +75,185 @@ func Load() (*Config, error) {
 		DBPath:    getEnv("DB_PATH", "./battlewordle.db"),
 		JWTSecret: os.Getenv("JWT_SECRET"),
 		Port:      getEnv("PORT", "8080"),
+
+		RatingSystem: getEnv("RATING_SYSTEM", "elo"),
+
+		PublicURL:     strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:3000"), "/"),
+		SMTPHost:      os.Getenv("SMTP_HOST"),
+		SMTPUsername:  os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:  os.Getenv("SMTP_PASSWORD"),
+		MailFrom:      getEnv("MAIL_FROM", "Battle Wordle <noreply@battlewordle.app>"),
+		MailOutboxDir: getEnv("MAIL_OUTBOX_DIR", "./outbox"),
+
+		NameBlocklistFile: os.Getenv("NAME_BLOCKLIST_FILE"),
+		ChatBlocklistFile: os.Getenv("CHAT_BLOCKLIST_FILE"),
 	}
 	if cfg.JWTSecret == "" {
 		return nil, fmt.Errorf("JWT_SECRET is required but not set")
 	}
+	var err error
+	if cfg.EloInitialRating, err = getEnvInt("ELO_INITIAL_RATING", 1200); err != nil {
+		return nil, err
+	}
+	if cfg.EloKFactor, err = getEnvInt("ELO_K_FACTOR", 32); err != nil {
+		return nil, err
+	}
+	if cfg.EloProvisionalKFactor, err = getEnvInt("ELO_PROVISIONAL_K_FACTOR", 64); err != nil {
+		return nil, err
+	}
+	if cfg.EloProvisionalGames, err = getEnvInt("ELO_PROVISIONAL_GAMES", 10); err != nil {
+		return nil, err
+	}
+	if cfg.Glicko2Tau, err = getEnvFloat("GLICKO2_TAU", 0.5); err != nil {
+		return nil, err
+	}
+	if cfg.Glicko2RatingPeriod, err = getEnvDuration("GLICKO2_RATING_PERIOD", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.AnalyticsInterval, err = getEnvDuration("ANALYTICS_INTERVAL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.AnalyticsInterval <= 0 {
+		return nil, fmt.Errorf("ANALYTICS_INTERVAL must be positive")
+	}
+	if cfg.SMTPPort, err = getEnvInt("SMTP_PORT", 587); err != nil {
+		return nil, err
+	}
+	if cfg.ChatSpectators, err = getEnvBool("CHAT_SPECTATORS", false); err != nil {
+		return nil, err
+	}
+	if cfg.ChallengeTTL, err = getEnvDuration("CHALLENGE_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.ChallengeTTL <= 0 {
+		return nil, fmt.Errorf("CHALLENGE_TTL must be positive")
+	}
+	if cfg.OIDCProviders, err = loadOIDCProviders(); err != nil {
+		return nil, err
+	}
+	if cfg.TrustedProxies, err = getEnvNetworks("TRUSTED_PROXIES"); err != nil {
+		return nil, err
+	}
+	for _, limit := range []struct {
+		key      string
+		fallback int
+		value    *int
+	}{
+		{"RATE_LIMIT_IP", 600, &cfg.RateLimitIP},
+		{"RATE_LIMIT_PLAYER", 300, &cfg.RateLimitPlayer},
+		{"RATE_LIMIT_AUTH", 10, &cfg.RateLimitAuth},
+		{"RATE_LIMIT_SEARCH", 60, &cfg.RateLimitSearch},
+	} {
+		if *limit.value, err = getEnvInt(limit.key, limit.fallback); err != nil {
+			return nil, err
+		}
+		if *limit.value <= 0 {
+			return nil, fmt.Errorf("%s must be positive", limit.key)
+		}
+	}
 	return cfg, nil
 }
 
+func loadOIDCProviders() ([]OIDCProvider, error) {
+	var providers []OIDCProvider
+	for _, id := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(id) + "_"
+		p := OIDCProvider{
+			ID:           id,
+			Name:         getEnv(prefix+"NAME", id),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required for OIDC provider %q", prefix, prefix, id)
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return n, nil
+}
+
+func getEnvFloat(key string, fallback float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+	return f, nil
+}
+
+func getEnvBool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false: %w", key, err)
+	}
+	return b, nil
+}
+
+// getEnvNetworks parses a comma-separated list of IP addresses and CIDR
+// ranges.
+func getEnvNetworks(key string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if ip := net.ParseIP(v); ip != nil {
+			bits := 128
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s must list IP addresses or CIDR ranges: %w", key, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 168h: %w", key, err)
+	}
+	return d, nil
+}
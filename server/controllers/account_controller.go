@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/dto"
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/services"
+)
+
+// AccountController handles the authenticated player's own account.
+type AccountController struct {
+	service *services.AccountService
+}
+
+// NewAccountController creates a new AccountController.
+func NewAccountController(service *services.AccountService) *AccountController {
+	return &AccountController{service: service}
+}
+
+// ExportAccount returns everything stored about the authenticated player as
+// JSON, or as a ZIP archive with ?format=zip.
+func (c *AccountController) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	sessionID, _ := middleware.SessionIDFromContext(ctx)
+	export, err := c.service.Export(ctx, playerID, sessionID)
+	if err != nil {
+		if err.Error() == "player_not_found" {
+			http.Error(w, "Player not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error exporting account of player %q: %v", playerID, err)
+		http.Error(w, "Failed to export account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "zip" {
+		// Render into a buffer so that errors can still change the status code.
+		var buf bytes.Buffer
+		if err := c.service.WriteZip(ctx, &buf, export); err != nil {
+			log.Printf("error writing account archive of player %q: %v", playerID, err)
+			http.Error(w, "Failed to export account", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="battle-wordle-`+playerID+`.zip"`)
+		w.Write(buf.Bytes())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="battle-wordle-`+playerID+`.json"`)
+	json.NewEncoder(w).Encode(export)
+}
+
+// DeleteAccount anonymises the authenticated player and logs out all of their
+// sessions. Players with a password confirm with it.
+func (c *AccountController) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Password string `json:"password"`
+	}
+	// Guests and players of external providers have no password, so the body
+	// is optional.
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.DeleteAccount(ctx, playerID, req.Password); err != nil {
+		switch err.Error() {
+		case "invalid_password":
+			http.Error(w, "Incorrect password", http.StatusForbidden)
+		case "player_not_found":
+			http.Error(w, "Player not found", http.StatusNotFound)
+		default:
+			log.Printf("error deleting account of player %q: %v", playerID, err)
+			http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeGuest moves a guest, identified by the guest's access token, into the
+// authenticated registered player, e.g. after a guest logs in to their
+// account. It returns the player.
+func (c *AccountController) MergeGuest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GuestToken string `json:"guest_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.GuestToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	player, err := c.service.MergeGuest(ctx, playerID, req.GuestToken)
+	if err != nil {
+		switch err.Error() {
+		case "invalid_guest_token":
+			http.Error(w, "Invalid guest token", http.StatusBadRequest)
+		case "player_not_registered":
+			http.Error(w, "Only registered players can take over a guest", http.StatusForbidden)
+		case "player_not_found":
+			http.Error(w, "Player not found", http.StatusNotFound)
+		default:
+			log.Printf("error merging guest into player %q: %v", playerID, err)
+			http.Error(w, "Failed to merge guest", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.MapPlayer(player))
+}
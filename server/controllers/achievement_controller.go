@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/services"
+
+	"github.com/gorilla/mux"
+)
+
+// AchievementController handles HTTP requests related to achievements.
+type AchievementController struct {
+	service *services.AchievementService
+}
+
+// NewAchievementController creates a new AchievementController.
+func NewAchievementController(service *services.AchievementService) *AchievementController {
+	return &AchievementController{service: service}
+}
+
+// GetPlayerAchievements lists every achievement and whether the player has unlocked it.
+func (c *AchievementController) GetPlayerAchievements(w http.ResponseWriter, r *http.Request) {
+	playerID := mux.Vars(r)["id"]
+	if playerID == "" {
+		http.Error(w, "Missing required parameter: id", http.StatusBadRequest)
+		return
+	}
+
+	achievements, err := c.service.GetPlayerAchievements(r.Context(), playerID)
+	if err != nil {
+		if err.Error() == "player_not_found" {
+			http.Error(w, "Player not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error fetching achievements for player %q: %v", playerID, err)
+		http.Error(w, "Failed to fetch achievements", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(achievements)
+}
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"battle-wordle/server/dto"
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/repositories"
+	"battle-wordle/server/services"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminController handles the moderator and admin API under /api/admin.
+type AdminController struct {
+	service *services.AdminService
+}
+
+// NewAdminController creates a new AdminController.
+func NewAdminController(service *services.AdminService) *AdminController {
+	return &AdminController{service: service}
+}
+
+// writeAdminError maps AdminService errors to responses.
+func writeAdminError(w http.ResponseWriter, action string, err error) {
+	switch err.Error() {
+	case "player_not_found":
+		http.Error(w, "Player not found", http.StatusNotFound)
+	case "game_not_found":
+		http.Error(w, "Game not found", http.StatusNotFound)
+	case "insufficient_role":
+		http.Error(w, "Not allowed for this player", http.StatusForbidden)
+	case "username_taken":
+		http.Error(w, "Username is already taken", http.StatusConflict)
+	case "player_not_banned":
+		http.Error(w, "Player is not banned", http.StatusConflict)
+	case "game_not_finished":
+		http.Error(w, "Game is not finished", http.StatusConflict)
+	case "game_imported":
+		http.Error(w, "Imported games cannot be changed", http.StatusConflict)
+	case "word_list_empty":
+		http.Error(w, "The word list cannot be empty", http.StatusConflict)
+	case "invalid_ban_end", "invalid_ban_reason", "invalid_name", "name_not_allowed", "invalid_role", "player_not_registered", "invalid_result", "invalid_word":
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("error in admin action %s: %v", action, err)
+		http.Error(w, "Failed to "+action, http.StatusInternalServerError)
+	}
+}
+
+// GetPlayer returns a player with their role and ban.
+func (c *AdminController) GetPlayer(w http.ResponseWriter, r *http.Request) {
+	player, err := c.service.GetPlayer(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeAdminError(w, "get player", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.MapAdminPlayer(player))
+}
+
+// BanPlayer bans a player, or suspends them when "until" is given.
+func (c *AdminController) BanPlayer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Until  *time.Time `json:"until"`
+		Reason string     `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	actorID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.BanPlayer(ctx, actorID, mux.Vars(r)["id"], req.Until, req.Reason); err != nil {
+		writeAdminError(w, "ban player", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnbanPlayer lifts a player's ban or suspension.
+func (c *AdminController) UnbanPlayer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actorID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.UnbanPlayer(ctx, actorID, mux.Vars(r)["id"]); err != nil {
+		writeAdminError(w, "unban player", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RenamePlayer replaces a player's name.
+func (c *AdminController) RenamePlayer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	actorID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.RenamePlayer(ctx, actorID, mux.Vars(r)["id"], req.Name); err != nil {
+		writeAdminError(w, "rename player", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetRole changes a player's role.
+func (c *AdminController) SetRole(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	actorID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.SetRole(ctx, actorID, mux.Vars(r)["id"], req.Role); err != nil {
+		writeAdminError(w, "set role", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetGame returns a game including its solution.
+func (c *AdminController) GetGame(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actorID, _ := middleware.PlayerIDFromContext(ctx)
+	game, err := c.service.GetGame(ctx, actorID, mux.Vars(r)["id"])
+	if err != nil {
+		writeAdminError(w, "get game", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(game)
+}
+
+// SetGameResult voids a finished game or changes its result.
+func (c *AdminController) SetGameResult(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Result string `json:"result"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	actorID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.SetGameResult(ctx, actorID, mux.Vars(r)["id"], req.Result, req.Reason); err != nil {
+		writeAdminError(w, "set game result", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWords lists the words new games pick their solution from.
+func (c *AdminController) GetWords(w http.ResponseWriter, r *http.Request) {
+	words, err := c.service.ListWords(r.Context())
+	if err != nil {
+		writeAdminError(w, "list words", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"words": words})
+}
+
+// AddWords adds words to the word list.
+func (c *AdminController) AddWords(w http.ResponseWriter, r *http.Request) {
+	c.changeWords(w, r, "added", c.service.AddWords)
+}
+
+// RemoveWords removes words from the word list.
+func (c *AdminController) RemoveWords(w http.ResponseWriter, r *http.Request) {
+	c.changeWords(w, r, "removed", c.service.RemoveWords)
+}
+
+func (c *AdminController) changeWords(w http.ResponseWriter, r *http.Request, key string, change func(ctx context.Context, actorID string, words []string) ([]string, error)) {
+	var req struct {
+		Words []string `json:"words"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	actorID, _ := middleware.PlayerIDFromContext(ctx)
+	words, err := change(ctx, actorID, req.Words)
+	if err != nil {
+		writeAdminError(w, "change words", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{key: words})
+}
+
+// GetAuditLog lists audit log entries, newest first. It can be filtered by
+// actor, action, target_type and target_id, and paged with before, the ID of
+// the last entry of the previous page.
+func (c *AdminController) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var beforeID int64
+	if v := q.Get("before"); v != "" {
+		var err error
+		if beforeID, err = strconv.ParseInt(v, 10, 64); err != nil || beforeID <= 0 {
+			http.Error(w, "Invalid before", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	f := repositories.AuditFilter{
+		ActorID:    q.Get("actor"),
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+	}
+	entries, err := c.service.ListAudit(r.Context(), f, beforeID, limit)
+	if err != nil {
+		writeAdminError(w, "list audit log", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"entries": entries})
+}
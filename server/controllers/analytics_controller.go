@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/services"
+)
+
+// AnalyticsController handles HTTP requests for server-wide statistics.
+type AnalyticsController struct {
+	service *services.AnalyticsService
+}
+
+// NewAnalyticsController creates a new AnalyticsController.
+func NewAnalyticsController(service *services.AnalyticsService) *AnalyticsController {
+	return &AnalyticsController{service: service}
+}
+
+// GetGlobalStats returns the most recently computed global statistics.
+func (c *AnalyticsController) GetGlobalStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := c.service.GetGlobalStats(r.Context())
+	if err != nil {
+		log.Printf("error fetching global stats: %v", err)
+		http.Error(w, "Failed to fetch stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
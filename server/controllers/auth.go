@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/ws"
+)
+
+// actingPlayerID returns the ID of the authenticated player a request acts
+// as. claimedID, if given, must match it. It returns "unauthorized" or
+// "forbidden" otherwise.
+func actingPlayerID(ctx context.Context, claimedID string) (string, error) {
+	playerID, ok := middleware.PlayerIDFromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("unauthorized")
+	}
+	if claimedID != "" && claimedID != playerID {
+		return "", fmt.Errorf("forbidden")
+	}
+	return playerID, nil
+}
+
+// writeAuthError responds to an error from actingPlayerID.
+func writeAuthError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case "unauthorized":
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	case "forbidden":
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	default:
+		log.Printf("error authenticating player: %v", err)
+		http.Error(w, "Failed to authenticate player", http.StatusInternalServerError)
+	}
+}
+
+// trackSession registers conn under the request's session, if any, so that
+// revoking the session closes it. The returned func unregisters it.
+func trackSession(sessions *ws.Hub, r *http.Request, conn *ws.Conn) func() {
+	sessionID, ok := middleware.SessionIDFromContext(r.Context())
+	if !ok {
+		return func() {}
+	}
+	sessions.AddConnection(sessionID, conn)
+	return func() { sessions.RemoveConnection(sessionID, conn) }
+}
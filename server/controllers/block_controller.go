@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/services"
+
+	"github.com/gorilla/mux"
+)
+
+// BlockController handles the players the authenticated player blocked.
+type BlockController struct {
+	service *services.BlockService
+}
+
+// NewBlockController creates a new BlockController.
+func NewBlockController(service *services.BlockService) *BlockController {
+	return &BlockController{service: service}
+}
+
+// GetBlocks lists the players the player blocked, most recent first.
+func (c *BlockController) GetBlocks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	blocked, err := c.service.List(ctx, playerID)
+	if err != nil {
+		log.Printf("error fetching blocks of player %q: %v", playerID, err)
+		http.Error(w, "Failed to fetch blocked players", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocked)
+}
+
+// Block blocks the player in the body.
+func (c *BlockController) Block(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlayerID string `json:"player_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	blocked, err := c.service.Block(ctx, playerID, req.PlayerID)
+	if err != nil {
+		switch err.Error() {
+		case "cannot_block_self":
+			http.Error(w, "You cannot block yourself", http.StatusBadRequest)
+		case "player_not_found":
+			http.Error(w, "Player not found", http.StatusNotFound)
+		default:
+			log.Printf("error blocking player %q for %q: %v", req.PlayerID, playerID, err)
+			http.Error(w, "Failed to block player", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(blocked)
+}
+
+// Unblock lifts the block of the player in the path.
+func (c *BlockController) Unblock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	blockedID := mux.Vars(r)["id"]
+	if err := c.service.Unblock(ctx, playerID, blockedID); err != nil {
+		if err.Error() == "block_not_found" {
+			http.Error(w, "Player is not blocked", http.StatusNotFound)
+			return
+		}
+		log.Printf("error unblocking player %q for %q: %v", blockedID, playerID, err)
+		http.Error(w, "Failed to unblock player", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/services"
+
+	"github.com/gorilla/mux"
+)
+
+// ChallengeController handles the authenticated player's challenges, so that
+// they can be answered without a notification connection.
+type ChallengeController struct {
+	service *services.ChallengeService
+}
+
+// NewChallengeController creates a new ChallengeController.
+func NewChallengeController(service *services.ChallengeService) *ChallengeController {
+	return &ChallengeController{service: service}
+}
+
+func writeChallengeError(w http.ResponseWriter, action string, err error) {
+	switch err.Error() {
+	case "player_not_found":
+		http.Error(w, "Player not found", http.StatusNotFound)
+	case "challenge_not_found":
+		http.Error(w, "Challenge not found", http.StatusNotFound)
+	case "challenge_expired":
+		http.Error(w, "Challenge expired", http.StatusGone)
+	case "cannot_challenge_self":
+		http.Error(w, "You cannot challenge yourself", http.StatusBadRequest)
+	default:
+		log.Printf("error trying to %s: %v", action, err)
+		http.Error(w, "Failed to "+action, http.StatusInternalServerError)
+	}
+}
+
+// GetChallenges lists the pending challenges the player sent or received.
+func (c *ChallengeController) GetChallenges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	challenges, err := c.service.Pending(ctx, playerID)
+	if err != nil {
+		writeChallengeError(w, "fetch challenges", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(challenges)
+}
+
+// SendChallenge challenges the player in the body.
+func (c *ChallengeController) SendChallenge(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlayerID string `json:"player_id"`
+		Rematch  bool   `json:"rematch"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	challenge, err := c.service.Invite(ctx, playerID, req.PlayerID, req.Rematch)
+	if err != nil {
+		writeChallengeError(w, "send challenge", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(challenge)
+}
+
+// AcceptChallenge accepts the challenge in the path and returns it with the
+// game it started.
+func (c *ChallengeController) AcceptChallenge(w http.ResponseWriter, r *http.Request) {
+	c.respond(w, r, true)
+}
+
+// DeclineChallenge declines the challenge in the path.
+func (c *ChallengeController) DeclineChallenge(w http.ResponseWriter, r *http.Request) {
+	c.respond(w, r, false)
+}
+
+func (c *ChallengeController) respond(w http.ResponseWriter, r *http.Request, accept bool) {
+	action := "decline challenge"
+	if accept {
+		action = "accept challenge"
+	}
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	challenge, err := c.service.Respond(ctx, playerID, mux.Vars(r)["id"], accept)
+	if err != nil {
+		writeChallengeError(w, action, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(challenge)
+}
+
+// CancelChallenge withdraws the challenge in the path, which the player sent.
+func (c *ChallengeController) CancelChallenge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	if _, err := c.service.Cancel(ctx, playerID, mux.Vars(r)["id"]); err != nil {
+		writeChallengeError(w, "cancel challenge", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/services"
+
+	"github.com/gorilla/mux"
+)
+
+// FriendController handles the authenticated player's friends and friend
+// requests.
+type FriendController struct {
+	service *services.FriendService
+}
+
+// NewFriendController creates a new FriendController.
+func NewFriendController(service *services.FriendService) *FriendController {
+	return &FriendController{service: service}
+}
+
+func writeFriendError(w http.ResponseWriter, action string, err error) {
+	switch err.Error() {
+	case "player_not_found":
+		http.Error(w, "Player not found", http.StatusNotFound)
+	case "friend_request_not_found":
+		http.Error(w, "Friend request not found", http.StatusNotFound)
+	case "friendship_not_found":
+		http.Error(w, "Not friends with this player", http.StatusNotFound)
+	case "player_not_registered":
+		http.Error(w, "Register to add friends", http.StatusForbidden)
+	case "cannot_friend_self":
+		http.Error(w, "You cannot add yourself as a friend", http.StatusBadRequest)
+	case "friend_request_exists":
+		http.Error(w, "Friend request already sent", http.StatusConflict)
+	case "already_friends":
+		http.Error(w, "Already friends with this player", http.StatusConflict)
+	default:
+		log.Printf("error trying to %s: %v", action, err)
+		http.Error(w, "Failed to "+action, http.StatusInternalServerError)
+	}
+}
+
+// GetFriends lists the player's friends, with their online status, and
+// pending friend requests in both directions.
+func (c *FriendController) GetFriends(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	friends, err := c.service.List(ctx, playerID)
+	if err != nil {
+		writeFriendError(w, "fetch friends", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(friends)
+}
+
+// SendRequest sends a friend request to the player in the body, or accepts
+// theirs if they already sent one.
+func (c *FriendController) SendRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlayerID string `json:"player_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	friend, err := c.service.Request(ctx, playerID, req.PlayerID)
+	if err != nil {
+		writeFriendError(w, "send friend request", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(friend)
+}
+
+// AcceptRequest accepts the friend request from the player in the path.
+func (c *FriendController) AcceptRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	friend, err := c.service.Accept(ctx, playerID, mux.Vars(r)["id"])
+	if err != nil {
+		writeFriendError(w, "accept friend request", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(friend)
+}
+
+// DeclineRequest turns down the friend request from the player in the path.
+func (c *FriendController) DeclineRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.Decline(ctx, playerID, mux.Vars(r)["id"]); err != nil {
+		writeFriendError(w, "decline friend request", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveFriend ends the friendship with the player in the path, or withdraws
+// the friend request sent to them.
+func (c *FriendController) RemoveFriend(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.Remove(ctx, playerID, mux.Vars(r)["id"]); err != nil {
+		writeFriendError(w, "remove friend", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,10 +2,14 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"battle-wordle/server/dto"
+	"battle-wordle/server/models"
 	"battle-wordle/server/services"
 
 	"github.com/google/uuid"
@@ -61,6 +65,12 @@ func (c *GameController) GetGameByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(dto.MapGame(game, firstPlayer, secondPlayer, feedbackStrings, solutionPtr))
 }
 
+// GetGamesByPlayer returns a page of the player's games as a JSON array. The
+// cursor for the next page, if any, is returned in the X-Next-Cursor header.
+// Query parameters: status (in_progress, finished), opponent, result (win,
+// loss, draw), mode, from and to (RFC 3339 times or 2006-01-02 dates, to being
+// inclusive for dates), sort (created_at, updated_at), order (desc, asc),
+// limit and cursor.
 func (c *GameController) GetGamesByPlayer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	playerID := vars["id"]
@@ -73,18 +83,29 @@ func (c *GameController) GetGamesByPlayer(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	games, err := c.service.GetByPlayer(ctx, playerID)
+	query, err := parseGameHistoryQuery(r, playerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	history, err := c.service.ListPlayerGames(ctx, query)
 	if err != nil {
-		log.Printf("error fetching game for player %q: %v", playerID, err)
-		http.Error(w, "Games not found", http.StatusNotFound)
+		switch err.Error() {
+		case "invalid_filter", "invalid_sort", "invalid_cursor":
+			http.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("error fetching game for player %q: %v", playerID, err)
+			http.Error(w, "Games not found", http.StatusNotFound)
+		}
 		return
 	}
 
-	var dtos []dto.GameDTO
-	for _, game := range games {
-		firstPlayer, _ := c.playerService.GetByID(ctx, game.FirstPlayer)
-		secondPlayer, _ := c.playerService.GetByID(ctx, game.SecondPlayer)
+	dtos := make([]dto.GameDTO, 0, len(history.Games))
+	for _, item := range history.Games {
+		game := item.Game
+		firstPlayer := &models.Player{ID: game.FirstPlayer, Name: item.FirstPlayerName}
+		secondPlayer := &models.Player{ID: game.SecondPlayer, Name: item.SecondPlayerName}
 		feedbacks := c.service.GetFeedbacks(game)
 		feedbackStrings := make([][]string, len(feedbacks))
 		for i, fb := range feedbacks {
@@ -99,21 +120,80 @@ func (c *GameController) GetGamesByPlayer(w http.ResponseWriter, r *http.Request
 		}
 		dtos = append(dtos, *dto.MapGame(game, firstPlayer, secondPlayer, feedbackStrings, solutionPtr))
 	}
+	if history.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", history.NextCursor)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(dtos)
 }
 
+// parseGameHistoryQuery reads the game history filters from the query string.
+// Errors are suitable for a 400 response.
+func parseGameHistoryQuery(r *http.Request, playerID string) (services.GameHistoryQuery, error) {
+	params := r.URL.Query()
+	query := services.GameHistoryQuery{
+		PlayerID:   playerID,
+		Status:     params.Get("status"),
+		OpponentID: params.Get("opponent"),
+		Result:     params.Get("result"),
+		Mode:       params.Get("mode"),
+		Sort:       params.Get("sort"),
+		Order:      params.Get("order"),
+		Cursor:     params.Get("cursor"),
+	}
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return query, fmt.Errorf("Invalid limit query parameter")
+		}
+		query.Limit = n
+	}
+	var err error
+	if query.From, err = parseHistoryTime(params.Get("from"), false); err != nil {
+		return query, fmt.Errorf("Invalid from query parameter")
+	}
+	if query.To, err = parseHistoryTime(params.Get("to"), true); err != nil {
+		return query, fmt.Errorf("Invalid to query parameter")
+	}
+	return query, nil
+}
+
+// parseHistoryTime parses an RFC 3339 time or a 2006-01-02 date. An end date
+// is moved to the start of the following day so that the whole day is included.
+func parseHistoryTime(v string, end bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
 func (c *GameController) CreateGame(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		PlayerOne string `json:"player_one"`
 		PlayerTwo string `json:"player_two"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerOne == "" || req.PlayerTwo == "" {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerTwo == "" {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 	ctx := r.Context()
-	game, err := c.service.CreateGame(ctx, req.PlayerOne, req.PlayerTwo)
+	// The requesting player is always player one.
+	playerOne, err := actingPlayerID(ctx, req.PlayerOne)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	game, err := c.service.CreateGame(ctx, playerOne, req.PlayerTwo)
 	if err != nil {
 		log.Printf("error creating game: %v", err)
 		http.Error(w, "Failed to create game", http.StatusInternalServerError)
@@ -145,11 +225,16 @@ func (c *GameController) SubmitGuess(w http.ResponseWriter, r *http.Request) {
 		Guess    string `json:"guess"`
 		PlayerID string `json:"player_id"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Guess == "" || req.PlayerID == "" {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Guess == "" {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	game, err := c.service.SubmitGuess(ctx, gameID, req.Guess, req.PlayerID)
+	playerID, err := actingPlayerID(ctx, req.PlayerID)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	game, err := c.service.SubmitGuess(ctx, gameID, req.Guess, playerID)
 	if err != nil {
 		log.Printf("error submitting guess: %v", err)
 		http.Error(w, "Failed to submit guess", http.StatusInternalServerError)
@@ -4,6 +4,7 @@ import (
 	"battle-wordle/server/dto"
 	"battle-wordle/server/middleware"
 	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
 	"battle-wordle/server/services"
 	"context"
 	"encoding/json"
@@ -34,22 +35,72 @@ func (m *mockGameRepo) CreateGame(ctx context.Context, game *models.Game) error
 	m.byPlayer[game.SecondPlayer] = append(m.byPlayer[game.SecondPlayer], game)
 	return nil
 }
+func (m *mockGameRepo) ImportGames(ctx context.Context, games []*models.Game) error {
+	for _, game := range games {
+		m.CreateGame(ctx, game)
+	}
+	return nil
+}
 func (m *mockGameRepo) GetByID(ctx context.Context, id string) (*models.Game, error) {
 	return m.games[id], nil
 }
 func (m *mockGameRepo) GetByPlayer(ctx context.Context, playerID string) ([]*models.Game, error) {
 	return m.byPlayer[playerID], nil
 }
+func (m *mockGameRepo) ListByPlayer(ctx context.Context, f repositories.GameFilter, after *repositories.GameCursor, limit int) ([]*models.GameWithPlayers, *repositories.GameCursor, error) {
+	games := m.byPlayer[f.PlayerID]
+	start := 0
+	if after != nil {
+		for i, g := range games {
+			if g.ID == after.GameID {
+				start = i + 1
+			}
+		}
+	}
+	var page []*models.GameWithPlayers
+	for _, g := range games[start:] {
+		if len(page) == limit {
+			return page, &repositories.GameCursor{SortKey: "k", GameID: page[len(page)-1].Game.ID}, nil
+		}
+		page = append(page, &models.GameWithPlayers{Game: g})
+	}
+	return page, nil, nil
+}
+func (m *mockGameRepo) GetBetween(ctx context.Context, firstPlayerID, secondPlayerID string) ([]*models.Game, error) {
+	return nil, nil
+}
+func (m *mockGameRepo) GetRatingChangesBetween(ctx context.Context, firstPlayerID, secondPlayerID string) ([]*models.RatingChange, error) {
+	return nil, nil
+}
+func (m *mockGameRepo) GetRatingChanges(ctx context.Context, gameID string) ([]*models.RatingChange, error) {
+	return nil, nil
+}
+func (m *mockGameRepo) GetRatingChangesByPlayer(ctx context.Context, playerID string) ([]*models.RatingChange, error) {
+	return nil, nil
+}
 func (m *mockGameRepo) UpdateGame(ctx context.Context, game *models.Game) error {
 	m.games[game.ID] = game
 	return nil
 }
+func (m *mockGameRepo) FinishGame(ctx context.Context, game *models.Game, changes []*models.RatingChange, summaries []*models.PlayerGameSummary) error {
+	m.games[game.ID] = game
+	return nil
+}
+func (m *mockGameRepo) GetFinished(ctx context.Context) ([]*models.Game, error) {
+	return nil, nil
+}
+func (m *mockGameRepo) ReplaceRatings(ctx context.Context, changes []*models.RatingChange) error {
+	return nil
+}
+func (m *mockGameRepo) SetResult(ctx context.Context, gameID string, result string) error {
+	return nil
+}
 
-func setupGameTestServer(t *testing.T) (*httptest.Server, *mockGameRepo, *mockPlayerRepo, func()) {
+func setupGameTestServer(t *testing.T) (*httptest.Server, *mockGameRepo, *mockPlayerRepo, *services.PlayerService, func()) {
 	gameRepo := newMockGameRepo()
 	playerRepo := newMockPlayerRepo()
-	gameService := services.NewGameService(gameRepo, []string{"APPLE"})
-	playerService := services.NewPlayerService(playerRepo, "testsecret")
+	gameService := services.NewGameService(gameRepo, []string{"APPLE"}, nil)
+	playerService := services.NewPlayerService(playerRepo, newMockSessionRepo(), "testsecret")
 	gameController := NewGameController(gameService, playerService)
 
 	router := mux.NewRouter()
@@ -59,14 +110,23 @@ func setupGameTestServer(t *testing.T) (*httptest.Server, *mockGameRepo, *mockPl
 	router.HandleFunc("/api/game/{id}/guess", gameController.SubmitGuess).Methods("POST")
 	// Add more routes as needed
 
-	h := middleware.Logger(middleware.ErrorHandler(router))
+	h := middleware.Logger(middleware.ErrorHandler(middleware.Authenticate(playerService.VerifyJWT)(router)))
 	ts := httptest.NewServer(h)
 	cleanup := func() { ts.Close() }
-	return ts, gameRepo, playerRepo, cleanup
+	return ts, gameRepo, playerRepo, playerService, cleanup
+}
+
+// accessToken starts a session for the player and returns its access token.
+func accessToken(t *testing.T, playerService *services.PlayerService, player *models.Player) string {
+	tokens, err := playerService.StartSession(context.Background(), player, services.SessionClient{})
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	return tokens.AccessToken
 }
 
 func TestGameAPI_GetGameByID(t *testing.T) {
-	ts, gameRepo, playerRepo, cleanup := setupGameTestServer(t)
+	ts, gameRepo, playerRepo, _, cleanup := setupGameTestServer(t)
 	defer cleanup()
 	// Add a player and a game with valid UUIDs
 	p1ID := uuid.NewString()
@@ -96,14 +156,18 @@ func TestGameAPI_GetGameByID(t *testing.T) {
 }
 
 func TestGameAPI_CreateGame(t *testing.T) {
-	ts, _, playerRepo, cleanup := setupGameTestServer(t)
+	ts, _, playerRepo, playerService, cleanup := setupGameTestServer(t)
 	defer cleanup()
 	p1ID := uuid.NewString()
 	p2ID := uuid.NewString()
 	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: p1ID, Name: "Alice"})
 	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: p2ID, Name: "Bob"})
+	token := accessToken(t, playerService, &models.Player{ID: p1ID})
 	body := strings.NewReader(`{"player_one":"` + p1ID + `","player_two":"` + p2ID + `"}`)
-	resp, err := http.Post(ts.URL+"/api/game", "application/json", body)
+	req, _ := http.NewRequest("POST", ts.URL+"/api/game", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("POST failed: %v", err)
 	}
@@ -121,7 +185,7 @@ func TestGameAPI_CreateGame(t *testing.T) {
 }
 
 func TestGameAPI_GetGamesByPlayer(t *testing.T) {
-	ts, gameRepo, playerRepo, cleanup := setupGameTestServer(t)
+	ts, gameRepo, playerRepo, _, cleanup := setupGameTestServer(t)
 	defer cleanup()
 	p1ID := uuid.NewString()
 	p2ID := uuid.NewString()
@@ -147,8 +211,52 @@ func TestGameAPI_GetGamesByPlayer(t *testing.T) {
 	}
 }
 
+func TestGameAPI_GetGamesByPlayer_Pagination(t *testing.T) {
+	ts, gameRepo, _, _, cleanup := setupGameTestServer(t)
+	defer cleanup()
+	p1ID := uuid.NewString()
+	p2ID := uuid.NewString()
+	for i := 0; i < 3; i++ {
+		gameRepo.CreateGame(context.Background(), &models.Game{ID: uuid.NewString(), FirstPlayer: p1ID, SecondPlayer: p2ID, Guesses: []string{}, Solution: "APPLE"})
+	}
+
+	resp, err := http.Get(ts.URL + "/api/player/" + p1ID + "/games?limit=2")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	var first []dto.GameDTO
+	json.NewDecoder(resp.Body).Decode(&first)
+	resp.Body.Close()
+	cursor := resp.Header.Get("X-Next-Cursor")
+	if len(first) != 2 || cursor == "" {
+		t.Fatalf("Expected 2 games and a cursor, got %d games and %q", len(first), cursor)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/player/" + p1ID + "/games?limit=2&cursor=" + cursor)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	var second []dto.GameDTO
+	json.NewDecoder(resp.Body).Decode(&second)
+	resp.Body.Close()
+	if len(second) != 1 || resp.Header.Get("X-Next-Cursor") != "" {
+		t.Errorf("Expected a final page of 1 game, got %d", len(second))
+	}
+
+	for _, query := range []string{"status=lost", "sort=name", "from=yesterday", "cursor=bogus"} {
+		resp, err := http.Get(ts.URL + "/api/player/" + p1ID + "/games?" + query)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
 func TestGameAPI_SubmitGuess(t *testing.T) {
-	ts, gameRepo, playerRepo, cleanup := setupGameTestServer(t)
+	ts, gameRepo, playerRepo, playerService, cleanup := setupGameTestServer(t)
 	defer cleanup()
 	p1ID := uuid.NewString()
 	p2ID := uuid.NewString()
@@ -163,6 +271,8 @@ func TestGameAPI_SubmitGuess(t *testing.T) {
 		t.Fatalf("NewRequest failed: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	token := accessToken(t, playerService, &models.Player{ID: p1ID})
+	req.Header.Set("Authorization", "Bearer "+token)
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
@@ -180,3 +290,45 @@ func TestGameAPI_SubmitGuess(t *testing.T) {
 		t.Errorf("Expected guess APPLE, got %+v", got.Guesses)
 	}
 }
+
+func TestGameAPI_SubmitGuess_RequiresToken(t *testing.T) {
+	ts, gameRepo, playerRepo, playerService, cleanup := setupGameTestServer(t)
+	defer cleanup()
+	p1ID := uuid.NewString()
+	p2ID := uuid.NewString()
+	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: p1ID, Name: "Alice", Registered: true})
+	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: p2ID, Name: "Bob", Registered: true})
+	gID := uuid.NewString()
+	gameRepo.CreateGame(context.Background(), &models.Game{ID: gID, FirstPlayer: p1ID, SecondPlayer: p2ID, CurrentPlayer: p1ID, Guesses: []string{}, Solution: "APPLE"})
+	aliceToken := accessToken(t, playerService, &models.Player{ID: p1ID, Registered: true})
+	bobToken := accessToken(t, playerService, &models.Player{ID: p2ID, Registered: true})
+
+	guess := func(token, playerID string) int {
+		body := strings.NewReader(`{"guess":"CRANE","player_id":"` + playerID + `"}`)
+		req, _ := http.NewRequest("POST", ts.URL+"/api/game/"+gID+"/guess", body)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := guess("", p1ID); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", status)
+	}
+	if status := guess("not-a-token", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an invalid token, got %d", status)
+	}
+	if status := guess(bobToken, p1ID); status != http.StatusForbidden {
+		t.Errorf("Expected 403 when claiming another player, got %d", status)
+	}
+	if status := guess(aliceToken, ""); status != http.StatusOK {
+		t.Errorf("Expected 200 with the player's token, got %d", status)
+	}
+	if got := gameRepo.games[gID].Guesses; len(got) != 1 {
+		t.Errorf("Expected exactly one guess, got %v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// gameRecordContentType is the media type of exported game records.
+const gameRecordContentType = "text/x-battle-wordle; charset=utf-8"
+
+// maxGameRecordImportSize bounds the size of an imported game record file.
+const maxGameRecordImportSize = 1 << 20
+
+// GameRecordController handles exporting and importing game records.
+type GameRecordController struct {
+	service *services.GameRecordService
+}
+
+// NewGameRecordController creates a new GameRecordController.
+func NewGameRecordController(service *services.GameRecordService) *GameRecordController {
+	return &GameRecordController{service: service}
+}
+
+// ExportGame returns the record of a single game.
+func (c *GameRecordController) ExportGame(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["id"]
+	if err := uuid.Validate(gameID); err != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	// Render into a buffer so that errors can still change the status code.
+	var buf bytes.Buffer
+	if err := c.service.ExportGame(r.Context(), gameID, &buf); err != nil {
+		if err.Error() == "game_not_found" {
+			http.Error(w, "Game not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error exporting game %q: %v", gameID, err)
+		http.Error(w, "Failed to export game", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", gameRecordContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+gameID+`.bwr"`)
+	w.Write(buf.Bytes())
+}
+
+// ExportPlayerGames returns the records of the player's games. It accepts the
+// same filters as GetGamesByPlayer, except for limit and cursor.
+func (c *GameRecordController) ExportPlayerGames(w http.ResponseWriter, r *http.Request) {
+	playerID := mux.Vars(r)["id"]
+	if err := uuid.Validate(playerID); err != nil {
+		http.Error(w, "Games not found", http.StatusNotFound)
+		return
+	}
+	query, err := parseGameHistoryQuery(r, playerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := c.service.ExportPlayerGames(r.Context(), query, &buf); err != nil {
+		switch err.Error() {
+		case "invalid_filter", "invalid_sort":
+			http.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("error exporting games for player %q: %v", playerID, err)
+			http.Error(w, "Failed to export games", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", gameRecordContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+playerID+`.bwr"`)
+	w.Write(buf.Bytes())
+}
+
+// ImportGames archives the game records in the request body and returns the
+// IDs of the stored games. Nothing is stored unless every record is valid.
+// Players may only import their own games; admins may import any.
+func (c *GameRecordController) ImportGames(w http.ResponseWriter, r *http.Request) {
+	playerID, err := actingPlayerID(r.Context(), "")
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	ids, err := c.service.ImportGames(r.Context(), playerID, http.MaxBytesReader(w, r.Body, maxGameRecordImportSize))
+	if err != nil {
+		var recordErr *services.GameRecordError
+		var tooLarge *http.MaxBytesError
+		switch {
+		case err.Error() == "player_not_found":
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		case err.Error() == "forbidden":
+			http.Error(w, "Only games you played can be imported", http.StatusForbidden)
+		case errors.As(err, &recordErr):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.As(err, &tooLarge):
+			http.Error(w, "Game records too large", http.StatusRequestEntityTooLarge)
+		default:
+			log.Printf("error importing games: %v", err)
+			http.Error(w, "Failed to import games", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"imported": ids})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"battle-wordle/server/dto"
+	"battle-wordle/server/services"
+)
+
+// LeaderboardController handles HTTP requests related to leaderboards.
+type LeaderboardController struct {
+	service *services.LeaderboardService
+}
+
+// NewLeaderboardController creates a new LeaderboardController.
+func NewLeaderboardController(service *services.LeaderboardService) *LeaderboardController {
+	return &LeaderboardController{service: service}
+}
+
+// GetLeaderboard returns a page of the leaderboard. Query parameters: period
+// (all, season, month), mode, word_length, min_games, limit, cursor and
+// player_id (the requesting player, whose own standing is included).
+func (c *LeaderboardController) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := services.LeaderboardQuery{
+		Period:   params.Get("period"),
+		Mode:     params.Get("mode"),
+		Cursor:   params.Get("cursor"),
+		PlayerID: params.Get("player_id"),
+	}
+	for name, dest := range map[string]*int{
+		"word_length": &query.WordLength,
+		"min_games":   &query.MinGames,
+		"limit":       &query.Limit,
+	} {
+		if v := params.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" query parameter", http.StatusBadRequest)
+				return
+			}
+			*dest = n
+		}
+	}
+
+	board, err := c.service.GetLeaderboard(r.Context(), query)
+	if err != nil {
+		switch err.Error() {
+		case "invalid_period", "invalid_cursor", "invalid_filter":
+			http.Error(w, "Invalid leaderboard query", http.StatusBadRequest)
+		default:
+			log.Printf("error fetching leaderboard: %v", err)
+			http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.MapLeaderboard(board.Sort, board.Entries, board.NextCursor, board.Me))
+}
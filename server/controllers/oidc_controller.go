@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/services"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// oidcStateCookie ties a login to the browser that started it.
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/auth/oidc/"
+	oidcStateCookieAge  = 10 * time.Minute
+)
+
+// OIDCController handles logging in with OpenID Connect providers.
+type OIDCController struct {
+	service *services.OIDCService
+	// callbackURL is the UI page the provider's callback redirects to.
+	callbackURL string
+	// secureCookies is set when the UI is served over HTTPS.
+	secureCookies bool
+}
+
+// NewOIDCController creates a new OIDCController. publicURL is the address of
+// the UI.
+func NewOIDCController(service *services.OIDCService, publicURL string) *OIDCController {
+	return &OIDCController{
+		service:       service,
+		callbackURL:   publicURL + "/auth/callback",
+		secureCookies: strings.HasPrefix(publicURL, "https://"),
+	}
+}
+
+// GetProviders lists the providers players can log in with.
+func (c *OIDCController) GetProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.service.Providers())
+}
+
+// StartLogin returns the URL of the provider's login page. When called with a
+// player's token, a guest is upgraded and a registered player has the
+// identity linked to their account. The login is bound to the browser with a
+// cookie, which the callback requires.
+func (c *OIDCController) StartLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	providerID := mux.Vars(r)["provider"]
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	sessionID, _ := middleware.SessionIDFromContext(ctx)
+
+	loginURL, binding, err := c.service.StartLogin(ctx, providerID, playerID, sessionID)
+	if err != nil {
+		switch err.Error() {
+		case "provider_not_found":
+			http.Error(w, "Provider not found", http.StatusNotFound)
+		case "too_many_logins":
+			http.Error(w, "Too many logins in progress", http.StatusServiceUnavailable)
+		default:
+			log.Printf("error starting login with %q: %v", providerID, err)
+			http.Error(w, "Failed to start login", http.StatusBadGateway)
+		}
+		return
+	}
+
+	c.setStateCookie(w, binding, int(oidcStateCookieAge.Seconds()))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": loginURL})
+}
+
+// Callback is where the provider redirects back to. It finishes the login and
+// redirects to the UI with a one-time code, or with an error.
+func (c *OIDCController) Callback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	providerID := mux.Vars(r)["provider"]
+	q := r.URL.Query()
+	binding := ""
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		binding = cookie.Value
+	}
+	c.setStateCookie(w, "", -1)
+
+	if providerErr := q.Get("error"); providerErr != "" {
+		c.redirect(w, r, url.Values{"error": {"login_cancelled"}})
+		return
+	}
+
+	code, err := c.service.FinishLogin(ctx, providerID, q.Get("state"), binding, q.Get("code"), sessionClient(r))
+	if err != nil {
+		switch err.Error() {
+		case "invalid_login_state":
+			c.redirect(w, r, url.Values{"error": {"login_expired"}})
+		case "identity_linked_elsewhere", "player_banned":
+			c.redirect(w, r, url.Values{"error": {err.Error()}})
+		default:
+			log.Printf("error finishing login with %q: %v", providerID, err)
+			c.redirect(w, r, url.Values{"error": {"login_failed"}})
+		}
+		return
+	}
+	c.redirect(w, r, url.Values{"code": {code}})
+}
+
+// setStateCookie sets the cookie binding a login to the browser, or clears
+// it if maxAge is negative. It is sent on the provider's redirect back, a
+// top-level navigation, but not on requests other sites make.
+func (c *OIDCController) setStateCookie(w http.ResponseWriter, binding string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    binding,
+		Path:     oidcStateCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (c *OIDCController) redirect(w http.ResponseWriter, r *http.Request, q url.Values) {
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, c.callbackURL+"?"+q.Encode(), http.StatusFound)
+}
+
+// Exchange redeems the one-time code from the callback for the session's
+// tokens.
+func (c *OIDCController) Exchange(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	player, tokens, err := c.service.RedeemLogin(req.Code)
+	if err != nil {
+		http.Error(w, "Invalid login code", http.StatusUnauthorized)
+		return
+	}
+	writeAuthResponse(w, player, tokens)
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/services"
+)
+
+// PasswordController handles password changes and resets.
+type PasswordController struct {
+	service *services.PasswordService
+}
+
+// NewPasswordController creates a new PasswordController.
+func NewPasswordController(service *services.PasswordService) *PasswordController {
+	return &PasswordController{service: service}
+}
+
+// ChangePassword sets the authenticated player's password. The player's other
+// sessions are logged out.
+func (c *PasswordController) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	sessionID, _ := middleware.SessionIDFromContext(ctx)
+	if err := c.service.ChangePassword(ctx, playerID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		writePasswordError(w, err, "changing password")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetEmail sets or removes the authenticated player's email address.
+func (c *PasswordController) SetEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Password string `json:"password"`
+		Email    string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	if err := c.service.SetEmail(ctx, playerID, req.Password, req.Email); err != nil {
+		writePasswordError(w, err, "setting email")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestReset emails a password reset link. It responds the same whether or
+// not the player exists.
+func (c *PasswordController) RequestReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Name == "" && req.Email == "") {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	nameOrEmail := req.Name
+	if req.Email != "" {
+		nameOrEmail = req.Email
+	}
+	if err := c.service.RequestReset(r.Context(), nameOrEmail); err != nil {
+		// Failing visibly would reveal that the player exists.
+		log.Printf("error requesting password reset for %q: %v", nameOrEmail, err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with a token from a reset email.
+func (c *PasswordController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.Password == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		writePasswordError(w, err, "resetting password")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePasswordError(w http.ResponseWriter, err error, action string) {
+	switch err.Error() {
+	case "invalid_password":
+		http.Error(w, "Incorrect password", http.StatusForbidden)
+	case "password_too_short":
+		http.Error(w, "Password is too short", http.StatusBadRequest)
+	case "invalid_email":
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+	case "email_taken":
+		http.Error(w, "Email address is already in use", http.StatusConflict)
+	case "invalid_reset_token":
+		http.Error(w, "Invalid or expired reset link", http.StatusBadRequest)
+	default:
+		log.Printf("error %s: %v", action, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
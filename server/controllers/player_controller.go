@@ -2,10 +2,15 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"battle-wordle/server/dto"
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/models"
 	"battle-wordle/server/services"
 
 	"github.com/google/uuid"
@@ -49,7 +54,8 @@ func (c *PlayerController) Register(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Name     string  `json:"name"`
 		Password *string `json:"password,omitempty"`
-		ID       *string `json:"id,omitempty"`
+		// GuestToken upgrades the guest it was issued to instead of creating a player.
+		GuestToken *string `json:"guest_token,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
@@ -58,31 +64,24 @@ func (c *PlayerController) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	player, jwtToken, err := c.service.CreatePlayer(ctx, req.Name, req.Password, req.ID)
+	player, tokens, err := c.service.CreatePlayer(ctx, req.Name, req.Password, req.GuestToken, sessionClient(r))
 	if err != nil {
-		if err.Error() == "username_taken" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Username is already taken."})
+		if writeNameError(w, err) {
 			return
 		}
-		log.Printf("error creating player: %v", err)
-		http.Error(w, "Failed to create player", http.StatusInternalServerError)
+		switch err.Error() {
+		case "invalid_guest_token":
+			http.Error(w, "Invalid guest token", http.StatusUnauthorized)
+		case "player_banned":
+			http.Error(w, "Player is banned", http.StatusForbidden)
+		default:
+			log.Printf("error creating player: %v", err)
+			http.Error(w, "Failed to create player", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if player.Registered && jwtToken != nil {
-		json.NewEncoder(w).Encode(struct {
-			Player *dto.PlayerDTO `json:"player"`
-			Token  string         `json:"token"`
-		}{
-			Player: dto.MapPlayer(player),
-			Token:  *jwtToken,
-		})
-	} else {
-		json.NewEncoder(w).Encode(dto.MapPlayer(player))
-	}
+	writeAuthResponse(w, player, tokens)
 }
 
 func (c *PlayerController) Login(w http.ResponseWriter, r *http.Request) {
@@ -97,22 +96,233 @@ func (c *PlayerController) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	player, jwtToken, err := c.service.Login(ctx, req.Name, req.Password)
-	if err != nil {
+	player, tokens, err := c.service.Login(ctx, req.Name, req.Password, sessionClient(r))
+	var locked *services.LoginLockedError
+	if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(middleware.RetryAfterSeconds(locked.RetryAfter)))
+		http.Error(w, "Too many failed logins, try again later", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil && err.Error() == "player_banned" {
+		http.Error(w, "Player is banned", http.StatusForbidden)
+		return
+	}
+	if err != nil || player == nil {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
+	writeAuthResponse(w, player, tokens)
+}
+
+// Refresh exchanges a refresh token for new tokens. A refresh token can only
+// be used once; reusing one ends its session.
+func (c *PlayerController) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	player, tokens, err := c.service.Refresh(r.Context(), req.RefreshToken, sessionClient(r))
+	if err != nil {
+		switch err.Error() {
+		case "invalid_refresh_token", "refresh_token_reused":
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		case "player_banned":
+			http.Error(w, "Player is banned", http.StatusForbidden)
+		default:
+			log.Printf("error refreshing session: %v", err)
+			http.Error(w, "Failed to refresh session", http.StatusInternalServerError)
+		}
+		return
+	}
+	writeAuthResponse(w, player, tokens)
+}
+
+// Logout ends the session the request was made with.
+func (c *PlayerController) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	sessionID, _ := middleware.SessionIDFromContext(ctx)
+	if err := c.service.RevokeSession(ctx, playerID, sessionID); err != nil && err.Error() != "session_not_found" {
+		log.Printf("error logging out session %q: %v", sessionID, err)
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSessions lists the authenticated player's active sessions.
+func (c *PlayerController) GetSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	sessionID, _ := middleware.SessionIDFromContext(ctx)
+	sessions, err := c.service.ListSessions(ctx, playerID, sessionID)
+	if err != nil {
+		log.Printf("error listing sessions for player %q: %v", playerID, err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []*models.Session{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession ends one of the authenticated player's sessions, or every
+// session but the current one when no session ID is given.
+func (c *PlayerController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	sessionID, ok := mux.Vars(r)["id"]
+	if !ok {
+		currentID, _ := middleware.SessionIDFromContext(ctx)
+		revoked, err := c.service.RevokeOtherSessions(ctx, playerID, currentID)
+		if err != nil {
+			log.Printf("error revoking sessions for player %q: %v", playerID, err)
+			http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+		return
+	}
+
+	if err := c.service.RevokeSession(ctx, playerID, sessionID); err != nil {
+		if err.Error() == "session_not_found" {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error revoking session %q: %v", sessionID, err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPlayerByName returns the player with the given name, or the player who
+// had it before renaming.
+func (c *PlayerController) GetPlayerByName(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	player, err := c.service.ResolveName(r.Context(), name)
+	if err != nil {
+		log.Printf("error resolving player name %q: %v", name, err)
+		http.Error(w, "Failed to find player", http.StatusInternalServerError)
+		return
+	}
+	if player == nil || player.DeletedAt != nil {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.MapPlayer(player))
+}
+
+// GetNameHistory returns the names a player used before, newest first.
+func (c *PlayerController) GetNameHistory(w http.ResponseWriter, r *http.Request) {
+	playerID := mux.Vars(r)["id"]
+	history, err := c.service.NameHistory(r.Context(), playerID)
+	if err != nil {
+		log.Printf("error fetching name history of player %q: %v", playerID, err)
+		http.Error(w, "Failed to fetch name history", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// Rename changes the authenticated player's name. The old name keeps
+// resolving to the player.
+func (c *PlayerController) Rename(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	playerID, _ := middleware.PlayerIDFromContext(ctx)
+	player, err := c.service.Rename(ctx, playerID, req.Name)
+	var cooldown *services.RenameCooldownError
+	if errors.As(err, &cooldown) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(middleware.RetryAfterSeconds(time.Until(cooldown.Until))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":        "You renamed recently.",
+			"renamable_at": cooldown.Until.UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err != nil {
+		if writeNameError(w, err) {
+			return
+		}
+		switch err.Error() {
+		case "player_not_registered":
+			http.Error(w, "Guests choose a name when they register", http.StatusForbidden)
+		case "player_not_found":
+			http.Error(w, "Player not found", http.StatusNotFound)
+		default:
+			log.Printf("error renaming player %q: %v", playerID, err)
+			http.Error(w, "Failed to rename player", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.MapPlayer(player))
+}
+
+// writeNameError writes the response for a name that cannot be used, and
+// reports whether err was about the name.
+func writeNameError(w http.ResponseWriter, err error) bool {
+	status := http.StatusBadRequest
+	var message string
+	switch err.Error() {
+	case "invalid_name":
+		message = "Names must be 3 to 20 letters, digits, '_', '-' or '.', starting and ending with a letter or digit."
+	case "name_not_allowed":
+		message = "This name is not allowed."
+	case "username_taken":
+		status = http.StatusConflict
+		message = "Username is already taken."
+	default:
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	return true
+}
+
+// writeAuthResponse returns a player with the tokens of their session. The
+// access token is sent as "token", as it was before refresh tokens existed.
+func writeAuthResponse(w http.ResponseWriter, player *models.Player, tokens *services.AuthTokens) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(struct {
-		Player *dto.PlayerDTO `json:"player"`
-		Token  string         `json:"token"`
+		Player       *dto.PlayerDTO `json:"player"`
+		Token        string         `json:"token"`
+		RefreshToken string         `json:"refresh_token"`
+		ExpiresIn    int            `json:"expires_in"`
 	}{
-		Player: dto.MapPlayer(player),
-		Token:  *jwtToken,
+		Player:       dto.MapPlayer(player),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
 	})
 }
 
+func sessionClient(r *http.Request) services.SessionClient {
+	return services.SessionClient{UserAgent: r.UserAgent(), IP: middleware.ClientIP(r)}
+}
+
 // SearchPlayers allows searching for players by (partial) name, case-insensitive
 func (c *PlayerController) SearchPlayers(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
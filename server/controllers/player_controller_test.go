@@ -3,13 +3,17 @@ package controllers
 import (
 	"battle-wordle/server/dto"
 	"battle-wordle/server/middleware"
+	"battle-wordle/server/ratelimit"
 	"battle-wordle/server/services"
 	"context"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"battle-wordle/server/models"
 
@@ -20,6 +24,7 @@ import (
 type mockPlayerRepo struct {
 	players map[string]*models.Player
 	byName  map[string]*models.Player
+	history []*models.NameChange
 }
 
 func newMockPlayerRepo() *mockPlayerRepo {
@@ -57,6 +62,119 @@ func (m *mockPlayerRepo) UpdateGuestToRegistered(ctx context.Context, id, newNam
 	m.byName[newName] = p
 	return nil
 }
+func (m *mockPlayerRepo) GetByEmail(ctx context.Context, email string) (*models.Player, error) {
+	for _, p := range m.players {
+		if p.Email != nil && *p.Email == email {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockPlayerRepo) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	p, ok := m.players[id]
+	if !ok || !p.Registered {
+		return &mockError{"player_not_found"}
+	}
+	p.PasswordHash = &passwordHash
+	return nil
+}
+func (m *mockPlayerRepo) UpdateEmail(ctx context.Context, id string, email *string) error {
+	p, ok := m.players[id]
+	if !ok {
+		return &mockError{"player_not_found"}
+	}
+	if email != nil {
+		if other, _ := m.GetByEmail(ctx, *email); other != nil && other.ID != id {
+			return &mockError{"email_taken"}
+		}
+	}
+	p.Email = email
+	return nil
+}
+func (m *mockPlayerRepo) Anonymise(ctx context.Context, id string, deletedAt time.Time) error {
+	p, ok := m.players[id]
+	if !ok || p.DeletedAt != nil {
+		return &mockError{"player_not_found"}
+	}
+	delete(m.byName, p.Name)
+	p.Name = "deleted-" + id
+	p.Registered = false
+	p.PasswordHash = nil
+	p.Email = nil
+	p.DeletedAt = &deletedAt
+	m.byName[p.Name] = p
+	return nil
+}
+func (m *mockPlayerRepo) RegisterGuest(ctx context.Context, id, newName string) error {
+	p, ok := m.players[id]
+	if !ok || p.Registered {
+		return &mockError{"guest_not_found_or_already_registered"}
+	}
+	if other, exists := m.byName[newName]; exists && other.ID != id {
+		return &mockError{"username_taken"}
+	}
+	delete(m.byName, p.Name)
+	p.Name = newName
+	p.Registered = true
+	m.byName[newName] = p
+	return nil
+}
+func (m *mockPlayerRepo) SetRole(ctx context.Context, id, role string) error {
+	p, ok := m.players[id]
+	if !ok {
+		return &mockError{"player_not_found"}
+	}
+	p.Role = role
+	return nil
+}
+func (m *mockPlayerRepo) SetBan(ctx context.Context, id string, bannedAt, until *time.Time, reason string) error {
+	p, ok := m.players[id]
+	if !ok {
+		return &mockError{"player_not_found"}
+	}
+	p.BannedAt, p.BannedUntil, p.BanReason = bannedAt, until, reason
+	return nil
+}
+func (m *mockPlayerRepo) Rename(ctx context.Context, id, newName string, at time.Time) error {
+	p, ok := m.players[id]
+	if !ok {
+		return &mockError{"player_not_found"}
+	}
+	if other, exists := m.byName[newName]; exists && other.ID != id {
+		return &mockError{"username_taken"}
+	}
+	m.history = append([]*models.NameChange{{PlayerID: id, Name: p.Name, ChangedAt: at}}, m.history...)
+	delete(m.byName, p.Name)
+	p.Name = newName
+	m.byName[newName] = p
+	return nil
+}
+func (m *mockPlayerRepo) GetNameHistory(ctx context.Context, id string) ([]*models.NameChange, error) {
+	var history []*models.NameChange
+	for _, c := range m.history {
+		if c.PlayerID == id {
+			history = append(history, c)
+		}
+	}
+	return history, nil
+}
+func (m *mockPlayerRepo) GetByFormerName(ctx context.Context, name string, since time.Time) (*models.Player, error) {
+	for _, c := range m.history {
+		if c.Name == name && !c.ChangedAt.Before(since) {
+			return m.players[c.PlayerID], nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockPlayerRepo) MergeGuest(ctx context.Context, guestID, targetID string, summarize func(game *models.Game) []*models.PlayerGameSummary) error {
+	guest, ok := m.players[guestID]
+	if !ok || guest.Registered {
+		return &mockError{"guest_not_found"}
+	}
+	delete(m.players, guestID)
+	delete(m.byName, guest.Name)
+	return nil
+}
 func (m *mockPlayerRepo) SearchByName(ctx context.Context, name string) ([]*models.Player, error) {
 	var result []*models.Player
 	for _, p := range m.byName {
@@ -72,9 +190,68 @@ func (e *mockError) Error() string { return e.msg }
 
 type mockError struct{ msg string }
 
+type mockSessionRepo struct {
+	tokens map[string]*models.RefreshToken
+}
+
+func newMockSessionRepo() *mockSessionRepo {
+	return &mockSessionRepo{tokens: make(map[string]*models.RefreshToken)}
+}
+func (m *mockSessionRepo) Create(ctx context.Context, token *models.RefreshToken) error {
+	m.tokens[token.ID] = token
+	return nil
+}
+func (m *mockSessionRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	for _, t := range m.tokens {
+		if t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockSessionRepo) Rotate(ctx context.Context, previousID string, next *models.RefreshToken) (bool, error) {
+	previous, ok := m.tokens[previousID]
+	if !ok || previous.RotatedAt != nil || previous.RevokedAt != nil {
+		return false, nil
+	}
+	rotatedAt := next.CreatedAt
+	previous.RotatedAt = &rotatedAt
+	m.tokens[next.ID] = next
+	return true, nil
+}
+func (m *mockSessionRepo) RevokeSession(ctx context.Context, sessionID string, now time.Time) (bool, error) {
+	active, _ := m.IsActive(ctx, sessionID, now)
+	for _, t := range m.tokens {
+		if t.SessionID == sessionID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return active, nil
+}
+func (m *mockSessionRepo) IsActive(ctx context.Context, sessionID string, now time.Time) (bool, error) {
+	for _, t := range m.tokens {
+		if t.SessionID == sessionID && t.RotatedAt == nil && t.RevokedAt == nil && t.ExpiresAt.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *mockSessionRepo) ListActive(ctx context.Context, playerID string, now time.Time) ([]*models.Session, error) {
+	var sessions []*models.Session
+	for _, t := range m.tokens {
+		if t.PlayerID == playerID && t.RotatedAt == nil && t.RevokedAt == nil && t.ExpiresAt.After(now) {
+			sessions = append(sessions, &models.Session{ID: t.SessionID, PlayerID: t.PlayerID, LastUsedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt})
+		}
+	}
+	return sessions, nil
+}
+func (m *mockSessionRepo) DeleteExpired(ctx context.Context, now time.Time) error {
+	return nil
+}
+
 func setupTestServer(t *testing.T) (*httptest.Server, *mockPlayerRepo, func()) {
 	repo := newMockPlayerRepo()
-	playerService := services.NewPlayerService(repo, "testsecret")
+	playerService := services.NewPlayerService(repo, newMockSessionRepo(), "testsecret")
 	playerController := NewPlayerController(playerService)
 
 	router := mux.NewRouter()
@@ -99,12 +276,40 @@ func TestPlayerAPI_GuestRegistration(t *testing.T) {
 	if resp.StatusCode != 200 {
 		t.Fatalf("Expected 200, got %d", resp.StatusCode)
 	}
-	var player dto.PlayerDTO
-	if err := json.NewDecoder(resp.Body).Decode(&player); err != nil {
+	var reg struct {
+		Player dto.PlayerDTO `json:"player"`
+		Token  string        `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reg); err != nil {
 		t.Fatalf("Decode failed: %v", err)
 	}
-	if player.Name != "guest1" || player.Registered {
-		t.Errorf("Expected guest player, got %+v", player)
+	if reg.Player.Name != "guest1" || reg.Player.Registered {
+		t.Errorf("Expected guest player, got %+v", reg.Player)
+	}
+	if reg.Token == "" {
+		t.Errorf("Expected a guest token, got empty string")
+	}
+
+	// Upgrading needs the guest's own token.
+	resp, err = http.Post(ts.URL+"/api/player/register", "application/json", strings.NewReader(`{"name":"user1","password":"pw","guest_token":"forged"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an invalid guest token, got %d", resp.StatusCode)
+	}
+	resp, err = http.Post(ts.URL+"/api/player/register", "application/json", strings.NewReader(`{"name":"user1","password":"pw","guest_token":"`+reg.Token+`"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var upgraded struct {
+		Player dto.PlayerDTO `json:"player"`
+	}
+	json.NewDecoder(resp.Body).Decode(&upgraded)
+	if resp.StatusCode != 200 || upgraded.Player.ID != reg.Player.ID || !upgraded.Player.Registered {
+		t.Errorf("Expected the guest to be upgraded, got %d %+v", resp.StatusCode, upgraded.Player)
 	}
 }
 
@@ -181,6 +386,73 @@ func TestPlayerAPI_LoginFailure(t *testing.T) {
 	}
 }
 
+func TestPlayerAPI_LoginLockout(t *testing.T) {
+	ts, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	resp, err := http.Post(ts.URL+"/api/player/register", "application/json", strings.NewReader(`{"name":"user4","password":"pw"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+
+	status := 0
+	for i := 0; i < 10 && status != http.StatusTooManyRequests; i++ {
+		resp, err := http.Post(ts.URL+"/api/player/login", "application/json", strings.NewReader(`{"name":"user4","password":"wrong"}`))
+		if err != nil {
+			t.Fatalf("Login POST failed: %v", err)
+		}
+		resp.Body.Close()
+		status = resp.StatusCode
+		if status == http.StatusTooManyRequests && resp.Header.Get("Retry-After") == "" {
+			t.Error("Expected a Retry-After header")
+		}
+	}
+	if status != http.StatusTooManyRequests {
+		t.Errorf("Expected repeated failed logins to be locked out, got %d", status)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limited := middleware.RateLimit(ratelimit.New(ratelimit.PerMinute(2)), middleware.ByIP)
+	// Requests from httptest come from 192.0.2.1, which is the trusted proxy.
+	_, proxy, _ := net.ParseCIDR("192.0.2.1/32")
+	h := middleware.RealIP([]*net.IPNet{proxy})(limited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		req := httptest.NewRequest("GET", "/api/player/search?q=a", nil)
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("Request %d: expected %d, got %d", i+1, want, w.Code)
+		}
+		if want == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "30" {
+			t.Errorf("Expected to retry after 30 seconds, got %q", w.Header().Get("Retry-After"))
+		}
+	}
+	// Other clients have their own limit.
+	req := httptest.NewRequest("GET", "/api/player/search?q=a", nil)
+	req.Header.Set("X-Real-IP", "10.0.0.2")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected another IP address to be allowed, got %d", w.Code)
+	}
+
+	// Clients that are not the proxy cannot pick their own address.
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		req := httptest.NewRequest("GET", "/api/player/search?q=a", nil)
+		req.RemoteAddr = "203.0.113.9:4321"
+		req.Header.Set("X-Real-IP", fmt.Sprintf("10.0.1.%d", i))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("Direct request %d: expected %d, got %d", i+1, want, w.Code)
+		}
+	}
+}
+
 func TestPlayerAPI_GetPlayerByID(t *testing.T) {
 	ts, _, cleanup := setupTestServer(t)
 	defer cleanup()
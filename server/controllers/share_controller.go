@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"image/png"
+	"log"
+	"net/http"
+
+	"battle-wordle/server/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// sharePageTemplate is served to link preview crawlers. Browsers are sent on
+// to the game page in the UI.
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Battle Wordle: {{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:site_name" content="Battle Wordle">
+<meta property="og:title" content="Battle Wordle: {{.Title}}">
+<meta property="og:url" content="{{.URL}}">
+{{- if .Finished}}
+<meta property="og:description" content="{{.Summary}}">
+<meta property="og:image" content="{{.ImageURL}}">
+<meta name="twitter:card" content="summary_large_image">
+{{- end}}
+<meta http-equiv="refresh" content="0; url={{.URL}}">
+</head>
+<body><a href="{{.URL}}">{{.Title}}</a></body>
+</html>
+`))
+
+// ShareController handles shareable views of games.
+type ShareController struct {
+	service   *services.ShareService
+	publicURL string
+}
+
+// NewShareController creates a new ShareController. publicURL is the address
+// of the UI, which links and previews point to.
+func NewShareController(service *services.ShareService, publicURL string) *ShareController {
+	return &ShareController{service: service, publicURL: publicURL}
+}
+
+// GetShareText returns the emoji grid of a game and a link to it.
+func (c *ShareController) GetShareText(w http.ResponseWriter, r *http.Request) {
+	card, ok := c.shareCard(w, r)
+	if !ok {
+		return
+	}
+	url := c.gameURL(card.Game.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"title":   card.Title,
+		"summary": card.Summary,
+		"grid":    card.Grid,
+		"text":    card.Text(url),
+		"url":     url,
+	})
+}
+
+// GetShareImage returns the board of a game as a PNG image.
+func (c *ShareController) GetShareImage(w http.ResponseWriter, r *http.Request) {
+	card, ok := c.shareCard(w, r)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, services.RenderBoard(card)); err != nil {
+		log.Printf("error rendering game %q: %v", card.Game.ID, err)
+		http.Error(w, "Failed to render game", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	setShareCacheControl(w, card)
+	w.Write(buf.Bytes())
+}
+
+// GetSharePage returns an HTML page with Open Graph tags for link previews.
+// Only finished games get a description and an image.
+func (c *ShareController) GetSharePage(w http.ResponseWriter, r *http.Request) {
+	card, ok := c.shareCard(w, r)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	err := sharePageTemplate.Execute(&buf, map[string]any{
+		"Title":    card.Title,
+		"Summary":  card.Summary,
+		"Finished": card.Game.Finished(),
+		"URL":      c.gameURL(card.Game.ID),
+		"ImageURL": c.publicURL + "/api/game/" + card.Game.ID + "/image.png",
+	})
+	if err != nil {
+		log.Printf("error rendering share page for game %q: %v", card.Game.ID, err)
+		http.Error(w, "Failed to render game", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	setShareCacheControl(w, card)
+	w.Write(buf.Bytes())
+}
+
+func (c *ShareController) shareCard(w http.ResponseWriter, r *http.Request) (*services.ShareCard, bool) {
+	gameID := mux.Vars(r)["id"]
+	if err := uuid.Validate(gameID); err != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return nil, false
+	}
+	card, err := c.service.GetShareCard(r.Context(), gameID)
+	if err != nil {
+		if err.Error() == "game_not_found" {
+			http.Error(w, "Game not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Printf("error getting share card for game %q: %v", gameID, err)
+		http.Error(w, "Failed to get game", http.StatusInternalServerError)
+		return nil, false
+	}
+	return card, true
+}
+
+// setShareCacheControl lets finished games, which no longer change, be cached.
+func setShareCacheControl(w http.ResponseWriter, card *services.ShareCard) {
+	if card.Game.Finished() {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+}
+
+// gameURL returns the URL of the game's page in the UI.
+func (c *ShareController) gameURL(gameID string) string {
+	return c.publicURL + "/game/" + gameID
+}
@@ -5,10 +5,16 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 )
 
+const (
+	defaultRecentGames = 10
+	maxRecentGames     = 50
+)
+
 // StatsController handles HTTP requests related to player and game statistics.
 type StatsController struct {
 	service *services.StatsService
@@ -30,10 +36,47 @@ func (c *StatsController) GetHeadToHeadStats(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	stats, err := c.service.GetHeadToHeadStats(ctx, firstPlayerID, secondPlayerID)
+	recentGames := defaultRecentGames
+	if v := r.URL.Query().Get("recent"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxRecentGames {
+			http.Error(w, "Invalid recent query parameter", http.StatusBadRequest)
+			return
+		}
+		recentGames = n
+	}
+
+	stats, err := c.service.GetHeadToHeadStats(ctx, firstPlayerID, secondPlayerID, recentGames)
 	if err != nil {
+		if err.Error() == "player_not_found" {
+			http.Error(w, "Stats not found", http.StatusNotFound)
+			return
+		}
 		log.Printf("error fetching head to head stats for players %q and %q: %v", firstPlayerID, secondPlayerID, err)
-		http.Error(w, "Stats not found", http.StatusNotFound)
+		http.Error(w, "Failed to fetch stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (c *StatsController) GetPlayerStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playerID := mux.Vars(r)["id"]
+	if playerID == "" {
+		http.Error(w, "Missing required parameter: id", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := c.service.GetPlayerStats(ctx, playerID)
+	if err != nil {
+		if err.Error() == "player_not_found" {
+			http.Error(w, "Player not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("error fetching stats for player %q: %v", playerID, err)
+		http.Error(w, "Failed to fetch stats", http.StatusInternalServerError)
 		return
 	}
 
@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"battle-wordle/server/middleware"
 	"battle-wordle/server/models"
 	"battle-wordle/server/services"
 	"battle-wordle/server/ws"
 	"context"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -16,19 +20,31 @@ import (
 type WSGameController struct {
 	gameService   *services.GameService
 	playerService *services.PlayerService
+	chatService   *services.ChatService
 	gameHub       *ws.Hub
+	sessionHub    *ws.Hub
+	limits        messageLimiter
 	upgrader      websocket.Upgrader
+	// viewers maps each connection to its authenticated player, or "" if it
+	// is not logged in, to decide who receives chat messages.
+	mu      sync.RWMutex
+	viewers map[*ws.Conn]string
 }
 
-func NewWSGameController(gameService *services.GameService, playerService *services.PlayerService, gameHub *ws.Hub) *WSGameController {
+func NewWSGameController(gameService *services.GameService, playerService *services.PlayerService, chatService *services.ChatService, gameHub, sessionHub *ws.Hub) *WSGameController {
 	return &WSGameController{
 		gameService:   gameService,
 		playerService: playerService,
+		chatService:   chatService,
 		gameHub:       gameHub,
+		sessionHub:    sessionHub,
+		limits:        newMessageLimiter(gameMessageLimits),
+		viewers:       make(map[*ws.Conn]string),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 			CheckOrigin:     func(r *http.Request) bool { return true },
+			Subprotocols:    []string{middleware.BearerSubprotocol},
 		},
 	}
 }
@@ -39,14 +55,24 @@ func (c *WSGameController) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	gameID := vars["id"]
 	ctx := r.Context()
 
-	conn, err := c.upgrader.Upgrade(w, r, nil)
+	upgraded, err := c.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, "Could not upgrade to WebSocket", http.StatusBadRequest)
 		return
 	}
+	conn := ws.NewConn(upgraded)
+	viewerID, _ := middleware.PlayerIDFromContext(ctx)
+	c.mu.Lock()
+	c.viewers[conn] = viewerID
+	c.mu.Unlock()
 	c.gameHub.AddConnection(gameID, conn)
+	untrack := trackSession(c.sessionHub, r, conn)
 	defer func() {
+		untrack()
 		c.gameHub.RemoveConnection(gameID, conn)
+		c.mu.Lock()
+		delete(c.viewers, conn)
+		c.mu.Unlock()
 		conn.Close()
 	}()
 
@@ -59,8 +85,10 @@ func (c *WSGameController) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 			Type     string `json:"type"`
 			Guess    string `json:"guess,omitempty"`
 			PlayerID string `json:"player_id,omitempty"`
+			Text     string `json:"text,omitempty"`
+			Mode     string `json:"mode,omitempty"`
 		}
-		if err := json.Unmarshal(msgData, &msg); err != nil {
+		if err := json.Unmarshal(msgData, &msg); err != nil || !c.limits.allow(conn, r, msg.Type) {
 			continue
 		}
 		switch msg.Type {
@@ -70,77 +98,140 @@ func (c *WSGameController) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 				continue
 			}
 			c.sendGameState(conn, game)
+			c.sendChatHistory(conn, gameID, viewerID)
 		case "guess":
 			game, err := c.gameService.GetByID(ctx, gameID)
 			if err != nil || game == nil {
 				continue
 			}
-			if msg.PlayerID != game.CurrentPlayer {
+			// Anyone may watch, but only the authenticated player may guess.
+			playerID, err := actingPlayerID(ctx, msg.PlayerID)
+			if err != nil || playerID != game.CurrentPlayer {
 				continue
 			}
-			updatedGame, err := c.gameService.SubmitGuess(ctx, gameID, msg.Guess, msg.PlayerID)
+			updatedGame, err := c.gameService.SubmitGuess(ctx, gameID, msg.Guess, playerID)
 			if err != nil {
 				continue
 			}
 			c.broadcastGameState(gameID, updatedGame)
+		case "chat":
+			playerID, err := actingPlayerID(ctx, msg.PlayerID)
+			if err != nil {
+				c.sendChatError(conn, "chat_rejected", err)
+				continue
+			}
+			chat, err := c.chatService.Send(ctx, gameID, playerID, msg.Text)
+			if err != nil {
+				c.sendChatError(conn, "chat_rejected", err)
+				continue
+			}
+			c.broadcastChat(gameID, chat)
+		case "chat_settings":
+			playerID, err := actingPlayerID(ctx, msg.PlayerID)
+			if err == nil {
+				err = c.chatService.SetMode(ctx, gameID, playerID, msg.Mode)
+			}
+			if err != nil {
+				c.sendChatError(conn, "chat_settings_rejected", err)
+				continue
+			}
+			sendJSON(conn, map[string]string{"type": "chat_settings", "mode": msg.Mode})
+			// Messages hidden by the old mode may be visible now.
+			c.sendChatHistory(conn, gameID, playerID)
 		}
 	}
 }
 
-func (c *WSGameController) sendGameState(conn *websocket.Conn, game *models.Game) {
+// sendChatHistory sends the chat messages of the game the viewer may see,
+// and their chat mode if they play it.
+func (c *WSGameController) sendChatHistory(conn *ws.Conn, gameID, viewerID string) {
 	ctx := context.Background()
-	firstPlayer, _ := c.playerService.GetByID(ctx, game.FirstPlayer)
-	secondPlayer, _ := c.playerService.GetByID(ctx, game.SecondPlayer)
-	feedbacks := c.gameService.GetFeedbacks(game)
-	feedbackStrings := make([][]string, len(feedbacks))
-	for i, fb := range feedbacks {
-		feedbackStrings[i] = make([]string, len(fb))
-		for j, f := range fb {
-			feedbackStrings[i][j] = string(f)
-		}
-	}
-	var solutionPtr *string
-	if game.Result != "" {
-		solutionPtr = &game.Solution
+	messages, err := c.chatService.History(ctx, gameID, viewerID)
+	if err != nil {
+		log.Printf("error loading chat history of game %s: %v", gameID, err)
+		return
 	}
-	type PlayerSummary struct {
-		ID   string `json:"id"`
-		Name string `json:"name"`
+	mode := ""
+	if viewerID != "" {
+		if mode, err = c.chatService.Mode(ctx, gameID, viewerID); err != nil {
+			log.Printf("error loading chat mode of player %s: %v", viewerID, err)
+		}
 	}
-	type GameStateMessage struct {
-		Type          string        `json:"type"`
-		ID            string        `json:"id"`
-		CreatedAt     string        `json:"created_at"`
-		UpdatedAt     string        `json:"updated_at"`
-		FirstPlayer   PlayerSummary `json:"first_player"`
-		SecondPlayer  PlayerSummary `json:"second_player"`
-		CurrentPlayer string        `json:"current_player"`
-		Result        string        `json:"result"`
-		Guesses       []string      `json:"guesses"`
-		Feedback      [][]string    `json:"feedback"`
-		Solution      *string       `json:"solution,omitempty"`
+	sendJSON(conn, struct {
+		Type         string                `json:"type"`
+		Messages     []*models.ChatMessage `json:"messages"`
+		Mode         string                `json:"mode,omitempty"`
+		QuickPhrases []string              `json:"quick_phrases"`
+	}{"chat_history", messages, mode, services.QuickPhrases})
+}
+
+// broadcastChat sends a chat message to each connection to the game whose
+// viewer may see it.
+func (c *WSGameController) broadcastChat(gameID string, msg *models.ChatMessage) {
+	ctx := context.Background()
+	for _, conn := range c.gameHub.Connections(gameID) {
+		c.mu.RLock()
+		viewerID := c.viewers[conn]
+		c.mu.RUnlock()
+		visible, err := c.chatService.CanSee(ctx, viewerID, msg)
+		if err != nil {
+			log.Printf("error checking chat visibility for %q: %v", viewerID, err)
+			continue
+		}
+		if visible {
+			sendJSON(conn, struct {
+				Type string `json:"type"`
+				*models.ChatMessage
+			}{"chat", msg})
+		}
 	}
-	msg := GameStateMessage{
-		Type:          "game_state",
-		ID:            game.ID,
-		CreatedAt:     game.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:     game.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		FirstPlayer:   PlayerSummary{ID: game.FirstPlayer, Name: firstPlayer.Name},
-		SecondPlayer:  PlayerSummary{ID: game.SecondPlayer, Name: secondPlayer.Name},
-		CurrentPlayer: game.CurrentPlayer,
-		Result:        game.Result,
-		Guesses:       game.Guesses,
-		Feedback:      feedbackStrings,
-		Solution:      solutionPtr,
+}
+
+// sendChatError tells the client why its chat message or setting was
+// rejected.
+func (c *WSGameController) sendChatError(conn *ws.Conn, msgType string, err error) {
+	switch err.Error() {
+	case "unauthorized", "forbidden", "game_not_found", "not_a_participant",
+		"empty_message", "message_too_long", "message_not_allowed", "invalid_chat_mode":
+	default:
+		log.Printf("error handling %s: %v", msgType, err)
+		err = fmt.Errorf("internal_error")
 	}
-	data, err := json.Marshal(msg)
+	sendJSON(conn, map[string]string{"type": msgType, "reason": err.Error()})
+}
+
+func (c *WSGameController) sendGameState(conn *ws.Conn, game *models.Game) {
+	sendJSON(conn, c.gameStateMessage(game))
+}
+
+func (c *WSGameController) broadcastGameState(gameID string, game *models.Game) {
+	data, err := json.Marshal(c.gameStateMessage(game))
 	if err != nil {
 		return
 	}
-	conn.WriteMessage(websocket.TextMessage, data)
+	c.gameHub.Broadcast(gameID, data)
 }
 
-func (c *WSGameController) broadcastGameState(gameID string, game *models.Game) {
+type playerSummary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type gameStateMessage struct {
+	Type          string        `json:"type"`
+	ID            string        `json:"id"`
+	CreatedAt     string        `json:"created_at"`
+	UpdatedAt     string        `json:"updated_at"`
+	FirstPlayer   playerSummary `json:"first_player"`
+	SecondPlayer  playerSummary `json:"second_player"`
+	CurrentPlayer string        `json:"current_player"`
+	Result        string        `json:"result"`
+	Guesses       []string      `json:"guesses"`
+	Feedback      [][]string    `json:"feedback"`
+	Solution      *string       `json:"solution,omitempty"`
+}
+
+func (c *WSGameController) gameStateMessage(game *models.Game) gameStateMessage {
 	ctx := context.Background()
 	firstPlayer, _ := c.playerService.GetByID(ctx, game.FirstPlayer)
 	secondPlayer, _ := c.playerService.GetByID(ctx, game.SecondPlayer)
@@ -156,39 +247,28 @@ func (c *WSGameController) broadcastGameState(gameID string, game *models.Game)
 	if game.Result != "" {
 		solutionPtr = &game.Solution
 	}
-	type PlayerSummary struct {
-		ID   string `json:"id"`
-		Name string `json:"name"`
-	}
-	type GameStateMessage struct {
-		Type          string        `json:"type"`
-		ID            string        `json:"id"`
-		CreatedAt     string        `json:"created_at"`
-		UpdatedAt     string        `json:"updated_at"`
-		FirstPlayer   PlayerSummary `json:"first_player"`
-		SecondPlayer  PlayerSummary `json:"second_player"`
-		CurrentPlayer string        `json:"current_player"`
-		Result        string        `json:"result"`
-		Guesses       []string      `json:"guesses"`
-		Feedback      [][]string    `json:"feedback"`
-		Solution      *string       `json:"solution,omitempty"`
-	}
-	msg := GameStateMessage{
+	return gameStateMessage{
 		Type:          "game_state",
 		ID:            game.ID,
 		CreatedAt:     game.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:     game.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		FirstPlayer:   PlayerSummary{ID: game.FirstPlayer, Name: firstPlayer.Name},
-		SecondPlayer:  PlayerSummary{ID: game.SecondPlayer, Name: secondPlayer.Name},
+		FirstPlayer:   playerSummary{ID: game.FirstPlayer, Name: firstPlayer.DisplayName()},
+		SecondPlayer:  playerSummary{ID: game.SecondPlayer, Name: secondPlayer.DisplayName()},
 		CurrentPlayer: game.CurrentPlayer,
 		Result:        game.Result,
 		Guesses:       game.Guesses,
 		Feedback:      feedbackStrings,
 		Solution:      solutionPtr,
 	}
-	data, err := json.Marshal(msg)
+}
+
+// sendJSON writes v to conn as a JSON text message. Game connections are
+// written by their own handler and by other players' handlers broadcasting
+// to the game, so every write goes through ws.Conn, which serializes them.
+func sendJSON(conn *ws.Conn, v any) {
+	data, err := json.Marshal(v)
 	if err != nil {
 		return
 	}
-	c.gameHub.Broadcast(gameID, data)
+	conn.WriteMessage(websocket.TextMessage, data)
 }
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"battle-wordle/server/middleware"
+	"battle-wordle/server/ratelimit"
+	"battle-wordle/server/ws"
+
+	"github.com/gorilla/websocket"
+)
+
+// Limits on how often a player, or an IP address for unauthenticated
+// connections, may send each type of WebSocket message. Types without a
+// limit are not limited.
+var (
+	gameMessageLimits = map[string]ratelimit.Limit{
+		"join":  ratelimit.PerMinute(30),
+		"guess": {Events: 10, Per: 10 * time.Second},
+		"chat":  {Events: 5, Per: 10 * time.Second},
+		// Changing the chat mode resends the chat history.
+		"chat_settings": ratelimit.PerMinute(10),
+	}
+	notificationMessageLimits = map[string]ratelimit.Limit{
+		"challenge_invite":   ratelimit.PerMinute(5),
+		"challenge_response": ratelimit.PerMinute(20),
+		"challenge_cancel":   ratelimit.PerMinute(20),
+		"rematch_offer":      ratelimit.PerMinute(5),
+		"rematch_response":   ratelimit.PerMinute(20),
+	}
+)
+
+// messageLimiter limits WebSocket messages by type. The limits are shared by
+// all of a player's connections, so reconnecting does not reset them.
+type messageLimiter map[string]*ratelimit.Limiter
+
+func newMessageLimiter(limits map[string]ratelimit.Limit) messageLimiter {
+	l := make(messageLimiter, len(limits))
+	for msgType, limit := range limits {
+		l[msgType] = ratelimit.New(limit)
+	}
+	return l
+}
+
+// allow reports whether the sender of r may send a message of msgType. If
+// not, it tells the client when it may send one again.
+func (l messageLimiter) allow(conn *ws.Conn, r *http.Request, msgType string) bool {
+	limiter, ok := l[msgType]
+	if !ok {
+		return true
+	}
+	allowed, retryAfter := limiter.Allow(middleware.ByPlayer(r))
+	if allowed {
+		return true
+	}
+	b, _ := json.Marshal(struct {
+		Type        string `json:"type"`
+		MessageType string `json:"message_type"`
+		RetryAfter  int    `json:"retry_after"`
+	}{
+		Type:        "rate_limited",
+		MessageType: msgType,
+		RetryAfter:  middleware.RetryAfterSeconds(retryAfter),
+	})
+	conn.WriteMessage(websocket.TextMessage, b)
+	return false
+}
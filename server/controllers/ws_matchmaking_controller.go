@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"battle-wordle/server/middleware"
 	"battle-wordle/server/services"
+	"battle-wordle/server/ws"
 	"encoding/json"
 	"net/http"
 
@@ -11,30 +13,35 @@ import (
 // WSMatchmakingController handles matchmaking WebSocket connections.
 type WSMatchmakingController struct {
 	matchmakingService *services.MatchmakingService
+	sessionHub         *ws.Hub
 	upgrader           websocket.Upgrader
 }
 
-func NewWSMatchmakingController(matchmakingService *services.MatchmakingService) *WSMatchmakingController {
+func NewWSMatchmakingController(matchmakingService *services.MatchmakingService, sessionHub *ws.Hub) *WSMatchmakingController {
 	return &WSMatchmakingController{
 		matchmakingService: matchmakingService,
+		sessionHub:         sessionHub,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 			CheckOrigin:     func(r *http.Request) bool { return true },
+			Subprotocols:    []string{middleware.BearerSubprotocol},
 		},
 	}
 }
 
 // HandleWebSocket handles the matchmaking WebSocket connection.
 func (c *WSMatchmakingController) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := c.upgrader.Upgrade(w, r, nil)
+	upgraded, err := c.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, "Could not upgrade to WebSocket", http.StatusBadRequest)
 		return
 	}
+	conn := ws.NewConn(upgraded)
 	defer conn.Close()
+	defer trackSession(c.sessionHub, r, conn)()
 
-	// Read player ID from first message
+	// The first message joins; a player ID in it must be the authenticated player's.
 	_, msg, err := conn.ReadMessage()
 	if err != nil {
 		return
@@ -43,11 +50,13 @@ func (c *WSMatchmakingController) HandleWebSocket(w http.ResponseWriter, r *http
 		Type     string `json:"type"`
 		PlayerID string `json:"player_id"`
 	}
-	if err := json.Unmarshal(msg, &joinMsg); err != nil || joinMsg.Type != "join" || joinMsg.PlayerID == "" {
+	if err := json.Unmarshal(msg, &joinMsg); err != nil || joinMsg.Type != "join" {
+		return
+	}
+	playerID, err := actingPlayerID(r.Context(), joinMsg.PlayerID)
+	if err != nil {
 		return
 	}
-
-	playerID := joinMsg.PlayerID
 	c.matchmakingService.JoinQueue(playerID, conn)
 
 	// Try to match
@@ -58,7 +67,7 @@ func (c *WSMatchmakingController) HandleWebSocket(w http.ResponseWriter, r *http
 		}{Type: "match_found", GameID: gameID}
 		b, _ := json.Marshal(resp)
 		for _, c := range conns {
-			if wsConn, ok := c.(*websocket.Conn); ok {
+			if wsConn, ok := c.(*ws.Conn); ok {
 				wsConn.WriteMessage(websocket.TextMessage, b)
 				wsConn.Close()
 			}
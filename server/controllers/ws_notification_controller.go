@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"battle-wordle/server/middleware"
 	"battle-wordle/server/services"
+	"battle-wordle/server/ws"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -14,32 +16,43 @@ type WSNotificationController struct {
 	gameService        *services.GameService
 	playerService      *services.PlayerService
 	matchmakingService *services.MatchmakingService
+	blockService       *services.BlockService
+	challengeService   *services.ChallengeService
+	sessionHub         *ws.Hub
+	limits             messageLimiter
 	upgrader           websocket.Upgrader
 }
 
-func NewWSNotificationController(gameService *services.GameService, playerService *services.PlayerService, matchmakingService *services.MatchmakingService) *WSNotificationController {
+func NewWSNotificationController(gameService *services.GameService, playerService *services.PlayerService, matchmakingService *services.MatchmakingService, blockService *services.BlockService, challengeService *services.ChallengeService, sessionHub *ws.Hub) *WSNotificationController {
 	return &WSNotificationController{
 		gameService:        gameService,
 		playerService:      playerService,
 		matchmakingService: matchmakingService,
+		blockService:       blockService,
+		challengeService:   challengeService,
+		sessionHub:         sessionHub,
+		limits:             newMessageLimiter(notificationMessageLimits),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 			CheckOrigin:     func(r *http.Request) bool { return true },
+			Subprotocols:    []string{middleware.BearerSubprotocol},
 		},
 	}
 }
 
 // HandleWebSocket handles the notification WebSocket connection.
 func (c *WSNotificationController) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := c.upgrader.Upgrade(w, r, nil)
+	upgraded, err := c.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, "Could not upgrade to WebSocket", http.StatusBadRequest)
 		return
 	}
+	conn := ws.NewConn(upgraded)
 	defer conn.Close()
+	defer trackSession(c.sessionHub, r, conn)()
 
-	// Read player ID from first message
+	// The first message joins; a player ID in it must be the authenticated player's.
 	_, msg, err := conn.ReadMessage()
 	if err != nil {
 		return
@@ -48,100 +61,80 @@ func (c *WSNotificationController) HandleWebSocket(w http.ResponseWriter, r *htt
 		Type     string `json:"type"`
 		PlayerID string `json:"player_id"`
 	}
-	if err := json.Unmarshal(msg, &joinMsg); err != nil || joinMsg.Type != "join" || joinMsg.PlayerID == "" {
+	if err := json.Unmarshal(msg, &joinMsg); err != nil || joinMsg.Type != "join" {
+		return
+	}
+	playerID, err := actingPlayerID(r.Context(), joinMsg.PlayerID)
+	if err != nil {
 		return
 	}
-
-	playerID := joinMsg.PlayerID
 	c.matchmakingService.RegisterConnection(playerID, conn)
 	log.Printf("[notif-ws] Registered playerID=%s", playerID)
 	defer c.matchmakingService.UnregisterConnection(playerID)
+	// Deliver the challenges received while offline.
+	if invites, err := c.challengeService.Incoming(r.Context(), playerID); err != nil {
+		log.Printf("[notif-ws] Failed to load pending challenges for %s: %v", playerID, err)
+	} else {
+		for _, invite := range invites {
+			b, _ := json.Marshal(invite)
+			conn.WriteMessage(websocket.TextMessage, b)
+		}
+	}
 
 	for {
 		_, msgData, err := conn.ReadMessage()
 		if err != nil {
 			return
 		}
-		// Parse message type
+		// Messages are only relayed from the connected player.
 		var baseMsg struct {
 			Type string `json:"type"`
 		}
-		if err := json.Unmarshal(msgData, &baseMsg); err != nil {
+		if err := json.Unmarshal(msgData, &baseMsg); err != nil || !c.limits.allow(conn, r, baseMsg.Type) {
 			continue
 		}
 		switch baseMsg.Type {
 		case "challenge_invite":
 			var invite struct {
-				Type     string `json:"type"`
-				From     string `json:"from"`
-				To       string `json:"to"`
-				FromName string `json:"from_name"`
-				Rematch  bool   `json:"rematch"`
+				From    string `json:"from"`
+				To      string `json:"to"`
+				Rematch bool   `json:"rematch"`
 			}
-			if err := json.Unmarshal(msgData, &invite); err != nil || invite.From == "" || invite.To == "" {
+			if err := json.Unmarshal(msgData, &invite); err != nil || invite.From != playerID || invite.To == "" {
 				continue
 			}
-			log.Printf("[notif-ws] challenge_invite: from=%s to=%s from_name=%s rematch=%v", invite.From, invite.To, invite.FromName, invite.Rematch)
-			// Forward to target player if online
-			if targetConn, ok := c.matchmakingService.OnlineConnection(invite.To); ok {
-				b, _ := json.Marshal(invite)
-				log.Printf("[notif-ws] Forwarding challenge_invite to %s: %s", invite.To, string(b))
-				if wsConn, ok := targetConn.(*websocket.Conn); ok {
-					wsConn.WriteMessage(websocket.TextMessage, b)
-				}
-			} else {
-				log.Printf("[notif-ws] Target player %s not online for challenge_invite", invite.To)
+			log.Printf("[notif-ws] challenge_invite: from=%s to=%s rematch=%v", invite.From, invite.To, invite.Rematch)
+			// The invite reaches the challenged player now if they are online,
+			// or when they next connect. Blocked players are not told.
+			if _, err := c.challengeService.Invite(r.Context(), playerID, invite.To, invite.Rematch); err != nil {
+				log.Printf("[notif-ws] Failed to send challenge from %s to %s: %v", playerID, invite.To, err)
 			}
 		case "challenge_response":
 			var resp struct {
-				Type     string `json:"type"`
 				From     string `json:"from"`
 				To       string `json:"to"`
 				Accepted bool   `json:"accepted"`
-				Rematch  bool   `json:"rematch"`
 			}
-			if err := json.Unmarshal(msgData, &resp); err != nil || resp.From == "" || resp.To == "" {
+			if err := json.Unmarshal(msgData, &resp); err != nil || resp.From != playerID || resp.To == "" {
 				continue
 			}
-			log.Printf("[notif-ws] challenge_response: from=%s to=%s accepted=%v rematch=%v", resp.From, resp.To, resp.Accepted, resp.Rematch)
-			var gameID *string
-			if resp.Accepted {
-				// Create a new game if not a rematch (for rematch, frontend may handle differently)
-				game, err := c.gameService.CreateGame(r.Context(), resp.From, resp.To)
-				if err == nil && game != nil {
-					gid := game.ID
-					gameID = &gid
-					log.Printf("[notif-ws] Created game for challenge: id=%s", game.ID)
-				} else {
-					log.Printf("[notif-ws] Failed to create game for challenge: %v", err)
-				}
+			log.Printf("[notif-ws] challenge_response: from=%s to=%s accepted=%v", resp.From, resp.To, resp.Accepted)
+			// Both players are sent a challenge_result.
+			if _, err := c.challengeService.RespondTo(r.Context(), playerID, resp.To, resp.Accepted); err != nil {
+				log.Printf("[notif-ws] Failed to answer challenge from %s to %s: %v", resp.To, playerID, err)
 			}
-			// Send challenge_result to both players
-			result := struct {
-				Type     string  `json:"type"`
-				Accepted bool    `json:"accepted"`
-				GameID   *string `json:"game_id,omitempty"`
-				From     string  `json:"from"`
-				To       string  `json:"to"`
-				Rematch  bool    `json:"rematch"`
-			}{
-				Type:     "challenge_result",
-				Accepted: resp.Accepted,
-				GameID:   gameID,
-				From:     resp.From,
-				To:       resp.To,
-				Rematch:  resp.Rematch,
+		case "challenge_cancel":
+			var cancel struct {
+				From string `json:"from"`
+				To   string `json:"to"`
 			}
-			b, _ := json.Marshal(result)
-			for _, pid := range []string{resp.From, resp.To} {
-				if targetConn, ok := c.matchmakingService.OnlineConnection(pid); ok {
-					log.Printf("[notif-ws] Sending challenge_result to %s: %s", pid, string(b))
-					if wsConn, ok := targetConn.(*websocket.Conn); ok {
-						wsConn.WriteMessage(websocket.TextMessage, b)
-					}
-				} else {
-					log.Printf("[notif-ws] Target player %s not online for challenge_result", pid)
-				}
+			if err := json.Unmarshal(msgData, &cancel); err != nil || cancel.From != playerID || cancel.To == "" {
+				continue
+			}
+			log.Printf("[notif-ws] challenge_cancel: from=%s to=%s", cancel.From, cancel.To)
+			// Both players are sent a challenge_cancelled.
+			if _, err := c.challengeService.CancelTo(r.Context(), playerID, cancel.To); err != nil {
+				log.Printf("[notif-ws] Failed to cancel challenge from %s to %s: %v", playerID, cancel.To, err)
 			}
 		case "rematch_offer":
 			var offer struct {
@@ -150,12 +143,12 @@ func (c *WSNotificationController) HandleWebSocket(w http.ResponseWriter, r *htt
 				To       string `json:"to"`
 				FromName string `json:"from_name"`
 			}
-			if err := json.Unmarshal(msgData, &offer); err != nil || offer.From == "" || offer.To == "" {
+			if err := json.Unmarshal(msgData, &offer); err != nil || offer.From != playerID || offer.To == "" || c.blocked(offer.From, offer.To) {
 				continue
 			}
 			if targetConn, ok := c.matchmakingService.OnlineConnection(offer.To); ok {
 				b, _ := json.Marshal(offer)
-				if wsConn, ok := targetConn.(*websocket.Conn); ok {
+				if wsConn, ok := targetConn.(*ws.Conn); ok {
 					wsConn.WriteMessage(websocket.TextMessage, b)
 				}
 			}
@@ -167,15 +160,64 @@ func (c *WSNotificationController) HandleWebSocket(w http.ResponseWriter, r *htt
 				Accepted bool   `json:"accepted"`
 				FromName string `json:"from_name"`
 			}
-			if err := json.Unmarshal(msgData, &resp); err != nil || resp.From == "" || resp.To == "" {
+			if err := json.Unmarshal(msgData, &resp); err != nil || resp.From != playerID || resp.To == "" || c.blocked(resp.From, resp.To) {
 				continue
 			}
 			if targetConn, ok := c.matchmakingService.OnlineConnection(resp.To); ok {
 				b, _ := json.Marshal(resp)
-				if wsConn, ok := targetConn.(*websocket.Conn); ok {
+				if wsConn, ok := targetConn.(*ws.Conn); ok {
 					wsConn.WriteMessage(websocket.TextMessage, b)
 				}
 			}
 		}
 	}
 }
+
+// blocked reports whether messages between two players are dropped because
+// one blocked the other. The sender is not told, so that blocks stay private.
+func (c *WSNotificationController) blocked(from, to string) bool {
+	if !c.blockService.Blocks(from, to) {
+		return false
+	}
+	log.Printf("[notif-ws] Dropping message from %s to %s: blocked", from, to)
+	return true
+}
+
+// NotifyAchievement pushes a newly unlocked achievement to the player if they are online.
+func (c *WSNotificationController) NotifyAchievement(playerID string, achievement services.Achievement) {
+	msg := struct {
+		Type        string               `json:"type"`
+		Achievement services.Achievement `json:"achievement"`
+	}{
+		Type:        "achievement_unlocked",
+		Achievement: achievement,
+	}
+	b, _ := json.Marshal(msg)
+	if targetConn, ok := c.matchmakingService.OnlineConnection(playerID); ok {
+		if wsConn, ok := targetConn.(*ws.Conn); ok {
+			wsConn.WriteMessage(websocket.TextMessage, b)
+		}
+	}
+}
+
+// NotifyFriend pushes a friend request or acceptance to the player if they
+// are online.
+func (c *WSNotificationController) NotifyFriend(playerID string, event services.FriendEvent) {
+	b, _ := json.Marshal(event)
+	if targetConn, ok := c.matchmakingService.OnlineConnection(playerID); ok {
+		if wsConn, ok := targetConn.(*ws.Conn); ok {
+			wsConn.WriteMessage(websocket.TextMessage, b)
+		}
+	}
+}
+
+// NotifyChallenge pushes a challenge event to the player if they are online.
+// Pending invites are delivered again when they connect.
+func (c *WSNotificationController) NotifyChallenge(playerID string, event services.ChallengeEvent) {
+	b, _ := json.Marshal(event)
+	if targetConn, ok := c.matchmakingService.OnlineConnection(playerID); ok {
+		if wsConn, ok := targetConn.(*ws.Conn); ok {
+			wsConn.WriteMessage(websocket.TextMessage, b)
+		}
+	}
+}
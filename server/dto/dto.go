@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"time"
+
 	"battle-wordle/server/models"
 )
 
@@ -10,7 +12,24 @@ type PlayerDTO struct {
 	Name       string `json:"name"`
 	Registered bool   `json:"registered"`
 	Elo        *int   `json:"elo,omitempty"`
-	CreatedAt  string `json:"created_at"`
+	// RatingDeviation is only set when the Glicko-2 rating system is in use.
+	RatingDeviation *float64 `json:"rating_deviation,omitempty"`
+	CreatedAt       string   `json:"created_at"`
+	// Deleted marks players who deleted their account.
+	Deleted bool `json:"deleted,omitempty"`
+	// Role is only set for moderators and admins.
+	Role string `json:"role,omitempty"`
+}
+
+// AdminPlayerDTO is a player as moderators and admins see them.
+type AdminPlayerDTO struct {
+	PlayerDTO
+	Name        string  `json:"name"`
+	Role        string  `json:"role"`
+	DeletedAt   *string `json:"deleted_at,omitempty"`
+	BannedAt    *string `json:"banned_at,omitempty"`
+	BannedUntil *string `json:"banned_until,omitempty"`
+	BanReason   string  `json:"ban_reason,omitempty"`
 }
 
 // GameDTO is the API-safe representation of a game.
@@ -38,12 +57,44 @@ func MapPlayer(p *models.Player) *PlayerDTO {
 		return nil
 	}
 	return &PlayerDTO{
-		ID:         p.ID,
-		Name:       p.Name,
-		Registered: p.Registered,
-		Elo:        p.Elo,
-		CreatedAt:  p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:              p.ID,
+		Name:            p.DisplayName(),
+		Registered:      p.Registered,
+		Elo:             p.Elo,
+		RatingDeviation: p.RatingDeviation,
+		CreatedAt:       p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Deleted:         p.DeletedAt != nil,
+		Role:            staffRole(p.Role),
+	}
+}
+
+func staffRole(role string) string {
+	if models.RoleAtLeast(role, models.RoleModerator) {
+		return role
+	}
+	return ""
+}
+
+// MapAdminPlayer maps a Player model to an AdminPlayerDTO, which keeps the
+// stored name of deleted players.
+func MapAdminPlayer(p *models.Player) *AdminPlayerDTO {
+	return &AdminPlayerDTO{
+		PlayerDTO:   *MapPlayer(p),
+		Name:        p.Name,
+		Role:        p.Role,
+		DeletedAt:   formatTime(p.DeletedAt),
+		BannedAt:    formatTime(p.BannedAt),
+		BannedUntil: formatTime(p.BannedUntil),
+		BanReason:   p.BanReason,
+	}
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
 	}
+	s := t.Format("2006-01-02T15:04:05Z07:00")
+	return &s
 }
 
 // MapGame maps a Game model to a GameDTO. Requires player lookup for names.
@@ -64,7 +115,58 @@ func MapGame(game *models.Game, firstPlayer *models.Player, secondPlayer *models
 
 func getName(p *models.Player) string {
 	if p != nil {
-		return p.Name
+		return p.DisplayName()
 	}
 	return "Unknown"
 }
+
+// LeaderboardEntryDTO is the API-safe representation of a leaderboard standing.
+type LeaderboardEntryDTO struct {
+	Rank   int              `json:"rank"`
+	Player PlayerSummaryDTO `json:"player"`
+	Rating *int             `json:"rating,omitempty"`
+	Points int              `json:"points"`
+	Games  int              `json:"games"`
+	Wins   int              `json:"wins"`
+	Draws  int              `json:"draws"`
+	Losses int              `json:"losses"`
+}
+
+// LeaderboardDTO is the API-safe representation of a leaderboard page.
+type LeaderboardDTO struct {
+	Sort       string                `json:"sort"`
+	Entries    []LeaderboardEntryDTO `json:"entries"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+	Me         *LeaderboardEntryDTO  `json:"me,omitempty"`
+}
+
+// MapLeaderboardEntry maps a LeaderboardEntry model to a LeaderboardEntryDTO.
+func MapLeaderboardEntry(e *models.LeaderboardEntry) *LeaderboardEntryDTO {
+	if e == nil {
+		return nil
+	}
+	return &LeaderboardEntryDTO{
+		Rank:   e.Rank,
+		Player: PlayerSummaryDTO{ID: e.PlayerID, Name: e.Name},
+		Rating: e.Rating,
+		Points: e.Points,
+		Games:  e.Games,
+		Wins:   e.Wins,
+		Draws:  e.Draws,
+		Losses: e.Losses,
+	}
+}
+
+// MapLeaderboard maps a leaderboard page to a LeaderboardDTO.
+func MapLeaderboard(sort string, entries []*models.LeaderboardEntry, nextCursor string, me *models.LeaderboardEntry) *LeaderboardDTO {
+	dtos := make([]LeaderboardEntryDTO, 0, len(entries))
+	for _, e := range entries {
+		dtos = append(dtos, *MapLeaderboardEntry(e))
+	}
+	return &LeaderboardDTO{
+		Sort:       sort,
+		Entries:    dtos,
+		NextCursor: nextCursor,
+		Me:         MapLeaderboardEntry(me),
+	}
+}
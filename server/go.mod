@@ -5,16 +5,16 @@ go 1.23.0
 toolchain go1.23.10
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/google/uuid v1.6.0
+	github.com/gorilla/handlers v1.5.2
 	github.com/gorilla/mux v1.8.1
 	github.com/gorilla/websocket v1.5.1
 	github.com/mattn/go-sqlite3 v1.14.28
+	golang.org/x/crypto v0.39.0
 )
 
 require (
 	github.com/felixge/httpsnoop v1.0.3 // indirect
-	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
-	github.com/gorilla/handlers v1.5.2 // indirect
-	golang.org/x/crypto v0.39.0 // indirect
 	golang.org/x/net v0.21.0 // indirect
 )
@@ -2,14 +2,21 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"battle-wordle/server/config"
 	"battle-wordle/server/controllers"
+	"battle-wordle/server/mail"
 	"battle-wordle/server/middleware"
+	"battle-wordle/server/models"
+	"battle-wordle/server/oidc"
+	"battle-wordle/server/ratelimit"
 	"battle-wordle/server/repositories"
 	"battle-wordle/server/services"
 	"battle-wordle/server/ws"
@@ -42,6 +49,26 @@ func loadWordList(path string) ([]string, error) {
 	return wordList, nil
 }
 
+// loadBlocklist reads blocked words, one per line. Blank lines and lines
+// starting with # are skipped.
+func loadBlocklist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	var words []string
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word != "" && !strings.HasPrefix(word, "#") {
+			words = append(words, word)
+		}
+	}
+	return words, scanner.Err()
+}
+
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -57,62 +84,282 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create player repository: %v", err)
 	}
+	leaderboardRepository, err := repositories.NewLeaderboardRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create leaderboard repository: %v", err)
+	}
+	analyticsRepository, err := repositories.NewAnalyticsRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create analytics repository: %v", err)
+	}
+	playerStatsRepository, err := repositories.NewPlayerStatsRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create player stats repository: %v", err)
+	}
+	achievementRepository, err := repositories.NewAchievementRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create achievement repository: %v", err)
+	}
+	sessionRepository, err := repositories.NewSessionRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create session repository: %v", err)
+	}
+	passwordResetRepository, err := repositories.NewPasswordResetRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create password reset repository: %v", err)
+	}
+	identityRepository, err := repositories.NewIdentityRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create identity repository: %v", err)
+	}
+	wordRepository, err := repositories.NewWordRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create word repository: %v", err)
+	}
+	auditRepository, err := repositories.NewAuditRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create audit repository: %v", err)
+	}
+	friendshipRepository, err := repositories.NewFriendshipRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create friendship repository: %v", err)
+	}
+	blockRepository, err := repositories.NewBlockRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create block repository: %v", err)
+	}
+	chatRepository, err := repositories.NewChatRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create chat repository: %v", err)
+	}
+	challengeRepository, err := repositories.NewChallengeRepository(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to create challenge repository: %v", err)
+	}
+
+	var mailer mail.Mailer
+	if cfg.SMTPHost != "" {
+		mailer = mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	} else {
+		log.Printf("SMTP_HOST is not set, writing mail to %s", cfg.MailOutboxDir)
+		if mailer, err = mail.NewOutboxMailer(cfg.MailOutboxDir, cfg.MailFrom); err != nil {
+			log.Fatalf("Failed to create mail outbox: %v", err)
+		}
+	}
+
+	var oidcProviders []*oidc.Provider
+	for _, p := range cfg.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(oidc.Config{
+			ID:           p.ID,
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.PublicURL + "/api/auth/oidc/" + p.ID + "/callback",
+		}, &http.Client{Timeout: 10 * time.Second}))
+	}
 
-	// Load word list
-	wordList, err := loadWordList("word_list.txt")
+	// Load word list, seeding it from word_list.txt on first start
+	wordList, err := wordRepository.List(context.Background())
 	if err != nil {
 		log.Fatalf("Failed to load word list: %v", err)
 	}
+	if len(wordList) == 0 {
+		seed, err := loadWordList("word_list.txt")
+		if err != nil {
+			log.Fatalf("Failed to load word list: %v", err)
+		}
+		if _, err := wordRepository.Add(context.Background(), seed, time.Now()); err != nil {
+			log.Fatalf("Failed to seed word list: %v", err)
+		}
+		if wordList, err = wordRepository.List(context.Background()); err != nil {
+			log.Fatalf("Failed to load word list: %v", err)
+		}
+	}
 
 	// Pass cfg.JWTSecret to player service (update player_service.go to accept it)
-	gameService := services.NewGameService(gameRepository, wordList)
-	playerService := services.NewPlayerService(playerRepository, cfg.JWTSecret)
-	statsService := services.NewStatsService(gameRepository, playerRepository)
+	ratingSystem, err := services.NewRatingSystem(cfg.RatingSystem, services.EloConfig{
+		InitialRating:      cfg.EloInitialRating,
+		KFactor:            cfg.EloKFactor,
+		ProvisionalKFactor: cfg.EloProvisionalKFactor,
+		ProvisionalGames:   cfg.EloProvisionalGames,
+	}, services.Glicko2Config{
+		Tau:          cfg.Glicko2Tau,
+		RatingPeriod: cfg.Glicko2RatingPeriod,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create rating system: %v", err)
+	}
+	ratingService := services.NewRatingService(playerRepository, gameRepository, ratingSystem)
+	gameService := services.NewGameService(gameRepository, wordList, ratingService)
+	playerService := services.NewPlayerService(playerRepository, sessionRepository, cfg.JWTSecret)
+	if cfg.NameBlocklistFile != "" {
+		blocklist, err := loadBlocklist(cfg.NameBlocklistFile)
+		if err != nil {
+			log.Fatalf("Failed to load name blocklist: %v", err)
+		}
+		playerService.SetNamePolicy(services.NewNamePolicy(blocklist))
+	}
+	passwordService := services.NewPasswordService(playerRepository, passwordResetRepository, playerService, mailer, cfg.PublicURL+"/reset-password")
+	oidcService := services.NewOIDCService(oidcProviders, playerRepository, identityRepository, playerService)
+	statsService := services.NewStatsService(gameRepository, playerRepository, playerStatsRepository)
+	achievementService := services.NewAchievementService(achievementRepository, gameRepository, playerRepository, playerStatsRepository, services.DefaultAchievements)
+	gameService.OnGameFinished(achievementService.EvaluateGame)
+	gameRecordService := services.NewGameRecordService(gameService, gameRepository, playerRepository)
+	shareService := services.NewShareService(gameRepository, playerRepository)
+	adminService := services.NewAdminService(playerRepository, gameRepository, wordRepository, auditRepository, gameService, ratingService, statsService, achievementService, playerService)
+	accountService := services.NewAccountService(playerRepository, gameRepository, achievementRepository, playerStatsRepository, chatRepository, gameService, gameRecordService, playerService)
 	matchmakingService := services.NewMatchmakingService(gameService)
+	accountService.OnGuestMerged(matchmakingService.ReassignPlayer)
+	blockService := services.NewBlockService(blockRepository, playerRepository, friendshipRepository)
+	matchmakingService.SetBlockFilter(blockService.BlocksAmong)
+	friendService := services.NewFriendService(friendshipRepository, playerRepository, matchmakingService, blockService)
+	challengeService := services.NewChallengeService(challengeRepository, playerRepository, gameService, blockService, cfg.ChallengeTTL)
+	var chatBlocklist []string
+	if cfg.ChatBlocklistFile != "" {
+		if chatBlocklist, err = loadBlocklist(cfg.ChatBlocklistFile); err != nil {
+			log.Fatalf("Failed to load chat blocklist: %v", err)
+		}
+	}
+	chatService := services.NewChatService(chatRepository, gameRepository, blockService, chatBlocklist, cfg.ChatSpectators)
+	leaderboardService := services.NewLeaderboardService(leaderboardRepository)
+	analyticsService := services.NewAnalyticsService(analyticsRepository)
+	go analyticsService.Run(context.Background(), cfg.AnalyticsInterval)
 
 	gameController := controllers.NewGameController(gameService, playerService)
 	playerController := controllers.NewPlayerController(playerService)
+	passwordController := controllers.NewPasswordController(passwordService)
+	oidcController := controllers.NewOIDCController(oidcService, cfg.PublicURL)
 	statsController := controllers.NewStatsController(statsService)
+	leaderboardController := controllers.NewLeaderboardController(leaderboardService)
+	analyticsController := controllers.NewAnalyticsController(analyticsService)
+	achievementController := controllers.NewAchievementController(achievementService)
+	gameRecordController := controllers.NewGameRecordController(gameRecordService)
+	shareController := controllers.NewShareController(shareService, cfg.PublicURL)
+	accountController := controllers.NewAccountController(accountService)
+	adminController := controllers.NewAdminController(adminService)
+	friendController := controllers.NewFriendController(friendService)
+	blockController := controllers.NewBlockController(blockService)
+	challengeController := controllers.NewChallengeController(challengeService)
 	gameHub := ws.NewHub()
-	wsGameController := controllers.NewWSGameController(gameService, playerService, gameHub)
-	wsMatchmakingController := controllers.NewWSMatchmakingController(matchmakingService)
-	wsNotificationController := controllers.NewWSNotificationController(gameService, playerService, matchmakingService)
+	// Connections by session, so that revoking a session disconnects it.
+	sessionHub := ws.NewHub()
+	playerService.OnSessionRevoked(func(sessionID string) {
+		sessionHub.CloseConnections(sessionID, "session revoked")
+	})
+	wsGameController := controllers.NewWSGameController(gameService, playerService, chatService, gameHub, sessionHub)
+	wsMatchmakingController := controllers.NewWSMatchmakingController(matchmakingService, sessionHub)
+	wsNotificationController := controllers.NewWSNotificationController(gameService, playerService, matchmakingService, blockService, challengeService, sessionHub)
+	achievementService.OnUnlocked(wsNotificationController.NotifyAchievement)
+	friendService.OnFriendEvent(wsNotificationController.NotifyFriend)
+	challengeService.OnChallengeEvent(wsNotificationController.NotifyChallenge)
+	// Check often enough that players hear of an expired challenge soon after.
+	go challengeService.Run(context.Background(), min(max(cfg.ChallengeTTL/10, time.Second), time.Minute))
+
+	// Rate limits for endpoints that guess at credentials or are expensive
+	authLimit := middleware.RateLimit(ratelimit.New(ratelimit.PerMinute(cfg.RateLimitAuth)), middleware.ByIP)
+	searchLimit := middleware.RateLimit(ratelimit.New(ratelimit.PerMinute(cfg.RateLimitSearch)), middleware.ByPlayer)
+	// The same limit as for challenge invites over the notification socket.
+	challengeLimit := middleware.RateLimit(ratelimit.New(ratelimit.PerMinute(5)), middleware.ByPlayer)
 
 	// Set up API routes
 	apiRouter := mux.NewRouter()
-	apiRouter.HandleFunc("/api/player/register", playerController.Register)
-	apiRouter.HandleFunc("/api/player/login", playerController.Login)
-	apiRouter.HandleFunc("/api/player/search", playerController.SearchPlayers)
+	apiRouter.Handle("/api/player/register", authLimit(http.HandlerFunc(playerController.Register)))
+	apiRouter.Handle("/api/player/login", authLimit(http.HandlerFunc(playerController.Login)))
+	apiRouter.HandleFunc("/api/player/refresh", playerController.Refresh).Methods("POST")
+	apiRouter.Handle("/api/player/logout", middleware.RequireAuth(http.HandlerFunc(playerController.Logout))).Methods("POST")
+	apiRouter.Handle("/api/player/sessions", middleware.RequireAuth(http.HandlerFunc(playerController.GetSessions))).Methods("GET")
+	apiRouter.Handle("/api/player/sessions", middleware.RequireAuth(http.HandlerFunc(playerController.RevokeSession))).Methods("DELETE")
+	apiRouter.Handle("/api/player/sessions/{id}", middleware.RequireAuth(http.HandlerFunc(playerController.RevokeSession))).Methods("DELETE")
+	apiRouter.Handle("/api/player/password", middleware.RequireAuth(http.HandlerFunc(passwordController.ChangePassword))).Methods("PUT")
+	apiRouter.Handle("/api/player/email", middleware.RequireAuth(http.HandlerFunc(passwordController.SetEmail))).Methods("PUT")
+	apiRouter.Handle("/api/player/password/reset", authLimit(http.HandlerFunc(passwordController.RequestReset))).Methods("POST")
+	apiRouter.Handle("/api/player/password/reset/confirm", authLimit(http.HandlerFunc(passwordController.ResetPassword))).Methods("POST")
+	apiRouter.Handle("/api/player/me/export", middleware.RequireAuth(http.HandlerFunc(accountController.ExportAccount))).Methods("GET")
+	apiRouter.Handle("/api/player/me", middleware.RequireAuth(http.HandlerFunc(accountController.DeleteAccount))).Methods("DELETE")
+	apiRouter.Handle("/api/player/me/merge", middleware.RequireAuth(http.HandlerFunc(accountController.MergeGuest))).Methods("POST")
+	apiRouter.Handle("/api/player/me/friends", middleware.RequireAuth(http.HandlerFunc(friendController.GetFriends))).Methods("GET")
+	apiRouter.Handle("/api/player/me/friends", middleware.RequireAuth(http.HandlerFunc(friendController.SendRequest))).Methods("POST")
+	apiRouter.Handle("/api/player/me/friends/{id}/accept", middleware.RequireAuth(http.HandlerFunc(friendController.AcceptRequest))).Methods("POST")
+	apiRouter.Handle("/api/player/me/friends/{id}/decline", middleware.RequireAuth(http.HandlerFunc(friendController.DeclineRequest))).Methods("POST")
+	apiRouter.Handle("/api/player/me/friends/{id}", middleware.RequireAuth(http.HandlerFunc(friendController.RemoveFriend))).Methods("DELETE")
+	apiRouter.Handle("/api/player/me/blocks", middleware.RequireAuth(http.HandlerFunc(blockController.GetBlocks))).Methods("GET")
+	apiRouter.Handle("/api/player/me/blocks", middleware.RequireAuth(http.HandlerFunc(blockController.Block))).Methods("POST")
+	apiRouter.Handle("/api/player/me/blocks/{id}", middleware.RequireAuth(http.HandlerFunc(blockController.Unblock))).Methods("DELETE")
+	apiRouter.Handle("/api/player/me/challenges", middleware.RequireAuth(http.HandlerFunc(challengeController.GetChallenges))).Methods("GET")
+	apiRouter.Handle("/api/player/me/challenges", middleware.RequireAuth(challengeLimit(http.HandlerFunc(challengeController.SendChallenge)))).Methods("POST")
+	apiRouter.Handle("/api/player/me/challenges/{id}/accept", middleware.RequireAuth(http.HandlerFunc(challengeController.AcceptChallenge))).Methods("POST")
+	apiRouter.Handle("/api/player/me/challenges/{id}/decline", middleware.RequireAuth(http.HandlerFunc(challengeController.DeclineChallenge))).Methods("POST")
+	apiRouter.Handle("/api/player/me/challenges/{id}", middleware.RequireAuth(http.HandlerFunc(challengeController.CancelChallenge))).Methods("DELETE")
+	apiRouter.Handle("/api/player/me/name", middleware.RequireAuth(http.HandlerFunc(playerController.Rename))).Methods("PUT")
+	apiRouter.HandleFunc("/api/auth/oidc/providers", oidcController.GetProviders).Methods("GET")
+	apiRouter.Handle("/api/auth/oidc/exchange", authLimit(http.HandlerFunc(oidcController.Exchange))).Methods("POST")
+	apiRouter.HandleFunc("/api/auth/oidc/{provider}/start", oidcController.StartLogin).Methods("POST")
+	apiRouter.HandleFunc("/api/auth/oidc/{provider}/callback", oidcController.Callback).Methods("GET")
+	apiRouter.Handle("/api/player/search", searchLimit(http.HandlerFunc(playerController.SearchPlayers)))
+	apiRouter.HandleFunc("/api/player/name/{name}", playerController.GetPlayerByName).Methods("GET")
 	apiRouter.HandleFunc("/api/player/{id}", playerController.GetPlayerByID)
+	apiRouter.HandleFunc("/api/player/{id}/names", playerController.GetNameHistory).Methods("GET")
 	apiRouter.HandleFunc("/api/player/{id}/games", gameController.GetGamesByPlayer)
+	apiRouter.HandleFunc("/api/player/{id}/games/export", gameRecordController.ExportPlayerGames)
+	apiRouter.HandleFunc("/api/player/{id}/stats", statsController.GetPlayerStats)
+	apiRouter.HandleFunc("/api/player/{id}/achievements", achievementController.GetPlayerAchievements)
+	apiRouter.Handle("/api/game/import", middleware.RequireAuth(http.HandlerFunc(gameRecordController.ImportGames))).Methods("POST")
+	apiRouter.HandleFunc("/api/game/{id}/export", gameRecordController.ExportGame)
+	apiRouter.HandleFunc("/api/game/{id}/share", shareController.GetShareText)
+	apiRouter.HandleFunc("/api/game/{id}/image.png", shareController.GetShareImage)
+	apiRouter.HandleFunc("/api/game/{id}/preview", shareController.GetSharePage)
+	moderator := middleware.RequireRole(adminService.Role, models.RoleModerator, models.RoleAdmin)
+	admin := middleware.RequireRole(adminService.Role, models.RoleAdmin)
+	apiRouter.Handle("/api/admin/players/{id}", moderator(http.HandlerFunc(adminController.GetPlayer))).Methods("GET")
+	apiRouter.Handle("/api/admin/players/{id}/ban", moderator(http.HandlerFunc(adminController.BanPlayer))).Methods("POST")
+	apiRouter.Handle("/api/admin/players/{id}/ban", moderator(http.HandlerFunc(adminController.UnbanPlayer))).Methods("DELETE")
+	apiRouter.Handle("/api/admin/players/{id}/name", moderator(http.HandlerFunc(adminController.RenamePlayer))).Methods("PUT")
+	apiRouter.Handle("/api/admin/players/{id}/role", admin(http.HandlerFunc(adminController.SetRole))).Methods("PUT")
+	apiRouter.Handle("/api/admin/games/{id}", moderator(http.HandlerFunc(adminController.GetGame))).Methods("GET")
+	apiRouter.Handle("/api/admin/games/{id}/result", admin(http.HandlerFunc(adminController.SetGameResult))).Methods("PUT")
+	apiRouter.Handle("/api/admin/words", admin(http.HandlerFunc(adminController.GetWords))).Methods("GET")
+	apiRouter.Handle("/api/admin/words", admin(http.HandlerFunc(adminController.AddWords))).Methods("POST")
+	apiRouter.Handle("/api/admin/words", admin(http.HandlerFunc(adminController.RemoveWords))).Methods("DELETE")
+	apiRouter.Handle("/api/admin/audit", admin(http.HandlerFunc(adminController.GetAuditLog))).Methods("GET")
+	apiRouter.HandleFunc("/api/stats/global", analyticsController.GetGlobalStats)
 	apiRouter.HandleFunc("/api/stats/h2h/{first_player}/{second_player}", statsController.GetHeadToHeadStats)
+	apiRouter.HandleFunc("/api/leaderboard", leaderboardController.GetLeaderboard)
 	// ... add any other API routes ...
 
-	// Set up WebSocket routes (no middleware)
+	// Set up WebSocket routes (authentication only)
 	wsRouter := mux.NewRouter()
 	wsRouter.HandleFunc("/ws/game/{id}", wsGameController.HandleWebSocket)
 	wsRouter.HandleFunc("/ws/matchmaking", wsMatchmakingController.HandleWebSocket)
 	wsRouter.HandleFunc("/ws/notifications", wsNotificationController.HandleWebSocket)
 
 	// Middleware
+	// Tokens are optional; handlers decide what an unauthenticated request may do.
+	authenticate := middleware.Authenticate(playerService.VerifyJWT)
+	// Every request counts against its IP address and, once authenticated,
+	// against its player.
+	ipLimit := middleware.RateLimit(ratelimit.New(ratelimit.PerMinute(cfg.RateLimitIP)), middleware.ByIP)
+	playerLimit := middleware.RateLimit(ratelimit.New(ratelimit.PerMinute(cfg.RateLimitPlayer)), middleware.ByPlayer)
+
 	// Define allowed CORS options
 	corsHandler := handlers.CORS(
 		handlers.AllowedOrigins([]string{"*"}), // Or use your frontend's origin
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
 		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		handlers.ExposedHeaders([]string{"X-Next-Cursor", "Retry-After"}),
 	)
 
 	// Compose middleware for API only
-	apiHandler := middleware.Logger(middleware.ErrorHandler(corsHandler(apiRouter)))
+	apiHandler := middleware.Logger(middleware.ErrorHandler(corsHandler(ipLimit(authenticate(playerLimit(apiRouter))))))
 
 	// Main router
 	mainRouter := mux.NewRouter()
-	mainRouter.PathPrefix("/ws/").Handler(wsRouter)
+	mainRouter.PathPrefix("/ws/").Handler(ipLimit(authenticate(wsRouter)))
 	mainRouter.PathPrefix("/").Handler(apiHandler)
 
 	// Start server
 	log.Printf("Server is running on port %s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, mainRouter); err != nil {
+	if err := http.ListenAndServe(":"+cfg.Port, middleware.RealIP(cfg.TrustedProxies)(mainRouter)); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
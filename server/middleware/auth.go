@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// BearerSubprotocol is the WebSocket subprotocol that carries a token.
+// Browsers cannot set headers on WebSocket requests, so clients offer the
+// subprotocols "bearer" and the token itself, and the server selects "bearer".
+const BearerSubprotocol = "bearer"
+
+// TokenVerifier returns the player and session a token was issued for, or an
+// error if the token is invalid or expired.
+type TokenVerifier func(ctx context.Context, token string) (playerID, sessionID string, err error)
+
+type identityKey struct{}
+
+type identity struct {
+	playerID  string
+	sessionID string
+}
+
+// WithIdentity returns a copy of ctx carrying the authenticated player's ID
+// and the session the request was made with.
+func WithIdentity(ctx context.Context, playerID, sessionID string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity{playerID: playerID, sessionID: sessionID})
+}
+
+// PlayerIDFromContext returns the ID of the authenticated player, if any.
+func PlayerIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(identityKey{}).(identity)
+	return id.playerID, ok && id.playerID != ""
+}
+
+// SessionIDFromContext returns the ID of the authenticated session, if any.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(identityKey{}).(identity)
+	return id.sessionID, ok && id.sessionID != ""
+}
+
+// Authenticate verifies the token from the Authorization header or the bearer
+// WebSocket subprotocol, in that order, and puts the player's ID in the
+// request context. Requests without a token pass through unauthenticated;
+// requests with an invalid token are rejected.
+func Authenticate(verify TokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := requestToken(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			playerID, sessionID, err := verify(r.Context(), token)
+			if err != nil {
+				unauthorized(w)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), playerID, sessionID)))
+		})
+	}
+}
+
+// RequireAuth rejects requests that Authenticate did not authenticate.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := PlayerIDFromContext(r.Context()); !ok {
+			unauthorized(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RoleLookup returns the role of a player, or "" if the player has none.
+type RoleLookup func(ctx context.Context, playerID string) (string, error)
+
+// RequireRole rejects requests that Authenticate did not authenticate, or
+// whose player's role is not one of allowed.
+func RequireRole(lookup RoleLookup, allowed ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			playerID, ok := PlayerIDFromContext(r.Context())
+			if !ok {
+				unauthorized(w)
+				return
+			}
+			role, err := lookup(r.Context(), playerID)
+			if err != nil {
+				http.Error(w, "Failed to check role", http.StatusInternalServerError)
+				return
+			}
+			for _, a := range allowed {
+				if role == a {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Forbidden"})
+		})
+	}
+}
+
+func requestToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if scheme, token, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(p))
+		}
+	}
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == BearerSubprotocol {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+}
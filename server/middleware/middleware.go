@@ -3,7 +3,9 @@ package middleware
 import (
 	"encoding/json"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -42,3 +44,45 @@ func (w *responseWriter) WriteHeader(status int) {
 	w.status = status
 	w.ResponseWriter.WriteHeader(status)
 }
+
+// RealIP sets the remote address of requests from the trusted proxies to the
+// client address they pass in X-Real-IP. Other clients could send any
+// address, so their header is ignored.
+func RealIP(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+			if realIP != nil && trustedProxy(trusted, ClientIP(r)) {
+				_, port, err := net.SplitHostPort(r.RemoteAddr)
+				if err != nil {
+					port = "0"
+				}
+				r.RemoteAddr = net.JoinHostPort(realIP.String(), port)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func trustedProxy(trusted []*net.IPNet, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client. Behind a trusted proxy,
+// RealIP has set it to the address the proxy passed on.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
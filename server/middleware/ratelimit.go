@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"battle-wordle/server/ratelimit"
+)
+
+// RateKey returns the key a request is rate limited by.
+type RateKey func(r *http.Request) string
+
+// ByIP limits requests by client IP address.
+func ByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// ByPlayer limits requests by authenticated player, and unauthenticated
+// requests by client IP address. It must run after Authenticate.
+func ByPlayer(r *http.Request) string {
+	if playerID, ok := PlayerIDFromContext(r.Context()); ok {
+		return "player:" + playerID
+	}
+	return ByIP(r)
+}
+
+// RateLimit rejects requests over the limiter's limit for their key with
+// 429 Too Many Requests.
+func RateLimit(limiter *ratelimit.Limiter, key RateKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retryAfter := limiter.Allow(key(r)); !ok {
+				TooManyRequests(w, retryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TooManyRequests writes a 429 response telling the client when to retry.
+func TooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds(retryAfter)))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{"error": "Too many requests"})
+}
+
+// RetryAfterSeconds rounds d up to whole seconds, and at least one.
+func RetryAfterSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PlayerAchievement records that a player unlocked an achievement.
+type PlayerAchievement struct {
+	PlayerID      string `json:"player_id"`
+	AchievementID string `json:"achievement_id"`
+	// GameID is the game that unlocked the achievement.
+	GameID     string    `json:"game_id"`
+	UnlockedAt time.Time `json:"unlocked_at"`
+}
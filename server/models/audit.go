@@ -0,0 +1,21 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records an action taken by a moderator or admin.
+type AuditEntry struct {
+	ID int64 `json:"id"`
+	// ActorID is the ID of the player who took the action.
+	ActorID string `json:"actor_id"`
+	// Action names the action, e.g. "ban_player".
+	Action string `json:"action"`
+	// TargetType is the kind of thing acted on: "player", "game" or "word".
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	// Details holds the action's parameters and the previous state, as JSON.
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// BlockedPlayer is a player someone blocked. Blocked players cannot
+// challenge, offer rematches to or chat with the player who blocked them, and
+// the two are never matched against each other.
+type BlockedPlayer struct {
+	PlayerID  string    `json:"player_id"`
+	Name      string    `json:"name"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Challenge statuses. Only pending challenges can change status.
+const (
+	ChallengePending   = "pending"
+	ChallengeAccepted  = "accepted"
+	ChallengeDeclined  = "declined"
+	ChallengeExpired   = "expired"
+	ChallengeCancelled = "cancelled"
+)
+
+// Challenge is an invitation from one player to play another.
+type Challenge struct {
+	ID             string `json:"id"`
+	ChallengerID   string `json:"challenger_id"`
+	ChallengerName string `json:"challenger_name"`
+	ChallengedID   string `json:"challenged_id"`
+	ChallengedName string `json:"challenged_name"`
+	// Rematch is set for challenges to replay the opponent of a game.
+	Rematch bool   `json:"rematch"`
+	Status  string `json:"status"`
+	// GameID is the game started when the challenge was accepted.
+	GameID      *string    `json:"game_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ChatMessage is a message a player sent in a game's chat.
+type ChatMessage struct {
+	ID       int64  `json:"id"`
+	GameID   string `json:"game_id"`
+	PlayerID string `json:"player_id"`
+	Text     string `json:"text"`
+	// QuickPhrase is set when Text is one of the predefined quick phrases.
+	QuickPhrase bool      `json:"quick_phrase"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Chat modes decide which of their opponent's messages a player receives.
+const (
+	ChatModeAll          = "all"
+	ChatModeQuickPhrases = "quick_phrases"
+	ChatModeMuted        = "muted"
+)
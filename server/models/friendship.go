@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Friendship statuses.
+const (
+	// FriendshipPending is a friend request the addressee has not answered.
+	FriendshipPending = "pending"
+	// FriendshipAccepted is a friendship both players agreed to.
+	FriendshipAccepted = "accepted"
+)
+
+// Friendship links two players. There is at most one friendship between any
+// two players, in either direction.
+type Friendship struct {
+	// RequesterID sent the friend request to AddresseeID.
+	RequesterID string
+	AddresseeID string
+	Status      string
+	CreatedAt   time.Time
+	// AcceptedAt is set once the request is accepted.
+	AcceptedAt *time.Time
+}
+
+// Friend is a friendship from one player's side.
+type Friend struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	// Incoming is set for pending requests the other player sent.
+	Incoming bool `json:"incoming"`
+	Online   bool `json:"online"`
+	// Since is when the friendship was accepted, or requested while pending.
+	Since time.Time `json:"since"`
+}
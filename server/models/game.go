@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Game represents a two-player Wordle game session.
 type Game struct {
@@ -8,6 +11,8 @@ type Game struct {
 	ID string `json:"id"`
 	// Solution is the word to be guessed.
 	Solution string `json:"solution"`
+	// Mode is the game mode (e.g., "classic").
+	Mode string `json:"mode"`
 	// CreatedAt is the timestamp when the game was created.
 	CreatedAt time.Time `json:"created_at"`
 	// UpdatedAt is the timestamp when the game was last updated.
@@ -22,4 +27,81 @@ type Game struct {
 	Result string `json:"result"`
 	// Guesses is the list of guesses made in the game.
 	Guesses []string `json:"guesses"`
+	// GuessTimes holds when each guess was made, parallel to Guesses (empty for older games).
+	GuessTimes []time.Time `json:"guess_times"`
+	// Imported marks games archived from a game record rather than played
+	// here; they are never rated or counted on leaderboards.
+	Imported bool `json:"imported"`
+	// ImportedBy is the player who imported the game, or empty if an admin
+	// did. A game imported by a player is left out of their opponent's history.
+	ImportedBy string `json:"-"`
+}
+
+// ModeClassic is the standard two-player mode.
+const ModeClassic = "classic"
+
+const (
+	// ResultDraw is the result of a game that ran out of guesses.
+	ResultDraw = "draw"
+	// ResultLosePrefix prefixes the ID of the player who lost the game.
+	ResultLosePrefix = "lose:"
+	// ResultVoid is the result of a game an admin voided. Voided games are
+	// over but count for no one.
+	ResultVoid = "void"
+)
+
+// Finished reports whether the game has a result.
+func (g *Game) Finished() bool {
+	return g.Result != ""
+}
+
+// IsDraw reports whether the game ended in a draw.
+func (g *Game) IsDraw() bool {
+	return g.Result == ResultDraw
+}
+
+// IsVoid reports whether the game was voided.
+func (g *Game) IsVoid() bool {
+	return g.Result == ResultVoid
+}
+
+// Loser returns the ID of the player who lost, or "" if there is no loser.
+func (g *Game) Loser() string {
+	if !strings.HasPrefix(g.Result, ResultLosePrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(g.Result, ResultLosePrefix)
+}
+
+// Winner returns the ID of the player who won, or "" if there is no winner.
+func (g *Game) Winner() string {
+	switch g.Loser() {
+	case "":
+		return ""
+	case g.FirstPlayer:
+		return g.SecondPlayer
+	default:
+		return g.FirstPlayer
+	}
+}
+
+// GuessedBy returns the ID of the player who made the guess at index i.
+// Players alternate turns, starting with the first player.
+func (g *Game) GuessedBy(i int) string {
+	if i%2 == 0 {
+		return g.FirstPlayer
+	}
+	return g.SecondPlayer
+}
+
+// HasPlayer reports whether playerID is one of the game's players.
+func (g *Game) HasPlayer(playerID string) bool {
+	return playerID != "" && (playerID == g.FirstPlayer || playerID == g.SecondPlayer)
+}
+
+// GameWithPlayers is a game together with the names of its players.
+type GameWithPlayers struct {
+	Game             *Game
+	FirstPlayerName  string
+	SecondPlayerName string
 }
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// GlobalStats are server-wide statistics over every finished game.
+type GlobalStats struct {
+	// GeneratedAt is when the statistics were computed.
+	GeneratedAt time.Time `json:"generated_at"`
+	// Games is the number of finished games.
+	Games int `json:"games"`
+	// AverageGuesses is the mean number of guesses per finished game.
+	AverageGuesses float64 `json:"average_guesses"`
+	// AverageDurationSeconds is the mean time from a game's creation to its end.
+	AverageDurationSeconds float64 `json:"average_duration_seconds"`
+	// FirstPlayerWins, SecondPlayerWins and Draws split finished games by who won.
+	FirstPlayerWins  int `json:"first_player_wins"`
+	SecondPlayerWins int `json:"second_player_wins"`
+	Draws            int `json:"draws"`
+	// Openings are the most popular opening guesses, most used first.
+	Openings []*OpeningPerformance `json:"openings"`
+	// HardestWords and EasiestWords are solutions ranked by solve rate.
+	HardestWords []*WordDifficulty `json:"hardest_words"`
+	EasiestWords []*WordDifficulty `json:"easiest_words"`
+	// DailyActivePlayers counts distinct players per UTC day, oldest first.
+	DailyActivePlayers []*DailyActivity `json:"daily_active_players"`
+}
+
+// OpeningPerformance is how often a word was played as the first guess of a
+// game and how the player who opened with it fared.
+type OpeningPerformance struct {
+	Word string `json:"word"`
+	Uses int    `json:"uses"`
+	// Wins is the number of those games won by the player who opened.
+	Wins    int     `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+}
+
+// WordDifficulty is how often games with a solution ended with it being guessed.
+type WordDifficulty struct {
+	Word  string `json:"word"`
+	Games int    `json:"games"`
+	// Solved is the number of games in which the solution was guessed.
+	Solved    int     `json:"solved"`
+	SolveRate float64 `json:"solve_rate"`
+	// AverageGuesses is the mean number of guesses in games that were solved.
+	AverageGuesses float64 `json:"average_guesses"`
+}
+
+// DailyActivity is the number of distinct players who played on a day.
+type DailyActivity struct {
+	// Date is the UTC day, formatted as 2006-01-02.
+	Date    string `json:"date"`
+	Players int    `json:"players"`
+}
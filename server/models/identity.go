@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Identity links a player to an account at an external OpenID Connect
+// provider.
+type Identity struct {
+	// Provider is the ID of the configured provider.
+	Provider string `json:"provider"`
+	// Subject is the provider's stable identifier for the account.
+	Subject  string `json:"subject"`
+	PlayerID string `json:"player_id"`
+	// Email is the address the provider reported when the identity was linked.
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// LeaderboardEntry is a single player's standing on a leaderboard.
+type LeaderboardEntry struct {
+	// Rank is the player's position; tied players share a rank.
+	Rank int `json:"rank"`
+	// PlayerID is the ID of the ranked player.
+	PlayerID string `json:"player_id"`
+	// Name is the ranked player's display name.
+	Name string `json:"name"`
+	// Rating is the player's current rating (optional).
+	Rating *int `json:"rating,omitempty"`
+	// Points is two per win plus one per draw over the games counted.
+	Points int `json:"points"`
+	// Games is the number of games counted for the player.
+	Games int `json:"games"`
+	// Wins is the number of counted games the player won.
+	Wins int `json:"wins"`
+	// Draws is the number of counted games that ended in a draw.
+	Draws int `json:"draws"`
+	// Losses is the number of counted games the player lost.
+	Losses int `json:"losses"`
+}
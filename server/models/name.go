@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// NameChange is a name a player used before renaming.
+type NameChange struct {
+	PlayerID string `json:"-"`
+	// Name is the name the player had until ChangedAt.
+	Name      string    `json:"name"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// confusables maps characters to the Latin letter or digit they look like.
+// Characters are looked up before and after lowercasing. Capital I passes for
+// l and lowercases to i, so every kind of i folds to l along with them.
+var confusables = map[rune]rune{
+	// Characters that pass for other letters
+	'0': 'o', '1': 'l', '|': 'l', 'I': 'l', 'i': 'l',
+	// Separators
+	'-': '_', '.': '_',
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'l', 'ї': 'l',
+	'ј': 'j', 'ԁ': 'd', 'һ': 'h', 'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w', 'ь': 'b',
+	// Greek
+	'α': 'a', 'β': 'b', 'γ': 'y', 'ε': 'e', 'η': 'n', 'ι': 'l', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ζ': 'z', 'μ': 'u',
+	// Latin letters with diacritics
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a', 'ă': 'a', 'ą': 'a',
+	'ç': 'c', 'ć': 'c', 'č': 'c', 'ď': 'd', 'đ': 'd',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e', 'ė': 'e', 'ę': 'e', 'ě': 'e',
+	'ğ': 'g', 'ì': 'l', 'í': 'l', 'î': 'l', 'ï': 'l', 'ī': 'l', 'į': 'l', 'ı': 'l',
+	'ł': 'l', 'ľ': 'l', 'ñ': 'n', 'ń': 'n', 'ň': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o', 'ő': 'o',
+	'ř': 'r', 'ś': 's', 'š': 's', 'ş': 's', 'ť': 't', 'ţ': 't',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u', 'ů': 'u', 'ű': 'u',
+	'ý': 'y', 'ÿ': 'y', 'ź': 'z', 'ż': 'z', 'ž': 'z',
+}
+
+// CanonicalName folds a name to the form two names have in common when they
+// look alike: case, look-alike letters from other scripts, diacritics, digits
+// that pass for letters and separators are folded, and fullwidth forms are
+// narrowed. Player names are unique by their canonical form, so "admin" and
+// "аdmin" (with a Cyrillic а) cannot both exist.
+func CanonicalName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		// Fullwidth ASCII, e.g. "ａ"
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		if c, ok := confusables[r]; ok {
+			b.WriteRune(c)
+			continue
+		}
+		r = unicode.ToLower(r)
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PasswordReset is a single-use token that lets a player set a new password
+// without knowing the current one.
+type PasswordReset struct {
+	ID       string `json:"id"`
+	PlayerID string `json:"player_id"`
+	// TokenHash is the hex SHA-256 of the token; the token itself is only sent
+	// to the player.
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// UsedAt is set once the token has been used or superseded by a newer one.
+	UsedAt *time.Time `json:"used_at,omitempty"`
+}
@@ -10,10 +10,72 @@ type Player struct {
 	Name string `json:"name"`
 	// Registered indicates if the player has a registered account.
 	Registered bool `json:"registered"`
+	// Role is the player's role: RolePlayer, RoleModerator or RoleAdmin.
+	Role string `json:"role"`
 	// PasswordHash is the bcrypt hash of the player's password (if registered).
 	PasswordHash *string `json:"-"`
 	// Elo is the player's rating (optional).
 	Elo *int `json:"elo,omitempty"`
+	// RatedGames is the number of rated games the player has finished.
+	RatedGames int `json:"rated_games"`
+	// RatingDeviation is the Glicko-2 rating deviation (optional).
+	RatingDeviation *float64 `json:"rating_deviation,omitempty"`
+	// RatingVolatility is the Glicko-2 rating volatility (optional).
+	RatingVolatility *float64 `json:"rating_volatility,omitempty"`
+	// RatedAt is the timestamp of the player's last rated game (optional).
+	RatedAt *time.Time `json:"rated_at,omitempty"`
+	// Email is the player's lowercased email address, used for password
+	// resets (optional). It is never shown to other players.
+	Email *string `json:"-"`
 	// CreatedAt is the timestamp when the player was created.
 	CreatedAt time.Time `json:"created_at"`
+	// DeletedAt is set once the player deleted their account. The player is
+	// kept, anonymised, so that their opponents' games stay intact.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// BannedAt is set while the player is banned or suspended.
+	BannedAt *time.Time `json:"banned_at,omitempty"`
+	// BannedUntil ends a suspension; a ban without it is permanent.
+	BannedUntil *time.Time `json:"banned_until,omitempty"`
+	// BanReason is the reason given for the ban.
+	BanReason string `json:"ban_reason,omitempty"`
+}
+
+// Player roles. Moderators handle other players; admins also manage games,
+// word lists and roles.
+const (
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRanks orders the roles by privilege.
+var roleRanks = map[string]int{RolePlayer: 0, RoleModerator: 1, RoleAdmin: 2}
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role has at least the privileges of min.
+// Unknown roles have none.
+func RoleAtLeast(role, min string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[min]
+}
+
+// Banned reports whether the player is banned at now.
+func (p *Player) Banned(now time.Time) bool {
+	return p.BannedAt != nil && (p.BannedUntil == nil || now.Before(*p.BannedUntil))
+}
+
+// DeletedPlayerName is shown in place of the name of a deleted player.
+const DeletedPlayerName = "Deleted player"
+
+// DisplayName returns the name to show for the player.
+func (p *Player) DisplayName() string {
+	if p.DeletedAt != nil {
+		return DeletedPlayerName
+	}
+	return p.Name
 }
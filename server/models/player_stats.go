@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// StatsModeAll is the mode under which statistics across every mode are kept.
+const StatsModeAll = "all"
+
+const (
+	OutcomeWin  = "win"
+	OutcomeLoss = "loss"
+	OutcomeDraw = "draw"
+)
+
+// PlayerStats holds a player's aggregate results in one mode.
+type PlayerStats struct {
+	// PlayerID is the ID of the player.
+	PlayerID string `json:"player_id"`
+	// Mode is the game mode, or StatsModeAll for every mode combined.
+	Mode string `json:"mode"`
+	// Games is the number of finished games.
+	Games int `json:"games"`
+	// Wins is the number of games won.
+	Wins int `json:"wins"`
+	// Losses is the number of games lost.
+	Losses int `json:"losses"`
+	// Draws is the number of games drawn.
+	Draws int `json:"draws"`
+	// CurrentStreak is the number of consecutive wins up to the latest game.
+	CurrentStreak int `json:"current_streak"`
+	// BestStreak is the longest run of consecutive wins.
+	BestStreak int `json:"best_streak"`
+	// Turns is the number of timed turns the player has taken.
+	Turns int `json:"turns"`
+	// TurnTime is the total time spent on those turns.
+	TurnTime time.Duration `json:"turn_time"`
+}
+
+// OpeningStats holds how often a player opened with a word and how it went.
+type OpeningStats struct {
+	// Word is the opening guess.
+	Word string `json:"word"`
+	// Uses is the number of games opened with the word.
+	Uses int `json:"uses"`
+	// Wins is the number of those games the player won.
+	Wins int `json:"wins"`
+}
+
+// PlayerGameSummary is one player's part in a finished game, applied to their aggregate statistics.
+type PlayerGameSummary struct {
+	// PlayerID is the ID of the player.
+	PlayerID string
+	// Mode is the game mode.
+	Mode string
+	// Outcome is OutcomeWin, OutcomeLoss or OutcomeDraw.
+	Outcome string
+	// SolvedIn is the number of guesses the game took if it was solved, or 0 for a draw.
+	SolvedIn int
+	// Opening is the player's first guess, or empty if they made none.
+	Opening string
+	// Turns is the number of timed turns the player took.
+	Turns int
+	// TurnTime is the total time the player spent on those turns.
+	TurnTime time.Duration
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// RatingChange records how a single finished game moved a player's rating.
+type RatingChange struct {
+	// GameID is the ID of the game that produced the change.
+	GameID string `json:"game_id"`
+	// PlayerID is the ID of the rated player.
+	PlayerID string `json:"player_id"`
+	// Before is the player's rating going into the game.
+	Before int `json:"before"`
+	// After is the player's rating after the game.
+	After int `json:"after"`
+	// Deviation is the player's rating deviation after the game, for systems that track it.
+	Deviation *float64 `json:"deviation,omitempty"`
+	// Volatility is the player's rating volatility after the game, for systems that track it.
+	Volatility *float64 `json:"volatility,omitempty"`
+	// RatedGames is the number of rated games the player had finished going
+	// into the game. The change is only applied while the stored count still
+	// matches, so a concurrent finish cannot be overwritten.
+	RatedGames int `json:"-"`
+	// CreatedAt is the timestamp when the change was applied.
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// RefreshToken is one refresh token issued for a session. Tokens are rotated
+// on every refresh; all tokens of one login share a SessionID, so that reusing
+// a rotated token can revoke the whole session.
+type RefreshToken struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	PlayerID  string `json:"player_id"`
+	// TokenHash is the hex SHA-256 of the token; the token itself is never stored.
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// RotatedAt is set once the token has been exchanged for a new one.
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	// RevokedAt is set when the session is revoked.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	UserAgent string     `json:"user_agent"`
+	IP        string     `json:"ip"`
+}
+
+// Session is an active login of a player on one device.
+type Session struct {
+	ID         string    `json:"id"`
+	PlayerID   string    `json:"player_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	// Current marks the session the request was made with.
+	Current bool `json:"current"`
+}
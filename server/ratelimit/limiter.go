@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limit is a rate of Events per Per. Up to Events may happen at once; after
+// that they are allowed again at the average rate.
+type Limit struct {
+	Events int
+	Per    time.Duration
+}
+
+// PerMinute returns a limit of n events per minute.
+func PerMinute(n int) Limit { return Limit{Events: n, Per: time.Minute} }
+
+// pruneInterval is how often buckets that have refilled are dropped.
+const pruneInterval = time.Minute
+
+// Limiter keeps a token bucket for each key, e.g. an IP address or a player ID.
+type Limiter struct {
+	burst    float64
+	interval time.Duration // time to refill one token
+	now      func() time.Time
+
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	lastPruned time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// New creates a Limiter for limit, which must have positive Events and Per.
+func New(limit Limit) *Limiter {
+	return &Limiter{
+		burst:    float64(limit.Events),
+		interval: limit.Per / time.Duration(limit.Events),
+		now:      time.Now,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket. If the bucket is empty, it returns
+// false and how long until the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, at: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refilled(b, now)
+	b.at = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.interval))
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *Limiter) refilled(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + float64(now.Sub(b.at))/float64(l.interval)
+	if tokens > l.burst {
+		return l.burst
+	}
+	return tokens
+}
+
+// prune drops full buckets, which are the same as no bucket.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPruned) < pruneInterval {
+		return
+	}
+	l.lastPruned = now
+	for key, b := range l.buckets {
+		if l.refilled(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// LockoutPolicy configures a Lockout.
+type LockoutPolicy struct {
+	// FreeFailures is how many failures are allowed before the first lock.
+	FreeFailures int
+	// Base is how long the first lock lasts. Each further failure doubles it,
+	// up to Max.
+	Base time.Duration
+	Max  time.Duration
+	// ResetAfter is how long after the last failure a key starts over.
+	ResetAfter time.Duration
+}
+
+// Lockout locks keys, e.g. account names, out after repeated failures, for
+// progressively longer.
+type Lockout struct {
+	policy LockoutPolicy
+	now    func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]*lockoutEntry
+	lastPruned time.Time
+}
+
+type lockoutEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// NewLockout creates a Lockout.
+func NewLockout(policy LockoutPolicy) *Lockout {
+	return &Lockout{policy: policy, now: time.Now, entries: make(map[string]*lockoutEntry)}
+}
+
+// Locked returns how long key remains locked, or 0 if it is not.
+func (l *Lockout) Locked(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[key]
+	if !ok {
+		return 0
+	}
+	if wait := e.lockedUntil.Sub(l.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Fail records a failure for key and returns how long key is now locked, or
+// 0 if it is not.
+func (l *Lockout) Fail(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.prune(now)
+
+	e, ok := l.entries[key]
+	if !ok || now.Sub(e.lastFailure) > l.policy.ResetAfter {
+		e = &lockoutEntry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+	over := e.failures - l.policy.FreeFailures
+	if over <= 0 {
+		return 0
+	}
+	lock := l.policy.Max
+	if over <= 30 && l.policy.Base<<(over-1) < l.policy.Max {
+		lock = l.policy.Base << (over - 1)
+	}
+	e.lockedUntil = now.Add(lock)
+	return lock
+}
+
+// Reset forgets key's failures, e.g. after a successful login.
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	delete(l.entries, key)
+	l.mu.Unlock()
+}
+
+func (l *Lockout) prune(now time.Time) {
+	if now.Sub(l.lastPruned) < pruneInterval {
+		return
+	}
+	l.lastPruned = now
+	for key, e := range l.entries {
+		if now.After(e.lockedUntil) && now.Sub(e.lastFailure) > l.policy.ResetAfter {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// AchievementRepository stores unlocked achievements and answers the history
+// queries achievement rules need.
+type AchievementRepository struct {
+	db *sql.DB
+}
+
+func NewAchievementRepository(dbPath string) (*AchievementRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &AchievementRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *AchievementRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS player_achievements (
+			player_id TEXT NOT NULL,
+			achievement_id TEXT NOT NULL,
+			game_id TEXT,
+			unlocked_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (player_id, achievement_id),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// Unlock stores an achievement for a player. It reports false if the player
+// had already unlocked it, in which case the original unlock is kept.
+func (r *AchievementRepository) Unlock(ctx context.Context, achievement *models.PlayerAchievement) (bool, error) {
+	const query = `
+		INSERT INTO player_achievements (player_id, achievement_id, game_id, unlocked_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (player_id, achievement_id) DO NOTHING
+	`
+	res, err := r.db.ExecContext(ctx, query, achievement.PlayerID, achievement.AchievementID, achievement.GameID, achievement.UnlockedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to unlock achievement: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to unlock achievement: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ReplacePlayer discards the player's unlocked achievements and stores the
+// given ones instead, in a single transaction.
+func (r *AchievementRepository) ReplacePlayer(ctx context.Context, playerID string, achievements []*models.PlayerAchievement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM player_achievements WHERE player_id = $1`, playerID); err != nil {
+		return fmt.Errorf("failed to clear achievements: %w", err)
+	}
+	const query = `
+		INSERT INTO player_achievements (player_id, achievement_id, game_id, unlocked_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	for _, a := range achievements {
+		if _, err := tx.ExecContext(ctx, query, playerID, a.AchievementID, a.GameID, a.UnlockedAt); err != nil {
+			return fmt.Errorf("failed to unlock achievement: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit achievements: %w", err)
+	}
+	return nil
+}
+
+// GetByPlayer returns the player's unlocked achievements, oldest first.
+func (r *AchievementRepository) GetByPlayer(ctx context.Context, playerID string) ([]*models.PlayerAchievement, error) {
+	const query = `
+		SELECT player_id, achievement_id, COALESCE(game_id, ''), unlocked_at
+		FROM player_achievements
+		WHERE player_id = $1
+		ORDER BY unlocked_at ASC, achievement_id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("query achievements failed: %w", err)
+	}
+	defer rows.Close()
+
+	var achievements []*models.PlayerAchievement
+	for rows.Next() {
+		var a models.PlayerAchievement
+		if err := rows.Scan(&a.PlayerID, &a.AchievementID, &a.GameID, &a.UnlockedAt); err != nil {
+			return nil, fmt.Errorf("scan achievement failed: %w", err)
+		}
+		achievements = append(achievements, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return achievements, nil
+}
+
+// ActiveDays returns up to limit distinct UTC days, formatted as 2006-01-02,
+// on which the player finished a game on this server, newest first. Voided
+// games do not count.
+func (r *AchievementRepository) ActiveDays(ctx context.Context, playerID string, limit int) ([]string, error) {
+	const query = `
+		SELECT DISTINCT date(updated_at) AS day
+		FROM games
+		WHERE (first_player = $1 OR second_player = $1)
+			AND result IS NOT NULL AND result != '' AND result != 'void' AND imported = 0
+		ORDER BY day DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, playerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query active days failed: %w", err)
+	}
+	defer rows.Close()
+
+	var days []string
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			return nil, fmt.Errorf("scan active day failed: %w", err)
+		}
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return days, nil
+}
+
+type AchievementRepositoryI interface {
+	Unlock(ctx context.Context, achievement *models.PlayerAchievement) (bool, error)
+	GetByPlayer(ctx context.Context, playerID string) ([]*models.PlayerAchievement, error)
+	ActiveDays(ctx context.Context, playerID string, limit int) ([]string, error)
+	ReplacePlayer(ctx context.Context, playerID string, achievements []*models.PlayerAchievement) error
+}
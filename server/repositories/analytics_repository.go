@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// AnalyticsRepository runs the server-wide aggregation queries over the games table.
+// Imported games were not played on this server and are left out.
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(dbPath string) (*AnalyticsRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &AnalyticsRepository{db: db}, nil
+}
+
+// GameTotals fills the game counts, averages and win split of stats.
+func (r *AnalyticsRepository) GameTotals(ctx context.Context, stats *models.GlobalStats) error {
+	const query = `
+		SELECT
+			COUNT(*),
+			COALESCE(AVG(json_array_length(guesses)), 0),
+			COALESCE(AVG((julianday(updated_at) - julianday(created_at)) * 86400), 0),
+			COALESCE(SUM(CASE WHEN result = 'lose:' || second_player THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN result = 'lose:' || first_player THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN result = 'draw' THEN 1 ELSE 0 END), 0)
+		FROM games
+		WHERE result IS NOT NULL AND result != '' AND result != 'void' AND imported = 0
+	`
+	err := r.db.QueryRowContext(ctx, query).Scan(
+		&stats.Games,
+		&stats.AverageGuesses,
+		&stats.AverageDurationSeconds,
+		&stats.FirstPlayerWins,
+		&stats.SecondPlayerWins,
+		&stats.Draws,
+	)
+	if err != nil {
+		return fmt.Errorf("query game totals failed: %w", err)
+	}
+	return nil
+}
+
+// TopOpenings returns the most used first guesses of finished games. The first
+// player always opens, so a win for the opening is a win for the first player.
+func (r *AnalyticsRepository) TopOpenings(ctx context.Context, limit int) ([]*models.OpeningPerformance, error) {
+	const query = `
+		SELECT word, COUNT(*) AS uses, SUM(opener_won)
+		FROM (
+			SELECT json_extract(guesses, '$[0]') AS word,
+				CASE WHEN result = 'lose:' || second_player THEN 1 ELSE 0 END AS opener_won
+			FROM games
+			WHERE result IS NOT NULL AND result != '' AND result != 'void' AND imported = 0 AND json_array_length(guesses) > 0
+		)
+		GROUP BY word
+		ORDER BY uses DESC, word ASC
+		LIMIT $1
+	`
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query openings failed: %w", err)
+	}
+	defer rows.Close()
+
+	openings := []*models.OpeningPerformance{}
+	for rows.Next() {
+		var o models.OpeningPerformance
+		if err := rows.Scan(&o.Word, &o.Uses, &o.Wins); err != nil {
+			return nil, fmt.Errorf("scan opening failed: %w", err)
+		}
+		o.WinRate = float64(o.Wins) / float64(o.Uses)
+		openings = append(openings, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return openings, nil
+}
+
+// WordDifficulties returns solutions with at least minGames finished games,
+// ordered from the lowest solve rate if hardest is set, or the highest otherwise.
+func (r *AnalyticsRepository) WordDifficulties(ctx context.Context, minGames int, limit int, hardest bool) ([]*models.WordDifficulty, error) {
+	order := `solve_rate DESC, avg_guesses ASC`
+	if hardest {
+		order = `solve_rate ASC, avg_guesses DESC`
+	}
+	query := `
+		SELECT solution, COUNT(*) AS games,
+			SUM(CASE WHEN result != 'draw' THEN 1 ELSE 0 END) AS solved,
+			1.0 * SUM(CASE WHEN result != 'draw' THEN 1 ELSE 0 END) / COUNT(*) AS solve_rate,
+			COALESCE(AVG(CASE WHEN result != 'draw' THEN json_array_length(guesses) END), 0) AS avg_guesses
+		FROM games
+		WHERE result IS NOT NULL AND result != '' AND result != 'void' AND imported = 0
+		GROUP BY solution
+		HAVING COUNT(*) >= $1
+		ORDER BY ` + order + `, games DESC, solution ASC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, minGames, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query word difficulty failed: %w", err)
+	}
+	defer rows.Close()
+
+	words := []*models.WordDifficulty{}
+	for rows.Next() {
+		var w models.WordDifficulty
+		if err := rows.Scan(&w.Word, &w.Games, &w.Solved, &w.SolveRate, &w.AverageGuesses); err != nil {
+			return nil, fmt.Errorf("scan word difficulty failed: %w", err)
+		}
+		words = append(words, &w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return words, nil
+}
+
+// DailyActivePlayers counts, for each UTC day since the given time, the
+// distinct players who started or played in a game that day.
+func (r *AnalyticsRepository) DailyActivePlayers(ctx context.Context, since time.Time) ([]*models.DailyActivity, error) {
+	const query = `
+		WITH activity AS (
+			SELECT date(created_at) AS day, first_player AS player_id FROM games WHERE created_at >= $1 AND imported = 0
+			UNION
+			SELECT date(created_at), second_player FROM games WHERE created_at >= $1 AND imported = 0
+			UNION
+			SELECT date(updated_at), first_player FROM games WHERE updated_at >= $1 AND imported = 0
+			UNION
+			SELECT date(updated_at), second_player FROM games WHERE updated_at >= $1 AND imported = 0
+		)
+		SELECT day, COUNT(DISTINCT player_id)
+		FROM activity
+		WHERE day >= date($1)
+		GROUP BY day
+		ORDER BY day ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("query daily active players failed: %w", err)
+	}
+	defer rows.Close()
+
+	days := []*models.DailyActivity{}
+	for rows.Next() {
+		var d models.DailyActivity
+		if err := rows.Scan(&d.Date, &d.Players); err != nil {
+			return nil, fmt.Errorf("scan daily activity failed: %w", err)
+		}
+		days = append(days, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return days, nil
+}
+
+type AnalyticsRepositoryI interface {
+	GameTotals(ctx context.Context, stats *models.GlobalStats) error
+	TopOpenings(ctx context.Context, limit int) ([]*models.OpeningPerformance, error)
+	WordDifficulties(ctx context.Context, minGames int, limit int, hardest bool) ([]*models.WordDifficulty, error)
+	DailyActivePlayers(ctx context.Context, since time.Time) ([]*models.DailyActivity, error)
+}
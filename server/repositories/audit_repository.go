@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// AuditRepository stores the audit log of moderator and admin actions.
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(dbPath string) (*AuditRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &AuditRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *AuditRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			actor_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			target_type TEXT NOT NULL,
+			target_id TEXT NOT NULL,
+			details TEXT,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (actor_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target_type, target_id, id);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id, id);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// AuditFilter narrows down the audit log. Empty fields match everything.
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+}
+
+// Create appends an entry to the audit log and sets its ID.
+func (r *AuditRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	const query = `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	var details sql.NullString
+	if len(entry.Details) > 0 {
+		details = sql.NullString{String: string(entry.Details), Valid: true}
+	}
+	res, err := r.db.ExecContext(ctx, query, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, details, entry.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	if entry.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get audit entry id: %w", err)
+	}
+	return nil
+}
+
+// List returns up to limit entries matching the filter, newest first. With a
+// non-zero beforeID only entries older than that entry are returned.
+func (r *AuditRepository) List(ctx context.Context, f AuditFilter, beforeID int64, limit int) ([]*models.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	for _, c := range []struct{ column, value string }{
+		{"actor_id", f.ActorID},
+		{"action", f.Action},
+		{"target_type", f.TargetType},
+		{"target_id", f.TargetID},
+	} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if beforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, beforeID)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+	query := `
+		SELECT id, actor_id, action, target_type, target_id, details, created_at
+		FROM audit_log
+		` + where + `
+		ORDER BY id DESC
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit log failed: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var details sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.TargetType, &entry.TargetID, &details, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan audit entry failed: %w", err)
+		}
+		if details.Valid {
+			entry.Details = []byte(details.String)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return entries, nil
+}
+
+type AuditRepositoryI interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+	List(ctx context.Context, f AuditFilter, beforeID int64, limit int) ([]*models.AuditEntry, error)
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// BlockRepository stores which players blocked which.
+type BlockRepository struct {
+	db *sql.DB
+}
+
+func NewBlockRepository(dbPath string) (*BlockRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &BlockRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *BlockRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS blocks (
+			blocker_id TEXT NOT NULL,
+			blocked_id TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (blocker_id, blocked_id),
+			FOREIGN KEY (blocker_id) REFERENCES players(id),
+			FOREIGN KEY (blocked_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_blocks_blocked ON blocks(blocked_id);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// Block stores that blockerID blocked blockedID. Blocking a player again
+// keeps the original block.
+func (r *BlockRepository) Block(ctx context.Context, blockerID, blockedID string, at time.Time) error {
+	const query = `
+		INSERT INTO blocks (blocker_id, blocked_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, query, blockerID, blockedID, at.UTC()); err != nil {
+		return fmt.Errorf("failed to insert block: %w", err)
+	}
+	return nil
+}
+
+// Unblock removes a block. It returns "block_not_found" if blockerID had not
+// blocked blockedID.
+func (r *BlockRepository) Unblock(ctx context.Context, blockerID, blockedID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2`, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to delete block: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("block_not_found")
+	}
+	return nil
+}
+
+// IsBlocked reports whether either player blocked the other.
+func (r *BlockRepository) IsBlocked(ctx context.Context, playerID, otherID string) (bool, error) {
+	const query = `
+		SELECT EXISTS (
+			SELECT 1 FROM blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+		)
+	`
+	var blocked bool
+	if err := r.db.QueryRowContext(ctx, query, playerID, otherID).Scan(&blocked); err != nil {
+		return false, fmt.Errorf("query block failed: %w", err)
+	}
+	return blocked, nil
+}
+
+// ListAmong returns every block between two of the given players as blocker
+// and blocked ID pairs, in one query.
+func (r *BlockRepository) ListAmong(ctx context.Context, playerIDs []string) ([][2]string, error) {
+	if len(playerIDs) < 2 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(playerIDs))
+	args := make([]any, 0, 2*len(playerIDs))
+	for i, id := range playerIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+	args = append(args, args...)
+	in := strings.Join(placeholders, ", ")
+	query := `
+		SELECT blocker_id, blocked_id FROM blocks
+		WHERE blocker_id IN (` + in + `) AND blocked_id IN (` + in + `)
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query blocks failed: %w", err)
+	}
+	defer rows.Close()
+
+	var blocks [][2]string
+	for rows.Next() {
+		var b [2]string
+		if err := rows.Scan(&b[0], &b[1]); err != nil {
+			return nil, fmt.Errorf("scan block failed: %w", err)
+		}
+		blocks = append(blocks, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return blocks, nil
+}
+
+// ListByPlayer returns the players blockerID blocked, most recent first.
+func (r *BlockRepository) ListByPlayer(ctx context.Context, blockerID string) ([]*models.BlockedPlayer, error) {
+	const query = `
+		SELECT p.id, p.name, b.created_at
+		FROM blocks b
+		JOIN players p ON p.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY julianday(b.created_at) DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, blockerID)
+	if err != nil {
+		return nil, fmt.Errorf("query blocks failed: %w", err)
+	}
+	defer rows.Close()
+
+	blocked := []*models.BlockedPlayer{}
+	for rows.Next() {
+		var b models.BlockedPlayer
+		if err := rows.Scan(&b.PlayerID, &b.Name, &b.BlockedAt); err != nil {
+			return nil, fmt.Errorf("scan block failed: %w", err)
+		}
+		blocked = append(blocked, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return blocked, nil
+}
+
+type BlockRepositoryI interface {
+	Block(ctx context.Context, blockerID, blockedID string, at time.Time) error
+	Unblock(ctx context.Context, blockerID, blockedID string) error
+	IsBlocked(ctx context.Context, playerID, otherID string) (bool, error)
+	ListAmong(ctx context.Context, playerIDs []string) ([][2]string, error)
+	ListByPlayer(ctx context.Context, blockerID string) ([]*models.BlockedPlayer, error)
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ChallengeRepository stores challenges between players.
+type ChallengeRepository struct {
+	db *sql.DB
+}
+
+func NewChallengeRepository(dbPath string) (*ChallengeRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &ChallengeRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *ChallengeRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS challenges (
+			id TEXT PRIMARY KEY,
+			challenger_id TEXT NOT NULL,
+			challenged_id TEXT NOT NULL,
+			rematch BOOLEAN NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			game_id TEXT,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP,
+			responded_at TIMESTAMP,
+			FOREIGN KEY (challenger_id) REFERENCES players(id),
+			FOREIGN KEY (challenged_id) REFERENCES players(id),
+			FOREIGN KEY (game_id) REFERENCES games(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_challenges_challenged ON challenges(challenged_id, status);
+		CREATE INDEX IF NOT EXISTS idx_challenges_challenger ON challenges(challenger_id, status);
+	`
+	if _, err := r.db.Exec(query); err != nil {
+		return err
+	}
+	return ensureColumn(r.db, "challenges", "expires_at", "TIMESTAMP")
+}
+
+const challengeColumns = `
+	c.id, c.challenger_id, challenger.name, c.challenged_id, challenged.name,
+	c.rematch, c.status, c.game_id, c.created_at, c.expires_at, c.responded_at
+`
+
+const challengeJoins = `
+	FROM challenges c
+	JOIN players challenger ON challenger.id = c.challenger_id
+	JOIN players challenged ON challenged.id = c.challenged_id
+`
+
+func scanChallenge(row rowScanner) (*models.Challenge, error) {
+	var c models.Challenge
+	var gameID sql.NullString
+	var expiresAt, respondedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.ChallengerID, &c.ChallengerName, &c.ChallengedID, &c.ChallengedName,
+		&c.Rematch, &c.Status, &gameID, &c.CreatedAt, &expiresAt, &respondedAt); err != nil {
+		return nil, err
+	}
+	// Challenges from before expiry was introduced have already expired.
+	c.ExpiresAt = c.CreatedAt
+	if expiresAt.Valid {
+		c.ExpiresAt = expiresAt.Time
+	}
+	if gameID.Valid {
+		c.GameID = &gameID.String
+	}
+	if respondedAt.Valid {
+		c.RespondedAt = &respondedAt.Time
+	}
+	return &c, nil
+}
+
+// Create stores a new challenge.
+func (r *ChallengeRepository) Create(ctx context.Context, c *models.Challenge) error {
+	const query = `
+		INSERT INTO challenges (id, challenger_id, challenged_id, rematch, status, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	if _, err := r.db.ExecContext(ctx, query, c.ID, c.ChallengerID, c.ChallengedID, c.Rematch, c.Status, c.CreatedAt.UTC(), c.ExpiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to insert challenge: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns the challenge, or nil if there is none.
+func (r *ChallengeRepository) GetByID(ctx context.Context, id string) (*models.Challenge, error) {
+	c, err := scanChallenge(r.db.QueryRowContext(ctx, `SELECT `+challengeColumns+challengeJoins+` WHERE c.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query challenge failed: %w", err)
+	}
+	return c, nil
+}
+
+// GetPending returns the latest pending challenge from challengerID to
+// challengedID, or nil if there is none.
+func (r *ChallengeRepository) GetPending(ctx context.Context, challengerID, challengedID string) (*models.Challenge, error) {
+	query := `SELECT ` + challengeColumns + challengeJoins + `
+		WHERE c.challenger_id = $1 AND c.challenged_id = $2 AND c.status = $3
+		ORDER BY c.created_at DESC
+		LIMIT 1
+	`
+	c, err := scanChallenge(r.db.QueryRowContext(ctx, query, challengerID, challengedID, models.ChallengePending))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query pending challenge failed: %w", err)
+	}
+	return c, nil
+}
+
+// ListPending returns the pending challenges the player sent to or received
+// from current players, oldest first.
+func (r *ChallengeRepository) ListPending(ctx context.Context, playerID string) ([]*models.Challenge, error) {
+	query := `SELECT ` + challengeColumns + challengeJoins + `
+		WHERE (c.challenger_id = $1 OR c.challenged_id = $1) AND c.status = $2
+			AND challenger.deleted_at IS NULL AND challenged.deleted_at IS NULL
+		ORDER BY c.created_at ASC
+	`
+	return r.queryChallenges(ctx, query, playerID, models.ChallengePending)
+}
+
+// ListExpired returns the pending challenges that expired at or before now,
+// oldest first.
+func (r *ChallengeRepository) ListExpired(ctx context.Context, now time.Time) ([]*models.Challenge, error) {
+	query := `SELECT ` + challengeColumns + challengeJoins + `
+		WHERE c.status = $1 AND (c.expires_at IS NULL OR julianday(c.expires_at) <= julianday($2))
+		ORDER BY c.created_at ASC
+	`
+	return r.queryChallenges(ctx, query, models.ChallengePending, now.UTC())
+}
+
+func (r *ChallengeRepository) queryChallenges(ctx context.Context, query string, args ...any) ([]*models.Challenge, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query challenges failed: %w", err)
+	}
+	defer rows.Close()
+
+	challenges := []*models.Challenge{}
+	for rows.Next() {
+		c, err := scanChallenge(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan challenge failed: %w", err)
+		}
+		challenges = append(challenges, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return challenges, nil
+}
+
+// Resolve moves a pending challenge to status, with the game it started, if
+// any. It returns "challenge_not_found" unless the challenge is pending.
+func (r *ChallengeRepository) Resolve(ctx context.Context, id, status string, gameID *string, at time.Time) error {
+	const query = `
+		UPDATE challenges SET status = $1, game_id = $2, responded_at = $3
+		WHERE id = $4 AND status = $5
+	`
+	res, err := r.db.ExecContext(ctx, query, status, gameID, at.UTC(), id, models.ChallengePending)
+	if err != nil {
+		return fmt.Errorf("failed to resolve challenge: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("challenge_not_found")
+	}
+	return nil
+}
+
+type ChallengeRepositoryI interface {
+	Create(ctx context.Context, c *models.Challenge) error
+	GetByID(ctx context.Context, id string) (*models.Challenge, error)
+	GetPending(ctx context.Context, challengerID, challengedID string) (*models.Challenge, error)
+	ListPending(ctx context.Context, playerID string) ([]*models.Challenge, error)
+	ListExpired(ctx context.Context, now time.Time) ([]*models.Challenge, error)
+	Resolve(ctx context.Context, id, status string, gameID *string, at time.Time) error
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ChatRepository stores game chat messages and each player's chat mode.
+type ChatRepository struct {
+	db *sql.DB
+}
+
+func NewChatRepository(dbPath string) (*ChatRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &ChatRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *ChatRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS chat_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			game_id TEXT NOT NULL,
+			player_id TEXT NOT NULL,
+			text TEXT NOT NULL,
+			quick_phrase BOOLEAN NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (game_id) REFERENCES games(id),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_chat_messages_game ON chat_messages(game_id, id);
+		CREATE INDEX IF NOT EXISTS idx_chat_messages_player ON chat_messages(player_id);
+		CREATE TABLE IF NOT EXISTS chat_settings (
+			game_id TEXT NOT NULL,
+			player_id TEXT NOT NULL,
+			mode TEXT NOT NULL,
+			PRIMARY KEY (game_id, player_id),
+			FOREIGN KEY (game_id) REFERENCES games(id),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// AddMessage stores a chat message and sets its ID.
+func (r *ChatRepository) AddMessage(ctx context.Context, msg *models.ChatMessage) error {
+	const query = `
+		INSERT INTO chat_messages (game_id, player_id, text, quick_phrase, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	res, err := r.db.ExecContext(ctx, query, msg.GameID, msg.PlayerID, msg.Text, msg.QuickPhrase, msg.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert chat message: %w", err)
+	}
+	if msg.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get chat message ID: %w", err)
+	}
+	return nil
+}
+
+// ListByGame returns the game's most recent chat messages, at most limit,
+// oldest first.
+func (r *ChatRepository) ListByGame(ctx context.Context, gameID string, limit int) ([]*models.ChatMessage, error) {
+	const query = `
+		SELECT id, game_id, player_id, text, quick_phrase, created_at FROM (
+			SELECT id, game_id, player_id, text, quick_phrase, created_at
+			FROM chat_messages
+			WHERE game_id = $1
+			ORDER BY id DESC
+			LIMIT $2
+		) ORDER BY id ASC
+	`
+	return r.queryMessages(ctx, query, gameID, limit)
+}
+
+// ListByPlayer returns every chat message the player sent, oldest first.
+func (r *ChatRepository) ListByPlayer(ctx context.Context, playerID string) ([]*models.ChatMessage, error) {
+	const query = `
+		SELECT id, game_id, player_id, text, quick_phrase, created_at
+		FROM chat_messages
+		WHERE player_id = $1
+		ORDER BY id ASC
+	`
+	return r.queryMessages(ctx, query, playerID)
+}
+
+func (r *ChatRepository) queryMessages(ctx context.Context, query string, args ...any) ([]*models.ChatMessage, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query chat messages failed: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.ChatMessage{}
+	for rows.Next() {
+		var msg models.ChatMessage
+		if err := rows.Scan(&msg.ID, &msg.GameID, &msg.PlayerID, &msg.Text, &msg.QuickPhrase, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan chat message failed: %w", err)
+		}
+		messages = append(messages, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return messages, nil
+}
+
+// DeleteByPlayer deletes every chat message the player sent.
+func (r *ChatRepository) DeleteByPlayer(ctx context.Context, playerID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM chat_messages WHERE player_id = $1`, playerID); err != nil {
+		return fmt.Errorf("failed to delete chat messages: %w", err)
+	}
+	return nil
+}
+
+// GetMode returns the player's chat mode in the game, ChatModeAll unless
+// they changed it.
+func (r *ChatRepository) GetMode(ctx context.Context, gameID, playerID string) (string, error) {
+	var mode string
+	err := r.db.QueryRowContext(ctx, `SELECT mode FROM chat_settings WHERE game_id = $1 AND player_id = $2`, gameID, playerID).Scan(&mode)
+	if err == sql.ErrNoRows {
+		return models.ChatModeAll, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("query chat mode failed: %w", err)
+	}
+	return mode, nil
+}
+
+// SetMode stores the player's chat mode in the game.
+func (r *ChatRepository) SetMode(ctx context.Context, gameID, playerID, mode string) error {
+	const query = `
+		INSERT INTO chat_settings (game_id, player_id, mode) VALUES ($1, $2, $3)
+		ON CONFLICT (game_id, player_id) DO UPDATE SET mode = excluded.mode
+	`
+	if _, err := r.db.ExecContext(ctx, query, gameID, playerID, mode); err != nil {
+		return fmt.Errorf("failed to set chat mode: %w", err)
+	}
+	return nil
+}
+
+type ChatRepositoryI interface {
+	AddMessage(ctx context.Context, msg *models.ChatMessage) error
+	ListByGame(ctx context.Context, gameID string, limit int) ([]*models.ChatMessage, error)
+	ListByPlayer(ctx context.Context, playerID string) ([]*models.ChatMessage, error)
+	DeleteByPlayer(ctx context.Context, playerID string) error
+	GetMode(ctx context.Context, gameID, playerID string) (string, error)
+	SetMode(ctx context.Context, gameID, playerID, mode string) error
+}
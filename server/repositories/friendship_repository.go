@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// FriendshipRepository stores friend requests and friendships.
+type FriendshipRepository struct {
+	db *sql.DB
+}
+
+func NewFriendshipRepository(dbPath string) (*FriendshipRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &FriendshipRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *FriendshipRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS friendships (
+			requester_id TEXT NOT NULL,
+			addressee_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			accepted_at TIMESTAMP,
+			PRIMARY KEY (requester_id, addressee_id),
+			FOREIGN KEY (requester_id) REFERENCES players(id),
+			FOREIGN KEY (addressee_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_friendships_addressee ON friendships(addressee_id);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// Get returns the friendship between two players in either direction, or nil
+// if there is none.
+func (r *FriendshipRepository) Get(ctx context.Context, playerID, otherID string) (*models.Friendship, error) {
+	const query = `
+		SELECT requester_id, addressee_id, status, created_at, accepted_at
+		FROM friendships
+		WHERE (requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1)
+	`
+	var f models.Friendship
+	var acceptedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, playerID, otherID).
+		Scan(&f.RequesterID, &f.AddresseeID, &f.Status, &f.CreatedAt, &acceptedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query friendship failed: %w", err)
+	}
+	if acceptedAt.Valid {
+		f.AcceptedAt = &acceptedAt.Time
+	}
+	return &f, nil
+}
+
+// Request stores a pending friend request. It returns "friendship_exists" if
+// the two players already have a friendship or request in either direction.
+func (r *FriendshipRepository) Request(ctx context.Context, requesterID, addresseeID string, at time.Time) error {
+	const query = `
+		INSERT INTO friendships (requester_id, addressee_id, status, created_at)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (
+			SELECT 1 FROM friendships
+			WHERE (requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1)
+		)
+	`
+	res, err := r.db.ExecContext(ctx, query, requesterID, addresseeID, models.FriendshipPending, at.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert friend request: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("friendship_exists")
+	}
+	return nil
+}
+
+// Accept accepts the pending request requesterID sent to addresseeID. It
+// returns "friend_request_not_found" if there is no such request.
+func (r *FriendshipRepository) Accept(ctx context.Context, requesterID, addresseeID string, at time.Time) error {
+	const query = `
+		UPDATE friendships SET status = $1, accepted_at = $2
+		WHERE requester_id = $3 AND addressee_id = $4 AND status = $5
+	`
+	res, err := r.db.ExecContext(ctx, query, models.FriendshipAccepted, at.UTC(), requesterID, addresseeID, models.FriendshipPending)
+	if err != nil {
+		return fmt.Errorf("failed to accept friend request: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("friend_request_not_found")
+	}
+	return nil
+}
+
+// Delete removes the friendship or request between two players in either
+// direction. It returns "friendship_not_found" if there is none.
+func (r *FriendshipRepository) Delete(ctx context.Context, playerID, otherID string) error {
+	const query = `
+		DELETE FROM friendships
+		WHERE (requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1)
+	`
+	res, err := r.db.ExecContext(ctx, query, playerID, otherID)
+	if err != nil {
+		return fmt.Errorf("failed to delete friendship: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("friendship_not_found")
+	}
+	return nil
+}
+
+// ListByPlayer returns the player's friends and friend requests in both
+// directions, by name. Deleted players are left out.
+func (r *FriendshipRepository) ListByPlayer(ctx context.Context, playerID string) ([]*models.Friend, error) {
+	const query = `
+		SELECT p.id, p.name, f.status, f.addressee_id = $1 AND f.status = $2, f.created_at, f.accepted_at
+		FROM friendships f
+		JOIN players p ON p.id = CASE WHEN f.requester_id = $1 THEN f.addressee_id ELSE f.requester_id END
+		WHERE (f.requester_id = $1 OR f.addressee_id = $1) AND p.deleted_at IS NULL
+		ORDER BY lower(p.name) ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, playerID, models.FriendshipPending)
+	if err != nil {
+		return nil, fmt.Errorf("query friends failed: %w", err)
+	}
+	defer rows.Close()
+
+	friends := []*models.Friend{}
+	for rows.Next() {
+		var f models.Friend
+		var acceptedAt sql.NullTime
+		if err := rows.Scan(&f.PlayerID, &f.Name, &f.Status, &f.Incoming, &f.Since, &acceptedAt); err != nil {
+			return nil, fmt.Errorf("scan friend failed: %w", err)
+		}
+		if acceptedAt.Valid {
+			f.Since = acceptedAt.Time
+		}
+		friends = append(friends, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return friends, nil
+}
+
+type FriendshipRepositoryI interface {
+	Get(ctx context.Context, playerID, otherID string) (*models.Friendship, error)
+	Request(ctx context.Context, requesterID, addresseeID string, at time.Time) error
+	Accept(ctx context.Context, requesterID, addresseeID string, at time.Time) error
+	Delete(ctx context.Context, playerID, otherID string) error
+	ListByPlayer(ctx context.Context, playerID string) ([]*models.Friend, error)
+}
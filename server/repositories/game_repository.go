@@ -6,6 +6,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -34,36 +36,72 @@ func (r *GameRepository) initializeTable() error {
 		CREATE TABLE IF NOT EXISTS games (
 			id TEXT PRIMARY KEY,
 			solution TEXT NOT NULL,
+			mode TEXT NOT NULL DEFAULT 'classic',
 			first_player TEXT NOT NULL,
 			second_player TEXT NOT NULL,
 			current_player TEXT,
 			result TEXT,
 			guesses TEXT,
+			guess_times TEXT,
+			imported INTEGER NOT NULL DEFAULT 0,
+			imported_by TEXT,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (first_player) REFERENCES players(id),
 			FOREIGN KEY (second_player) REFERENCES players(id)
-		)
+		);
+		CREATE TABLE IF NOT EXISTS rating_changes (
+			game_id TEXT NOT NULL,
+			player_id TEXT NOT NULL,
+			rating_before INTEGER NOT NULL,
+			rating_after INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (game_id, player_id),
+			FOREIGN KEY (game_id) REFERENCES games(id),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_rating_changes_player ON rating_changes (player_id, created_at);
+	`
+	if _, err := r.db.Exec(query); err != nil {
+		return err
+	}
+	if err := ensureColumn(r.db, "games", "mode", "TEXT NOT NULL DEFAULT 'classic'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(r.db, "games", "guess_times", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(r.db, "games", "imported", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(r.db, "games", "imported_by", "TEXT"); err != nil {
+		return err
+	}
+	const indexes = `
+		CREATE INDEX IF NOT EXISTS idx_games_first_player ON games (first_player, created_at);
+		CREATE INDEX IF NOT EXISTS idx_games_second_player ON games (second_player, created_at);
+		CREATE INDEX IF NOT EXISTS idx_games_first_player_updated_at ON games (first_player, updated_at);
+		CREATE INDEX IF NOT EXISTS idx_games_second_player_updated_at ON games (second_player, updated_at);
+		CREATE INDEX IF NOT EXISTS idx_games_updated_at ON games (updated_at);
+		CREATE INDEX IF NOT EXISTS idx_games_mode_updated_at ON games (mode, updated_at);
 	`
-	_, err := r.db.Exec(query)
+	_, err := r.db.Exec(indexes)
 	return err
 }
 
-func (r *GameRepository) GetByID(ctx context.Context, id string) (*models.Game, error) {
-	const query = `
-			SELECT id, solution, created_at, updated_at, first_player, second_player, current_player, result, guesses
-			FROM games
-			WHERE id = $1
-	`
-
-	row := r.db.QueryRowContext(ctx, query, id)
+// gameColumns lists the games columns in the order scanGame expects them.
+const gameColumns = `id, solution, mode, created_at, updated_at, first_player, second_player, current_player, result, guesses, guess_times, imported`
 
+// scanGame scans a row selected with gameColumns into a Game. Any columns
+// selected after gameColumns are scanned into extra.
+func scanGame(row rowScanner, extra ...any) (*models.Game, error) {
 	var game models.Game
-	var guessesJSON []byte
+	var guessesJSON, guessTimesJSON []byte
 
-	err := row.Scan(
+	dest := []any{
 		&game.ID,
 		&game.Solution,
+		&game.Mode,
 		&game.CreatedAt,
 		&game.UpdatedAt,
 		&game.FirstPlayer,
@@ -71,8 +109,76 @@ func (r *GameRepository) GetByID(ctx context.Context, id string) (*models.Game,
 		&game.CurrentPlayer,
 		&game.Result,
 		&guessesJSON,
-	)
+		&guessTimesJSON,
+		&game.Imported,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(guessesJSON, &game.Guesses); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal guesses: %w", err)
+	}
+	// Games created before guess times were recorded have none.
+	if guessTimesJSON != nil {
+		if err := json.Unmarshal(guessTimesJSON, &game.GuessTimes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal guess times: %w", err)
+		}
+	}
+	return &game, nil
+}
+
+// qualifiedGameColumns is gameColumns prefixed with a table alias, for queries that join other tables.
+func qualifiedGameColumns(alias string) string {
+	columns := strings.Split(gameColumns, ", ")
+	for i, c := range columns {
+		columns[i] = alias + "." + c
+	}
+	return strings.Join(columns, ", ")
+}
 
+// displayName selects the name to show for the joined player with the given
+// alias, hiding the names of deleted players.
+func displayName(alias string) string {
+	return `CASE WHEN ` + alias + `.deleted_at IS NOT NULL THEN '` + models.DeletedPlayerName + `' ELSE COALESCE(` + alias + `.name, '') END`
+}
+
+// marshalGuesses encodes the guesses and guess times for storage.
+func marshalGuesses(game *models.Game) (guessesJSON []byte, guessTimesJSON []byte, err error) {
+	guessesJSON, err = json.Marshal(game.Guesses)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal guesses: %w", err)
+	}
+	guessTimesJSON, err = json.Marshal(game.GuessTimes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal guess times: %w", err)
+	}
+	return guessesJSON, guessTimesJSON, nil
+}
+
+// scanGames scans every row selected with gameColumns.
+func scanGames(rows *sql.Rows) ([]*models.Game, error) {
+	var games []*models.Game
+	for rows.Next() {
+		game, err := scanGame(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan game row failed: %w", err)
+		}
+		games = append(games, game)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return games, nil
+}
+
+func (r *GameRepository) GetByID(ctx context.Context, id string) (*models.Game, error) {
+	query := `
+			SELECT ` + gameColumns + `
+			FROM games
+			WHERE id = $1
+	`
+
+	game, err := scanGame(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
@@ -80,19 +186,12 @@ func (r *GameRepository) GetByID(ctx context.Context, id string) (*models.Game,
 		return nil, fmt.Errorf("query game by ID failed: %w", err)
 	}
 
-	if err := json.Unmarshal(guessesJSON, &game.Guesses); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal guesses: %w", err)
-	}
-
-	return &game, nil
+	return game, nil
 }
 
 func (r *GameRepository) GetByPlayer(ctx context.Context, playerID string) ([]*models.Game, error) {
-	const query = `
-			SELECT 
-					id, solution, created_at, updated_at, 
-					first_player, second_player, current_player, 
-					result, guesses
+	query := `
+			SELECT ` + gameColumns + `
 			FROM games
 			WHERE first_player = $1 OR second_player = $1
 			ORDER BY created_at DESC
@@ -103,64 +202,325 @@ func (r *GameRepository) GetByPlayer(ctx context.Context, playerID string) ([]*m
 	}
 	defer rows.Close()
 
-	var games []*models.Game
+	return scanGames(rows)
+}
+
+// Game history filter values.
+const (
+	GameStatusInProgress = "in_progress"
+	GameStatusFinished   = "finished"
+
+	GameSortCreatedAt = "created_at"
+	GameSortUpdatedAt = "updated_at"
+)
+
+// GameFilter selects and orders a player's games.
+type GameFilter struct {
+	PlayerID string
+	// Status is GameStatusInProgress, GameStatusFinished, or empty for both.
+	Status string
+	// OpponentID restricts the games to those against one player.
+	OpponentID string
+	// Result is models.OutcomeWin, models.OutcomeLoss or models.OutcomeDraw,
+	// from PlayerID's point of view, or empty for any result.
+	Result string
+	// Mode restricts the games to one game mode.
+	Mode string
+	// From and To bound the games' creation time; zero values leave the range open.
+	From time.Time
+	To   time.Time
+	// Sort is GameSortCreatedAt or GameSortUpdatedAt.
+	Sort string
+	// Ascending orders the oldest first instead of the newest.
+	Ascending bool
+}
+
+// GameCursor is the position of the last game on the previous page. SortKey
+// is the stored value of the sort column, compared as stored.
+type GameCursor struct {
+	SortKey string
+	GameID  string
+}
+
+// ListByPlayer returns up to limit of the player's games matching the filter,
+// starting after the cursor, with both players' names. The returned cursor is
+// nil on the last page. Games another player imported against them are left
+// out; games imported before importers were recorded are left out for both
+// players. The games the player started and the games they were
+// invited to are read separately, so each side can page through its
+// (player, sort column) index, and then merged.
+func (r *GameRepository) ListByPlayer(ctx context.Context, f GameFilter, after *GameCursor, limit int) ([]*models.GameWithPlayers, *GameCursor, error) {
+	sortColumn := "created_at"
+	if f.Sort == GameSortUpdatedAt {
+		sortColumn = "updated_at"
+	}
+	conditions := []string{"(g.imported = 0 OR g.imported_by = '' OR g.imported_by = ?)"}
+	args := []any{f.PlayerID}
+	switch f.Status {
+	case GameStatusInProgress:
+		conditions = append(conditions, "(g.result IS NULL OR g.result = '')")
+	case GameStatusFinished:
+		conditions = append(conditions, "g.result != ''")
+	}
+	if f.OpponentID != "" {
+		conditions = append(conditions, "(g.first_player = ? OR g.second_player = ?)")
+		args = append(args, f.OpponentID, f.OpponentID)
+	}
+	switch f.Result {
+	case models.OutcomeWin:
+		conditions = append(conditions, "g.result LIKE 'lose:%' AND g.result != ?")
+		args = append(args, models.ResultLosePrefix+f.PlayerID)
+	case models.OutcomeLoss:
+		conditions = append(conditions, "g.result = ?")
+		args = append(args, models.ResultLosePrefix+f.PlayerID)
+	case models.OutcomeDraw:
+		conditions = append(conditions, "g.result = ?")
+		args = append(args, models.ResultDraw)
+	}
+	if f.Mode != "" {
+		conditions = append(conditions, "g.mode = ?")
+		args = append(args, f.Mode)
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, "julianday(g.created_at) >= julianday(?)")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, "julianday(g.created_at) < julianday(?)")
+		args = append(args, f.To)
+	}
+	direction, comparison := "DESC", "<"
+	if f.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if after != nil {
+		conditions = append(conditions, "(g."+sortColumn+" "+comparison+" ? OR (g."+sortColumn+" = ? AND g.id "+comparison+" ?))")
+		args = append(args, after.SortKey, after.SortKey, after.GameID)
+	}
+	filter := ""
+	for _, c := range conditions {
+		filter += " AND " + c
+	}
+	order := `g.` + sortColumn + ` ` + direction + `, g.id ` + direction
+
+	// Fetch one extra game to learn whether there is a next page.
+	query := `
+		SELECT ` + qualifiedGameColumns("g") + `,
+			CAST(g.` + sortColumn + ` AS TEXT), ` + displayName("p1") + `, ` + displayName("p2") + `
+		FROM (
+			SELECT * FROM (
+				SELECT * FROM games g
+				WHERE g.first_player = ?` + filter + `
+				ORDER BY ` + order + ` LIMIT ?
+			)
+			UNION ALL
+			SELECT * FROM (
+				SELECT * FROM games g
+				WHERE g.second_player = ? AND g.first_player != ?` + filter + `
+				ORDER BY ` + order + ` LIMIT ?
+			)
+		) g
+		LEFT JOIN players p1 ON p1.id = g.first_player
+		LEFT JOIN players p2 ON p2.id = g.second_player
+		ORDER BY ` + order + `
+		LIMIT ?
+	`
+	var queryArgs []any
+	queryArgs = append(queryArgs, f.PlayerID)
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, limit+1, f.PlayerID, f.PlayerID)
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, limit+1, limit+1)
 
+	rows, err := r.db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query game history failed: %w", err)
+	}
+	defer rows.Close()
+
+	var games []*models.GameWithPlayers
+	var lastSortKey string
+	hasMore := false
 	for rows.Next() {
-		var game models.Game
-		var guessesJSON []byte
-
-		err := rows.Scan(
-			&game.ID,
-			&game.Solution,
-			&game.CreatedAt,
-			&game.UpdatedAt,
-			&game.FirstPlayer,
-			&game.SecondPlayer,
-			&game.CurrentPlayer,
-			&game.Result,
-			&guessesJSON,
-		)
+		if len(games) == limit {
+			hasMore = true
+			break
+		}
+		var item models.GameWithPlayers
+		item.Game, err = scanGame(rows, &lastSortKey, &item.FirstPlayerName, &item.SecondPlayerName)
 		if err != nil {
-			return nil, fmt.Errorf("scan game row failed: %w", err)
+			return nil, nil, fmt.Errorf("scan game row failed: %w", err)
 		}
+		games = append(games, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	var next *GameCursor
+	if hasMore {
+		next = &GameCursor{SortKey: lastSortKey, GameID: games[len(games)-1].Game.ID}
+	}
+	return games, next, nil
+}
 
-		if err := json.Unmarshal(guessesJSON, &game.Guesses); err != nil {
-			return nil, fmt.Errorf("unmarshal guesses failed: %w", err)
+// GetBetween returns every game played between the two players, newest first.
+func (r *GameRepository) GetBetween(ctx context.Context, firstPlayerID string, secondPlayerID string) ([]*models.Game, error) {
+	query := `
+			SELECT ` + gameColumns + `
+			FROM games
+			WHERE ((first_player = $1 AND second_player = $2)
+				OR (first_player = $2 AND second_player = $1))
+				AND imported = 0
+			ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, firstPlayerID, secondPlayerID)
+	if err != nil {
+		return nil, fmt.Errorf("query games between players failed: %w", err)
+	}
+	defer rows.Close()
+
+	return scanGames(rows)
+}
+
+// GetRatingChangesBetween returns the rating changes from games played between
+// the two players, oldest first.
+func (r *GameRepository) GetRatingChangesBetween(ctx context.Context, firstPlayerID string, secondPlayerID string) ([]*models.RatingChange, error) {
+	const query = `
+			SELECT rc.game_id, rc.player_id, rc.rating_before, rc.rating_after, rc.created_at
+			FROM rating_changes rc
+			JOIN games g ON g.id = rc.game_id
+			WHERE ((g.first_player = $1 AND g.second_player = $2)
+				OR (g.first_player = $2 AND g.second_player = $1))
+				AND g.imported = 0
+			ORDER BY rc.created_at ASC, rc.game_id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, firstPlayerID, secondPlayerID)
+	if err != nil {
+		return nil, fmt.Errorf("query rating changes between players failed: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*models.RatingChange
+	for rows.Next() {
+		var change models.RatingChange
+		if err := rows.Scan(&change.GameID, &change.PlayerID, &change.Before, &change.After, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan rating change failed: %w", err)
 		}
+		changes = append(changes, &change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return changes, nil
+}
 
-		games = append(games, &game)
+// GetRatingChangesByPlayer returns every rating change of the player, oldest first.
+func (r *GameRepository) GetRatingChangesByPlayer(ctx context.Context, playerID string) ([]*models.RatingChange, error) {
+	const query = `
+		SELECT game_id, player_id, rating_before, rating_after, created_at
+		FROM rating_changes
+		WHERE player_id = $1
+		ORDER BY created_at ASC, game_id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("query player rating changes failed: %w", err)
 	}
+	defer rows.Close()
 
+	var changes []*models.RatingChange
+	for rows.Next() {
+		var change models.RatingChange
+		if err := rows.Scan(&change.GameID, &change.PlayerID, &change.Before, &change.After, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan rating change failed: %w", err)
+		}
+		changes = append(changes, &change)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows iteration error: %w", err)
 	}
+	return changes, nil
+}
 
-	return games, nil
+// GetRatingChanges returns the rating changes recorded for a game, or none if it was unrated.
+func (r *GameRepository) GetRatingChanges(ctx context.Context, gameID string) ([]*models.RatingChange, error) {
+	const query = `
+		SELECT game_id, player_id, rating_before, rating_after, created_at
+		FROM rating_changes
+		WHERE game_id = $1
+	`
+	rows, err := r.db.QueryContext(ctx, query, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("query rating changes failed: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*models.RatingChange
+	for rows.Next() {
+		var change models.RatingChange
+		if err := rows.Scan(&change.GameID, &change.PlayerID, &change.Before, &change.After, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan rating change failed: %w", err)
+		}
+		changes = append(changes, &change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return changes, nil
 }
 
 func (r *GameRepository) CreateGame(ctx context.Context, game *models.Game) error {
-	guessesJSON, err := json.Marshal(game.Guesses)
+	return insertGame(ctx, r.db, game)
+}
+
+// ImportGames stores the given games in a single transaction, so either all
+// of them are stored or none are.
+func (r *GameRepository) ImportGames(ctx context.Context, games []*models.Game) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to marshal guesses: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, game := range games {
+		if err := insertGame(ctx, tx, game); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit imported games: %w", err)
+	}
+	return nil
+}
+
+func insertGame(ctx context.Context, db execer, game *models.Game) error {
+	guessesJSON, guessTimesJSON, err := marshalGuesses(game)
+	if err != nil {
+		return err
 	}
 
 	const query = `
 		INSERT INTO games (
-			id, solution, first_player, second_player, current_player, result, guesses, created_at, updated_at
+			id, solution, mode, first_player, second_player, current_player, result, guesses, guess_times, imported, imported_by, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
 		)
 	`
-	_, err = r.db.ExecContext(
+	_, err = db.ExecContext(
 		ctx,
 		query,
 		game.ID,
 		game.Solution,
+		game.Mode,
 		game.FirstPlayer,
 		game.SecondPlayer,
 		game.CurrentPlayer,
 		game.Result,
 		guessesJSON,
+		guessTimesJSON,
+		game.Imported,
+		game.ImportedBy,
 		game.CreatedAt,
 		game.UpdatedAt,
 	)
@@ -171,9 +531,9 @@ func (r *GameRepository) CreateGame(ctx context.Context, game *models.Game) erro
 }
 
 func (r *GameRepository) UpdateGame(ctx context.Context, game *models.Game) error {
-	guessesJSON, err := json.Marshal(game.Guesses)
+	guessesJSON, guessTimesJSON, err := marshalGuesses(game)
 	if err != nil {
-		return fmt.Errorf("failed to marshal guesses: %w", err)
+		return err
 	}
 
 	const query = `
@@ -184,8 +544,9 @@ func (r *GameRepository) UpdateGame(ctx context.Context, game *models.Game) erro
 			current_player = $4,
 			result = $5,
 			guesses = $6,
-			updated_at = $7
-		WHERE id = $8
+			guess_times = $7,
+			updated_at = $8
+		WHERE id = $9
 	`
 	_, err = r.db.ExecContext(
 		ctx,
@@ -196,6 +557,7 @@ func (r *GameRepository) UpdateGame(ctx context.Context, game *models.Game) erro
 		game.CurrentPlayer,
 		game.Result,
 		guessesJSON,
+		guessTimesJSON,
 		game.UpdatedAt,
 		game.ID,
 	)
@@ -205,9 +567,178 @@ func (r *GameRepository) UpdateGame(ctx context.Context, game *models.Game) erro
 	return nil
 }
 
+// FinishGame stores the final state of a game, applies the rating changes it
+// produced and adds the players' summaries of it to their aggregate
+// statistics, all in a single transaction. It returns "game_already_finished"
+// if the stored game already has a result, so a game is never counted twice,
+// and "rating_conflict" if a player was rated after changes were computed.
+func (r *GameRepository) FinishGame(ctx context.Context, game *models.Game, changes []*models.RatingChange, summaries []*models.PlayerGameSummary) error {
+	guessesJSON, guessTimesJSON, err := marshalGuesses(game)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const updateGameQuery = `
+		UPDATE games SET
+			current_player = $1,
+			result = $2,
+			guesses = $3,
+			guess_times = $4,
+			updated_at = $5
+		WHERE id = $6 AND (result IS NULL OR result = '')
+	`
+	res, err := tx.ExecContext(ctx, updateGameQuery, game.CurrentPlayer, game.Result, guessesJSON, guessTimesJSON, game.UpdatedAt, game.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update game: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("game_already_finished")
+	}
+
+	for _, change := range changes {
+		if err := applyRatingChange(ctx, tx, change); err != nil {
+			return err
+		}
+	}
+	if err := recordSummaries(ctx, tx, summaries); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit game result: %w", err)
+	}
+	return nil
+}
+
+// SetResult replaces the result of a finished game, keeping when it ended.
+// Ratings are left alone. It returns "game_not_finished" if the game has no
+// result.
+func (r *GameRepository) SetResult(ctx context.Context, gameID string, result string) error {
+	const query = `UPDATE games SET result = $1 WHERE id = $2 AND result IS NOT NULL AND result != ''`
+	res, err := r.db.ExecContext(ctx, query, result, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to set game result: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("game_not_finished")
+	}
+	return nil
+}
+
+// GetFinished returns every finished game played on this server, in the order
+// the games ended. Voided games are left out.
+func (r *GameRepository) GetFinished(ctx context.Context) ([]*models.Game, error) {
+	query := `
+			SELECT ` + gameColumns + `
+			FROM games
+			WHERE result IS NOT NULL AND result != '' AND result != 'void' AND imported = 0
+			ORDER BY updated_at ASC, id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query finished games failed: %w", err)
+	}
+	defer rows.Close()
+
+	return scanGames(rows)
+}
+
+// ReplaceRatings discards every stored rating and rating change and applies the
+// given changes in order, all in a single transaction.
+func (r *GameRepository) ReplaceRatings(ctx context.Context, changes []*models.RatingChange) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const resetQuery = `
+		UPDATE players SET
+			elo = NULL,
+			rated_games = 0,
+			rating_deviation = NULL,
+			rating_volatility = NULL,
+			rated_at = NULL
+	`
+	if _, err := tx.ExecContext(ctx, resetQuery); err != nil {
+		return fmt.Errorf("failed to reset ratings: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM rating_changes`); err != nil {
+		return fmt.Errorf("failed to clear rating changes: %w", err)
+	}
+	for _, change := range changes {
+		if err := applyRatingChange(ctx, tx, change); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ratings: %w", err)
+	}
+	return nil
+}
+
+// applyRatingChange updates the player's rating and records the change. It
+// returns "rating_conflict" if the player has been rated since the change was
+// computed.
+func applyRatingChange(ctx context.Context, tx *sql.Tx, change *models.RatingChange) error {
+	const updatePlayerQuery = `
+		UPDATE players SET
+			elo = $1,
+			rating_deviation = $2,
+			rating_volatility = $3,
+			rated_at = $4,
+			rated_games = rated_games + 1
+		WHERE id = $5 AND rated_games = $6
+	`
+	const insertChangeQuery = `
+		INSERT INTO rating_changes (game_id, player_id, rating_before, rating_after, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	res, err := tx.ExecContext(ctx, updatePlayerQuery, change.After, change.Deviation, change.Volatility, change.CreatedAt, change.PlayerID, change.RatedGames)
+	if err != nil {
+		return fmt.Errorf("failed to update rating: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update rating: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("rating_conflict")
+	}
+	if _, err := tx.ExecContext(ctx, insertChangeQuery, change.GameID, change.PlayerID, change.Before, change.After, change.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert rating change: %w", err)
+	}
+	return nil
+}
+
 type GameRepositoryI interface {
 	CreateGame(ctx context.Context, game *models.Game) error
+	ImportGames(ctx context.Context, games []*models.Game) error
 	GetByID(ctx context.Context, id string) (*models.Game, error)
 	GetByPlayer(ctx context.Context, playerID string) ([]*models.Game, error)
+	ListByPlayer(ctx context.Context, f GameFilter, after *GameCursor, limit int) ([]*models.GameWithPlayers, *GameCursor, error)
+	GetBetween(ctx context.Context, firstPlayerID string, secondPlayerID string) ([]*models.Game, error)
+	GetRatingChangesBetween(ctx context.Context, firstPlayerID string, secondPlayerID string) ([]*models.RatingChange, error)
+	GetRatingChanges(ctx context.Context, gameID string) ([]*models.RatingChange, error)
+	GetRatingChangesByPlayer(ctx context.Context, playerID string) ([]*models.RatingChange, error)
 	UpdateGame(ctx context.Context, game *models.Game) error
+	FinishGame(ctx context.Context, game *models.Game, changes []*models.RatingChange, summaries []*models.PlayerGameSummary) error
+	GetFinished(ctx context.Context) ([]*models.Game, error)
+	ReplaceRatings(ctx context.Context, changes []*models.RatingChange) error
+	SetResult(ctx context.Context, gameID string, result string) error
 }
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// IdentityRepository stores the external identities players log in with.
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(dbPath string) (*IdentityRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &IdentityRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *IdentityRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS identities (
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			player_id TEXT NOT NULL,
+			email TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (provider, subject),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_identities_player_id ON identities(player_id);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// Get returns the identity with the given subject at the provider, or nil if
+// it is not linked to a player.
+func (r *IdentityRepository) Get(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	const query = `
+		SELECT provider, subject, player_id, email, created_at
+		FROM identities
+		WHERE provider = $1 AND subject = $2
+	`
+	var identity models.Identity
+	err := r.db.QueryRowContext(ctx, query, provider, subject).
+		Scan(&identity.Provider, &identity.Subject, &identity.PlayerID, &identity.Email, &identity.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query identity failed: %w", err)
+	}
+	return &identity, nil
+}
+
+// Create links an identity to a player. It returns "identity_taken" if the
+// identity is already linked.
+func (r *IdentityRepository) Create(ctx context.Context, identity *models.Identity) error {
+	const query = `
+		INSERT INTO identities (provider, subject, player_id, email, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.ExecContext(ctx, query, identity.Provider, identity.Subject, identity.PlayerID, identity.Email, identity.CreatedAt.UTC())
+	if err != nil {
+		if isUniqueViolation(err, "identities.provider") {
+			return fmt.Errorf("identity_taken")
+		}
+		return fmt.Errorf("failed to insert identity: %w", err)
+	}
+	return nil
+}
+
+// Delete unlinks an identity.
+func (r *IdentityRepository) Delete(ctx context.Context, provider, subject string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM identities WHERE provider = $1 AND subject = $2`, provider, subject); err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	return nil
+}
+
+// ListByPlayer returns the identities linked to the player, oldest first.
+func (r *IdentityRepository) ListByPlayer(ctx context.Context, playerID string) ([]*models.Identity, error) {
+	const query = `
+		SELECT provider, subject, player_id, email, created_at
+		FROM identities
+		WHERE player_id = $1
+		ORDER BY julianday(created_at) ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("query identities failed: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*models.Identity
+	for rows.Next() {
+		var identity models.Identity
+		if err := rows.Scan(&identity.Provider, &identity.Subject, &identity.PlayerID, &identity.Email, &identity.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan identity failed: %w", err)
+		}
+		identities = append(identities, &identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return identities, nil
+}
+
+type IdentityRepositoryI interface {
+	Get(ctx context.Context, provider, subject string) (*models.Identity, error)
+	Create(ctx context.Context, identity *models.Identity) error
+	Delete(ctx context.Context, provider, subject string) error
+	ListByPlayer(ctx context.Context, playerID string) ([]*models.Identity, error)
+}
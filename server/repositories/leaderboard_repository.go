@@ -0,0 +1,286 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// LeaderboardRepository runs the ranking queries behind the leaderboards. It
+// reads the players, games and rating_changes tables owned by the other
+// repositories and relies on their indexes.
+type LeaderboardRepository struct {
+	db *sql.DB
+}
+
+func NewLeaderboardRepository(dbPath string) (*LeaderboardRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &LeaderboardRepository{db: db}, nil
+}
+
+// LeaderboardFilter selects the games counted on a points leaderboard.
+type LeaderboardFilter struct {
+	// Since excludes games that ended before it; the zero value counts all games.
+	Since time.Time
+	// Mode restricts the board to one game mode; empty counts every mode.
+	Mode string
+	// WordLength restricts the board to solutions of this length; 0 counts every length.
+	WordLength int
+	// MinGames is the number of counted games a player needs to appear.
+	MinGames int
+}
+
+// LeaderboardCursor is the last entry of the previous page.
+type LeaderboardCursor struct {
+	Score    int
+	PlayerID string
+}
+
+// RatingPage returns registered players with at least minGames rated games,
+// ordered by rating, starting after the cursor. Ranks are left for the caller.
+func (r *LeaderboardRepository) RatingPage(ctx context.Context, minGames int, after *LeaderboardCursor, limit int) ([]*models.LeaderboardEntry, error) {
+	query := `
+		SELECT id, name, elo, rated_games
+		FROM players
+		WHERE registered = 1 AND elo IS NOT NULL AND rated_games >= ?
+	`
+	args := []any{minGames}
+	if after != nil {
+		query += ` AND (elo < ? OR (elo = ? AND id > ?))`
+		args = append(args, after.Score, after.Score, after.PlayerID)
+	}
+	query += ` ORDER BY elo DESC, id ASC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query rating leaderboard failed: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.LeaderboardEntry
+	for rows.Next() {
+		var e models.LeaderboardEntry
+		var elo int
+		if err := rows.Scan(&e.PlayerID, &e.Name, &elo, &e.Games); err != nil {
+			return nil, fmt.Errorf("scan leaderboard row failed: %w", err)
+		}
+		e.Rating = &elo
+		entries = append(entries, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	if err := r.fillRatedResults(ctx, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// RatingStanding returns the player's ranked entry on the rating leaderboard,
+// or nil if they do not qualify for it.
+func (r *LeaderboardRepository) RatingStanding(ctx context.Context, minGames int, playerID string) (*models.LeaderboardEntry, error) {
+	const query = `
+		SELECT me.id, me.name, me.elo, me.rated_games,
+			(SELECT COUNT(*) FROM players p
+			 WHERE p.registered = 1 AND p.elo > me.elo AND p.rated_games >= $1) + 1
+		FROM players me
+		WHERE me.id = $2 AND me.registered = 1 AND me.elo IS NOT NULL AND me.rated_games >= $1
+	`
+	var e models.LeaderboardEntry
+	var elo int
+	err := r.db.QueryRowContext(ctx, query, minGames, playerID).Scan(&e.PlayerID, &e.Name, &elo, &e.Games, &e.Rank)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not on the leaderboard
+		}
+		return nil, fmt.Errorf("query rating standing failed: %w", err)
+	}
+	e.Rating = &elo
+	if err := r.fillRatedResults(ctx, []*models.LeaderboardEntry{&e}); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// fillRatedResults sets wins, draws, losses and points from each player's rated games in one query.
+func (r *LeaderboardRepository) fillRatedResults(ctx context.Context, entries []*models.LeaderboardEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	byID := make(map[string]*models.LeaderboardEntry, len(entries))
+	placeholders := make([]string, len(entries))
+	args := make([]any, len(entries))
+	for i, e := range entries {
+		byID[e.PlayerID] = e
+		placeholders[i] = "?"
+		args[i] = e.PlayerID
+	}
+	query := `
+		SELECT rc.player_id,
+			SUM(CASE WHEN g.result = 'draw' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN g.result = 'lose:' || rc.player_id THEN 1 ELSE 0 END)
+		FROM rating_changes rc
+		JOIN games g ON g.id = rc.game_id
+		WHERE rc.player_id IN (` + strings.Join(placeholders, ", ") + `)
+		GROUP BY rc.player_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query rated results failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var playerID string
+		var draws, losses int
+		if err := rows.Scan(&playerID, &draws, &losses); err != nil {
+			return fmt.Errorf("scan rated results failed: %w", err)
+		}
+		if e, ok := byID[playerID]; ok {
+			e.Draws = draws
+			e.Losses = losses
+			e.Wins = e.Games - draws - losses
+			e.Points = 2*e.Wins + e.Draws
+		}
+	}
+	return rows.Err()
+}
+
+// pointsBoard builds a CTE named board holding one row per registered player
+// who finished at least f.MinGames games matching the filter.
+func pointsBoard(f LeaderboardFilter) (string, []any) {
+	conditions := []string{"result IS NOT NULL", "result != ''", "result != 'void'", "imported = 0"}
+	var args []any
+	if !f.Since.IsZero() {
+		conditions = append(conditions, "julianday(updated_at) >= julianday(?)")
+		args = append(args, f.Since)
+	}
+	if f.Mode != "" {
+		conditions = append(conditions, "mode = ?")
+		args = append(args, f.Mode)
+	}
+	if f.WordLength > 0 {
+		conditions = append(conditions, "length(solution) = ?")
+		args = append(args, f.WordLength)
+	}
+	cte := `
+		WITH finished AS (
+			SELECT first_player, second_player, result
+			FROM games
+			WHERE ` + strings.Join(conditions, " AND ") + `
+		), results AS (
+			SELECT first_player AS player_id, result FROM finished
+			UNION ALL
+			SELECT second_player AS player_id, result FROM finished
+		), standings AS (
+			SELECT player_id,
+				COUNT(*) AS games,
+				SUM(CASE WHEN result = 'draw' THEN 1 ELSE 0 END) AS draws,
+				SUM(CASE WHEN result = 'lose:' || player_id THEN 1 ELSE 0 END) AS losses
+			FROM results
+			GROUP BY player_id
+			HAVING COUNT(*) >= ?
+		), board AS (
+			SELECT s.player_id, p.name, p.elo, s.games, s.draws, s.losses,
+				s.games - s.draws - s.losses AS wins,
+				2 * (s.games - s.draws - s.losses) + s.draws AS points
+			FROM standings s
+			JOIN players p ON p.id = s.player_id
+			WHERE p.registered = 1
+		)
+	`
+	args = append(args, f.MinGames)
+	return cte, args
+}
+
+// PointsPage returns players ordered by points over the games matching the
+// filter, starting after the cursor. Ranks are left for the caller.
+func (r *LeaderboardRepository) PointsPage(ctx context.Context, f LeaderboardFilter, after *LeaderboardCursor, limit int) ([]*models.LeaderboardEntry, error) {
+	cte, args := pointsBoard(f)
+	query := cte + `
+		SELECT player_id, name, elo, games, wins, draws, losses, points
+		FROM board
+	`
+	if after != nil {
+		query += ` WHERE points < ? OR (points = ? AND player_id > ?)`
+		args = append(args, after.Score, after.Score, after.PlayerID)
+	}
+	query += ` ORDER BY points DESC, player_id ASC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query points leaderboard failed: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.LeaderboardEntry
+	for rows.Next() {
+		e, err := scanPointsEntry(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return entries, nil
+}
+
+// PointsStanding returns the player's ranked entry on the points leaderboard
+// for the filter, or nil if they do not qualify for it.
+func (r *LeaderboardRepository) PointsStanding(ctx context.Context, f LeaderboardFilter, playerID string) (*models.LeaderboardEntry, error) {
+	cte, args := pointsBoard(f)
+	query := cte + `
+		SELECT player_id, name, elo, games, wins, draws, losses, points,
+			(SELECT COUNT(*) FROM board b WHERE b.points > me.points) + 1
+		FROM board me
+		WHERE player_id = ?
+	`
+	args = append(args, playerID)
+	e, err := scanPointsEntry(r.db.QueryRowContext(ctx, query, args...), true)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not on the leaderboard
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// scanPointsEntry scans a board row. If ranked is set, the row has the rank as a trailing column.
+func scanPointsEntry(row rowScanner, ranked bool) (*models.LeaderboardEntry, error) {
+	var e models.LeaderboardEntry
+	var elo sql.NullInt64
+	dest := []any{&e.PlayerID, &e.Name, &elo, &e.Games, &e.Wins, &e.Draws, &e.Losses, &e.Points}
+	if ranked {
+		dest = append(dest, &e.Rank)
+	}
+	if err := row.Scan(dest...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan leaderboard row failed: %w", err)
+	}
+	if elo.Valid {
+		rating := int(elo.Int64)
+		e.Rating = &rating
+	}
+	return &e, nil
+}
+
+type LeaderboardRepositoryI interface {
+	RatingPage(ctx context.Context, minGames int, after *LeaderboardCursor, limit int) ([]*models.LeaderboardEntry, error)
+	RatingStanding(ctx context.Context, minGames int, playerID string) (*models.LeaderboardEntry, error)
+	PointsPage(ctx context.Context, f LeaderboardFilter, after *LeaderboardCursor, limit int) ([]*models.LeaderboardEntry, error)
+	PointsStanding(ctx context.Context, f LeaderboardFilter, playerID string) (*models.LeaderboardEntry, error)
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// PasswordResetRepository stores hashed password reset tokens.
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(dbPath string) (*PasswordResetRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &PasswordResetRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *PasswordResetRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS password_resets (
+			id TEXT PRIMARY KEY,
+			player_id TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_password_resets_player_id ON password_resets(player_id);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// Create stores a reset token. Earlier unused tokens of the player stop
+// working, so only the most recent email can be used.
+func (r *PasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const supersede = `UPDATE password_resets SET used_at = $1 WHERE player_id = $2 AND used_at IS NULL`
+	if _, err := tx.ExecContext(ctx, supersede, reset.CreatedAt.UTC(), reset.PlayerID); err != nil {
+		return fmt.Errorf("failed to supersede reset tokens: %w", err)
+	}
+	const insert = `
+		INSERT INTO password_resets (id, player_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.ExecContext(ctx, insert, reset.ID, reset.PlayerID, reset.TokenHash, reset.CreatedAt.UTC(), reset.ExpiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Consume marks the unused, unexpired token with the given hash as used and
+// returns it, or returns nil if there is no such token.
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordReset, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const selectQuery = `
+		SELECT id, player_id, token_hash, created_at, expires_at
+		FROM password_resets
+		WHERE token_hash = $1 AND used_at IS NULL AND julianday(expires_at) > julianday($2)
+	`
+	var reset models.PasswordReset
+	err = tx.QueryRowContext(ctx, selectQuery, tokenHash, now.UTC()).
+		Scan(&reset.ID, &reset.PlayerID, &reset.TokenHash, &reset.CreatedAt, &reset.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reset token: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE password_resets SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, now.UTC(), reset.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use reset token: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	usedAt := now
+	reset.UsedAt = &usedAt
+	return &reset, nil
+}
+
+// CountSince returns how many reset tokens were created for the player since
+// the given time.
+func (r *PasswordResetRepository) CountSince(ctx context.Context, playerID string, since time.Time) (int, error) {
+	const query = `SELECT COUNT(*) FROM password_resets WHERE player_id = $1 AND julianday(created_at) > julianday($2)`
+	var n int
+	if err := r.db.QueryRowContext(ctx, query, playerID, since.UTC()).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count reset tokens: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteExpired removes tokens that can no longer be used.
+func (r *PasswordResetRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	const query = `DELETE FROM password_resets WHERE julianday(expires_at) <= julianday($1)`
+	if _, err := r.db.ExecContext(ctx, query, now.UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired reset tokens: %w", err)
+	}
+	return nil
+}
+
+type PasswordResetRepositoryI interface {
+	Create(ctx context.Context, reset *models.PasswordReset) error
+	Consume(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordReset, error)
+	CountSince(ctx context.Context, playerID string, since time.Time) (int, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
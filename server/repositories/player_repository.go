@@ -38,33 +38,203 @@ func (r *PlayerRepository) initializeTable() error {
 				registered BOOLEAN NOT NULL,
 				password_hash TEXT,
 				elo INTEGER,
-				created_at TIMESTAMP NOT NULL
+				rated_games INTEGER NOT NULL DEFAULT 0,
+				rating_deviation REAL,
+				rating_volatility REAL,
+				rated_at TIMESTAMP,
+				email TEXT,
+				created_at TIMESTAMP NOT NULL,
+				deleted_at TIMESTAMP,
+				role TEXT NOT NULL DEFAULT 'player',
+				banned_at TIMESTAMP,
+				banned_until TIMESTAMP,
+				ban_reason TEXT NOT NULL DEFAULT '',
+				name_key TEXT
         );
     `
-	_, err := r.db.Exec(query)
+	if _, err := r.db.Exec(query); err != nil {
+		return err
+	}
+	columns := []struct{ name, definition string }{
+		{"rated_games", "INTEGER NOT NULL DEFAULT 0"},
+		{"rating_deviation", "REAL"},
+		{"rating_volatility", "REAL"},
+		{"rated_at", "TIMESTAMP"},
+		{"email", "TEXT"},
+		{"deleted_at", "TIMESTAMP"},
+		{"role", "TEXT NOT NULL DEFAULT 'player'"},
+		{"banned_at", "TIMESTAMP"},
+		{"banned_until", "TIMESTAMP"},
+		{"ban_reason", "TEXT NOT NULL DEFAULT ''"},
+		{"name_key", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := ensureColumn(r.db, "players", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+	// Names are unique by their canonical form. Players from before canonical
+	// forms whose names collide keep their names, without a key.
+	if _, err := r.db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_players_name_key ON players (name_key) WHERE name_key IS NOT NULL`); err != nil {
+		return err
+	}
+	const namesQuery = `
+        CREATE TABLE IF NOT EXISTS player_names (
+				player_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				name_key TEXT NOT NULL,
+				changed_at TIMESTAMP NOT NULL
+        );
+    `
+	if _, err := r.db.Exec(namesQuery); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_player_names_key ON player_names (name_key, changed_at DESC)`); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_player_names_player ON player_names (player_id, changed_at DESC)`); err != nil {
+		return err
+	}
+	if err := r.updateNameKeys(); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_players_elo ON players (elo DESC, id)`); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_players_email ON players (email) WHERE email IS NOT NULL`)
 	return err
 }
 
-func (r *PlayerRepository) GetByID(ctx context.Context, id string) (*models.Player, error) {
-	const query = `
-        SELECT id, name, registered, password_hash, elo, created_at
-        FROM players
-        WHERE id = $1
-    `
+// updateNameKeys sets the canonical name of every player whose stored one is
+// missing or out of date, because they were created before it was stored or
+// before canonical forms last changed, and does the same for former names.
+// When names collide, the oldest player gets the key and the others keep
+// their names without one. Deleted players keep their keys.
+func (r *PlayerRepository) updateNameKeys() error {
+	rows, err := r.db.Query(`
+		SELECT id, name, name_key, deleted_at IS NOT NULL
+		FROM players
+		WHERE name_key IS NOT NULL OR deleted_at IS NULL
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to read player names: %w", err)
+	}
+	type player struct {
+		id, name string
+		key      sql.NullString
+		deleted  bool
+	}
+	var players []player
+	for rows.Next() {
+		var p player
+		if err := rows.Scan(&p.id, &p.name, &p.key, &p.deleted); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan player name: %w", err)
+		}
+		players = append(players, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read player names: %w", err)
+	}
+
+	taken := make(map[string]bool)
+	for _, p := range players {
+		if p.deleted {
+			taken[p.key.String] = true
+		}
+	}
+	type change struct {
+		id  string
+		key *string
+	}
+	var changes []change
+	for _, p := range players {
+		if p.deleted {
+			continue
+		}
+		var want *string
+		if key := models.CanonicalName(p.name); !taken[key] {
+			taken[key] = true
+			want = &key
+		}
+		if want == nil && p.key.Valid || want != nil && (!p.key.Valid || p.key.String != *want) {
+			changes = append(changes, change{p.id, want})
+		}
+	}
 
-	row := r.db.QueryRowContext(ctx, query, id)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// Changed keys are cleared first, so that no two players share a key in between.
+	for _, c := range changes {
+		if _, err := tx.Exec(`UPDATE players SET name_key = NULL WHERE id = $1`, c.id); err != nil {
+			return fmt.Errorf("failed to clear canonical name: %w", err)
+		}
+	}
+	for _, c := range changes {
+		if c.key == nil {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE players SET name_key = $1 WHERE id = $2`, *c.key, c.id); err != nil {
+			return fmt.Errorf("failed to set canonical name: %w", err)
+		}
+	}
+
+	rows, err = tx.Query(`SELECT rowid, name, name_key FROM player_names`)
+	if err != nil {
+		return fmt.Errorf("failed to read former names: %w", err)
+	}
+	stale := make(map[int64]string)
+	for rows.Next() {
+		var rowID int64
+		var name, key string
+		if err := rows.Scan(&rowID, &name, &key); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan former name: %w", err)
+		}
+		if canonical := models.CanonicalName(name); canonical != key {
+			stale[rowID] = canonical
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read former names: %w", err)
+	}
+	for rowID, key := range stale {
+		if _, err := tx.Exec(`UPDATE player_names SET name_key = $1 WHERE rowid = $2`, key, rowID); err != nil {
+			return fmt.Errorf("failed to set canonical former name: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit canonical names: %w", err)
+	}
+	return nil
+}
+
+// playerColumns lists the players columns in the order scanPlayer expects them.
+const playerColumns = `id, name, registered, password_hash, elo, rated_games, rating_deviation, rating_volatility, rated_at, email, created_at, deleted_at, role, banned_at, banned_until, ban_reason`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
+// scanPlayer scans a row selected with playerColumns into a Player.
+func scanPlayer(row rowScanner) (*models.Player, error) {
 	var player models.Player
 	var passwordHash sql.NullString
 	var elo sql.NullInt64
+	var deviation, volatility sql.NullFloat64
+	var ratedAt, deletedAt, bannedAt, bannedUntil sql.NullTime
+	var email sql.NullString
 	var createdAt time.Time
 
-	err := row.Scan(&player.ID, &player.Name, &player.Registered, &passwordHash, &elo, &createdAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Not found
-		}
-		return nil, fmt.Errorf("query player by ID failed: %w", err)
+	if err := row.Scan(&player.ID, &player.Name, &player.Registered, &passwordHash, &elo, &player.RatedGames, &deviation, &volatility, &ratedAt, &email, &createdAt, &deletedAt, &player.Role, &bannedAt, &bannedUntil, &player.BanReason); err != nil {
+		return nil, err
 	}
 	if passwordHash.Valid {
 		player.PasswordHash = &passwordHash.String
@@ -73,17 +243,65 @@ func (r *PlayerRepository) GetByID(ctx context.Context, id string) (*models.Play
 		eloInt := int(elo.Int64)
 		player.Elo = &eloInt
 	}
+	if deviation.Valid {
+		player.RatingDeviation = &deviation.Float64
+	}
+	if volatility.Valid {
+		player.RatingVolatility = &volatility.Float64
+	}
+	if ratedAt.Valid {
+		player.RatedAt = &ratedAt.Time
+	}
+	if email.Valid {
+		player.Email = &email.String
+	}
 	player.CreatedAt = createdAt
+	if deletedAt.Valid {
+		player.DeletedAt = &deletedAt.Time
+	}
+	if bannedAt.Valid {
+		player.BannedAt = &bannedAt.Time
+	}
+	if bannedUntil.Valid {
+		player.BannedUntil = &bannedUntil.Time
+	}
 
 	return &player, nil
 }
 
+func (r *PlayerRepository) GetByID(ctx context.Context, id string) (*models.Player, error) {
+	query := `
+        SELECT ` + playerColumns + `
+        FROM players
+        WHERE id = $1
+    `
+
+	player, err := scanPlayer(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("query player by ID failed: %w", err)
+	}
+	return player, nil
+}
+
 func (r *PlayerRepository) CreatePlayer(ctx context.Context, player *models.Player) error {
 	const query = `
-		INSERT INTO players (id, name, registered, password_hash, elo, created_at) VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO players (id, name, name_key, registered, role, password_hash, elo, email, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
-	_, err := r.db.ExecContext(ctx, query, player.ID, player.Name, player.Registered, player.PasswordHash, player.Elo, player.CreatedAt)
+	role := player.Role
+	if role == "" {
+		role = models.RolePlayer
+	}
+	_, err := r.db.ExecContext(ctx, query, player.ID, player.Name, models.CanonicalName(player.Name), player.Registered, role, player.PasswordHash, player.Elo, player.Email, player.CreatedAt)
 	if err != nil {
+		if isUniqueViolation(err, "players.email") {
+			return fmt.Errorf("email_taken")
+		}
+		if isUniqueViolation(err, "players.name_key") {
+			return fmt.Errorf("username_taken")
+		}
 		if sqliteErr, ok := err.(interface{ Error() string }); ok && ( // fallback for sqlite3 driver
 		// Check for unique constraint violation (code 2067 for sqlite3)
 		// See: https://www.sqlite.org/rescode.html#constraint_unique
@@ -104,44 +322,32 @@ func (r *PlayerRepository) CreatePlayer(ctx context.Context, player *models.Play
 	return nil
 }
 
+// GetByName returns the player whose name has the same canonical form as
+// name, or nil if there is none.
 func (r *PlayerRepository) GetByName(ctx context.Context, name string) (*models.Player, error) {
-	const query = `
-        SELECT id, name, registered, password_hash, elo, created_at
+	query := `
+        SELECT ` + playerColumns + `
         FROM players
-        WHERE name = $1
+        WHERE name_key = $1 OR name = $2
+        ORDER BY name = $2 DESC
+        LIMIT 1
     `
 
-	row := r.db.QueryRowContext(ctx, query, name)
-
-	var player models.Player
-	var passwordHash sql.NullString
-	var elo sql.NullInt64
-	var createdAt time.Time
-
-	err := row.Scan(&player.ID, &player.Name, &player.Registered, &passwordHash, &elo, &createdAt)
+	player, err := scanPlayer(r.db.QueryRowContext(ctx, query, models.CanonicalName(name), name))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
 		}
 		return nil, fmt.Errorf("query player by name failed: %w", err)
 	}
-	if passwordHash.Valid {
-		player.PasswordHash = &passwordHash.String
-	}
-	if elo.Valid {
-		eloInt := int(elo.Int64)
-		player.Elo = &eloInt
-	}
-	player.CreatedAt = createdAt
-
-	return &player, nil
+	return player, nil
 }
 
 // UpdateGuestToRegistered updates a guest player to a registered player with a new name and password hash.
 func (r *PlayerRepository) UpdateGuestToRegistered(ctx context.Context, id string, newName string, passwordHash string) error {
 	// Check if the new name is already taken by another player
-	const checkNameQuery = `SELECT id FROM players WHERE name = $1 AND id != $2`
-	row := r.db.QueryRowContext(ctx, checkNameQuery, newName, id)
+	const checkNameQuery = `SELECT id FROM players WHERE name_key = $1 AND id != $2`
+	row := r.db.QueryRowContext(ctx, checkNameQuery, models.CanonicalName(newName), id)
 	var existingID string
 	err := row.Scan(&existingID)
 	if err == nil {
@@ -153,11 +359,14 @@ func (r *PlayerRepository) UpdateGuestToRegistered(ctx context.Context, id strin
 	// Update the guest player to registered
 	const updateQuery = `
 		UPDATE players
-		SET name = $1, registered = 1, password_hash = $2
-		WHERE id = $3 AND registered = 0
+		SET name = $1, name_key = $2, registered = 1, password_hash = $3
+		WHERE id = $4 AND registered = 0
 	`
-	res, err := r.db.ExecContext(ctx, updateQuery, newName, passwordHash, id)
+	res, err := r.db.ExecContext(ctx, updateQuery, newName, models.CanonicalName(newName), passwordHash, id)
 	if err != nil {
+		if isUniqueViolation(err, "players.name_key") {
+			return fmt.Errorf("username_taken")
+		}
 		return fmt.Errorf("failed to update guest: %w", err)
 	}
 	rowsAffected, err := res.RowsAffected()
@@ -170,25 +379,362 @@ func (r *PlayerRepository) UpdateGuestToRegistered(ctx context.Context, id strin
 	return nil
 }
 
+// GetByEmail returns the player with the given email address, or nil if there
+// is none. Addresses are stored lowercased.
+func (r *PlayerRepository) GetByEmail(ctx context.Context, email string) (*models.Player, error) {
+	query := `
+        SELECT ` + playerColumns + `
+        FROM players
+        WHERE email = $1
+    `
+
+	player, err := scanPlayer(r.db.QueryRowContext(ctx, query, email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("query player by email failed: %w", err)
+	}
+	return player, nil
+}
+
+// UpdatePassword replaces the password hash of a registered player.
+func (r *PlayerRepository) UpdatePassword(ctx context.Context, id string, passwordHash string) error {
+	const query = `UPDATE players SET password_hash = $1 WHERE id = $2 AND registered = 1`
+	res, err := r.db.ExecContext(ctx, query, passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("player_not_found")
+	}
+	return nil
+}
+
+// UpdateEmail sets or, with nil, clears a player's email address. It returns
+// "email_taken" if another player uses the address.
+func (r *PlayerRepository) UpdateEmail(ctx context.Context, id string, email *string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE players SET email = $1 WHERE id = $2`, email, id)
+	if err != nil {
+		if isUniqueViolation(err, "players.email") {
+			return fmt.Errorf("email_taken")
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("player_not_found")
+	}
+	return nil
+}
+
+// Anonymise turns a player into a tombstone: the name is replaced, the
+// credentials and email address are removed and the player is marked deleted.
+// The row itself is kept so that games keep both players.
+func (r *PlayerRepository) Anonymise(ctx context.Context, id string, deletedAt time.Time) error {
+	const query = `
+		UPDATE players
+		SET name = $1, name_key = NULL, registered = 0, password_hash = NULL, email = NULL, deleted_at = $2
+		WHERE id = $3 AND deleted_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, "deleted-"+id, deletedAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise player: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("player_not_found")
+	}
+	return nil
+}
+
+// RegisterGuest turns a guest into a registered player without a password,
+// for players who log in with an external identity.
+func (r *PlayerRepository) RegisterGuest(ctx context.Context, id string, newName string) error {
+	const checkNameQuery = `SELECT id FROM players WHERE name_key = $1 AND id != $2`
+	var existingID string
+	err := r.db.QueryRowContext(ctx, checkNameQuery, models.CanonicalName(newName), id).Scan(&existingID)
+	if err == nil {
+		return fmt.Errorf("username_taken")
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check name: %w", err)
+	}
+
+	const updateQuery = `UPDATE players SET name = $1, name_key = $2, registered = 1 WHERE id = $3 AND registered = 0 AND deleted_at IS NULL`
+	res, err := r.db.ExecContext(ctx, updateQuery, newName, models.CanonicalName(newName), id)
+	if err != nil {
+		if isUniqueViolation(err, "players.name_key") {
+			return fmt.Errorf("username_taken")
+		}
+		return fmt.Errorf("failed to register guest: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("guest_not_found_or_already_registered")
+	}
+	return nil
+}
+
+// SetRole changes a player's role.
+func (r *PlayerRepository) SetRole(ctx context.Context, id string, role string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE players SET role = $1 WHERE id = $2`, role, id)
+	if err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("player_not_found")
+	}
+	return nil
+}
+
+// SetBan bans a player until the given time, or permanently when until is
+// nil. With a nil bannedAt it lifts the ban.
+func (r *PlayerRepository) SetBan(ctx context.Context, id string, bannedAt *time.Time, until *time.Time, reason string) error {
+	var bannedAtUTC, untilUTC sql.NullTime
+	if bannedAt != nil {
+		bannedAtUTC = sql.NullTime{Time: bannedAt.UTC(), Valid: true}
+	}
+	if until != nil {
+		untilUTC = sql.NullTime{Time: until.UTC(), Valid: true}
+	}
+	const query = `UPDATE players SET banned_at = $1, banned_until = $2, ban_reason = $3 WHERE id = $4`
+	res, err := r.db.ExecContext(ctx, query, bannedAtUTC, untilUTC, reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to set ban: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("player_not_found")
+	}
+	return nil
+}
+
+// Rename changes a player's name and records the old one in their name
+// history. It returns "username_taken" if another player has the name.
+func (r *PlayerRepository) Rename(ctx context.Context, id string, newName string, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldName string
+	err = tx.QueryRowContext(ctx, `SELECT name FROM players WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&oldName)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("player_not_found")
+	} else if err != nil {
+		return fmt.Errorf("failed to read player name: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE players SET name = $1, name_key = $2 WHERE id = $3`, newName, models.CanonicalName(newName), id); err != nil {
+		if isUniqueViolation(err, "players.name_key") || isUniqueViolation(err, "players.name") {
+			return fmt.Errorf("username_taken")
+		}
+		return fmt.Errorf("failed to rename player: %w", err)
+	}
+	const historyQuery = `INSERT INTO player_names (player_id, name, name_key, changed_at) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, historyQuery, id, oldName, models.CanonicalName(oldName), at.UTC()); err != nil {
+		return fmt.Errorf("failed to record name change: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rename: %w", err)
+	}
+	return nil
+}
+
+// GetNameHistory returns the names a player used before, newest first.
+func (r *PlayerRepository) GetNameHistory(ctx context.Context, id string) ([]*models.NameChange, error) {
+	const query = `SELECT player_id, name, changed_at FROM player_names WHERE player_id = $1 ORDER BY changed_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query name history: %w", err)
+	}
+	defer rows.Close()
+	changes := []*models.NameChange{}
+	for rows.Next() {
+		var c models.NameChange
+		if err := rows.Scan(&c.PlayerID, &c.Name, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan name change: %w", err)
+		}
+		changes = append(changes, &c)
+	}
+	return changes, rows.Err()
+}
+
+// GetByFormerName returns the player who most recently gave up a name with
+// the same canonical form as name at or after since, or nil if there is none.
+// Deleted players are ignored.
+func (r *PlayerRepository) GetByFormerName(ctx context.Context, name string, since time.Time) (*models.Player, error) {
+	query := `
+        SELECT ` + playerColumns + `
+        FROM players
+        WHERE id = (
+            SELECT player_id FROM player_names
+            WHERE name_key = $1 AND julianday(changed_at) >= julianday($2)
+            ORDER BY changed_at DESC
+            LIMIT 1
+        ) AND deleted_at IS NULL
+    `
+
+	player, err := scanPlayer(r.db.QueryRowContext(ctx, query, models.CanonicalName(name), since.UTC()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("query player by former name failed: %w", err)
+	}
+	return player, nil
+}
+
+// MergeGuest moves everything a guest owns to a registered player and deletes
+// the guest, in a single transaction: games and rating changes are reassigned,
+// games between the two are deleted, achievements, identities and blocks are
+// moved, and both players' aggregate statistics are rebuilt from the target's
+// games as they are after the merge. summarize splits a game into its players'
+// summaries; only the target's are kept. Voided and imported games do not
+// count. It returns "guest_not_found" unless guestID is a current guest, and
+// "player_not_found" unless targetID is a current registered player.
+func (r *PlayerRepository) MergeGuest(ctx context.Context, guestID, targetID string, summarize func(game *models.Game) []*models.PlayerGameSummary) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const checkQuery = `SELECT COUNT(*) FROM players WHERE id = $1 AND registered = $2 AND deleted_at IS NULL`
+	for _, check := range []struct {
+		id         string
+		registered bool
+		err        string
+	}{{guestID, false, "guest_not_found"}, {targetID, true, "player_not_found"}} {
+		var n int
+		if err := tx.QueryRowContext(ctx, checkQuery, check.id, check.registered).Scan(&n); err != nil {
+			return fmt.Errorf("failed to read player: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%s", check.err)
+		}
+	}
+
+	// A game between the two would become a game against oneself.
+	const betweenQuery = `
+		SELECT id FROM games
+		WHERE (first_player = $1 AND second_player = $2) OR (first_player = $2 AND second_player = $1)
+	`
+	// SQLite numbers parameters in the order they first appear, so each
+	// query takes its own arguments in that order.
+	steps := []struct {
+		query string
+		args  []any
+		what  string
+	}{
+		{`DELETE FROM rating_changes WHERE game_id IN (` + betweenQuery + `)`, []any{guestID, targetID}, "delete rating changes"},
+		{`UPDATE player_achievements SET game_id = NULL WHERE game_id IN (` + betweenQuery + `)`, []any{guestID, targetID}, "unlink achievements"},
+		{`DELETE FROM chat_messages WHERE game_id IN (` + betweenQuery + `)`, []any{guestID, targetID}, "delete chat messages"},
+		{`DELETE FROM chat_settings WHERE game_id IN (` + betweenQuery + `)`, []any{guestID, targetID}, "delete chat settings"},
+		{`UPDATE challenges SET game_id = NULL WHERE game_id IN (` + betweenQuery + `)`, []any{guestID, targetID}, "unlink challenges"},
+		{`DELETE FROM games WHERE id IN (` + betweenQuery + `)`, []any{guestID, targetID}, "delete games"},
+		{`
+			UPDATE games SET
+				first_player = CASE WHEN first_player = $1 THEN $2 ELSE first_player END,
+				second_player = CASE WHEN second_player = $1 THEN $2 ELSE second_player END,
+				current_player = CASE WHEN current_player = $1 THEN $2 ELSE current_player END,
+				result = CASE WHEN result = 'lose:' || $1 THEN 'lose:' || $2 ELSE result END,
+				imported_by = CASE WHEN imported_by = $1 THEN $2 ELSE imported_by END
+			WHERE first_player = $1 OR second_player = $1
+		`, []any{guestID, targetID}, "reassign games"},
+		{`UPDATE rating_changes SET player_id = $1 WHERE player_id = $2`, []any{targetID, guestID}, "reassign rating changes"},
+		// The target keeps their own unlock of an achievement both have.
+		{`UPDATE OR IGNORE player_achievements SET player_id = $1 WHERE player_id = $2`, []any{targetID, guestID}, "move achievements"},
+		{`DELETE FROM player_achievements WHERE player_id = $1`, []any{guestID}, "delete achievements"},
+		{`UPDATE identities SET player_id = $1 WHERE player_id = $2`, []any{targetID, guestID}, "move identities"},
+		{`UPDATE chat_messages SET player_id = $1 WHERE player_id = $2`, []any{targetID, guestID}, "move chat messages"},
+		{`UPDATE chat_settings SET player_id = $1 WHERE player_id = $2`, []any{targetID, guestID}, "move chat settings"},
+		{`DELETE FROM challenges WHERE (challenger_id = $1 AND challenged_id = $2) OR (challenger_id = $2 AND challenged_id = $1)`, []any{guestID, targetID}, "delete challenges"},
+		{`UPDATE challenges SET challenger_id = $1 WHERE challenger_id = $2`, []any{targetID, guestID}, "move challenges"},
+		{`UPDATE challenges SET challenged_id = $1 WHERE challenged_id = $2`, []any{targetID, guestID}, "move challenges"},
+		{`UPDATE OR IGNORE blocks SET blocker_id = $1 WHERE blocker_id = $2`, []any{targetID, guestID}, "move blocks"},
+		{`UPDATE OR IGNORE blocks SET blocked_id = $1 WHERE blocked_id = $2`, []any{targetID, guestID}, "move blocks"},
+		{`DELETE FROM blocks WHERE blocker_id = $1 OR blocked_id = $1 OR blocker_id = blocked_id`, []any{guestID}, "delete blocks"},
+		{`DELETE FROM player_names WHERE player_id = $1`, []any{guestID}, "delete name history"},
+		{`DELETE FROM player_stats WHERE player_id IN ($1, $2)`, []any{guestID, targetID}, "delete player stats"},
+		{`DELETE FROM player_guess_distribution WHERE player_id IN ($1, $2)`, []any{guestID, targetID}, "delete guess distribution"},
+		{`DELETE FROM player_openings WHERE player_id IN ($1, $2)`, []any{guestID, targetID}, "delete openings"},
+		{`DELETE FROM players WHERE id = $1`, []any{guestID}, "delete guest"},
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step.query, step.args...); err != nil {
+			return fmt.Errorf("failed to %s: %w", step.what, err)
+		}
+	}
+
+	const gamesQuery = `
+		SELECT ` + gameColumns + `
+		FROM games
+		WHERE (first_player = $1 OR second_player = $1)
+			AND result IS NOT NULL AND result != '' AND result != 'void' AND imported = 0
+		ORDER BY updated_at ASC, id ASC
+	`
+	rows, err := tx.QueryContext(ctx, gamesQuery, targetID)
+	if err != nil {
+		return fmt.Errorf("query merged games failed: %w", err)
+	}
+	games, err := scanGames(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	var stats []*models.PlayerGameSummary
+	for _, game := range games {
+		for _, summary := range summarize(game) {
+			if summary.PlayerID == targetID {
+				stats = append(stats, summary)
+			}
+		}
+	}
+	if err := recordSummaries(ctx, tx, stats); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil
+}
+
 // SearchByName returns players whose names contain the substring (case-insensitive)
 func (r *PlayerRepository) SearchByName(ctx context.Context, name string) ([]*models.Player, error) {
 	pattern := "%" + name + "%"
-	rows, err := r.db.QueryContext(ctx, `SELECT id, name, registered, elo, created_at FROM players WHERE lower(name) LIKE lower(?)`, pattern)
+	rows, err := r.db.QueryContext(ctx, `SELECT `+playerColumns+` FROM players WHERE lower(name) LIKE lower(?) AND deleted_at IS NULL`, pattern)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var players []*models.Player
 	for rows.Next() {
-		var p models.Player
-		var elo *int
-		var createdAt time.Time
-		if err := rows.Scan(&p.ID, &p.Name, &p.Registered, &elo, &createdAt); err != nil {
+		p, err := scanPlayer(rows)
+		if err != nil {
 			return nil, err
 		}
-		p.Elo = elo
-		p.CreatedAt = createdAt
-		players = append(players, &p)
+		players = append(players, p)
 	}
 	return players, nil
 }
@@ -199,4 +745,15 @@ type PlayerRepositoryI interface {
 	GetByName(ctx context.Context, name string) (*models.Player, error)
 	UpdateGuestToRegistered(ctx context.Context, id string, newName string, passwordHash string) error
 	SearchByName(ctx context.Context, name string) ([]*models.Player, error)
+	GetByEmail(ctx context.Context, email string) (*models.Player, error)
+	UpdatePassword(ctx context.Context, id string, passwordHash string) error
+	UpdateEmail(ctx context.Context, id string, email *string) error
+	Anonymise(ctx context.Context, id string, deletedAt time.Time) error
+	RegisterGuest(ctx context.Context, id string, newName string) error
+	SetRole(ctx context.Context, id string, role string) error
+	SetBan(ctx context.Context, id string, bannedAt *time.Time, until *time.Time, reason string) error
+	Rename(ctx context.Context, id string, newName string, at time.Time) error
+	GetNameHistory(ctx context.Context, id string) ([]*models.NameChange, error)
+	GetByFormerName(ctx context.Context, name string, since time.Time) (*models.Player, error)
+	MergeGuest(ctx context.Context, guestID, targetID string, summarize func(game *models.Game) []*models.PlayerGameSummary) error
 }
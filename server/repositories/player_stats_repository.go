@@ -0,0 +1,247 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// PlayerStatsRepository stores per-player aggregate statistics. They are
+// updated by GameRepository.FinishGame, in the transaction that finishes a game.
+type PlayerStatsRepository struct {
+	db *sql.DB
+}
+
+func NewPlayerStatsRepository(dbPath string) (*PlayerStatsRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &PlayerStatsRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *PlayerStatsRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS player_stats (
+			player_id TEXT NOT NULL,
+			mode TEXT NOT NULL,
+			games INTEGER NOT NULL DEFAULT 0,
+			wins INTEGER NOT NULL DEFAULT 0,
+			losses INTEGER NOT NULL DEFAULT 0,
+			draws INTEGER NOT NULL DEFAULT 0,
+			current_streak INTEGER NOT NULL DEFAULT 0,
+			best_streak INTEGER NOT NULL DEFAULT 0,
+			turns INTEGER NOT NULL DEFAULT 0,
+			turn_time_ms INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (player_id, mode),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+		CREATE TABLE IF NOT EXISTS player_guess_distribution (
+			player_id TEXT NOT NULL,
+			guesses INTEGER NOT NULL,
+			games INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (player_id, guesses),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+		CREATE TABLE IF NOT EXISTS player_openings (
+			player_id TEXT NOT NULL,
+			word TEXT NOT NULL,
+			uses INTEGER NOT NULL DEFAULT 0,
+			wins INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (player_id, word),
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_player_openings_uses ON player_openings (player_id, uses DESC);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// recordSummaries applies summaries to the aggregates within tx, in order.
+// Each summary updates both its mode and StatsModeAll.
+func recordSummaries(ctx context.Context, tx *sql.Tx, summaries []*models.PlayerGameSummary) error {
+	const statsQuery = `
+		INSERT INTO player_stats (player_id, mode, games, wins, losses, draws, current_streak, best_streak, turns, turn_time_ms)
+		VALUES ($1, $2, 1, $3, $4, $5, $3, $3, $6, $7)
+		ON CONFLICT (player_id, mode) DO UPDATE SET
+			games = games + 1,
+			wins = wins + excluded.wins,
+			losses = losses + excluded.losses,
+			draws = draws + excluded.draws,
+			current_streak = CASE WHEN excluded.wins > 0 THEN current_streak + 1 ELSE 0 END,
+			best_streak = MAX(best_streak, CASE WHEN excluded.wins > 0 THEN current_streak + 1 ELSE 0 END),
+			turns = turns + excluded.turns,
+			turn_time_ms = turn_time_ms + excluded.turn_time_ms
+	`
+	const distributionQuery = `
+		INSERT INTO player_guess_distribution (player_id, guesses, games)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (player_id, guesses) DO UPDATE SET games = games + 1
+	`
+	const openingQuery = `
+		INSERT INTO player_openings (player_id, word, uses, wins)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (player_id, word) DO UPDATE SET
+			uses = uses + 1,
+			wins = wins + excluded.wins
+	`
+	for _, summary := range summaries {
+		var win, loss, draw int
+		switch summary.Outcome {
+		case models.OutcomeWin:
+			win = 1
+		case models.OutcomeLoss:
+			loss = 1
+		default:
+			draw = 1
+		}
+		for _, mode := range []string{summary.Mode, models.StatsModeAll} {
+			if _, err := tx.ExecContext(ctx, statsQuery, summary.PlayerID, mode, win, loss, draw, summary.Turns, summary.TurnTime.Milliseconds()); err != nil {
+				return fmt.Errorf("failed to update player stats: %w", err)
+			}
+		}
+		if summary.SolvedIn > 0 {
+			if _, err := tx.ExecContext(ctx, distributionQuery, summary.PlayerID, summary.SolvedIn); err != nil {
+				return fmt.Errorf("failed to update guess distribution: %w", err)
+			}
+		}
+		if summary.Opening != "" {
+			if _, err := tx.ExecContext(ctx, openingQuery, summary.PlayerID, summary.Opening, win); err != nil {
+				return fmt.Errorf("failed to update openings: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ReplacePlayer discards the player's aggregates and rebuilds them from the
+// given summaries of their games, in a single transaction. Summaries of other
+// players are skipped.
+func (r *PlayerStatsRepository) ReplacePlayer(ctx context.Context, playerID string, summaries []*models.PlayerGameSummary) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"player_stats", "player_guess_distribution", "player_openings"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE player_id = $1`, playerID); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	own := make([]*models.PlayerGameSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		if summary.PlayerID == playerID {
+			own = append(own, summary)
+		}
+	}
+	if err := recordSummaries(ctx, tx, own); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit player stats: %w", err)
+	}
+	return nil
+}
+
+// GetStats returns the player's aggregates for every mode they have played,
+// including StatsModeAll.
+func (r *PlayerStatsRepository) GetStats(ctx context.Context, playerID string) ([]*models.PlayerStats, error) {
+	const query = `
+		SELECT player_id, mode, games, wins, losses, draws, current_streak, best_streak, turns, turn_time_ms
+		FROM player_stats
+		WHERE player_id = $1
+		ORDER BY mode
+	`
+	rows, err := r.db.QueryContext(ctx, query, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("query player stats failed: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*models.PlayerStats
+	for rows.Next() {
+		var s models.PlayerStats
+		var turnTimeMS int64
+		if err := rows.Scan(&s.PlayerID, &s.Mode, &s.Games, &s.Wins, &s.Losses, &s.Draws, &s.CurrentStreak, &s.BestStreak, &s.Turns, &turnTimeMS); err != nil {
+			return nil, fmt.Errorf("scan player stats failed: %w", err)
+		}
+		s.TurnTime = time.Duration(turnTimeMS) * time.Millisecond
+		stats = append(stats, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return stats, nil
+}
+
+// GetGuessDistribution returns how many solved games took each number of guesses.
+func (r *PlayerStatsRepository) GetGuessDistribution(ctx context.Context, playerID string) (map[int]int, error) {
+	const query = `SELECT guesses, games FROM player_guess_distribution WHERE player_id = $1`
+	rows, err := r.db.QueryContext(ctx, query, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("query guess distribution failed: %w", err)
+	}
+	defer rows.Close()
+
+	distribution := make(map[int]int)
+	for rows.Next() {
+		var guesses, games int
+		if err := rows.Scan(&guesses, &games); err != nil {
+			return nil, fmt.Errorf("scan guess distribution failed: %w", err)
+		}
+		distribution[guesses] = games
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return distribution, nil
+}
+
+// GetTopOpenings returns the player's most used opening words.
+func (r *PlayerStatsRepository) GetTopOpenings(ctx context.Context, playerID string, limit int) ([]*models.OpeningStats, error) {
+	const query = `
+		SELECT word, uses, wins
+		FROM player_openings
+		WHERE player_id = $1
+		ORDER BY uses DESC, word ASC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, playerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query openings failed: %w", err)
+	}
+	defer rows.Close()
+
+	var openings []*models.OpeningStats
+	for rows.Next() {
+		var o models.OpeningStats
+		if err := rows.Scan(&o.Word, &o.Uses, &o.Wins); err != nil {
+			return nil, fmt.Errorf("scan opening failed: %w", err)
+		}
+		openings = append(openings, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return openings, nil
+}
+
+type PlayerStatsRepositoryI interface {
+	ReplacePlayer(ctx context.Context, playerID string, summaries []*models.PlayerGameSummary) error
+	GetStats(ctx context.Context, playerID string) ([]*models.PlayerStats, error)
+	GetGuessDistribution(ctx context.Context, playerID string) (map[int]int, error)
+	GetTopOpenings(ctx context.Context, playerID string, limit int) ([]*models.OpeningStats, error)
+}
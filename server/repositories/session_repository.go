@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SessionRepository stores hashed refresh tokens, grouped into sessions.
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(dbPath string) (*SessionRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &SessionRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *SessionRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			player_id TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			rotated_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			user_agent TEXT NOT NULL DEFAULT '',
+			ip TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY (player_id) REFERENCES players(id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_sessions_session_id ON sessions(session_id);
+		CREATE INDEX IF NOT EXISTS idx_sessions_player_id ON sessions(player_id);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+const refreshTokenColumns = `id, session_id, player_id, token_hash, created_at, expires_at, rotated_at, revoked_at, user_agent, ip`
+
+func scanRefreshToken(row interface{ Scan(...any) error }) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	var rotatedAt, revokedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.SessionID, &t.PlayerID, &t.TokenHash, &t.CreatedAt, &t.ExpiresAt, &rotatedAt, &revokedAt, &t.UserAgent, &t.IP); err != nil {
+		return nil, err
+	}
+	if rotatedAt.Valid {
+		t.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	return &t, nil
+}
+
+// Create stores the first refresh token of a new session.
+func (r *SessionRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertRefreshToken(ctx context.Context, db execer, token *models.RefreshToken) error {
+	const query = `
+		INSERT INTO sessions (` + refreshTokenColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, NULL, NULL, $7, $8)
+	`
+	_, err := db.ExecContext(ctx, query, token.ID, token.SessionID, token.PlayerID, token.TokenHash,
+		token.CreatedAt.UTC(), token.ExpiresAt.UTC(), token.UserAgent, token.IP)
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetByTokenHash returns the refresh token with the given hash, rotated and
+// revoked ones included, or nil if there is none.
+func (r *SessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	const query = `SELECT ` + refreshTokenColumns + ` FROM sessions WHERE token_hash = $1`
+	token, err := scanRefreshToken(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// Rotate marks the token previousID as rotated and stores next in its place.
+// It reports false, storing nothing, if previousID was already rotated or
+// revoked, e.g. by a concurrent refresh.
+func (r *SessionRepository) Rotate(ctx context.Context, previousID string, next *models.RefreshToken) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const query = `
+		UPDATE sessions SET rotated_at = $1
+		WHERE id = $2 AND rotated_at IS NULL AND revoked_at IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, next.CreatedAt.UTC(), previousID)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// RevokeSession revokes every token of a session. It reports whether the
+// session was still active.
+func (r *SessionRepository) RevokeSession(ctx context.Context, sessionID string, now time.Time) (bool, error) {
+	active, err := r.IsActive(ctx, sessionID, now)
+	if err != nil {
+		return false, err
+	}
+	const query = `UPDATE sessions SET revoked_at = $1 WHERE session_id = $2 AND revoked_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, now.UTC(), sessionID); err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return active, nil
+}
+
+// IsActive reports whether a session has an unexpired token that has been
+// neither rotated nor revoked.
+func (r *SessionRepository) IsActive(ctx context.Context, sessionID string, now time.Time) (bool, error) {
+	const query = `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE session_id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+				AND julianday(expires_at) > julianday($2)
+		)
+	`
+	var active bool
+	if err := r.db.QueryRowContext(ctx, query, sessionID, now.UTC()).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return active, nil
+}
+
+// ListActive returns the player's active sessions, most recently used first.
+func (r *SessionRepository) ListActive(ctx context.Context, playerID string, now time.Time) ([]*models.Session, error) {
+	// A session was created with its first token and last used when its
+	// current token was issued.
+	const query = `
+		SELECT s.session_id, s.player_id, f.created_at, s.created_at, s.expires_at, s.user_agent, s.ip
+		FROM sessions s
+		JOIN sessions f ON f.id = (
+			SELECT id FROM sessions WHERE session_id = s.session_id
+			ORDER BY julianday(created_at) ASC LIMIT 1
+		)
+		WHERE s.player_id = $1 AND s.rotated_at IS NULL AND s.revoked_at IS NULL
+			AND julianday(s.expires_at) > julianday($2)
+		ORDER BY julianday(s.created_at) DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, playerID, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query sessions failed: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.PlayerID, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.UserAgent, &s.IP); err != nil {
+			return nil, fmt.Errorf("scan session failed: %w", err)
+		}
+		sessions = append(sessions, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteExpired removes tokens of sessions that have expired. Rotated tokens
+// are kept until then so that their reuse is still detected.
+func (r *SessionRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	const query = `
+		DELETE FROM sessions WHERE session_id IN (
+			SELECT session_id FROM sessions
+			GROUP BY session_id
+			HAVING MAX(julianday(expires_at)) <= julianday($1)
+		)
+	`
+	if _, err := r.db.ExecContext(ctx, query, now.UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
+
+type SessionRepositoryI interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	Rotate(ctx context.Context, previousID string, next *models.RefreshToken) (bool, error)
+	RevokeSession(ctx context.Context, sessionID string, now time.Time) (bool, error)
+	IsActive(ctx context.Context, sessionID string, now time.Time) (bool, error)
+	ListActive(ctx context.Context, playerID string, now time.Time) ([]*models.Session, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
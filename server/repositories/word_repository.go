@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// WordRepository stores the words games pick their solution from.
+type WordRepository struct {
+	db *sql.DB
+}
+
+func NewWordRepository(dbPath string) (*WordRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &WordRepository{db: db}
+
+	if err := repo.initializeTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *WordRepository) initializeTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS words (
+			word TEXT PRIMARY KEY,
+			added_at TIMESTAMP NOT NULL
+		);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// List returns every word in alphabetical order.
+func (r *WordRepository) List(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT word FROM words ORDER BY word`)
+	if err != nil {
+		return nil, fmt.Errorf("query words failed: %w", err)
+	}
+	defer rows.Close()
+
+	words := []string{}
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, fmt.Errorf("scan word failed: %w", err)
+		}
+		words = append(words, word)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return words, nil
+}
+
+// Add adds the words that are not in the list yet, in a single transaction,
+// and returns those it added.
+func (r *WordRepository) Add(ctx context.Context, words []string, at time.Time) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	added := []string{}
+	for _, word := range words {
+		res, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO words (word, added_at) VALUES ($1, $2)`, word, at.UTC())
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert word: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		} else if n > 0 {
+			added = append(added, word)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit words: %w", err)
+	}
+	return added, nil
+}
+
+// Remove removes the words in a single transaction and returns those that
+// were in the list.
+func (r *WordRepository) Remove(ctx context.Context, words []string) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	removed := []string{}
+	for _, word := range words {
+		res, err := tx.ExecContext(ctx, `DELETE FROM words WHERE word = $1`, word)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete word: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		} else if n > 0 {
+			removed = append(removed, word)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit words: %w", err)
+	}
+	return removed, nil
+}
+
+type WordRepositoryI interface {
+	List(ctx context.Context) ([]string, error)
+	Add(ctx context.Context, words []string, at time.Time) ([]string, error)
+	Remove(ctx context.Context, words []string) ([]string, error)
+}
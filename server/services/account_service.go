@@ -0,0 +1,284 @@
+package services
+
+import (
+	"archive/zip"
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountService exports and deletes a player's own account.
+type AccountService struct {
+	players       repositories.PlayerRepositoryI
+	gameRepo      repositories.GameRepositoryI
+	achievements  repositories.AchievementRepositoryI
+	stats         repositories.PlayerStatsRepositoryI
+	chat          repositories.ChatRepositoryI
+	games         *GameService
+	records       *GameRecordService
+	playerService *PlayerService
+	now           func() time.Time
+	// mergeListeners are called after a guest is merged into a player.
+	mergeListeners []GuestMergedListener
+}
+
+// GuestMergedListener is notified after a guest has been merged into a
+// registered player, e.g. to hand over the guest's pending challenges.
+type GuestMergedListener func(guestID, playerID string)
+
+// NewAccountService creates a new AccountService.
+func NewAccountService(players repositories.PlayerRepositoryI, gameRepo repositories.GameRepositoryI, achievements repositories.AchievementRepositoryI, stats repositories.PlayerStatsRepositoryI, chat repositories.ChatRepositoryI, games *GameService, records *GameRecordService, playerService *PlayerService) *AccountService {
+	return &AccountService{
+		players:       players,
+		gameRepo:      gameRepo,
+		achievements:  achievements,
+		stats:         stats,
+		chat:          chat,
+		games:         games,
+		records:       records,
+		playerService: playerService,
+		now:           time.Now,
+	}
+}
+
+// AccountExport is everything stored about a player.
+type AccountExport struct {
+	ExportedAt    time.Time                   `json:"exported_at"`
+	Profile       AccountProfile              `json:"profile"`
+	Games         []ExportedGame              `json:"games"`
+	RatingChanges []*models.RatingChange      `json:"rating_changes"`
+	Achievements  []*models.PlayerAchievement `json:"achievements"`
+	Stats         []*models.PlayerStats       `json:"stats"`
+	Sessions      []*models.Session           `json:"sessions"`
+	ChatMessages  []*models.ChatMessage       `json:"chat_messages"`
+}
+
+// AccountProfile is the player's own view of their profile.
+type AccountProfile struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Registered       bool       `json:"registered"`
+	Email            *string    `json:"email,omitempty"`
+	Elo              *int       `json:"elo,omitempty"`
+	RatedGames       int        `json:"rated_games"`
+	RatingDeviation  *float64   `json:"rating_deviation,omitempty"`
+	RatingVolatility *float64   `json:"rating_volatility,omitempty"`
+	RatedAt          *time.Time `json:"rated_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// ExportedGame is one of the player's games. The solution of a game in
+// progress is left out.
+type ExportedGame struct {
+	ID               string      `json:"id"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+	Mode             string      `json:"mode"`
+	FirstPlayerID    string      `json:"first_player_id"`
+	FirstPlayerName  string      `json:"first_player_name"`
+	SecondPlayerID   string      `json:"second_player_id"`
+	SecondPlayerName string      `json:"second_player_name"`
+	Result           string      `json:"result"`
+	Guesses          []string    `json:"guesses"`
+	GuessTimes       []time.Time `json:"guess_times,omitempty"`
+	Solution         string      `json:"solution,omitempty"`
+	Imported         bool        `json:"imported,omitempty"`
+}
+
+// Export collects the player's data. currentSessionID marks the session the
+// export was requested from. It returns "player_not_found" for unknown or
+// deleted players.
+func (s *AccountService) Export(ctx context.Context, playerID, currentSessionID string) (*AccountExport, error) {
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.DeletedAt != nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+
+	export := &AccountExport{
+		ExportedAt: s.now().UTC(),
+		Profile: AccountProfile{
+			ID:               player.ID,
+			Name:             player.Name,
+			Registered:       player.Registered,
+			Email:            player.Email,
+			Elo:              player.Elo,
+			RatedGames:       player.RatedGames,
+			RatingDeviation:  player.RatingDeviation,
+			RatingVolatility: player.RatingVolatility,
+			RatedAt:          player.RatedAt,
+			CreatedAt:        player.CreatedAt,
+		},
+		Games: []ExportedGame{},
+	}
+	q := GameHistoryQuery{PlayerID: playerID, Order: "asc", Limit: maxGameHistoryLimit}
+	for {
+		page, err := s.games.ListPlayerGames(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Games {
+			export.Games = append(export.Games, exportedGame(item))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if export.RatingChanges, err = s.gameRepo.GetRatingChangesByPlayer(ctx, playerID); err != nil {
+		return nil, err
+	}
+	if export.Achievements, err = s.achievements.GetByPlayer(ctx, playerID); err != nil {
+		return nil, err
+	}
+	if export.Stats, err = s.stats.GetStats(ctx, playerID); err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = s.playerService.ListSessions(ctx, playerID, currentSessionID); err != nil {
+		return nil, err
+	}
+	if export.ChatMessages, err = s.chat.ListByPlayer(ctx, playerID); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func exportedGame(item *models.GameWithPlayers) ExportedGame {
+	game := item.Game
+	g := ExportedGame{
+		ID:               game.ID,
+		CreatedAt:        game.CreatedAt,
+		UpdatedAt:        game.UpdatedAt,
+		Mode:             game.Mode,
+		FirstPlayerID:    game.FirstPlayer,
+		FirstPlayerName:  item.FirstPlayerName,
+		SecondPlayerID:   game.SecondPlayer,
+		SecondPlayerName: item.SecondPlayerName,
+		Result:           game.Result,
+		Guesses:          game.Guesses,
+		GuessTimes:       game.GuessTimes,
+		Imported:         game.Imported,
+	}
+	if game.Finished() {
+		g.Solution = game.Solution
+	}
+	return g
+}
+
+// WriteZip writes the export as a ZIP archive holding account.json and the
+// player's games as game records in games.bwr.
+func (s *AccountService) WriteZip(ctx context.Context, w io.Writer, export *AccountExport) error {
+	zw := zip.NewWriter(w)
+	header := &zip.FileHeader{Name: "account.json", Method: zip.Deflate, Modified: export.ExportedAt}
+	f, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return err
+	}
+
+	header = &zip.FileHeader{Name: "games.bwr", Method: zip.Deflate, Modified: export.ExportedAt}
+	if f, err = zw.CreateHeader(header); err != nil {
+		return err
+	}
+	if err := s.records.ExportPlayerGames(ctx, GameHistoryQuery{PlayerID: export.Profile.ID, Order: "asc"}, f); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// DeleteAccount anonymises the player: their games in progress are forfeited,
+// their name is replaced, their credentials and chat messages are removed and
+// all of their sessions end. Finished games are kept so that opponents' histories stay
+// intact. Players with a password must confirm with it; it returns
+// "invalid_password" if it is wrong and "player_not_found" for unknown or
+// already deleted players.
+func (s *AccountService) DeleteAccount(ctx context.Context, playerID, password string) error {
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	if player == nil || player.DeletedAt != nil {
+		return fmt.Errorf("player_not_found")
+	}
+	// Players who log in with an external provider have no password.
+	if player.PasswordHash != nil && bcrypt.CompareHashAndPassword([]byte(*player.PasswordHash), []byte(password)) != nil {
+		return fmt.Errorf("invalid_password")
+	}
+
+	if err := s.games.ForfeitActiveGames(ctx, playerID); err != nil {
+		return err
+	}
+	if err := s.players.Anonymise(ctx, playerID, s.now()); err != nil {
+		return err
+	}
+	if err := s.chat.DeleteByPlayer(ctx, playerID); err != nil {
+		return err
+	}
+	_, err = s.playerService.RevokeOtherSessions(ctx, playerID, "")
+	return err
+}
+
+// OnGuestMerged registers a listener that is called after every merge.
+func (s *AccountService) OnGuestMerged(listener GuestMergedListener) {
+	s.mergeListeners = append(s.mergeListeners, listener)
+}
+
+// MergeGuest moves the guest that guestToken was issued to into the
+// registered player, for guests who log in to an account they already own.
+// The guest's games, achievements, challenges and linked identities become
+// the player's, the player's statistics are recomputed over both players'
+// games, and the guest is deleted and logged out. Games and challenges
+// between the two are deleted, as the player would be playing themselves.
+// Merged games keep their rating changes; games the guest played stay unrated
+// until ratings are recomputed.
+// It returns "invalid_guest_token" if the token does not belong to a current
+// guest, and "player_not_registered" unless the player is registered.
+func (s *AccountService) MergeGuest(ctx context.Context, playerID, guestToken string) (*models.Player, error) {
+	claims, err := s.playerService.verifyAccessToken(ctx, guestToken)
+	if err != nil || !claims.Guest || claims.PlayerID == playerID {
+		return nil, fmt.Errorf("invalid_guest_token")
+	}
+	guest, err := s.players.GetByID(ctx, claims.PlayerID)
+	if err != nil {
+		return nil, err
+	}
+	if guest == nil || guest.Registered || guest.DeletedAt != nil {
+		return nil, fmt.Errorf("invalid_guest_token")
+	}
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.DeletedAt != nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	if !player.Registered {
+		return nil, fmt.Errorf("player_not_registered")
+	}
+
+	if err := s.players.MergeGuest(ctx, guest.ID, player.ID, summarizeGame); err != nil {
+		if err.Error() == "guest_not_found" {
+			return nil, fmt.Errorf("invalid_guest_token")
+		}
+		return nil, err
+	}
+	if _, err := s.playerService.RevokeOtherSessions(ctx, guest.ID, ""); err != nil {
+		return nil, err
+	}
+	for _, listener := range s.mergeListeners {
+		listener(guest.ID, player.ID)
+	}
+	return s.players.GetByID(ctx, player.ID)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+func newTestAccountService(gameRepo *mockGameRepo) (*AccountService, *PlayerService, *mockPlayerRepo) {
+	players := newMockPlayerRepo()
+	playerService := NewPlayerService(players, newMockSessionRepo(), "testsecret")
+	games := NewGameService(gameRepo, []string{"APPLE"}, nil)
+	records := NewGameRecordService(games, gameRepo, players)
+	achievements := &mockAchievementRepo{}
+	stats := &mockStatsRepo{}
+	return NewAccountService(players, gameRepo, achievements, stats, &mockChatRepo{}, games, records, playerService), playerService, players
+}
+
+func TestAccountService_Export(t *testing.T) {
+	ctx := context.Background()
+	gameRepo := &mockGameRepo{}
+	service, playerService, _ := newTestAccountService(gameRepo)
+	pw := "password"
+	player, tokens, _ := playerService.CreatePlayer(ctx, "alice", &pw, nil, SessionClient{})
+	now := time.Now()
+	gameRepo.games = []*models.Game{
+		{ID: "g1", FirstPlayer: player.ID, SecondPlayer: "bob", Solution: "APPLE", Guesses: []string{"APPLE"}, Result: "lose:" + player.ID, CreatedAt: now, UpdatedAt: now},
+		{ID: "g2", FirstPlayer: "bob", SecondPlayer: player.ID, Solution: "CRANE", Guesses: []string{"SLATE"}, CreatedAt: now, UpdatedAt: now},
+	}
+
+	export, err := service.Export(ctx, player.ID, tokens.SessionID)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if export.Profile.ID != player.ID || export.Profile.Name != "alice" || !export.Profile.Registered {
+		t.Errorf("Unexpected profile: %+v", export.Profile)
+	}
+	if len(export.Games) != 2 {
+		t.Fatalf("Expected 2 games, got %d", len(export.Games))
+	}
+	for _, g := range export.Games {
+		if g.ID == "g1" && g.Solution != "APPLE" {
+			t.Errorf("Expected the finished game's solution, got %q", g.Solution)
+		}
+		if g.ID == "g2" && g.Solution != "" {
+			t.Errorf("Expected the solution of a game in progress to be hidden, got %q", g.Solution)
+		}
+	}
+	if len(export.Sessions) != 1 || !export.Sessions[0].Current {
+		t.Errorf("Expected the current session, got %+v", export.Sessions)
+	}
+
+	var buf bytes.Buffer
+	if err := service.WriteZip(ctx, &buf, export); err != nil {
+		t.Fatalf("WriteZip failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Invalid zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	var fromZip AccountExport
+	if err := json.Unmarshal(files["account.json"], &fromZip); err != nil || fromZip.Profile.ID != player.ID {
+		t.Errorf("Expected account.json with the profile, got %v", err)
+	}
+	if len(files["games.bwr"]) == 0 {
+		t.Error("Expected the games as game records")
+	}
+}
+
+func TestAccountService_DeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	gameRepo := &mockGameRepo{}
+	service, playerService, players := newTestAccountService(gameRepo)
+	pw := "password"
+	player, tokens, _ := playerService.CreatePlayer(ctx, "alice", &pw, nil, SessionClient{})
+	active := &models.Game{ID: "g1", FirstPlayer: "bob", SecondPlayer: player.ID, CurrentPlayer: player.ID, Solution: "APPLE", Guesses: []string{"SLATE"}}
+	gameRepo.games = []*models.Game{active}
+
+	if err := service.DeleteAccount(ctx, player.ID, "wrong"); err == nil || err.Error() != "invalid_password" {
+		t.Fatalf("Expected invalid_password, got %v", err)
+	}
+	if err := service.DeleteAccount(ctx, player.ID, pw); err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+
+	if active.Result != "lose:"+player.ID || gameRepo.updated != active {
+		t.Errorf("Expected the game in progress to be forfeited, got %q", active.Result)
+	}
+	deleted := players.players[player.ID]
+	if deleted.DisplayName() != models.DeletedPlayerName || deleted.Registered || deleted.PasswordHash != nil {
+		t.Errorf("Expected the player to be anonymised, got %+v", deleted)
+	}
+	if _, _, err := playerService.VerifyJWT(ctx, tokens.AccessToken); err == nil {
+		t.Error("Expected the player's sessions to end")
+	}
+	if p, _, _ := playerService.Login(ctx, "alice", pw, SessionClient{}); p != nil {
+		t.Error("Expected login to fail after deletion")
+	}
+	if err := service.DeleteAccount(ctx, player.ID, pw); err == nil || err.Error() != "player_not_found" {
+		t.Errorf("Expected player_not_found for a deleted player, got %v", err)
+	}
+}
+
+func TestAccountService_DeleteGuestWithoutPassword(t *testing.T) {
+	ctx := context.Background()
+	service, playerService, players := newTestAccountService(&mockGameRepo{})
+	guest, _, _ := playerService.CreatePlayer(ctx, "guest1", nil, nil, SessionClient{})
+	if err := service.DeleteAccount(ctx, guest.ID, ""); err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+	if players.players[guest.ID].DeletedAt == nil {
+		t.Error("Expected the guest to be deleted")
+	}
+}
+
+func TestAccountService_MergeGuest(t *testing.T) {
+	ctx := context.Background()
+	gameRepo := &mockGameRepo{}
+	service, playerService, players := newTestAccountService(gameRepo)
+	pw := "password"
+	player, _, _ := playerService.CreatePlayer(ctx, "alice", &pw, nil, SessionClient{})
+	guest, guestTokens, _ := playerService.CreatePlayer(ctx, "guest1", nil, nil, SessionClient{})
+	now := time.Now()
+	// The player's own game and the guest's game against bob, reassigned, in
+	// the order they finished.
+	players.mergeGames = []*models.Game{
+		{ID: "g2", FirstPlayer: "bob", SecondPlayer: player.ID, Solution: "APPLE", Guesses: []string{"SLATE", "APPLE"}, Result: "lose:bob", UpdatedAt: now.Add(time.Minute)},
+		{ID: "g1", FirstPlayer: player.ID, SecondPlayer: "bob", Solution: "APPLE", Guesses: []string{"CRANE", "APPLE"}, Result: "lose:" + player.ID, UpdatedAt: now.Add(2 * time.Minute)},
+	}
+	var merges []string
+	service.OnGuestMerged(func(guestID, playerID string) { merges = append(merges, guestID+">"+playerID) })
+
+	if _, err := service.MergeGuest(ctx, player.ID, "forged"); err == nil || err.Error() != "invalid_guest_token" {
+		t.Fatalf("Expected invalid_guest_token, got %v", err)
+	}
+	merged, err := service.MergeGuest(ctx, player.ID, guestTokens.AccessToken)
+	if err != nil {
+		t.Fatalf("MergeGuest failed: %v", err)
+	}
+	if merged.ID != player.ID {
+		t.Errorf("Expected the registered player, got %+v", merged)
+	}
+	if players.players[guest.ID] != nil {
+		t.Error("Expected the guest to be deleted")
+	}
+	// Stats are the player's side of every merged game.
+	stats := players.merged
+	if len(stats) != 2 || stats[0].PlayerID != player.ID || stats[0].Outcome != models.OutcomeWin || stats[1].Outcome != models.OutcomeLoss || stats[1].Opening != "CRANE" {
+		t.Errorf("Unexpected merged stats: %+v", stats)
+	}
+	if _, _, err := playerService.VerifyJWT(ctx, guestTokens.AccessToken); err == nil {
+		t.Error("Expected the guest's sessions to end")
+	}
+	if len(merges) != 1 || merges[0] != guest.ID+">"+player.ID {
+		t.Errorf("Expected the merge listener to be called once, got %v", merges)
+	}
+	if _, err := service.MergeGuest(ctx, player.ID, guestTokens.AccessToken); err == nil || err.Error() != "invalid_guest_token" {
+		t.Errorf("Expected invalid_guest_token after the merge, got %v", err)
+	}
+
+	other, otherTokens, _ := playerService.CreatePlayer(ctx, "guest2", nil, nil, SessionClient{})
+	guest3, _, _ := playerService.CreatePlayer(ctx, "guest3", nil, nil, SessionClient{})
+	if _, err := service.MergeGuest(ctx, guest3.ID, otherTokens.AccessToken); err == nil || err.Error() != "player_not_registered" {
+		t.Errorf("Expected player_not_registered when merging into a guest, got %v", err)
+	}
+	if players.players[other.ID] == nil {
+		t.Error("Expected the guest to be kept after a failed merge")
+	}
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+// achievementActivityWindow is how many active days are looked at when
+// counting days played in a row.
+const achievementActivityWindow = 366
+
+// AchievementService evaluates achievement definitions against finished games
+// and stores the ones players unlock.
+type AchievementService struct {
+	repo        repositories.AchievementRepositoryI
+	gameRepo    repositories.GameRepositoryI
+	playerRepo  repositories.PlayerRepositoryI
+	statsRepo   repositories.PlayerStatsRepositoryI
+	definitions []AchievementDefinition
+	mu          sync.RWMutex
+	listeners   []AchievementListener
+}
+
+// Achievement is an achievement definition together with a player's progress on it.
+type Achievement struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Unlocked    bool       `json:"unlocked"`
+	UnlockedAt  *time.Time `json:"unlocked_at,omitempty"`
+	// GameID is the game that unlocked the achievement.
+	GameID string `json:"game_id,omitempty"`
+}
+
+// AchievementListener is notified when a player unlocks an achievement.
+type AchievementListener func(playerID string, achievement Achievement)
+
+// NewAchievementService creates a new AchievementService awarding the given definitions.
+func NewAchievementService(repo repositories.AchievementRepositoryI, gameRepo repositories.GameRepositoryI, playerRepo repositories.PlayerRepositoryI, statsRepo repositories.PlayerStatsRepositoryI, definitions []AchievementDefinition) *AchievementService {
+	return &AchievementService{
+		repo:        repo,
+		gameRepo:    gameRepo,
+		playerRepo:  playerRepo,
+		statsRepo:   statsRepo,
+		definitions: definitions,
+	}
+}
+
+// OnUnlocked registers a listener that is called for every newly unlocked achievement.
+func (s *AchievementService) OnUnlocked(listener AchievementListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// EvaluateGame checks both players of a finished game against every
+// achievement they have not unlocked yet. It is registered as a GameService
+// listener, which runs after the game and the player totals it reads have
+// been stored; errors are logged.
+func (s *AchievementService) EvaluateGame(ctx context.Context, game *models.Game) {
+	changes, err := s.gameRepo.GetRatingChanges(ctx, game.ID)
+	if err != nil {
+		log.Printf("error loading rating changes for game %s: %v", game.ID, err)
+		return
+	}
+	ratings := ratingsBefore(changes)
+
+	for _, pair := range [][2]string{{game.FirstPlayer, game.SecondPlayer}, {game.SecondPlayer, game.FirstPlayer}} {
+		playerID, opponentID := pair[0], pair[1]
+		if err := s.evaluatePlayer(ctx, game, playerID, opponentID, ratings); err != nil {
+			log.Printf("error evaluating achievements for player %s in game %s: %v", playerID, game.ID, err)
+		}
+	}
+}
+
+// ratingsBefore maps each rated player of a game to their rating going into it.
+func ratingsBefore(changes []*models.RatingChange) map[string]*int {
+	ratings := make(map[string]*int, len(changes))
+	for _, change := range changes {
+		before := change.Before
+		ratings[change.PlayerID] = &before
+	}
+	return ratings
+}
+
+// RecomputePlayer replays the player's finished games against every
+// achievement definition and replaces their unlocked achievements with the
+// result, e.g. after the result of one of their games was changed.
+// Achievements the player did not have before are notified.
+func (s *AchievementService) RecomputePlayer(ctx context.Context, playerID string) error {
+	games, err := countedGames(ctx, s.gameRepo, playerID)
+	if err != nil {
+		return err
+	}
+	previous, err := s.repo.GetByPlayer(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	had := make(map[string]bool, len(previous))
+	for _, a := range previous {
+		had[a.AchievementID] = true
+	}
+
+	totals := &models.PlayerStats{PlayerID: playerID, Mode: models.StatsModeAll}
+	days := make(map[string]bool)
+	unlocked := make(map[string]bool)
+	var records []*models.PlayerAchievement
+	var gained []Achievement
+	for _, game := range games {
+		changes, err := s.gameRepo.GetRatingChanges(ctx, game.ID)
+		if err != nil {
+			return err
+		}
+		ratings := ratingsBefore(changes)
+		opponentID := game.SecondPlayer
+		if playerID == game.SecondPlayer {
+			opponentID = game.FirstPlayer
+		}
+
+		var summary *models.PlayerGameSummary
+		for _, sum := range summarizeGame(game) {
+			if sum.PlayerID == playerID {
+				summary = sum
+			}
+		}
+		addSummary(totals, summary)
+		stats := *totals
+		day := game.UpdatedAt.UTC()
+		days[day.Format("2006-01-02")] = true
+
+		event := &AchievementEvent{
+			Game:                 game,
+			PlayerID:             playerID,
+			OpponentID:           opponentID,
+			Outcome:              summary.Outcome,
+			Stats:                &stats,
+			RatingBefore:         ratings[playerID],
+			OpponentRatingBefore: ratings[opponentID],
+		}
+		for days[day.Format("2006-01-02")] {
+			event.DaysInARow++
+			day = day.AddDate(0, 0, -1)
+		}
+		for _, d := range s.definitions {
+			if unlocked[d.ID] || !d.Condition(event) {
+				continue
+			}
+			unlocked[d.ID] = true
+			record := &models.PlayerAchievement{PlayerID: playerID, AchievementID: d.ID, GameID: game.ID, UnlockedAt: game.UpdatedAt}
+			records = append(records, record)
+			if !had[d.ID] {
+				gained = append(gained, achievementFor(d, record))
+			}
+		}
+	}
+
+	if err := s.repo.ReplacePlayer(ctx, playerID, records); err != nil {
+		return err
+	}
+	for _, a := range gained {
+		s.notify(playerID, a)
+	}
+	return nil
+}
+
+// addSummary adds one game to a player's totals the way the stats repository
+// does, so that a replay sees the totals as they were after each game.
+func addSummary(stats *models.PlayerStats, summary *models.PlayerGameSummary) {
+	stats.Games++
+	switch summary.Outcome {
+	case models.OutcomeWin:
+		stats.Wins++
+		stats.CurrentStreak++
+		stats.BestStreak = max(stats.BestStreak, stats.CurrentStreak)
+	case models.OutcomeLoss:
+		stats.Losses++
+		stats.CurrentStreak = 0
+	default:
+		stats.Draws++
+		stats.CurrentStreak = 0
+	}
+	stats.Turns += summary.Turns
+	stats.TurnTime += summary.TurnTime
+}
+
+func (s *AchievementService) evaluatePlayer(ctx context.Context, game *models.Game, playerID, opponentID string, ratings map[string]*int) error {
+	unlocked, err := s.repo.GetByPlayer(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(unlocked))
+	for _, a := range unlocked {
+		have[a.AchievementID] = true
+	}
+	var pending []AchievementDefinition
+	for _, d := range s.definitions {
+		if !have[d.ID] {
+			pending = append(pending, d)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	event, err := s.buildEvent(ctx, game, playerID, opponentID, ratings)
+	if err != nil {
+		return err
+	}
+	for _, d := range pending {
+		if !d.Condition(event) {
+			continue
+		}
+		record := &models.PlayerAchievement{PlayerID: playerID, AchievementID: d.ID, GameID: game.ID, UnlockedAt: game.UpdatedAt}
+		isNew, err := s.repo.Unlock(ctx, record)
+		if err != nil {
+			return err
+		}
+		if isNew {
+			s.notify(playerID, achievementFor(d, record))
+		}
+	}
+	return nil
+}
+
+func (s *AchievementService) buildEvent(ctx context.Context, game *models.Game, playerID, opponentID string, ratings map[string]*int) (*AchievementEvent, error) {
+	event := &AchievementEvent{
+		Game:                 game,
+		PlayerID:             playerID,
+		OpponentID:           opponentID,
+		RatingBefore:         ratings[playerID],
+		OpponentRatingBefore: ratings[opponentID],
+	}
+	switch {
+	case game.IsDraw():
+		event.Outcome = models.OutcomeDraw
+	case game.Loser() == playerID:
+		event.Outcome = models.OutcomeLoss
+	default:
+		event.Outcome = models.OutcomeWin
+	}
+
+	stats, err := s.statsRepo.GetStats(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range stats {
+		if st.Mode == models.StatsModeAll {
+			event.Stats = st
+		}
+	}
+
+	days, err := s.repo.ActiveDays(ctx, playerID, achievementActivityWindow)
+	if err != nil {
+		return nil, err
+	}
+	expected := game.UpdatedAt.UTC()
+	for _, day := range days {
+		if day != expected.Format("2006-01-02") {
+			break
+		}
+		event.DaysInARow++
+		expected = expected.AddDate(0, 0, -1)
+	}
+	return event, nil
+}
+
+func (s *AchievementService) notify(playerID string, achievement Achievement) {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(playerID, achievement)
+	}
+}
+
+// GetPlayerAchievements returns every achievement with the player's progress on it.
+func (s *AchievementService) GetPlayerAchievements(ctx context.Context, playerID string) ([]Achievement, error) {
+	player, err := s.playerRepo.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	unlocked, err := s.repo.GetByPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.PlayerAchievement, len(unlocked))
+	for _, a := range unlocked {
+		byID[a.AchievementID] = a
+	}
+	achievements := make([]Achievement, 0, len(s.definitions))
+	for _, d := range s.definitions {
+		achievements = append(achievements, achievementFor(d, byID[d.ID]))
+	}
+	return achievements, nil
+}
+
+// achievementFor combines a definition with the player's unlock record, which may be nil.
+func achievementFor(d AchievementDefinition, record *models.PlayerAchievement) Achievement {
+	a := Achievement{ID: d.ID, Name: d.Name, Description: d.Description}
+	if record != nil {
+		unlockedAt := record.UnlockedAt
+		a.Unlocked = true
+		a.UnlockedAt = &unlockedAt
+		a.GameID = record.GameID
+	}
+	return a
+}
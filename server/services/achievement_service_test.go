@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+// mockAchievementRepo keeps unlocks in memory and serves fixed active days.
+type mockAchievementRepo struct {
+	unlocked []*models.PlayerAchievement
+	days     []string
+}
+
+func (m *mockAchievementRepo) Unlock(ctx context.Context, achievement *models.PlayerAchievement) (bool, error) {
+	for _, a := range m.unlocked {
+		if a.PlayerID == achievement.PlayerID && a.AchievementID == achievement.AchievementID {
+			return false, nil
+		}
+	}
+	m.unlocked = append(m.unlocked, achievement)
+	return true, nil
+}
+func (m *mockAchievementRepo) GetByPlayer(ctx context.Context, playerID string) ([]*models.PlayerAchievement, error) {
+	var result []*models.PlayerAchievement
+	for _, a := range m.unlocked {
+		if a.PlayerID == playerID {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+func (m *mockAchievementRepo) ActiveDays(ctx context.Context, playerID string, limit int) ([]string, error) {
+	return m.days, nil
+}
+func (m *mockAchievementRepo) ReplacePlayer(ctx context.Context, playerID string, achievements []*models.PlayerAchievement) error {
+	kept := achievements
+	for _, a := range m.unlocked {
+		if a.PlayerID != playerID {
+			kept = append(kept, a)
+		}
+	}
+	m.unlocked = kept
+	return nil
+}
+
+func TestEvaluateGame_UnlocksAndNotifies(t *testing.T) {
+	end := time.Date(2025, time.August, 14, 12, 0, 0, 0, time.UTC)
+	game := &models.Game{
+		ID: "g1", FirstPlayer: "p1", SecondPlayer: "p2", Result: "lose:p1", UpdatedAt: end,
+		Guesses: []string{"CRANE", "SLATE", "TOAST", "BLIMP", "WORDS", "APPLE"},
+	}
+	gameRepo := &mockGameRepo{changes: []*models.RatingChange{
+		{GameID: "g1", PlayerID: "p1", Before: 1300, After: 1280},
+		{GameID: "g1", PlayerID: "p2", Before: 1200, After: 1220},
+	}}
+	statsRepo := &mockStatsRepo{stats: []*models.PlayerStats{{Mode: models.StatsModeAll, Games: 1, Wins: 1, CurrentStreak: 1}}}
+	repo := &mockAchievementRepo{days: []string{"2025-08-14", "2025-08-13", "2025-08-11"}}
+	definitions := []AchievementDefinition{
+		{ID: "first_win", Condition: Won()},
+		{ID: "win_on_sixth", Condition: AllOf(Won(), GameLength(6))},
+		{ID: "giant_slayer", Condition: BeatHigherRated()},
+		{ID: "days_2", Condition: DaysInARow(2)},
+		{ID: "days_3", Condition: DaysInARow(3)},
+	}
+	service := NewAchievementService(repo, gameRepo, newMockPlayerRepo(), statsRepo, definitions)
+	var notified []string
+	service.OnUnlocked(func(playerID string, a Achievement) {
+		notified = append(notified, playerID+":"+a.ID)
+	})
+
+	service.EvaluateGame(context.Background(), game)
+
+	want := []string{"p2:first_win", "p2:win_on_sixth", "p2:giant_slayer", "p1:days_2", "p2:days_2"}
+	if len(notified) != len(want) {
+		t.Fatalf("Expected unlocks %v, got %v", want, notified)
+	}
+	got := make(map[string]bool)
+	for _, n := range notified {
+		got[n] = true
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("Expected %s to be unlocked, got %v", w, notified)
+		}
+	}
+
+	// Evaluating again must not unlock or notify anything twice.
+	notified = nil
+	service.EvaluateGame(context.Background(), game)
+	if len(notified) != 0 {
+		t.Errorf("Expected no repeat unlocks, got %v", notified)
+	}
+}
+
+func TestGetPlayerAchievements(t *testing.T) {
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: "p1", Name: "alice"})
+	unlockedAt := time.Now()
+	repo := &mockAchievementRepo{unlocked: []*models.PlayerAchievement{{PlayerID: "p1", AchievementID: "first_win", GameID: "g1", UnlockedAt: unlockedAt}}}
+	service := NewAchievementService(repo, &mockGameRepo{}, playerRepo, &mockStatsRepo{}, DefaultAchievements)
+
+	achievements, err := service.GetPlayerAchievements(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPlayerAchievements failed: %v", err)
+	}
+	if len(achievements) != len(DefaultAchievements) {
+		t.Fatalf("Expected every achievement to be listed, got %d", len(achievements))
+	}
+	for _, a := range achievements {
+		if unlocked := a.ID == "first_win"; a.Unlocked != unlocked {
+			t.Errorf("Unexpected unlock state for %s: %+v", a.ID, a)
+		}
+	}
+
+	if _, err := service.GetPlayerAchievements(context.Background(), "nobody"); err == nil || err.Error() != "player_not_found" {
+		t.Errorf("Expected player_not_found, got %v", err)
+	}
+}
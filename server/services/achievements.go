@@ -0,0 +1,104 @@
+package services
+
+import "battle-wordle/server/models"
+
+// AchievementDefinition declares an achievement and the condition that unlocks it.
+type AchievementDefinition struct {
+	ID          string
+	Name        string
+	Description string
+	// Condition is evaluated for each player after each of their finished games.
+	Condition AchievementCondition
+}
+
+// AchievementEvent is one player's view of a game that just finished, along
+// with the history achievement conditions look at.
+type AchievementEvent struct {
+	Game       *models.Game
+	PlayerID   string
+	OpponentID string
+	// Outcome is models.OutcomeWin, models.OutcomeLoss or models.OutcomeDraw.
+	Outcome string
+	// Stats are the player's totals across all modes, including this game.
+	Stats *models.PlayerStats
+	// RatingBefore and OpponentRatingBefore are the ratings going into the
+	// game, or nil if it was unrated.
+	RatingBefore         *int
+	OpponentRatingBefore *int
+	// DaysInARow is the number of consecutive UTC days, ending with the day of
+	// this game, on which the player finished a game.
+	DaysInARow int
+}
+
+// AchievementCondition reports whether an event unlocks an achievement.
+type AchievementCondition func(e *AchievementEvent) bool
+
+// DefaultAchievements are the achievements the server awards.
+var DefaultAchievements = []AchievementDefinition{
+	{ID: "first_win", Name: "First Victory", Description: "Win a game.", Condition: Won()},
+	{ID: "stalemate", Name: "Stalemate", Description: "Draw a game.", Condition: Drew()},
+	{ID: "solve_in_two", Name: "Mind Reader", Description: "Guess the solution within the first two guesses of a game.", Condition: SolvedWithin(2)},
+	{ID: "win_on_sixth", Name: "Down to the Wire", Description: "Win a game on its sixth and final guess.", Condition: AllOf(Won(), GameLength(6))},
+	{ID: "win_streak_10", Name: "Unstoppable", Description: "Win 10 games in a row.", Condition: WinStreak(10)},
+	{ID: "giant_slayer", Name: "Giant Slayer", Description: "Beat a higher-rated player.", Condition: BeatHigherRated()},
+	{ID: "games_100", Name: "Centurion", Description: "Finish 100 games.", Condition: GamesPlayed(100)},
+	{ID: "days_30", Name: "Creature of Habit", Description: "Play on 30 days in a row.", Condition: DaysInARow(30)},
+}
+
+// Won is met when the player won the game.
+func Won() AchievementCondition {
+	return func(e *AchievementEvent) bool { return e.Outcome == models.OutcomeWin }
+}
+
+// Drew is met when the game was drawn.
+func Drew() AchievementCondition {
+	return func(e *AchievementEvent) bool { return e.Outcome == models.OutcomeDraw }
+}
+
+// SolvedWithin is met when the player guessed the solution and the game took at most n guesses.
+func SolvedWithin(n int) AchievementCondition {
+	return func(e *AchievementEvent) bool {
+		return e.Game.Loser() == e.PlayerID && len(e.Game.Guesses) <= n
+	}
+}
+
+// GameLength is met when the game took exactly n guesses.
+func GameLength(n int) AchievementCondition {
+	return func(e *AchievementEvent) bool { return len(e.Game.Guesses) == n }
+}
+
+// WinStreak is met when the player has won at least n games in a row.
+func WinStreak(n int) AchievementCondition {
+	return func(e *AchievementEvent) bool { return e.Stats != nil && e.Stats.CurrentStreak >= n }
+}
+
+// GamesPlayed is met when the player has finished at least n games.
+func GamesPlayed(n int) AchievementCondition {
+	return func(e *AchievementEvent) bool { return e.Stats != nil && e.Stats.Games >= n }
+}
+
+// BeatHigherRated is met when the player won a rated game against an opponent
+// who was rated higher going into it.
+func BeatHigherRated() AchievementCondition {
+	return func(e *AchievementEvent) bool {
+		return e.Outcome == models.OutcomeWin && e.RatingBefore != nil && e.OpponentRatingBefore != nil &&
+			*e.OpponentRatingBefore > *e.RatingBefore
+	}
+}
+
+// DaysInARow is met when the player has finished a game on n consecutive days.
+func DaysInARow(n int) AchievementCondition {
+	return func(e *AchievementEvent) bool { return e.DaysInARow >= n }
+}
+
+// AllOf is met when every condition is met.
+func AllOf(conditions ...AchievementCondition) AchievementCondition {
+	return func(e *AchievementEvent) bool {
+		for _, c := range conditions {
+			if !c(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// solutionLength is the length of every solution; the board has five columns.
+	solutionLength     = 5
+	maxAuditPageSize   = 100
+	defaultAuditPage   = 50
+	maxBanReasonLength = 500
+)
+
+// Audit log actions.
+const (
+	AuditBanPlayer     = "ban_player"
+	AuditSuspendPlayer = "suspend_player"
+	AuditUnbanPlayer   = "unban_player"
+	AuditRenamePlayer  = "rename_player"
+	AuditSetRole       = "set_role"
+	AuditViewSolution  = "view_solution"
+	AuditVoidGame      = "void_game"
+	AuditSetGameResult = "set_game_result"
+	AuditAddWords      = "add_words"
+	AuditRemoveWords   = "remove_words"
+)
+
+// AdminService carries out moderator and admin actions. Every action is
+// recorded in the audit log before it is carried out, and is refused if it
+// cannot be recorded.
+type AdminService struct {
+	players       repositories.PlayerRepositoryI
+	gameRepo      repositories.GameRepositoryI
+	words         repositories.WordRepositoryI
+	audit         repositories.AuditRepositoryI
+	games         *GameService
+	ratings       *RatingService
+	stats         *StatsService
+	achievements  *AchievementService
+	playerService *PlayerService
+	now           func() time.Time
+}
+
+// NewAdminService creates a new AdminService. If ratings, stats or
+// achievements is nil, changing a game's result leaves them alone.
+func NewAdminService(players repositories.PlayerRepositoryI, gameRepo repositories.GameRepositoryI, words repositories.WordRepositoryI, audit repositories.AuditRepositoryI, games *GameService, ratings *RatingService, stats *StatsService, achievements *AchievementService, playerService *PlayerService) *AdminService {
+	return &AdminService{
+		players:       players,
+		gameRepo:      gameRepo,
+		words:         words,
+		audit:         audit,
+		games:         games,
+		ratings:       ratings,
+		stats:         stats,
+		achievements:  achievements,
+		playerService: playerService,
+		now:           time.Now,
+	}
+}
+
+// Role returns the player's role, or "" for unknown and deleted players.
+func (s *AdminService) Role(ctx context.Context, playerID string) (string, error) {
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil || player == nil || player.DeletedAt != nil {
+		return "", err
+	}
+	return player.Role, nil
+}
+
+// GetPlayer returns a player with their role and ban.
+func (s *AdminService) GetPlayer(ctx context.Context, playerID string) (*models.Player, error) {
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	return player, nil
+}
+
+// target returns the player actorID wants to act on. Players can only act on
+// players of a lower role; it returns "insufficient_role" otherwise.
+func (s *AdminService) target(ctx context.Context, actorID, playerID string) (*models.Player, error) {
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.DeletedAt != nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	actorRole, err := s.Role(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if actorID == playerID || models.RoleAtLeast(player.Role, actorRole) {
+		return nil, fmt.Errorf("insufficient_role")
+	}
+	return player, nil
+}
+
+// BanPlayer bans a player until the given time, or permanently when until is
+// nil. The player's sessions end and their games in progress are forfeited.
+// It returns "invalid_ban_end" if until is not in the future.
+func (s *AdminService) BanPlayer(ctx context.Context, actorID, playerID string, until *time.Time, reason string) error {
+	now := s.now()
+	if until != nil && !until.After(now) {
+		return fmt.Errorf("invalid_ban_end")
+	}
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxBanReasonLength {
+		return fmt.Errorf("invalid_ban_reason")
+	}
+	if _, err := s.target(ctx, actorID, playerID); err != nil {
+		return err
+	}
+
+	action := AuditBanPlayer
+	if until != nil {
+		action = AuditSuspendPlayer
+	}
+	if err := s.record(ctx, actorID, action, "player", playerID, map[string]any{"until": until, "reason": reason}); err != nil {
+		return err
+	}
+	if err := s.players.SetBan(ctx, playerID, &now, until, reason); err != nil {
+		return err
+	}
+	if _, err := s.playerService.RevokeOtherSessions(ctx, playerID, ""); err != nil {
+		return err
+	}
+	return s.games.ForfeitActiveGames(ctx, playerID)
+}
+
+// UnbanPlayer lifts a player's ban or suspension.
+func (s *AdminService) UnbanPlayer(ctx context.Context, actorID, playerID string) error {
+	player, err := s.target(ctx, actorID, playerID)
+	if err != nil {
+		return err
+	}
+	if player.BannedAt == nil {
+		return fmt.Errorf("player_not_banned")
+	}
+	if err := s.record(ctx, actorID, AuditUnbanPlayer, "player", playerID, map[string]any{"reason": player.BanReason, "until": player.BannedUntil}); err != nil {
+		return err
+	}
+	return s.players.SetBan(ctx, playerID, nil, nil, "")
+}
+
+// RenamePlayer replaces a player's name, e.g. an offensive one, without the
+// players' rename cooldown. The name is checked like in PlayerService.Rename.
+func (s *AdminService) RenamePlayer(ctx context.Context, actorID, playerID, name string) error {
+	player, err := s.target(ctx, actorID, playerID)
+	if err != nil {
+		return err
+	}
+	name = strings.TrimSpace(name)
+	if name == player.Name {
+		return nil
+	}
+	if err := s.playerService.checkName(ctx, name, player.ID); err != nil {
+		return err
+	}
+	if err := s.record(ctx, actorID, AuditRenamePlayer, "player", playerID, map[string]any{"from": player.Name, "to": name}); err != nil {
+		return err
+	}
+	return s.playerService.rename(ctx, player, name)
+}
+
+// SetRole changes a registered player's role. Admins cannot change their own
+// role, so there is always an admin left.
+func (s *AdminService) SetRole(ctx context.Context, actorID, playerID, role string) error {
+	if !models.ValidRole(role) {
+		return fmt.Errorf("invalid_role")
+	}
+	if actorID == playerID {
+		return fmt.Errorf("insufficient_role")
+	}
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	if player == nil || player.DeletedAt != nil {
+		return fmt.Errorf("player_not_found")
+	}
+	if !player.Registered && role != models.RolePlayer {
+		return fmt.Errorf("player_not_registered")
+	}
+	if err := s.record(ctx, actorID, AuditSetRole, "player", playerID, map[string]any{"from": player.Role, "to": role}); err != nil {
+		return err
+	}
+	return s.players.SetRole(ctx, playerID, role)
+}
+
+// GetGame returns a game including its solution, even while it is in
+// progress.
+func (s *AdminService) GetGame(ctx context.Context, actorID, gameID string) (*models.Game, error) {
+	game, err := s.gameRepo.GetByID(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, fmt.Errorf("game_not_found")
+	}
+	if err := s.record(ctx, actorID, AuditViewSolution, "game", gameID, map[string]any{"finished": game.Finished()}); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// SetGameResult voids a finished game or changes its result to a draw or a
+// loss for one of its players. The ratings are rolled back by replaying the
+// whole history with the new result, like cmd/recompute-ratings, and both
+// players' statistics and achievements are rebuilt from their games. No game
+// can finish meanwhile. It returns "invalid_result", "game_not_found",
+// "game_not_finished" and "game_imported".
+func (s *AdminService) SetGameResult(ctx context.Context, actorID, gameID, result, reason string) error {
+	game, err := s.gameRepo.GetByID(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	if game == nil {
+		return fmt.Errorf("game_not_found")
+	}
+	switch result {
+	case models.ResultVoid, models.ResultDraw, models.ResultLosePrefix + game.FirstPlayer, models.ResultLosePrefix + game.SecondPlayer:
+	default:
+		return fmt.Errorf("invalid_result")
+	}
+	if !game.Finished() {
+		return fmt.Errorf("game_not_finished")
+	}
+	if game.Imported {
+		return fmt.Errorf("game_imported")
+	}
+
+	action := AuditSetGameResult
+	if result == models.ResultVoid {
+		action = AuditVoidGame
+	}
+	if err := s.record(ctx, actorID, action, "game", gameID, map[string]any{"from": game.Result, "to": result, "reason": strings.TrimSpace(reason)}); err != nil {
+		return err
+	}
+	return s.games.RewriteResults(func() error {
+		if err := s.gameRepo.SetResult(ctx, gameID, result); err != nil {
+			return err
+		}
+		if s.ratings != nil {
+			if _, err := s.ratings.RecomputeAll(ctx); err != nil {
+				return fmt.Errorf("failed to recompute ratings: %w", err)
+			}
+		}
+		for _, playerID := range []string{game.FirstPlayer, game.SecondPlayer} {
+			if s.stats != nil {
+				if err := s.stats.RecomputePlayer(ctx, playerID); err != nil {
+					return fmt.Errorf("failed to recompute stats: %w", err)
+				}
+			}
+			if s.achievements != nil {
+				if err := s.achievements.RecomputePlayer(ctx, playerID); err != nil {
+					return fmt.Errorf("failed to recompute achievements: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ListWords returns the words new games pick their solution from.
+func (s *AdminService) ListWords(ctx context.Context) ([]string, error) {
+	return s.words.List(ctx)
+}
+
+// AddWords adds words to the word list and returns those that were new. It
+// returns "invalid_word" unless every word has five letters.
+func (s *AdminService) AddWords(ctx context.Context, actorID string, words []string) ([]string, error) {
+	words, err := normalizeWords(words)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.words.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if adding := wordsIn(words, current, false); len(adding) > 0 {
+		if err := s.record(ctx, actorID, AuditAddWords, "word_list", "solutions", map[string]any{"words": adding}); err != nil {
+			return nil, err
+		}
+	}
+	added, err := s.words.Add(ctx, words, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reloadWords(ctx); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveWords removes words from the word list and returns those that were in
+// it. Games in progress keep their solution. It returns "word_list_empty"
+// rather than remove every word.
+func (s *AdminService) RemoveWords(ctx context.Context, actorID string, words []string) ([]string, error) {
+	words, err := normalizeWords(words)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.words.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	removing := wordsIn(words, current, true)
+	if len(removing) == len(current) {
+		return nil, fmt.Errorf("word_list_empty")
+	}
+	if len(removing) > 0 {
+		if err := s.record(ctx, actorID, AuditRemoveWords, "word_list", "solutions", map[string]any{"words": removing}); err != nil {
+			return nil, err
+		}
+	}
+
+	removed, err := s.words.Remove(ctx, words)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reloadWords(ctx); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// wordsIn returns the distinct words that are in list if in is true, or that
+// are not in it otherwise, in their original order.
+func wordsIn(words, list []string, in bool) []string {
+	listed := make(map[string]bool, len(list))
+	for _, w := range list {
+		listed[w] = true
+	}
+	seen := make(map[string]bool, len(words))
+	var result []string
+	for _, w := range words {
+		if listed[w] == in && !seen[w] {
+			seen[w] = true
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// reloadWords hands the stored word list to the game service.
+func (s *AdminService) reloadWords(ctx context.Context) error {
+	words, err := s.words.List(ctx)
+	if err != nil {
+		return err
+	}
+	s.games.SetWordList(words)
+	return nil
+}
+
+func normalizeWords(words []string) ([]string, error) {
+	if len(words) == 0 {
+		return nil, fmt.Errorf("invalid_word")
+	}
+	normalized := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if len(word) != solutionLength {
+			return nil, fmt.Errorf("invalid_word")
+		}
+		for _, r := range word {
+			if r < 'a' || r > 'z' {
+				return nil, fmt.Errorf("invalid_word")
+			}
+		}
+		normalized = append(normalized, word)
+	}
+	return normalized, nil
+}
+
+// ListAudit returns up to limit audit log entries matching the filter, newest
+// first, starting before the entry with ID beforeID if it is non-zero.
+func (s *AdminService) ListAudit(ctx context.Context, f repositories.AuditFilter, beforeID int64, limit int) ([]*models.AuditEntry, error) {
+	if limit <= 0 {
+		limit = defaultAuditPage
+	}
+	if limit > maxAuditPageSize {
+		limit = maxAuditPageSize
+	}
+	return s.audit.List(ctx, f, beforeID, limit)
+}
+
+func (s *AdminService) entry(actorID, action, targetType, targetID string, details map[string]any) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		CreatedAt:  s.now(),
+	}
+	if details != nil {
+		entry.Details, _ = json.Marshal(details)
+	}
+	return entry
+}
+
+// record writes an audit log entry for an action that is about to be carried
+// out. The action must not be carried out if it returns an error.
+func (s *AdminService) record(ctx context.Context, actorID, action, targetType, targetID string, details map[string]any) error {
+	if err := s.audit.Create(ctx, s.entry(actorID, action, targetType, targetID, details)); err != nil {
+		return fmt.Errorf("failed to record %s: %w", action, err)
+	}
+	return nil
+}
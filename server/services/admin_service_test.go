@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+type mockWordRepo struct {
+	words map[string]bool
+}
+
+func (m *mockWordRepo) List(ctx context.Context) ([]string, error) {
+	words := []string{}
+	for w := range m.words {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words, nil
+}
+func (m *mockWordRepo) Add(ctx context.Context, words []string, at time.Time) ([]string, error) {
+	added := []string{}
+	for _, w := range words {
+		if !m.words[w] {
+			m.words[w] = true
+			added = append(added, w)
+		}
+	}
+	return added, nil
+}
+func (m *mockWordRepo) Remove(ctx context.Context, words []string) ([]string, error) {
+	removed := []string{}
+	for _, w := range words {
+		if m.words[w] {
+			delete(m.words, w)
+			removed = append(removed, w)
+		}
+	}
+	return removed, nil
+}
+
+type mockAuditRepo struct {
+	entries []*models.AuditEntry
+	// err is returned by Create when set.
+	err error
+}
+
+func (m *mockAuditRepo) Create(ctx context.Context, entry *models.AuditEntry) error {
+	if m.err != nil {
+		return m.err
+	}
+	entry.ID = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, entry)
+	return nil
+}
+func (m *mockAuditRepo) List(ctx context.Context, f repositories.AuditFilter, beforeID int64, limit int) ([]*models.AuditEntry, error) {
+	return m.entries, nil
+}
+
+type adminTest struct {
+	service       *AdminService
+	playerService *PlayerService
+	players       *mockPlayerRepo
+	gameRepo      *mockGameRepo
+	games         *GameService
+	words         *mockWordRepo
+	audit         *mockAuditRepo
+	stats         *mockStatsRepo
+	achievements  *mockAchievementRepo
+}
+
+func newAdminTest() *adminTest {
+	players := newMockPlayerRepo()
+	gameRepo := &mockGameRepo{}
+	ratings := NewRatingService(players, gameRepo, NewEloSystem(testEloConfig))
+	games := NewGameService(gameRepo, []string{"apple"}, ratings)
+	playerService := NewPlayerService(players, newMockSessionRepo(), "testsecret")
+	words := &mockWordRepo{words: map[string]bool{"apple": true}}
+	audit := &mockAuditRepo{}
+	statsRepo := &mockStatsRepo{}
+	achievementRepo := &mockAchievementRepo{}
+	stats := NewStatsService(gameRepo, players, statsRepo)
+	achievements := NewAchievementService(achievementRepo, gameRepo, players, statsRepo, DefaultAchievements)
+	return &adminTest{
+		service:       NewAdminService(players, gameRepo, words, audit, games, ratings, stats, achievements, playerService),
+		playerService: playerService,
+		players:       players,
+		gameRepo:      gameRepo,
+		games:         games,
+		words:         words,
+		audit:         audit,
+		stats:         statsRepo,
+		achievements:  achievementRepo,
+	}
+}
+
+func (a *adminTest) registered(t *testing.T, name, role string) *models.Player {
+	pw := "password"
+	player, _, err := a.playerService.CreatePlayer(context.Background(), name, &pw, nil, SessionClient{})
+	if err != nil {
+		t.Fatalf("CreatePlayer failed: %v", err)
+	}
+	player.Role = role
+	return player
+}
+
+func TestAdminService_BanAndUnban(t *testing.T) {
+	ctx := context.Background()
+	a := newAdminTest()
+	mod := a.registered(t, "mona", models.RoleModerator)
+	admin := a.registered(t, "ada", models.RoleAdmin)
+	pw := "password"
+	player, tokens, _ := a.playerService.CreatePlayer(ctx, "troll", &pw, nil, SessionClient{})
+
+	if err := a.service.BanPlayer(ctx, mod.ID, admin.ID, nil, "nope"); err == nil || err.Error() != "insufficient_role" {
+		t.Errorf("Expected moderators to be unable to ban admins, got %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := a.service.BanPlayer(ctx, mod.ID, player.ID, &past, ""); err == nil || err.Error() != "invalid_ban_end" {
+		t.Errorf("Expected invalid_ban_end, got %v", err)
+	}
+
+	until := time.Now().Add(24 * time.Hour)
+	if err := a.service.BanPlayer(ctx, mod.ID, player.ID, &until, "spam"); err != nil {
+		t.Fatalf("BanPlayer failed: %v", err)
+	}
+	if _, _, err := a.playerService.VerifyJWT(ctx, tokens.AccessToken); err == nil {
+		t.Error("Expected the banned player's sessions to end")
+	}
+	if _, _, err := a.playerService.Login(ctx, "troll", pw, SessionClient{}); err == nil || err.Error() != "player_banned" {
+		t.Errorf("Expected player_banned on login, got %v", err)
+	}
+	if len(a.audit.entries) != 1 || a.audit.entries[0].Action != AuditSuspendPlayer || a.audit.entries[0].ActorID != mod.ID {
+		t.Errorf("Expected the suspension to be audited, got %+v", a.audit.entries)
+	}
+
+	if err := a.service.UnbanPlayer(ctx, mod.ID, player.ID); err != nil {
+		t.Fatalf("UnbanPlayer failed: %v", err)
+	}
+	if _, _, err := a.playerService.Login(ctx, "troll", pw, SessionClient{}); err != nil {
+		t.Errorf("Expected login to work after the ban was lifted, got %v", err)
+	}
+}
+
+func TestAdminService_SetGameResultRecomputesRatings(t *testing.T) {
+	ctx := context.Background()
+	a := newAdminTest()
+	admin := a.registered(t, "ada", models.RoleAdmin)
+	first := a.registered(t, "first", models.RolePlayer)
+	second := a.registered(t, "second", models.RolePlayer)
+	game := &models.Game{ID: "g1", FirstPlayer: first.ID, SecondPlayer: second.ID, Result: "lose:" + second.ID, UpdatedAt: time.Now()}
+	a.gameRepo.updated = game
+	a.gameRepo.finished = []*models.Game{game}
+	a.gameRepo.games = []*models.Game{game}
+	a.gameRepo.changes = []*models.RatingChange{{GameID: "g1", PlayerID: first.ID, Before: 1200, After: 1232}}
+	a.achievements.unlocked = []*models.PlayerAchievement{{PlayerID: first.ID, AchievementID: "first_win", GameID: "g1"}}
+
+	if err := a.service.SetGameResult(ctx, admin.ID, "g1", "lose:someone", ""); err == nil || err.Error() != "invalid_result" {
+		t.Errorf("Expected invalid_result, got %v", err)
+	}
+	if err := a.service.SetGameResult(ctx, admin.ID, "g1", models.ResultVoid, "cheating"); err != nil {
+		t.Fatalf("SetGameResult failed: %v", err)
+	}
+	if game.Result != models.ResultVoid {
+		t.Errorf("Expected the game to be voided, got %q", game.Result)
+	}
+	if len(a.gameRepo.changes) != 0 {
+		t.Errorf("Expected the voided game's rating changes to be rolled back, got %+v", a.gameRepo.changes)
+	}
+	if summaries, ok := a.stats.replaced[first.ID]; !ok || len(summaries) != 0 {
+		t.Errorf("Expected the voided game to be dropped from the stats, got %+v", summaries)
+	}
+	if len(a.achievements.unlocked) != 0 {
+		t.Errorf("Expected the voided game's achievements to be revoked, got %+v", a.achievements.unlocked)
+	}
+
+	if err := a.service.SetGameResult(ctx, admin.ID, "g1", "lose:"+first.ID, ""); err != nil {
+		t.Fatalf("SetGameResult failed: %v", err)
+	}
+	if len(a.gameRepo.changes) != 2 || a.gameRepo.changes[1].PlayerID != second.ID || a.gameRepo.changes[1].After <= 1200 {
+		t.Errorf("Expected the new result to be rated, got %+v", a.gameRepo.changes)
+	}
+	if summaries := a.stats.replaced[second.ID]; len(summaries) != 2 || summaries[1].PlayerID != second.ID || summaries[1].Outcome != models.OutcomeWin {
+		t.Errorf("Expected the stats to be rebuilt with the new result, got %+v", summaries)
+	}
+	winners := map[string]bool{}
+	for _, unlock := range a.achievements.unlocked {
+		if unlock.AchievementID == "first_win" {
+			winners[unlock.PlayerID] = true
+		}
+	}
+	if len(winners) != 1 || !winners[second.ID] {
+		t.Errorf("Expected only the new winner to have first_win, got %v", winners)
+	}
+	if len(a.audit.entries) != 2 || a.audit.entries[0].Action != AuditVoidGame || a.audit.entries[1].Action != AuditSetGameResult {
+		t.Errorf("Expected both changes to be audited, got %+v", a.audit.entries)
+	}
+}
+
+func TestAdminService_RefusesUnauditedActions(t *testing.T) {
+	ctx := context.Background()
+	a := newAdminTest()
+	mod := a.registered(t, "mona", models.RoleModerator)
+	admin := a.registered(t, "ada", models.RoleAdmin)
+	player := a.registered(t, "troll", models.RolePlayer)
+	a.audit.err = fmt.Errorf("disk full")
+
+	if err := a.service.BanPlayer(ctx, mod.ID, player.ID, nil, "spam"); err == nil {
+		t.Error("Expected the ban to fail without an audit entry")
+	}
+	if banned, _ := a.players.GetByID(ctx, player.ID); banned.BannedAt != nil {
+		t.Error("Expected the player not to be banned")
+	}
+	if _, err := a.service.AddWords(ctx, admin.ID, []string{"crane"}); err == nil {
+		t.Error("Expected adding words to fail without an audit entry")
+	}
+	if got := a.games.WordList(); len(got) != 1 {
+		t.Errorf("Expected the word list to be unchanged, got %v", got)
+	}
+}
+
+func TestAdminService_Words(t *testing.T) {
+	ctx := context.Background()
+	a := newAdminTest()
+	admin := a.registered(t, "ada", models.RoleAdmin)
+
+	if _, err := a.service.AddWords(ctx, admin.ID, []string{"toolong"}); err == nil || err.Error() != "invalid_word" {
+		t.Errorf("Expected invalid_word, got %v", err)
+	}
+	added, err := a.service.AddWords(ctx, admin.ID, []string{"CRANE", "apple"})
+	if err != nil {
+		t.Fatalf("AddWords failed: %v", err)
+	}
+	if len(added) != 1 || added[0] != "crane" {
+		t.Errorf("Expected only the new word to be added, got %v", added)
+	}
+	if got := a.games.WordList(); len(got) != 2 {
+		t.Errorf("Expected new games to use the new word list, got %v", got)
+	}
+	if _, err := a.service.RemoveWords(ctx, admin.ID, []string{"apple", "crane"}); err == nil || err.Error() != "word_list_empty" {
+		t.Errorf("Expected word_list_empty, got %v", err)
+	}
+	if _, err := a.service.RemoveWords(ctx, admin.ID, []string{"apple"}); err != nil {
+		t.Fatalf("RemoveWords failed: %v", err)
+	}
+	if got := a.games.WordList(); len(got) != 1 || got[0] != "crane" {
+		t.Errorf("Expected only crane to be left, got %v", got)
+	}
+	if len(a.audit.entries) != 2 {
+		t.Errorf("Expected both changes to be audited, got %+v", a.audit.entries)
+	}
+}
+
+func TestAdminService_GetGameIsAudited(t *testing.T) {
+	ctx := context.Background()
+	a := newAdminTest()
+	mod := a.registered(t, "mona", models.RoleModerator)
+	a.gameRepo.created = &models.Game{ID: "g1", Solution: "APPLE"}
+
+	game, err := a.service.GetGame(ctx, mod.ID, "g1")
+	if err != nil || game.Solution != "APPLE" {
+		t.Fatalf("Expected the game with its solution, got %+v, %v", game, err)
+	}
+	if len(a.audit.entries) != 1 || a.audit.entries[0].Action != AuditViewSolution || a.audit.entries[0].TargetID != "g1" {
+		t.Errorf("Expected viewing the solution to be audited, got %+v", a.audit.entries)
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+const (
+	globalStatsOpenings       = 10
+	globalStatsWords          = 10
+	globalStatsMinWordGames   = 3
+	globalStatsActivityWindow = 30 // days
+)
+
+// AnalyticsService computes server-wide statistics and caches them between
+// refreshes, since the aggregation queries scan the whole games table.
+type AnalyticsService struct {
+	repo repositories.AnalyticsRepositoryI
+	now  func() time.Time
+
+	mu     sync.RWMutex
+	cached *models.GlobalStats
+}
+
+// NewAnalyticsService creates a new AnalyticsService.
+func NewAnalyticsService(repo repositories.AnalyticsRepositoryI) *AnalyticsService {
+	return &AnalyticsService{repo: repo, now: time.Now}
+}
+
+// GetGlobalStats returns the cached statistics, computing them first if no
+// refresh has completed yet.
+func (s *AnalyticsService) GetGlobalStats(ctx context.Context) (*models.GlobalStats, error) {
+	s.mu.RLock()
+	cached := s.cached
+	s.mu.RUnlock()
+	if cached != nil {
+		return cached, nil
+	}
+	return s.Refresh(ctx)
+}
+
+// Refresh recomputes the statistics and replaces the cached copy.
+func (s *AnalyticsService) Refresh(ctx context.Context) (*models.GlobalStats, error) {
+	now := s.now()
+	stats := &models.GlobalStats{GeneratedAt: now}
+	if err := s.repo.GameTotals(ctx, stats); err != nil {
+		return nil, err
+	}
+	var err error
+	if stats.Openings, err = s.repo.TopOpenings(ctx, globalStatsOpenings); err != nil {
+		return nil, err
+	}
+	if stats.HardestWords, err = s.repo.WordDifficulties(ctx, globalStatsMinWordGames, globalStatsWords, true); err != nil {
+		return nil, err
+	}
+	if stats.EasiestWords, err = s.repo.WordDifficulties(ctx, globalStatsMinWordGames, globalStatsWords, false); err != nil {
+		return nil, err
+	}
+	since := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -(globalStatsActivityWindow - 1))
+	if stats.DailyActivePlayers, err = s.repo.DailyActivePlayers(ctx, since); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cached = stats
+	s.mu.Unlock()
+	return stats, nil
+}
+
+// Run refreshes the statistics every interval until ctx is cancelled. Failed
+// refreshes are logged and the previous statistics stay cached.
+func (s *AnalyticsService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Refresh(ctx); err != nil {
+			log.Printf("error refreshing global stats: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+// mockAnalyticsRepo counts refreshes and reports that many games.
+type mockAnalyticsRepo struct {
+	refreshes int
+	since     time.Time
+}
+
+func (m *mockAnalyticsRepo) GameTotals(ctx context.Context, stats *models.GlobalStats) error {
+	m.refreshes++
+	stats.Games = m.refreshes
+	return nil
+}
+func (m *mockAnalyticsRepo) TopOpenings(ctx context.Context, limit int) ([]*models.OpeningPerformance, error) {
+	return []*models.OpeningPerformance{}, nil
+}
+func (m *mockAnalyticsRepo) WordDifficulties(ctx context.Context, minGames int, limit int, hardest bool) ([]*models.WordDifficulty, error) {
+	return []*models.WordDifficulty{}, nil
+}
+func (m *mockAnalyticsRepo) DailyActivePlayers(ctx context.Context, since time.Time) ([]*models.DailyActivity, error) {
+	m.since = since
+	return []*models.DailyActivity{}, nil
+}
+
+func TestGetGlobalStats_Cached(t *testing.T) {
+	repo := &mockAnalyticsRepo{}
+	service := NewAnalyticsService(repo)
+	service.now = func() time.Time { return time.Date(2025, time.August, 14, 12, 30, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	stats, err := service.GetGlobalStats(ctx)
+	if err != nil {
+		t.Fatalf("GetGlobalStats failed: %v", err)
+	}
+	if stats.Games != 1 {
+		t.Errorf("Expected stats to be computed on first use, got %+v", stats)
+	}
+	if want := time.Date(2025, time.July, 16, 0, 0, 0, 0, time.UTC); !repo.since.Equal(want) {
+		t.Errorf("Expected activity since %v, got %v", want, repo.since)
+	}
+
+	if stats, _ = service.GetGlobalStats(ctx); stats.Games != 1 || repo.refreshes != 1 {
+		t.Errorf("Expected cached stats, got %d refreshes", repo.refreshes)
+	}
+	if _, err := service.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if stats, _ = service.GetGlobalStats(ctx); stats.Games != 2 {
+		t.Errorf("Expected refreshed stats, got %+v", stats)
+	}
+}
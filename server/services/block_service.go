@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+// BlockService manages the players each player blocked.
+type BlockService struct {
+	repo        repositories.BlockRepositoryI
+	players     repositories.PlayerRepositoryI
+	friendships repositories.FriendshipRepositoryI
+	now         func() time.Time
+}
+
+// NewBlockService creates a new BlockService.
+func NewBlockService(repo repositories.BlockRepositoryI, players repositories.PlayerRepositoryI, friendships repositories.FriendshipRepositoryI) *BlockService {
+	return &BlockService{repo: repo, players: players, friendships: friendships, now: time.Now}
+}
+
+// Block blocks blockedID for the player and ends any friendship or friend
+// request between them. Blocking a player again is not an error. It returns
+// "cannot_block_self" and "player_not_found" for unknown or deleted players.
+func (s *BlockService) Block(ctx context.Context, playerID, blockedID string) (*models.BlockedPlayer, error) {
+	if playerID == blockedID {
+		return nil, fmt.Errorf("cannot_block_self")
+	}
+	blocked, err := s.players.GetByID(ctx, blockedID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked == nil || blocked.DeletedAt != nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	now := s.now()
+	if err := s.repo.Block(ctx, playerID, blockedID, now); err != nil {
+		return nil, err
+	}
+	if err := s.friendships.Delete(ctx, playerID, blockedID); err != nil && err.Error() != "friendship_not_found" {
+		return nil, err
+	}
+	return &models.BlockedPlayer{PlayerID: blocked.ID, Name: blocked.Name, BlockedAt: now}, nil
+}
+
+// Unblock lifts the player's block of blockedID. It returns "block_not_found"
+// if there is none.
+func (s *BlockService) Unblock(ctx context.Context, playerID, blockedID string) error {
+	return s.repo.Unblock(ctx, playerID, blockedID)
+}
+
+// List returns the players the player blocked, most recent first.
+func (s *BlockService) List(ctx context.Context, playerID string) ([]*models.BlockedPlayer, error) {
+	return s.repo.ListByPlayer(ctx, playerID)
+}
+
+// IsBlocked reports whether either player blocked the other.
+func (s *BlockService) IsBlocked(ctx context.Context, playerID, otherID string) (bool, error) {
+	return s.repo.IsBlocked(ctx, playerID, otherID)
+}
+
+// Blocks is IsBlocked for callers that cannot handle errors, such as
+// matchmaking. The two players are treated as blocked if it cannot tell.
+func (s *BlockService) Blocks(playerID, otherID string) bool {
+	blocked, err := s.repo.IsBlocked(context.Background(), playerID, otherID)
+	if err != nil {
+		log.Printf("error checking block between %s and %s: %v", playerID, otherID, err)
+		return true
+	}
+	return blocked
+}
+
+// BlocksAmong loads the blocks between the given players in one query and
+// returns Blocks for pairs of them, for matchmaking. If the blocks cannot be
+// loaded, every pair is treated as blocked.
+func (s *BlockService) BlocksAmong(playerIDs []string) func(playerID, otherID string) bool {
+	blocks, err := s.repo.ListAmong(context.Background(), playerIDs)
+	if err != nil {
+		log.Printf("error loading blocks between %d players: %v", len(playerIDs), err)
+		return func(playerID, otherID string) bool { return true }
+	}
+	blocked := make(map[[2]string]bool, 2*len(blocks))
+	for _, b := range blocks {
+		blocked[b] = true
+		blocked[[2]string{b[1], b[0]}] = true
+	}
+	return func(playerID, otherID string) bool {
+		return blocked[[2]string{playerID, otherID}]
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+// mockBlockRepo keeps blocks as "blocker>blocked" keys.
+type mockBlockRepo struct {
+	players *mockPlayerRepo
+	blocks  map[string]time.Time
+}
+
+func (m *mockBlockRepo) Block(ctx context.Context, blockerID, blockedID string, at time.Time) error {
+	if m.blocks == nil {
+		m.blocks = make(map[string]time.Time)
+	}
+	if _, ok := m.blocks[blockerID+">"+blockedID]; !ok {
+		m.blocks[blockerID+">"+blockedID] = at
+	}
+	return nil
+}
+func (m *mockBlockRepo) Unblock(ctx context.Context, blockerID, blockedID string) error {
+	if _, ok := m.blocks[blockerID+">"+blockedID]; !ok {
+		return fmt.Errorf("block_not_found")
+	}
+	delete(m.blocks, blockerID+">"+blockedID)
+	return nil
+}
+func (m *mockBlockRepo) IsBlocked(ctx context.Context, playerID, otherID string) (bool, error) {
+	_, a := m.blocks[playerID+">"+otherID]
+	_, b := m.blocks[otherID+">"+playerID]
+	return a || b, nil
+}
+func (m *mockBlockRepo) ListAmong(ctx context.Context, playerIDs []string) ([][2]string, error) {
+	among := make(map[string]bool, len(playerIDs))
+	for _, id := range playerIDs {
+		among[id] = true
+	}
+	var blocks [][2]string
+	for key := range m.blocks {
+		if blocker, blocked, _ := strings.Cut(key, ">"); among[blocker] && among[blocked] {
+			blocks = append(blocks, [2]string{blocker, blocked})
+		}
+	}
+	return blocks, nil
+}
+func (m *mockBlockRepo) ListByPlayer(ctx context.Context, blockerID string) ([]*models.BlockedPlayer, error) {
+	blocked := []*models.BlockedPlayer{}
+	for key, at := range m.blocks {
+		if blocker, id, _ := strings.Cut(key, ">"); blocker == blockerID {
+			blocked = append(blocked, &models.BlockedPlayer{PlayerID: id, Name: m.players.players[id].Name, BlockedAt: at})
+		}
+	}
+	return blocked, nil
+}
+
+func TestBlockService_Block(t *testing.T) {
+	ctx := context.Background()
+	ft := newFriendTest()
+	ft.player("alice", true)
+	ft.player("bob", true)
+	ft.service.Request(ctx, "alice", "bob")
+	ft.service.Accept(ctx, "bob", "alice")
+
+	if _, err := ft.blocks.Block(ctx, "alice", "alice"); err == nil || err.Error() != "cannot_block_self" {
+		t.Errorf("Expected cannot_block_self, got %v", err)
+	}
+	if _, err := ft.blocks.Block(ctx, "alice", "nobody"); err == nil || err.Error() != "player_not_found" {
+		t.Errorf("Expected player_not_found, got %v", err)
+	}
+	blocked, err := ft.blocks.Block(ctx, "bob", "alice")
+	if err != nil {
+		t.Fatalf("Block failed: %v", err)
+	}
+	if blocked.PlayerID != "alice" || blocked.Name != "alice" {
+		t.Errorf("Unexpected blocked player: %+v", blocked)
+	}
+	if _, err := ft.blocks.Block(ctx, "bob", "alice"); err != nil {
+		t.Errorf("Expected blocking again to succeed, got %v", err)
+	}
+
+	if friends, _ := ft.service.List(ctx, "alice"); len(friends) != 0 {
+		t.Errorf("Expected the friendship to end, got %+v", friends)
+	}
+	if !ft.blocks.Blocks("alice", "bob") {
+		t.Error("Expected the block to apply both ways")
+	}
+	if blocks := ft.blocks.BlocksAmong([]string{"alice", "bob"}); !blocks("alice", "bob") || !blocks("bob", "alice") {
+		t.Error("Expected the loaded blocks to apply both ways")
+	}
+	// The blocked player cannot tell a block from a missing player.
+	if _, err := ft.service.Request(ctx, "alice", "bob"); err == nil || err.Error() != "player_not_found" {
+		t.Errorf("Expected player_not_found for a friend request to a blocker, got %v", err)
+	}
+	if list, _ := ft.blocks.List(ctx, "bob"); len(list) != 1 || list[0].PlayerID != "alice" {
+		t.Errorf("Expected alice on bob's block list, got %+v", list)
+	}
+
+	if err := ft.blocks.Unblock(ctx, "alice", "bob"); err == nil || err.Error() != "block_not_found" {
+		t.Errorf("Expected only the blocker to unblock, got %v", err)
+	}
+	if err := ft.blocks.Unblock(ctx, "bob", "alice"); err != nil {
+		t.Fatalf("Unblock failed: %v", err)
+	}
+	if ft.blocks.Blocks("alice", "bob") {
+		t.Error("Expected the block to be lifted")
+	}
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Challenge event types.
+const (
+	ChallengeEventInvite    = "challenge_invite"
+	ChallengeEventResult    = "challenge_result"
+	ChallengeEventCancelled = "challenge_cancelled"
+	ChallengeEventExpired   = "challenge_expired"
+)
+
+// ChallengeEvent tells a player about a challenge: a challenge_invite to the
+// challenged player, and a challenge_result, challenge_cancelled or
+// challenge_expired to both players once it is no longer pending. From is the
+// player who sent the invite, answer or cancellation; for expired challenges
+// it is the challenger.
+type ChallengeEvent struct {
+	Type        string     `json:"type"`
+	ChallengeID string     `json:"challenge_id"`
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	FromName    string     `json:"from_name,omitempty"`
+	Rematch     bool       `json:"rematch"`
+	Accepted    bool       `json:"accepted"`
+	GameID      *string    `json:"game_id,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// ChallengeListener is called with the player an event is for.
+type ChallengeListener func(playerID string, event ChallengeEvent)
+
+// ChallengeService manages challenges between players. Challenges are stored,
+// so players who are offline find them when they return, and a player may
+// send and receive any number at once. A pending challenge is accepted,
+// declined, cancelled by its challenger or expires.
+type ChallengeService struct {
+	repo    repositories.ChallengeRepositoryI
+	players repositories.PlayerRepositoryI
+	games   GameServiceI
+	blocks  *BlockService
+	ttl     time.Duration
+	now     func() time.Time
+	// respondMu serialises changes to pending challenges, so that a
+	// challenge starts at most one game. Listeners are called after it is
+	// released, so that a slow one does not hold up other challenges.
+	respondMu sync.Mutex
+	// mu guards listeners.
+	mu        sync.RWMutex
+	listeners []ChallengeListener
+}
+
+// NewChallengeService creates a new ChallengeService. Challenges expire ttl
+// after they are sent. Players who blocked each other cannot challenge each
+// other.
+func NewChallengeService(repo repositories.ChallengeRepositoryI, players repositories.PlayerRepositoryI, games GameServiceI, blocks *BlockService, ttl time.Duration) *ChallengeService {
+	return &ChallengeService{repo: repo, players: players, games: games, blocks: blocks, ttl: ttl, now: time.Now}
+}
+
+// OnChallengeEvent registers a listener for invites and answers.
+func (s *ChallengeService) OnChallengeEvent(listener ChallengeListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// challengeOutbox collects the events of a change to pending challenges
+// until respondMu is released.
+type challengeOutbox []challengeNotice
+
+type challengeNotice struct {
+	playerID string
+	event    ChallengeEvent
+}
+
+func (o *challengeOutbox) add(playerID string, event ChallengeEvent) {
+	*o = append(*o, challengeNotice{playerID, event})
+}
+
+// addBoth adds the event for the challenger and the challenged player.
+func (o *challengeOutbox) addBoth(challenge *models.Challenge, event ChallengeEvent) {
+	o.add(challenge.ChallengerID, event)
+	o.add(challenge.ChallengedID, event)
+}
+
+// lock takes respondMu. The returned func releases it and then sends the
+// events collected in out.
+func (s *ChallengeService) lock(out *challengeOutbox) func() {
+	s.respondMu.Lock()
+	return func() {
+		s.respondMu.Unlock()
+		for _, n := range *out {
+			s.notify(n.playerID, n.event)
+		}
+	}
+}
+
+func (s *ChallengeService) notify(playerID string, event ChallengeEvent) {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(playerID, event)
+	}
+}
+
+// Invite challenges challengedID on behalf of the player. Inviting a player
+// who has not answered yet sends the pending challenge again, unless it
+// expired. It returns
+// "cannot_challenge_self" and "player_not_found" unless challengedID is a
+// current player who has not blocked or been blocked by the player.
+func (s *ChallengeService) Invite(ctx context.Context, playerID, challengedID string, rematch bool) (*models.Challenge, error) {
+	if playerID == challengedID {
+		return nil, fmt.Errorf("cannot_challenge_self")
+	}
+	player, err := s.currentPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	challenged, err := s.currentPlayer(ctx, challengedID)
+	if err != nil {
+		return nil, err
+	}
+	// Blocked players are not told that they are blocked.
+	if blocked, err := s.blocks.IsBlocked(ctx, playerID, challengedID); err != nil {
+		return nil, err
+	} else if blocked {
+		return nil, fmt.Errorf("player_not_found")
+	}
+
+	var out challengeOutbox
+	defer s.lock(&out)()
+	challenge, err := s.repo.GetPending(ctx, playerID, challengedID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if challenge != nil && !now.Before(challenge.ExpiresAt) {
+		if err := s.expire(ctx, challenge, &out); err != nil {
+			return nil, err
+		}
+		challenge = nil
+	}
+	if challenge == nil {
+		challenge = &models.Challenge{
+			ID:             uuid.NewString(),
+			ChallengerID:   player.ID,
+			ChallengerName: player.Name,
+			ChallengedID:   challenged.ID,
+			ChallengedName: challenged.Name,
+			Rematch:        rematch,
+			Status:         models.ChallengePending,
+			CreatedAt:      now,
+			ExpiresAt:      now.Add(s.ttl),
+		}
+		if err := s.repo.Create(ctx, challenge); err != nil {
+			return nil, err
+		}
+	}
+	out.add(challengedID, inviteEvent(challenge))
+	return challenge, nil
+}
+
+// Pending returns the pending challenges the player sent or received that
+// have not expired, oldest first.
+func (s *ChallengeService) Pending(ctx context.Context, playerID string) ([]*models.Challenge, error) {
+	challenges, err := s.repo.ListPending(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	pending := []*models.Challenge{}
+	for _, c := range challenges {
+		if now.Before(c.ExpiresAt) {
+			pending = append(pending, c)
+		}
+	}
+	return pending, nil
+}
+
+// Incoming returns the pending challenges the player received as the
+// invites they would have been sent while online, oldest first.
+func (s *ChallengeService) Incoming(ctx context.Context, playerID string) ([]ChallengeEvent, error) {
+	challenges, err := s.Pending(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	events := []ChallengeEvent{}
+	for _, c := range challenges {
+		if c.ChallengedID == playerID {
+			events = append(events, inviteEvent(c))
+		}
+	}
+	return events, nil
+}
+
+// Respond accepts or declines the pending challenge the player received.
+// Accepting starts a game between the two players. It returns
+// "challenge_not_found" unless the challenge is pending, was sent to the
+// player and its challenger is still a current player who is not blocked, and
+// "challenge_expired" if it expired.
+func (s *ChallengeService) Respond(ctx context.Context, playerID, challengeID string, accept bool) (*models.Challenge, error) {
+	var out challengeOutbox
+	defer s.lock(&out)()
+	challenge, err := s.repo.GetByID(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.respond(ctx, playerID, challenge, accept, &out)
+}
+
+// RespondTo answers the latest pending challenge challengerID sent to the
+// player, like Respond.
+func (s *ChallengeService) RespondTo(ctx context.Context, playerID, challengerID string, accept bool) (*models.Challenge, error) {
+	var out challengeOutbox
+	defer s.lock(&out)()
+	challenge, err := s.repo.GetPending(ctx, challengerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.respond(ctx, playerID, challenge, accept, &out)
+}
+
+func (s *ChallengeService) respond(ctx context.Context, playerID string, challenge *models.Challenge, accept bool, out *challengeOutbox) (*models.Challenge, error) {
+	if challenge == nil || challenge.ChallengedID != playerID || challenge.Status != models.ChallengePending {
+		return nil, fmt.Errorf("challenge_not_found")
+	}
+	if !s.now().Before(challenge.ExpiresAt) {
+		if err := s.expire(ctx, challenge, out); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("challenge_expired")
+	}
+	if _, err := s.currentPlayer(ctx, challenge.ChallengerID); err != nil {
+		if err.Error() == "player_not_found" {
+			return nil, fmt.Errorf("challenge_not_found")
+		}
+		return nil, err
+	}
+	if blocked, err := s.blocks.IsBlocked(ctx, challenge.ChallengerID, playerID); err != nil {
+		return nil, err
+	} else if blocked {
+		return nil, fmt.Errorf("challenge_not_found")
+	}
+
+	status := models.ChallengeDeclined
+	var gameID *string
+	if accept {
+		// The challenger moves first, as in a game from matchmaking.
+		game, err := s.games.CreateGame(ctx, challenge.ChallengerID, challenge.ChallengedID)
+		if err != nil {
+			return nil, err
+		}
+		status = models.ChallengeAccepted
+		gameID = &game.ID
+	}
+	now := s.now()
+	if err := s.repo.Resolve(ctx, challenge.ID, status, gameID, now); err != nil {
+		return nil, err
+	}
+	challenge.Status = status
+	challenge.GameID = gameID
+	challenge.RespondedAt = &now
+
+	event := ChallengeEvent{
+		Type:        ChallengeEventResult,
+		ChallengeID: challenge.ID,
+		From:        challenge.ChallengedID,
+		To:          challenge.ChallengerID,
+		FromName:    challenge.ChallengedName,
+		Rematch:     challenge.Rematch,
+		Accepted:    accept,
+		GameID:      gameID,
+	}
+	out.addBoth(challenge, event)
+	return challenge, nil
+}
+
+// Cancel withdraws a pending challenge the player sent. It returns
+// "challenge_not_found" unless the challenge is pending and the player sent
+// it.
+func (s *ChallengeService) Cancel(ctx context.Context, playerID, challengeID string) (*models.Challenge, error) {
+	var out challengeOutbox
+	defer s.lock(&out)()
+	challenge, err := s.repo.GetByID(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.cancel(ctx, playerID, challenge, &out)
+}
+
+// CancelTo withdraws the latest pending challenge the player sent to
+// challengedID, like Cancel.
+func (s *ChallengeService) CancelTo(ctx context.Context, playerID, challengedID string) (*models.Challenge, error) {
+	var out challengeOutbox
+	defer s.lock(&out)()
+	challenge, err := s.repo.GetPending(ctx, playerID, challengedID)
+	if err != nil {
+		return nil, err
+	}
+	return s.cancel(ctx, playerID, challenge, &out)
+}
+
+func (s *ChallengeService) cancel(ctx context.Context, playerID string, challenge *models.Challenge, out *challengeOutbox) (*models.Challenge, error) {
+	if challenge == nil || challenge.ChallengerID != playerID || challenge.Status != models.ChallengePending {
+		return nil, fmt.Errorf("challenge_not_found")
+	}
+	now := s.now()
+	if err := s.repo.Resolve(ctx, challenge.ID, models.ChallengeCancelled, nil, now); err != nil {
+		return nil, err
+	}
+	challenge.Status = models.ChallengeCancelled
+	challenge.RespondedAt = &now
+	out.addBoth(challenge, ChallengeEvent{
+		Type:        ChallengeEventCancelled,
+		ChallengeID: challenge.ID,
+		From:        challenge.ChallengerID,
+		To:          challenge.ChallengedID,
+		FromName:    challenge.ChallengerName,
+		Rematch:     challenge.Rematch,
+	})
+	return challenge, nil
+}
+
+// ExpireChallenges expires the pending challenges that are past their expiry
+// and tells both players. It returns how many expired.
+func (s *ChallengeService) ExpireChallenges(ctx context.Context) (int, error) {
+	var out challengeOutbox
+	defer s.lock(&out)()
+	challenges, err := s.repo.ListExpired(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for _, c := range challenges {
+		if err := s.expire(ctx, c, &out); err != nil {
+			if err.Error() == "challenge_not_found" {
+				continue
+			}
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// Run expires challenges every interval until ctx is cancelled. Failures are
+// logged and retried on the next run.
+func (s *ChallengeService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.ExpireChallenges(ctx); err != nil {
+			log.Printf("error expiring challenges: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expire marks the pending challenge expired and adds the event for both
+// players to out.
+func (s *ChallengeService) expire(ctx context.Context, challenge *models.Challenge, out *challengeOutbox) error {
+	now := s.now()
+	if err := s.repo.Resolve(ctx, challenge.ID, models.ChallengeExpired, nil, now); err != nil {
+		return err
+	}
+	challenge.Status = models.ChallengeExpired
+	challenge.RespondedAt = &now
+	out.addBoth(challenge, ChallengeEvent{
+		Type:        ChallengeEventExpired,
+		ChallengeID: challenge.ID,
+		From:        challenge.ChallengerID,
+		To:          challenge.ChallengedID,
+		FromName:    challenge.ChallengerName,
+		Rematch:     challenge.Rematch,
+	})
+	return nil
+}
+
+// currentPlayer returns the player unless they are unknown or deleted, in
+// which case it returns "player_not_found".
+func (s *ChallengeService) currentPlayer(ctx context.Context, playerID string) (*models.Player, error) {
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.DeletedAt != nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	return player, nil
+}
+
+func inviteEvent(c *models.Challenge) ChallengeEvent {
+	return ChallengeEvent{
+		Type:        ChallengeEventInvite,
+		ChallengeID: c.ID,
+		From:        c.ChallengerID,
+		To:          c.ChallengedID,
+		FromName:    c.ChallengerName,
+		Rematch:     c.Rematch,
+		ExpiresAt:   &c.ExpiresAt,
+	}
+}
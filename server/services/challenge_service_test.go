@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+// mockChallengeRepo keeps challenges in a slice, oldest first.
+type mockChallengeRepo struct {
+	players    *mockPlayerRepo
+	challenges []*models.Challenge
+}
+
+func (m *mockChallengeRepo) Create(ctx context.Context, c *models.Challenge) error {
+	stored := *c
+	m.challenges = append(m.challenges, &stored)
+	return nil
+}
+func (m *mockChallengeRepo) GetByID(ctx context.Context, id string) (*models.Challenge, error) {
+	for _, c := range m.challenges {
+		if c.ID == id {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockChallengeRepo) GetPending(ctx context.Context, challengerID, challengedID string) (*models.Challenge, error) {
+	for i := len(m.challenges) - 1; i >= 0; i-- {
+		if c := m.challenges[i]; c.ChallengerID == challengerID && c.ChallengedID == challengedID && c.Status == models.ChallengePending {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockChallengeRepo) ListPending(ctx context.Context, playerID string) ([]*models.Challenge, error) {
+	pending := []*models.Challenge{}
+	for _, c := range m.challenges {
+		if (c.ChallengerID == playerID || c.ChallengedID == playerID) && c.Status == models.ChallengePending &&
+			m.players.players[c.ChallengerID].DeletedAt == nil && m.players.players[c.ChallengedID].DeletedAt == nil {
+			found := *c
+			pending = append(pending, &found)
+		}
+	}
+	return pending, nil
+}
+func (m *mockChallengeRepo) ListExpired(ctx context.Context, now time.Time) ([]*models.Challenge, error) {
+	expired := []*models.Challenge{}
+	for _, c := range m.challenges {
+		if c.Status == models.ChallengePending && !now.Before(c.ExpiresAt) {
+			found := *c
+			expired = append(expired, &found)
+		}
+	}
+	return expired, nil
+}
+func (m *mockChallengeRepo) Resolve(ctx context.Context, id, status string, gameID *string, at time.Time) error {
+	for _, c := range m.challenges {
+		if c.ID == id && c.Status == models.ChallengePending {
+			c.Status = status
+			c.GameID = gameID
+			c.RespondedAt = &at
+			return nil
+		}
+	}
+	return fmt.Errorf("challenge_not_found")
+}
+
+type challengeTest struct {
+	service *ChallengeService
+	players *mockPlayerRepo
+	games   *mockGameService
+	blocks  *BlockService
+	clock   time.Time
+	events  []string
+}
+
+func newChallengeTest(ids ...string) *challengeTest {
+	players := newMockPlayerRepo()
+	for _, id := range ids {
+		players.players[id] = &models.Player{ID: id, Name: id}
+	}
+	games := &mockGameService{}
+	blocks := NewBlockService(&mockBlockRepo{players: players}, players, &mockFriendshipRepo{players: players})
+	ct := &challengeTest{
+		service: NewChallengeService(&mockChallengeRepo{players: players}, players, games, blocks, time.Hour),
+		players: players,
+		games:   games,
+		blocks:  blocks,
+		clock:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	ct.service.now = func() time.Time { return ct.clock }
+	ct.service.OnChallengeEvent(func(playerID string, event ChallengeEvent) {
+		ct.events = append(ct.events, fmt.Sprintf("%s:%s:%s>%s:%v", playerID, event.Type, event.From, event.To, event.Accepted))
+	})
+	return ct
+}
+
+func TestChallengeService_InviteAndAccept(t *testing.T) {
+	ctx := context.Background()
+	ct := newChallengeTest("alice", "bob")
+
+	challenge, err := ct.service.Invite(ctx, "alice", "bob", false)
+	if err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	if challenge.Status != models.ChallengePending || challenge.ChallengerName != "alice" {
+		t.Errorf("Unexpected challenge: %+v", challenge)
+	}
+	// Inviting again sends the same challenge.
+	again, _ := ct.service.Invite(ctx, "alice", "bob", false)
+	if again.ID != challenge.ID {
+		t.Errorf("Expected the pending challenge to be reused, got %s and %s", challenge.ID, again.ID)
+	}
+
+	// Bob was offline and picks the invite up on connecting.
+	invites, _ := ct.service.Incoming(ctx, "bob")
+	if len(invites) != 1 || invites[0].ChallengeID != challenge.ID || invites[0].From != "alice" || invites[0].FromName != "alice" {
+		t.Errorf("Expected alice's invite, got %+v", invites)
+	}
+	if invites, _ := ct.service.Incoming(ctx, "alice"); len(invites) != 0 {
+		t.Errorf("Expected no incoming challenges for alice, got %+v", invites)
+	}
+
+	if _, err := ct.service.Respond(ctx, "alice", challenge.ID, true); err == nil || err.Error() != "challenge_not_found" {
+		t.Errorf("Expected only the challenged player to answer, got %v", err)
+	}
+	accepted, err := ct.service.Respond(ctx, "bob", challenge.ID, true)
+	if err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+	if accepted.Status != models.ChallengeAccepted || accepted.GameID == nil || *accepted.GameID != "alice:bob" {
+		t.Errorf("Expected an accepted challenge with a game, got %+v", accepted)
+	}
+	if _, err := ct.service.Respond(ctx, "bob", challenge.ID, true); err == nil || err.Error() != "challenge_not_found" {
+		t.Errorf("Expected a challenge to be answered once, got %v", err)
+	}
+	if len(ct.games.createdGames) != 1 {
+		t.Errorf("Expected one game, got %d", len(ct.games.createdGames))
+	}
+	if pending, _ := ct.service.Pending(ctx, "alice"); len(pending) != 0 {
+		t.Errorf("Expected no pending challenges, got %+v", pending)
+	}
+
+	want := []string{
+		"bob:challenge_invite:alice>bob:false",
+		"bob:challenge_invite:alice>bob:false",
+		"alice:challenge_result:bob>alice:true",
+		"bob:challenge_result:bob>alice:true",
+	}
+	if fmt.Sprint(ct.events) != fmt.Sprint(want) {
+		t.Errorf("Expected events %v, got %v", want, ct.events)
+	}
+}
+
+func TestChallengeService_Decline(t *testing.T) {
+	ctx := context.Background()
+	ct := newChallengeTest("alice", "bob", "carol")
+	ct.service.Invite(ctx, "alice", "bob", true)
+	ct.service.Invite(ctx, "carol", "bob", false)
+
+	if _, err := ct.service.RespondTo(ctx, "bob", "nobody", true); err == nil || err.Error() != "challenge_not_found" {
+		t.Errorf("Expected challenge_not_found for an uninvited answer, got %v", err)
+	}
+	declined, err := ct.service.RespondTo(ctx, "bob", "alice", false)
+	if err != nil {
+		t.Fatalf("RespondTo failed: %v", err)
+	}
+	if declined.Status != models.ChallengeDeclined || declined.GameID != nil || !declined.Rematch {
+		t.Errorf("Expected a declined rematch, got %+v", declined)
+	}
+	if len(ct.games.createdGames) != 0 {
+		t.Errorf("Expected no game, got %v", ct.games.createdGames)
+	}
+	pending, _ := ct.service.Pending(ctx, "bob")
+	if len(pending) != 1 || pending[0].ChallengerID != "carol" {
+		t.Errorf("Expected carol's challenge to stay pending, got %+v", pending)
+	}
+}
+
+func TestChallengeService_InviteErrors(t *testing.T) {
+	ctx := context.Background()
+	ct := newChallengeTest("alice", "bob", "carol")
+	deletedAt := time.Now()
+	ct.players.players["gone"] = &models.Player{ID: "gone", Name: "gone", DeletedAt: &deletedAt}
+	ct.blocks.Block(ctx, "bob", "alice")
+
+	for _, tc := range []struct {
+		from, to, want string
+	}{
+		{"alice", "alice", "cannot_challenge_self"},
+		{"alice", "nobody", "player_not_found"},
+		{"alice", "gone", "player_not_found"},
+		{"alice", "bob", "player_not_found"},
+		{"bob", "alice", "player_not_found"},
+	} {
+		if _, err := ct.service.Invite(ctx, tc.from, tc.to, false); err == nil || err.Error() != tc.want {
+			t.Errorf("Invite(%s, %s): expected %s, got %v", tc.from, tc.to, tc.want, err)
+		}
+	}
+
+	// A challenge sent before a block can no longer be accepted.
+	challenge, _ := ct.service.Invite(ctx, "carol", "bob", false)
+	ct.blocks.Block(ctx, "bob", "carol")
+	if _, err := ct.service.Respond(ctx, "bob", challenge.ID, true); err == nil || err.Error() != "challenge_not_found" {
+		t.Errorf("Expected challenge_not_found after a block, got %v", err)
+	}
+}
+
+func TestChallengeService_Cancel(t *testing.T) {
+	ctx := context.Background()
+	ct := newChallengeTest("alice", "bob", "carol")
+	toBob, _ := ct.service.Invite(ctx, "alice", "bob", false)
+	toCarol, _ := ct.service.Invite(ctx, "alice", "carol", false)
+	ct.events = nil
+
+	if _, err := ct.service.Cancel(ctx, "bob", toBob.ID); err == nil || err.Error() != "challenge_not_found" {
+		t.Errorf("Expected only the challenger to cancel, got %v", err)
+	}
+	if _, err := ct.service.CancelTo(ctx, "alice", "bob"); err != nil {
+		t.Fatalf("CancelTo failed: %v", err)
+	}
+	if _, err := ct.service.Respond(ctx, "bob", toBob.ID, true); err == nil || err.Error() != "challenge_not_found" {
+		t.Errorf("Expected a cancelled challenge not to be accepted, got %v", err)
+	}
+	if _, err := ct.service.Cancel(ctx, "alice", toBob.ID); err == nil || err.Error() != "challenge_not_found" {
+		t.Errorf("Expected a challenge to be cancelled once, got %v", err)
+	}
+	// Alice's other challenge is unaffected.
+	if _, err := ct.service.Respond(ctx, "carol", toCarol.ID, true); err != nil {
+		t.Errorf("Expected carol to accept, got %v", err)
+	}
+
+	want := []string{
+		"alice:challenge_cancelled:alice>bob:false",
+		"bob:challenge_cancelled:alice>bob:false",
+		"alice:challenge_result:carol>alice:true",
+		"carol:challenge_result:carol>alice:true",
+	}
+	if fmt.Sprint(ct.events) != fmt.Sprint(want) {
+		t.Errorf("Expected events %v, got %v", want, ct.events)
+	}
+}
+
+func TestChallengeService_Expiry(t *testing.T) {
+	ctx := context.Background()
+	ct := newChallengeTest("alice", "bob", "carol")
+	first, _ := ct.service.Invite(ctx, "alice", "bob", false)
+	if !first.ExpiresAt.Equal(ct.clock.Add(time.Hour)) {
+		t.Errorf("Expected the challenge to expire in an hour, got %v", first.ExpiresAt)
+	}
+	ct.clock = ct.clock.Add(30 * time.Minute)
+	second, _ := ct.service.Invite(ctx, "carol", "bob", false)
+
+	ct.clock = ct.clock.Add(30 * time.Minute)
+	if _, err := ct.service.Respond(ctx, "bob", first.ID, true); err == nil || err.Error() != "challenge_expired" {
+		t.Errorf("Expected challenge_expired, got %v", err)
+	}
+	if invites, _ := ct.service.Incoming(ctx, "bob"); len(invites) != 1 || invites[0].ChallengeID != second.ID {
+		t.Errorf("Expected only carol's invite, got %+v", invites)
+	}
+
+	ct.clock = ct.clock.Add(30 * time.Minute)
+	ct.events = nil
+	n, err := ct.service.ExpireChallenges(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected one challenge to expire, got %d, %v", n, err)
+	}
+	want := []string{"carol:challenge_expired:carol>bob:false", "bob:challenge_expired:carol>bob:false"}
+	if fmt.Sprint(ct.events) != fmt.Sprint(want) {
+		t.Errorf("Expected events %v, got %v", want, ct.events)
+	}
+	if n, _ := ct.service.ExpireChallenges(ctx); n != 0 {
+		t.Errorf("Expected nothing left to expire, got %d", n)
+	}
+
+	// Inviting again after expiry sends a new challenge.
+	again, _ := ct.service.Invite(ctx, "carol", "bob", false)
+	if again.ID == second.ID || again.Status != models.ChallengePending {
+		t.Errorf("Expected a new challenge, got %+v", again)
+	}
+	if len(ct.games.createdGames) != 0 {
+		t.Errorf("Expected no games, got %v", ct.games.createdGames)
+	}
+}
+
+func TestChallengeService_ListenersRunUnlocked(t *testing.T) {
+	ctx := context.Background()
+	ct := newChallengeTest("alice", "bob")
+	// A listener may itself change challenges, e.g. decline an invite it is
+	// told about, which would deadlock if it ran under respondMu.
+	var declined *models.Challenge
+	ct.service.OnChallengeEvent(func(playerID string, event ChallengeEvent) {
+		if event.Type == ChallengeEventInvite {
+			declined, _ = ct.service.Respond(ctx, playerID, event.ChallengeID, false)
+		}
+	})
+	if _, err := ct.service.Invite(ctx, "alice", "bob", false); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	if declined == nil || declined.Status != models.ChallengeDeclined {
+		t.Errorf("Expected the listener to decline the challenge, got %+v", declined)
+	}
+}
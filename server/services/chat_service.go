@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+// Limits on game chat.
+const (
+	// MaxChatMessageLength is the longest chat message, in characters.
+	MaxChatMessageLength = 200
+	// chatHistoryLimit is how many of a game's latest messages are sent to a
+	// player who (re)joins it.
+	chatHistoryLimit = 100
+)
+
+// QuickPhrases are the messages players who only want quick phrases still
+// receive.
+var QuickPhrases = []string{
+	"Good luck!",
+	"Good game",
+	"Nice guess",
+	"Well played",
+	"Close one!",
+	"Thanks",
+	"Oops",
+	"Rematch?",
+}
+
+// ChatService manages the chat of each game. The players of a game may chat,
+// and each may mute the other or limit the chat to quick phrases.
+type ChatService struct {
+	repo   repositories.ChatRepositoryI
+	games  repositories.GameRepositoryI
+	blocks *BlockService
+	filter *WordFilter
+	// spectators allows players watching a game to read its chat.
+	spectators bool
+	now        func() time.Time
+}
+
+// NewChatService creates a new ChatService. Messages containing any of the
+// blocked words are rejected. If spectators is set, anyone watching a game
+// can read its chat; otherwise only its players can.
+func NewChatService(repo repositories.ChatRepositoryI, games repositories.GameRepositoryI, blocks *BlockService, blocked []string, spectators bool) *ChatService {
+	return &ChatService{repo: repo, games: games, blocks: blocks, filter: NewWordFilter(blocked), spectators: spectators, now: time.Now}
+}
+
+// Send posts a message from the player to the game's chat. It returns
+// "game_not_found", "not_a_participant" unless the player plays the game,
+// "empty_message", "message_too_long" and "message_not_allowed" if the message
+// contains a blocked word.
+func (s *ChatService) Send(ctx context.Context, gameID, playerID, text string) (*models.ChatMessage, error) {
+	game, err := s.game(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if !game.HasPlayer(playerID) {
+		return nil, fmt.Errorf("not_a_participant")
+	}
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "":
+		return nil, fmt.Errorf("empty_message")
+	case utf8.RuneCountInString(text) > MaxChatMessageLength:
+		return nil, fmt.Errorf("message_too_long")
+	case s.filter.ContainsWord(text):
+		return nil, fmt.Errorf("message_not_allowed")
+	}
+	msg := &models.ChatMessage{
+		GameID:      gameID,
+		PlayerID:    playerID,
+		Text:        text,
+		QuickPhrase: isQuickPhrase(text),
+		CreatedAt:   s.now(),
+	}
+	if err := s.repo.AddMessage(ctx, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// SetMode sets which of their opponent's messages the player receives in the
+// game: models.ChatModeAll, models.ChatModeQuickPhrases or
+// models.ChatModeMuted. It returns "invalid_chat_mode", "game_not_found" and
+// "not_a_participant".
+func (s *ChatService) SetMode(ctx context.Context, gameID, playerID, mode string) error {
+	switch mode {
+	case models.ChatModeAll, models.ChatModeQuickPhrases, models.ChatModeMuted:
+	default:
+		return fmt.Errorf("invalid_chat_mode")
+	}
+	game, err := s.game(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	if !game.HasPlayer(playerID) {
+		return fmt.Errorf("not_a_participant")
+	}
+	return s.repo.SetMode(ctx, gameID, playerID, mode)
+}
+
+// Mode returns the player's chat mode in the game.
+func (s *ChatService) Mode(ctx context.Context, gameID, playerID string) (string, error) {
+	return s.repo.GetMode(ctx, gameID, playerID)
+}
+
+// History returns the game's latest messages that viewerID may see, oldest
+// first. viewerID is "" for viewers who are not logged in.
+func (s *ChatService) History(ctx context.Context, gameID, viewerID string) ([]*models.ChatMessage, error) {
+	game, err := s.game(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.repo.ListByGame(ctx, gameID, chatHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	visible := []*models.ChatMessage{}
+	for _, msg := range messages {
+		ok, err := s.canSee(ctx, game, viewerID, msg)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, msg)
+		}
+	}
+	return visible, nil
+}
+
+// CanSee reports whether viewerID may see the message. viewerID is "" for
+// viewers who are not logged in.
+func (s *ChatService) CanSee(ctx context.Context, viewerID string, msg *models.ChatMessage) (bool, error) {
+	game, err := s.game(ctx, msg.GameID)
+	if err != nil {
+		return false, err
+	}
+	return s.canSee(ctx, game, viewerID, msg)
+}
+
+func (s *ChatService) canSee(ctx context.Context, game *models.Game, viewerID string, msg *models.ChatMessage) (bool, error) {
+	if viewerID == msg.PlayerID {
+		return true, nil
+	}
+	if !game.HasPlayer(viewerID) && !s.spectators {
+		return false, nil
+	}
+	if viewerID != "" {
+		if blocked, err := s.blocks.IsBlocked(ctx, viewerID, msg.PlayerID); err != nil || blocked {
+			return false, err
+		}
+	}
+	if !game.HasPlayer(viewerID) {
+		return true, nil
+	}
+	mode, err := s.repo.GetMode(ctx, game.ID, viewerID)
+	if err != nil {
+		return false, err
+	}
+	switch mode {
+	case models.ChatModeMuted:
+		return false, nil
+	case models.ChatModeQuickPhrases:
+		return msg.QuickPhrase, nil
+	default:
+		return true, nil
+	}
+}
+
+func (s *ChatService) game(ctx context.Context, gameID string) (*models.Game, error) {
+	game, err := s.games.GetByID(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, fmt.Errorf("game_not_found")
+	}
+	return game, nil
+}
+
+// isQuickPhrase reports whether text is one of the QuickPhrases.
+func isQuickPhrase(text string) bool {
+	for _, phrase := range QuickPhrases {
+		if text == phrase {
+			return true
+		}
+	}
+	return false
+}
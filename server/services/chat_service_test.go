@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"battle-wordle/server/models"
+)
+
+// mockChatRepo keeps messages in a slice and modes as "game/player" keys.
+type mockChatRepo struct {
+	messages []*models.ChatMessage
+	modes    map[string]string
+}
+
+func (m *mockChatRepo) AddMessage(ctx context.Context, msg *models.ChatMessage) error {
+	m.messages = append(m.messages, msg)
+	msg.ID = int64(len(m.messages))
+	return nil
+}
+func (m *mockChatRepo) ListByGame(ctx context.Context, gameID string, limit int) ([]*models.ChatMessage, error) {
+	messages := []*models.ChatMessage{}
+	for _, msg := range m.messages {
+		if msg.GameID == gameID {
+			messages = append(messages, msg)
+		}
+	}
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return messages, nil
+}
+func (m *mockChatRepo) ListByPlayer(ctx context.Context, playerID string) ([]*models.ChatMessage, error) {
+	messages := []*models.ChatMessage{}
+	for _, msg := range m.messages {
+		if msg.PlayerID == playerID {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+func (m *mockChatRepo) DeleteByPlayer(ctx context.Context, playerID string) error {
+	kept := m.messages[:0]
+	for _, msg := range m.messages {
+		if msg.PlayerID != playerID {
+			kept = append(kept, msg)
+		}
+	}
+	m.messages = kept
+	return nil
+}
+func (m *mockChatRepo) GetMode(ctx context.Context, gameID, playerID string) (string, error) {
+	if mode, ok := m.modes[gameID+"/"+playerID]; ok {
+		return mode, nil
+	}
+	return models.ChatModeAll, nil
+}
+func (m *mockChatRepo) SetMode(ctx context.Context, gameID, playerID, mode string) error {
+	if m.modes == nil {
+		m.modes = make(map[string]string)
+	}
+	m.modes[gameID+"/"+playerID] = mode
+	return nil
+}
+
+func newChatTest(spectators bool) (*ChatService, *BlockService) {
+	players := newMockPlayerRepo()
+	for _, id := range []string{"alice", "bob", "carol"} {
+		players.players[id] = &models.Player{ID: id, Name: id, Registered: true}
+	}
+	games := &mockGameRepo{created: &models.Game{ID: "g1", FirstPlayer: "alice", SecondPlayer: "bob", CurrentPlayer: "alice"}}
+	blocks := NewBlockService(&mockBlockRepo{players: players}, players, &mockFriendshipRepo{players: players})
+	return NewChatService(&mockChatRepo{}, games, blocks, []string{"darn", "ass"}, spectators), blocks
+}
+
+// chatTexts returns the texts of the game's messages viewerID may see.
+func chatTexts(t *testing.T, s *ChatService, viewerID string) string {
+	t.Helper()
+	messages, err := s.History(context.Background(), "g1", viewerID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	texts := []string{}
+	for _, msg := range messages {
+		texts = append(texts, msg.Text)
+	}
+	return strings.Join(texts, "|")
+}
+
+func TestChatService_Send(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newChatTest(false)
+
+	for _, tc := range []struct {
+		gameID, playerID, text, want string
+	}{
+		{"nope", "alice", "hi", "game_not_found"},
+		{"g1", "carol", "hi", "not_a_participant"},
+		{"g1", "", "hi", "not_a_participant"},
+		{"g1", "alice", "   ", "empty_message"},
+		{"g1", "alice", strings.Repeat("é", MaxChatMessageLength+1), "message_too_long"},
+		{"g1", "alice", "D.4.R.N it", "message_not_allowed"},
+		{"g1", "alice", "what an A$$!", "message_not_allowed"},
+	} {
+		if _, err := s.Send(ctx, tc.gameID, tc.playerID, tc.text); err == nil || err.Error() != tc.want {
+			t.Errorf("Send(%s, %s, %q): expected %s, got %v", tc.gameID, tc.playerID, tc.text, tc.want, err)
+		}
+	}
+
+	msg, err := s.Send(ctx, "g1", "alice", "  Good game ")
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if msg.ID == 0 || msg.Text != "Good game" || !msg.QuickPhrase || msg.PlayerID != "alice" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+	if _, err := s.Send(ctx, "g1", "alice", strings.Repeat("é", MaxChatMessageLength)); err != nil {
+		t.Errorf("Expected a message at the length limit to be allowed, got %v", err)
+	}
+	// Blocked words are matched as whole words, not inside or across them.
+	for _, text := range []string{"class", "was simple", "Nice pass!"} {
+		if _, err := s.Send(ctx, "g1", "alice", text); err != nil {
+			t.Errorf("Send(%q): expected the message to be allowed, got %v", text, err)
+		}
+	}
+}
+
+func TestChatService_Visibility(t *testing.T) {
+	ctx := context.Background()
+	s, blocks := newChatTest(false)
+	s.Send(ctx, "g1", "alice", "Nice guess")
+	s.Send(ctx, "g1", "alice", "lucky")
+
+	if got := chatTexts(t, s, "bob"); got != "Nice guess|lucky" {
+		t.Errorf("Expected bob to see everything, got %q", got)
+	}
+	if got := chatTexts(t, s, "carol") + chatTexts(t, s, ""); got != "" {
+		t.Errorf("Expected spectators to see nothing, got %q", got)
+	}
+
+	if err := s.SetMode(ctx, "g1", "bob", "shouting"); err == nil || err.Error() != "invalid_chat_mode" {
+		t.Errorf("Expected invalid_chat_mode, got %v", err)
+	}
+	if err := s.SetMode(ctx, "g1", "carol", models.ChatModeMuted); err == nil || err.Error() != "not_a_participant" {
+		t.Errorf("Expected not_a_participant, got %v", err)
+	}
+	s.SetMode(ctx, "g1", "bob", models.ChatModeQuickPhrases)
+	if got := chatTexts(t, s, "bob"); got != "Nice guess" {
+		t.Errorf("Expected only quick phrases, got %q", got)
+	}
+	s.SetMode(ctx, "g1", "bob", models.ChatModeMuted)
+	if got := chatTexts(t, s, "bob"); got != "" {
+		t.Errorf("Expected alice to be muted, got %q", got)
+	}
+	// Muting the opponent does not hide one's own messages.
+	s.Send(ctx, "g1", "bob", "gl")
+	if got := chatTexts(t, s, "bob"); got != "gl" {
+		t.Errorf("Expected bob to see their own message, got %q", got)
+	}
+
+	s.SetMode(ctx, "g1", "bob", models.ChatModeAll)
+	blocks.Block(ctx, "alice", "bob")
+	if got := chatTexts(t, s, "bob"); got != "gl" {
+		t.Errorf("Expected messages between blocked players to be hidden, got %q", got)
+	}
+	if ok, _ := s.CanSee(ctx, "alice", &models.ChatMessage{GameID: "g1", PlayerID: "bob", Text: "gl"}); ok {
+		t.Error("Expected alice not to see bob's messages")
+	}
+}
+
+func TestChatService_Spectators(t *testing.T) {
+	ctx := context.Background()
+	s, blocks := newChatTest(true)
+	s.Send(ctx, "g1", "alice", "hello")
+	s.Send(ctx, "g1", "bob", "hi")
+
+	if got := chatTexts(t, s, ""); got != "hello|hi" {
+		t.Errorf("Expected anonymous spectators to read the chat, got %q", got)
+	}
+	blocks.Block(ctx, "carol", "bob")
+	if got := chatTexts(t, s, "carol"); got != "hello" {
+		t.Errorf("Expected carol not to see the player carol blocked, got %q", got)
+	}
+	if _, err := s.Send(ctx, "g1", "carol", "hey"); err == nil || err.Error() != "not_a_participant" {
+		t.Errorf("Expected spectators not to chat, got %v", err)
+	}
+}
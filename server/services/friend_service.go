@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+// Friend event types.
+const (
+	FriendEventRequest  = "friend_request"
+	FriendEventAccepted = "friend_accepted"
+)
+
+// FriendEvent tells a player that another player sent them a friend request
+// or accepted theirs.
+type FriendEvent struct {
+	Type     string `json:"type"`
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+}
+
+// FriendListener is called with the player an event is for.
+type FriendListener func(playerID string, event FriendEvent)
+
+// FriendService manages friend requests and friendships between registered
+// players.
+type FriendService struct {
+	repo     repositories.FriendshipRepositoryI
+	players  repositories.PlayerRepositoryI
+	presence *MatchmakingService
+	blocks   *BlockService
+	now      func() time.Time
+	// mu guards listeners.
+	mu        sync.RWMutex
+	listeners []FriendListener
+}
+
+// NewFriendService creates a new FriendService. Friends are shown online while
+// they are connected to presence. Players who blocked each other cannot become
+// friends.
+func NewFriendService(repo repositories.FriendshipRepositoryI, players repositories.PlayerRepositoryI, presence *MatchmakingService, blocks *BlockService) *FriendService {
+	return &FriendService{repo: repo, players: players, presence: presence, blocks: blocks, now: time.Now}
+}
+
+// OnFriendEvent registers a listener for friend requests and acceptances.
+func (s *FriendService) OnFriendEvent(listener FriendListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *FriendService) notify(playerID string, event FriendEvent) {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(playerID, event)
+	}
+}
+
+// List returns the player's friends and pending requests in both directions,
+// with the friends' online status.
+func (s *FriendService) List(ctx context.Context, playerID string) ([]*models.Friend, error) {
+	friends, err := s.repo.ListByPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range friends {
+		f.Online = f.Status == models.FriendshipAccepted && s.presence.IsOnline(f.PlayerID)
+	}
+	return friends, nil
+}
+
+// Request sends a friend request from the player to friendID. If friendID
+// already asked the player, their request is accepted instead. It returns
+// "cannot_friend_self", "player_not_registered" for guests,
+// "player_not_found" unless friendID is a registered player who has not
+// blocked or been blocked by the player,
+// "friend_request_exists" if the player already asked and "already_friends".
+func (s *FriendService) Request(ctx context.Context, playerID, friendID string) (*models.Friend, error) {
+	if playerID == friendID {
+		return nil, fmt.Errorf("cannot_friend_self")
+	}
+	player, err := s.currentPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if !player.Registered {
+		return nil, fmt.Errorf("player_not_registered")
+	}
+	friend, err := s.currentPlayer(ctx, friendID)
+	if err != nil {
+		return nil, err
+	}
+	if !friend.Registered {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	// Blocked players are not told that they are blocked.
+	if blocked, err := s.blocks.IsBlocked(ctx, playerID, friendID); err != nil {
+		return nil, err
+	} else if blocked {
+		return nil, fmt.Errorf("player_not_found")
+	}
+
+	existing, err := s.repo.Get(ctx, playerID, friendID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		switch {
+		case existing.Status == models.FriendshipAccepted:
+			return nil, fmt.Errorf("already_friends")
+		case existing.RequesterID == friendID:
+			return s.Accept(ctx, playerID, friendID)
+		default:
+			return nil, fmt.Errorf("friend_request_exists")
+		}
+	}
+
+	now := s.now()
+	if err := s.repo.Request(ctx, playerID, friendID, now); err != nil {
+		if err.Error() == "friendship_exists" {
+			return nil, fmt.Errorf("friend_request_exists")
+		}
+		return nil, err
+	}
+	s.notify(friendID, FriendEvent{Type: FriendEventRequest, PlayerID: player.ID, Name: player.Name})
+	return &models.Friend{PlayerID: friend.ID, Name: friend.Name, Status: models.FriendshipPending, Since: now}, nil
+}
+
+// Accept accepts the friend request requesterID sent to the player. It returns
+// "friend_request_not_found" if there is no such request.
+func (s *FriendService) Accept(ctx context.Context, playerID, requesterID string) (*models.Friend, error) {
+	player, err := s.currentPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	requester, err := s.players.GetByID(ctx, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if requester == nil || requester.DeletedAt != nil {
+		return nil, fmt.Errorf("friend_request_not_found")
+	}
+	now := s.now()
+	if err := s.repo.Accept(ctx, requesterID, playerID, now); err != nil {
+		return nil, err
+	}
+	s.notify(requesterID, FriendEvent{Type: FriendEventAccepted, PlayerID: player.ID, Name: player.Name})
+	return &models.Friend{
+		PlayerID: requester.ID,
+		Name:     requester.Name,
+		Status:   models.FriendshipAccepted,
+		Online:   s.presence.IsOnline(requester.ID),
+		Since:    now,
+	}, nil
+}
+
+// Decline turns down the friend request requesterID sent to the player. It
+// returns "friend_request_not_found" if there is no such request.
+func (s *FriendService) Decline(ctx context.Context, playerID, requesterID string) error {
+	existing, err := s.repo.Get(ctx, playerID, requesterID)
+	if err != nil {
+		return err
+	}
+	if existing == nil || existing.Status != models.FriendshipPending || existing.RequesterID != requesterID {
+		return fmt.Errorf("friend_request_not_found")
+	}
+	return s.repo.Delete(ctx, playerID, requesterID)
+}
+
+// Remove ends a friendship, or withdraws a friend request, between the player
+// and friendID. It returns "friendship_not_found" if there is none.
+func (s *FriendService) Remove(ctx context.Context, playerID, friendID string) error {
+	return s.repo.Delete(ctx, playerID, friendID)
+}
+
+// currentPlayer returns the player unless they are unknown or deleted, in
+// which case it returns "player_not_found".
+func (s *FriendService) currentPlayer(ctx context.Context, playerID string) (*models.Player, error) {
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.DeletedAt != nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	return player, nil
+}
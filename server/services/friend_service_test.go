@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+// mockFriendshipRepo keeps friendships in a slice.
+type mockFriendshipRepo struct {
+	players     *mockPlayerRepo
+	friendships []*models.Friendship
+}
+
+func (m *mockFriendshipRepo) find(a, b string) int {
+	for i, f := range m.friendships {
+		if (f.RequesterID == a && f.AddresseeID == b) || (f.RequesterID == b && f.AddresseeID == a) {
+			return i
+		}
+	}
+	return -1
+}
+func (m *mockFriendshipRepo) Get(ctx context.Context, playerID, otherID string) (*models.Friendship, error) {
+	if i := m.find(playerID, otherID); i >= 0 {
+		return m.friendships[i], nil
+	}
+	return nil, nil
+}
+func (m *mockFriendshipRepo) Request(ctx context.Context, requesterID, addresseeID string, at time.Time) error {
+	if m.find(requesterID, addresseeID) >= 0 {
+		return fmt.Errorf("friendship_exists")
+	}
+	m.friendships = append(m.friendships, &models.Friendship{RequesterID: requesterID, AddresseeID: addresseeID, Status: models.FriendshipPending, CreatedAt: at})
+	return nil
+}
+func (m *mockFriendshipRepo) Accept(ctx context.Context, requesterID, addresseeID string, at time.Time) error {
+	i := m.find(requesterID, addresseeID)
+	if i < 0 || m.friendships[i].RequesterID != requesterID || m.friendships[i].Status != models.FriendshipPending {
+		return fmt.Errorf("friend_request_not_found")
+	}
+	m.friendships[i].Status = models.FriendshipAccepted
+	m.friendships[i].AcceptedAt = &at
+	return nil
+}
+func (m *mockFriendshipRepo) Delete(ctx context.Context, playerID, otherID string) error {
+	i := m.find(playerID, otherID)
+	if i < 0 {
+		return fmt.Errorf("friendship_not_found")
+	}
+	m.friendships = append(m.friendships[:i], m.friendships[i+1:]...)
+	return nil
+}
+func (m *mockFriendshipRepo) ListByPlayer(ctx context.Context, playerID string) ([]*models.Friend, error) {
+	friends := []*models.Friend{}
+	for _, f := range m.friendships {
+		otherID := f.AddresseeID
+		if otherID == playerID {
+			otherID = f.RequesterID
+		} else if f.RequesterID != playerID {
+			continue
+		}
+		friends = append(friends, &models.Friend{
+			PlayerID: otherID,
+			Name:     m.players.players[otherID].Name,
+			Status:   f.Status,
+			Incoming: f.Status == models.FriendshipPending && f.AddresseeID == playerID,
+			Since:    f.CreatedAt,
+		})
+	}
+	return friends, nil
+}
+
+type friendTest struct {
+	service  *FriendService
+	players  *mockPlayerRepo
+	presence *MatchmakingService
+	blocks   *BlockService
+	events   []string
+}
+
+func newFriendTest() *friendTest {
+	players := newMockPlayerRepo()
+	presence := NewMatchmakingService(&mockGameService{})
+	friendships := &mockFriendshipRepo{players: players}
+	blocks := NewBlockService(&mockBlockRepo{players: players}, players, friendships)
+	ft := &friendTest{
+		service:  NewFriendService(friendships, players, presence, blocks),
+		players:  players,
+		presence: presence,
+		blocks:   blocks,
+	}
+	ft.service.OnFriendEvent(func(playerID string, event FriendEvent) {
+		ft.events = append(ft.events, playerID+":"+event.Type+":"+event.Name)
+	})
+	return ft
+}
+
+func (ft *friendTest) player(id string, registered bool) {
+	p := &models.Player{ID: id, Name: id, Registered: registered}
+	ft.players.players[id] = p
+	ft.players.byName[id] = p
+}
+
+func TestFriendService_RequestAndAccept(t *testing.T) {
+	ctx := context.Background()
+	ft := newFriendTest()
+	ft.player("alice", true)
+	ft.player("bob", true)
+	ft.presence.RegisterConnection("alice", "conn")
+
+	if _, err := ft.service.Request(ctx, "bob", "alice"); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if _, err := ft.service.Request(ctx, "bob", "alice"); err == nil || err.Error() != "friend_request_exists" {
+		t.Errorf("Expected friend_request_exists, got %v", err)
+	}
+	friends, _ := ft.service.List(ctx, "alice")
+	if len(friends) != 1 || !friends[0].Incoming || friends[0].Status != models.FriendshipPending || friends[0].Online {
+		t.Fatalf("Expected an incoming request, got %+v", friends)
+	}
+
+	friend, err := ft.service.Accept(ctx, "alice", "bob")
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	if friend.PlayerID != "bob" || friend.Status != models.FriendshipAccepted {
+		t.Errorf("Unexpected friend: %+v", friend)
+	}
+	friends, _ = ft.service.List(ctx, "bob")
+	if len(friends) != 1 || !friends[0].Online || friends[0].Incoming {
+		t.Errorf("Expected alice as an online friend, got %+v", friends)
+	}
+	if _, err := ft.service.Request(ctx, "alice", "bob"); err == nil || err.Error() != "already_friends" {
+		t.Errorf("Expected already_friends, got %v", err)
+	}
+	want := []string{"alice:friend_request:bob", "bob:friend_accepted:alice"}
+	if fmt.Sprint(ft.events) != fmt.Sprint(want) {
+		t.Errorf("Expected events %v, got %v", want, ft.events)
+	}
+}
+
+func TestFriendService_CrossedRequestsBecomeFriends(t *testing.T) {
+	ctx := context.Background()
+	ft := newFriendTest()
+	ft.player("alice", true)
+	ft.player("bob", true)
+
+	ft.service.Request(ctx, "alice", "bob")
+	friend, err := ft.service.Request(ctx, "bob", "alice")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if friend.Status != models.FriendshipAccepted {
+		t.Errorf("Expected a request back to accept the first one, got %+v", friend)
+	}
+}
+
+func TestFriendService_DeclineAndRemove(t *testing.T) {
+	ctx := context.Background()
+	ft := newFriendTest()
+	ft.player("alice", true)
+	ft.player("bob", true)
+	ft.player("guest", false)
+
+	for _, tc := range []struct {
+		from, to, want string
+	}{
+		{"alice", "alice", "cannot_friend_self"},
+		{"guest", "alice", "player_not_registered"},
+		{"alice", "guest", "player_not_found"},
+		{"alice", "nobody", "player_not_found"},
+	} {
+		if _, err := ft.service.Request(ctx, tc.from, tc.to); err == nil || err.Error() != tc.want {
+			t.Errorf("Request(%s, %s): expected %s, got %v", tc.from, tc.to, tc.want, err)
+		}
+	}
+
+	ft.service.Request(ctx, "alice", "bob")
+	// Only the addressee can decline.
+	if err := ft.service.Decline(ctx, "alice", "bob"); err == nil || err.Error() != "friend_request_not_found" {
+		t.Errorf("Expected friend_request_not_found, got %v", err)
+	}
+	if err := ft.service.Decline(ctx, "bob", "alice"); err != nil {
+		t.Fatalf("Decline failed: %v", err)
+	}
+	if friends, _ := ft.service.List(ctx, "alice"); len(friends) != 0 {
+		t.Errorf("Expected the request to be gone, got %+v", friends)
+	}
+
+	ft.service.Request(ctx, "alice", "bob")
+	ft.service.Accept(ctx, "bob", "alice")
+	if err := ft.service.Remove(ctx, "bob", "alice"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := ft.service.Remove(ctx, "bob", "alice"); err == nil || err.Error() != "friendship_not_found" {
+		t.Errorf("Expected friendship_not_found, got %v", err)
+	}
+}
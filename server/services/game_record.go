@@ -0,0 +1,313 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+// A game record is a plain text description of one game, in the spirit of
+// PGN for chess. Records start with headers and are followed by one line per
+// guess; several records are separated by blank lines:
+//
+//	[Event "Battle Wordle"]
+//	[GameID "0b6e7a2c-..."]
+//	[Date "2025-08-14T12:00:00Z"]
+//	[FirstPlayer "alice"]
+//	[FirstPlayerID "..."]
+//	[SecondPlayer "bob"]
+//	[SecondPlayerID "..."]
+//	[Mode "classic"]
+//	[WordList "3f2a9c01b7d4"]
+//	[Solution "APPLE"]
+//	[Result "0-1"]
+//
+//	1. CRANE ..Y.G {2025-08-14T12:00:09Z}
+//	2. APPLE GGGGG {2025-08-14T12:00:21Z}
+//
+// Feedback has one character per letter: G for correct, Y for present and .
+// for absent. Timestamps are optional. Results are 1-0 when the first player
+// won, 0-1 when the second player won, 1/2-1/2 for a draw and * for a game in
+// progress, whose record has no Solution header. Lines starting with ; are
+// comments.
+
+const (
+	RecordResultFirstWins  = "1-0"
+	RecordResultSecondWins = "0-1"
+	RecordResultDraw       = "1/2-1/2"
+	RecordResultOngoing    = "*"
+
+	gameRecordEvent = "Battle Wordle"
+)
+
+// GameRecord is a parsed or to-be-formatted game record.
+type GameRecord struct {
+	GameID           string
+	Date             time.Time
+	FirstPlayerName  string
+	FirstPlayerID    string
+	SecondPlayerName string
+	SecondPlayerID   string
+	Mode             string
+	// WordList identifies the word list the game was played with.
+	WordList string
+	// Solution is empty while the game is in progress.
+	Solution string
+	// Result is one of the RecordResult constants.
+	Result string
+	Moves  []GameRecordMove
+}
+
+// GameRecordMove is one guess in a game record.
+type GameRecordMove struct {
+	Guess    string
+	Feedback []FeedbackType
+	// At is when the guess was made, or nil if unknown.
+	At *time.Time
+}
+
+// GameRecordError reports a malformed or inconsistent game record.
+type GameRecordError struct {
+	// Line is the line the problem was found on, or 0 if it concerns a whole record.
+	Line int
+	Msg  string
+}
+
+func (e *GameRecordError) Error() string {
+	if e.Line == 0 {
+		return "invalid game record: " + e.Msg
+	}
+	return fmt.Sprintf("invalid game record at line %d: %s", e.Line, e.Msg)
+}
+
+var feedbackSymbols = map[FeedbackType]byte{
+	FeedbackCorrect: 'G',
+	FeedbackPresent: 'Y',
+	FeedbackAbsent:  '.',
+}
+
+// WriteGameRecord writes rec in the game record format, followed by a blank line.
+func WriteGameRecord(w io.Writer, rec *GameRecord) error {
+	var b strings.Builder
+	header := func(key, value string) {
+		fmt.Fprintf(&b, "[%s %s]\n", key, strconv.Quote(value))
+	}
+	header("Event", gameRecordEvent)
+	header("GameID", rec.GameID)
+	header("Date", rec.Date.UTC().Format(time.RFC3339))
+	header("FirstPlayer", rec.FirstPlayerName)
+	header("FirstPlayerID", rec.FirstPlayerID)
+	header("SecondPlayer", rec.SecondPlayerName)
+	header("SecondPlayerID", rec.SecondPlayerID)
+	header("Mode", rec.Mode)
+	if rec.WordList != "" {
+		header("WordList", rec.WordList)
+	}
+	if rec.Solution != "" {
+		header("Solution", rec.Solution)
+	}
+	header("Result", rec.Result)
+	b.WriteString("\n")
+	for i, move := range rec.Moves {
+		symbols := make([]byte, len(move.Feedback))
+		for j, f := range move.Feedback {
+			symbols[j] = feedbackSymbols[f]
+		}
+		fmt.Fprintf(&b, "%d. %s %s", i+1, move.Guess, symbols)
+		if move.At != nil {
+			fmt.Fprintf(&b, " {%s}", move.At.UTC().Format(time.RFC3339Nano))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var (
+	recordHeaderPattern = regexp.MustCompile(`^\[([A-Za-z]+) ("(?:[^"\\]|\\.)*")\]$`)
+	recordMovePattern   = regexp.MustCompile(`^(\d+)\.\s+([A-Za-z]+)\s+([GY.]+)(?:\s+\{([^}]*)\})?$`)
+)
+
+// ParseGameRecords reads every game record in r. It checks the syntax only;
+// use ValidateGameRecord to check that a record describes a legal game.
+func ParseGameRecords(r io.Reader) ([]*GameRecord, error) {
+	var records []*GameRecord
+	var rec *GameRecord
+	inMoves := false
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") {
+			// A blank line after the moves ends the record.
+			if text == "" && inMoves {
+				rec, inMoves = nil, false
+			}
+			continue
+		}
+
+		if m := recordHeaderPattern.FindStringSubmatch(text); m != nil {
+			if inMoves {
+				return nil, &GameRecordError{Line: line, Msg: "header after moves"}
+			}
+			if rec == nil {
+				rec = &GameRecord{}
+				records = append(records, rec)
+			}
+			value, err := strconv.Unquote(m[2])
+			if err != nil {
+				return nil, &GameRecordError{Line: line, Msg: "malformed header value"}
+			}
+			if err := rec.setHeader(m[1], value); err != nil {
+				return nil, &GameRecordError{Line: line, Msg: err.Error()}
+			}
+			continue
+		}
+
+		m := recordMovePattern.FindStringSubmatch(text)
+		if m == nil {
+			return nil, &GameRecordError{Line: line, Msg: "expected a header or a move"}
+		}
+		if rec == nil {
+			return nil, &GameRecordError{Line: line, Msg: "move before headers"}
+		}
+		inMoves = true
+		if n, _ := strconv.Atoi(m[1]); n != len(rec.Moves)+1 {
+			return nil, &GameRecordError{Line: line, Msg: fmt.Sprintf("expected move %d", len(rec.Moves)+1)}
+		}
+		move := GameRecordMove{Guess: strings.ToUpper(m[2])}
+		for _, c := range []byte(m[3]) {
+			for f, symbol := range feedbackSymbols {
+				if symbol == c {
+					move.Feedback = append(move.Feedback, f)
+				}
+			}
+		}
+		if m[4] != "" {
+			at, err := time.Parse(time.RFC3339Nano, m[4])
+			if err != nil {
+				return nil, &GameRecordError{Line: line, Msg: "malformed timestamp"}
+			}
+			move.At = &at
+		}
+		rec.Moves = append(rec.Moves, move)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (rec *GameRecord) setHeader(key, value string) error {
+	switch key {
+	case "GameID":
+		rec.GameID = value
+	case "Date":
+		date, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("malformed Date header")
+		}
+		rec.Date = date
+	case "FirstPlayer":
+		rec.FirstPlayerName = value
+	case "FirstPlayerID":
+		rec.FirstPlayerID = value
+	case "SecondPlayer":
+		rec.SecondPlayerName = value
+	case "SecondPlayerID":
+		rec.SecondPlayerID = value
+	case "Mode":
+		if value != models.ModeClassic {
+			return fmt.Errorf("unknown Mode %q", value)
+		}
+		rec.Mode = value
+	case "WordList":
+		rec.WordList = value
+	case "Solution":
+		if len(value) != solutionLength || strings.IndexFunc(value, isNotASCIILetter) >= 0 {
+			return fmt.Errorf("the Solution header must be %d letters", solutionLength)
+		}
+		rec.Solution = strings.ToUpper(value)
+	case "Result":
+		rec.Result = value
+	}
+	// Other headers, such as Event, are informational.
+	return nil
+}
+
+func isNotASCIILetter(r rune) bool {
+	return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z')
+}
+
+// ValidateGameRecord checks that a finished game record describes a legal
+// game: every feedback matches getFeedback against the solution, the game ends
+// exactly when the solution is guessed or the guesses run out, the result
+// agrees with how it ended, and timestamps never go backwards.
+func ValidateGameRecord(rec *GameRecord) error {
+	if rec.FirstPlayerName == "" && rec.FirstPlayerID == "" || rec.SecondPlayerName == "" && rec.SecondPlayerID == "" {
+		return &GameRecordError{Msg: "both players are required"}
+	}
+	if rec.Date.IsZero() {
+		return &GameRecordError{Msg: "Date header is required"}
+	}
+	if rec.Solution == "" || rec.Result == "" || rec.Result == RecordResultOngoing {
+		return &GameRecordError{Msg: "only finished games with a Solution header can be imported"}
+	}
+	if len(rec.Moves) == 0 || len(rec.Moves) > maxGuesses {
+		return &GameRecordError{Msg: fmt.Sprintf("a game has between 1 and %d guesses", maxGuesses)}
+	}
+
+	timed := rec.Moves[0].At != nil
+	previous := rec.Date
+	solved := false
+	for i, move := range rec.Moves {
+		if solved {
+			return &GameRecordError{Msg: fmt.Sprintf("move %d follows the solution being guessed", i+1)}
+		}
+		if len(move.Guess) != len(rec.Solution) {
+			return &GameRecordError{Msg: fmt.Sprintf("move %d has the wrong length", i+1)}
+		}
+		expected := getFeedback(move.Guess, rec.Solution)
+		if len(move.Feedback) != len(expected) {
+			return &GameRecordError{Msg: fmt.Sprintf("move %d feedback has the wrong length", i+1)}
+		}
+		for j := range expected {
+			if move.Feedback[j] != expected[j] {
+				return &GameRecordError{Msg: fmt.Sprintf("move %d feedback does not match the solution", i+1)}
+			}
+		}
+		if (move.At != nil) != timed {
+			return &GameRecordError{Msg: "either every move or none must have a timestamp"}
+		}
+		if timed {
+			if move.At.Before(previous) {
+				return &GameRecordError{Msg: fmt.Sprintf("move %d is timestamped before the previous one", i+1)}
+			}
+			previous = *move.At
+		}
+		solved = move.Guess == rec.Solution
+	}
+
+	want := RecordResultDraw
+	if solved {
+		// The player who guesses the solution loses.
+		want = RecordResultSecondWins
+		if (len(rec.Moves)-1)%2 == 1 {
+			want = RecordResultFirstWins
+		}
+	} else if len(rec.Moves) < maxGuesses {
+		return &GameRecordError{Msg: "the game ends before it is finished"}
+	}
+	if rec.Result != want {
+		return &GameRecordError{Msg: fmt.Sprintf("result %s does not match the moves, expected %s", rec.Result, want)}
+	}
+	return nil
+}
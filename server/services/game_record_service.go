@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+
+	"github.com/google/uuid"
+)
+
+// GameRecordService exports games as game records and archives imported ones.
+type GameRecordService struct {
+	games      *GameService
+	gameRepo   repositories.GameRepositoryI
+	playerRepo repositories.PlayerRepositoryI
+}
+
+// NewGameRecordService creates a new GameRecordService for games played with
+// the word list of games.
+func NewGameRecordService(games *GameService, gameRepo repositories.GameRepositoryI, playerRepo repositories.PlayerRepositoryI) *GameRecordService {
+	return &GameRecordService{
+		games:      games,
+		gameRepo:   gameRepo,
+		playerRepo: playerRepo,
+	}
+}
+
+// WordListVersion identifies a word list by a short hash of its contents.
+func WordListVersion(wordList []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(wordList, "\n")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// ExportGame writes the record of a single game. It returns "game_not_found"
+// if there is no such game.
+func (s *GameRecordService) ExportGame(ctx context.Context, gameID string, w io.Writer) error {
+	game, err := s.gameRepo.GetByID(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	if game == nil {
+		return fmt.Errorf("game_not_found")
+	}
+	names := make([]string, 2)
+	for i, id := range []string{game.FirstPlayer, game.SecondPlayer} {
+		player, err := s.playerRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if player != nil {
+			names[i] = player.DisplayName()
+		}
+	}
+	return WriteGameRecord(w, s.record(&models.GameWithPlayers{Game: game, FirstPlayerName: names[0], SecondPlayerName: names[1]}))
+}
+
+// ExportPlayerGames writes the records of every game of the player matching
+// q, one page at a time. q.Cursor and q.Limit are ignored.
+func (s *GameRecordService) ExportPlayerGames(ctx context.Context, q GameHistoryQuery, w io.Writer) error {
+	q.Cursor = ""
+	q.Limit = maxGameHistoryLimit
+	for {
+		page, err := s.games.ListPlayerGames(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Games {
+			if err := WriteGameRecord(w, s.record(item)); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// record converts a game to a game record, hiding the solution of a game in progress.
+func (s *GameRecordService) record(item *models.GameWithPlayers) *GameRecord {
+	game := item.Game
+	rec := &GameRecord{
+		GameID:           game.ID,
+		Date:             game.CreatedAt,
+		FirstPlayerName:  item.FirstPlayerName,
+		FirstPlayerID:    game.FirstPlayer,
+		SecondPlayerName: item.SecondPlayerName,
+		SecondPlayerID:   game.SecondPlayer,
+		Mode:             game.Mode,
+		Result:           RecordResultOngoing,
+	}
+	// Imported games were not necessarily played with this server's words.
+	if !game.Imported {
+		rec.WordList = s.games.WordListVersion()
+	}
+	switch {
+	case game.IsDraw():
+		rec.Result = RecordResultDraw
+	case game.Winner() == game.FirstPlayer:
+		rec.Result = RecordResultFirstWins
+	case game.Winner() == game.SecondPlayer:
+		rec.Result = RecordResultSecondWins
+	}
+	if game.Finished() {
+		rec.Solution = game.Solution
+	}
+	timed := len(game.GuessTimes) == len(game.Guesses)
+	for i, guess := range game.Guesses {
+		move := GameRecordMove{Guess: strings.ToUpper(guess), Feedback: getFeedback(guess, game.Solution)}
+		if timed {
+			at := game.GuessTimes[i]
+			move.At = &at
+		}
+		rec.Moves = append(rec.Moves, move)
+	}
+	return rec
+}
+
+// ImportGames validates every record in r and, if all are valid, archives
+// them as imported games on behalf of importerID. Players are matched by ID,
+// then by name, and must exist on this server. Unless the importer is an
+// admin, they must have played every game, or it returns "forbidden", and the
+// games stay out of their opponents' histories. It returns the IDs of the
+// stored games.
+func (s *GameRecordService) ImportGames(ctx context.Context, importerID string, r io.Reader) ([]string, error) {
+	importer, err := s.playerRepo.GetByID(ctx, importerID)
+	if err != nil {
+		return nil, err
+	}
+	if importer == nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	isAdmin := models.RoleAtLeast(importer.Role, models.RoleAdmin)
+
+	records, err := ParseGameRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, &GameRecordError{Msg: "no game records found"}
+	}
+
+	games := make([]*models.Game, 0, len(records))
+	seen := make(map[string]bool)
+	for i, rec := range records {
+		if err := ValidateGameRecord(rec); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+		game, err := s.importedGame(ctx, rec)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+		if !isAdmin {
+			if !game.HasPlayer(importer.ID) {
+				return nil, fmt.Errorf("forbidden")
+			}
+			game.ImportedBy = importer.ID
+		}
+		if seen[game.ID] {
+			return nil, fmt.Errorf("record %d: %w", i+1, &GameRecordError{Msg: "duplicate GameID"})
+		}
+		seen[game.ID] = true
+		games = append(games, game)
+	}
+
+	if err := s.gameRepo.ImportGames(ctx, games); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(games))
+	for _, game := range games {
+		ids = append(ids, game.ID)
+	}
+	return ids, nil
+}
+
+// importedGame builds the game a valid record describes.
+func (s *GameRecordService) importedGame(ctx context.Context, rec *GameRecord) (*models.Game, error) {
+	first, err := s.resolvePlayer(ctx, rec.FirstPlayerID, rec.FirstPlayerName)
+	if err != nil {
+		return nil, err
+	}
+	second, err := s.resolvePlayer(ctx, rec.SecondPlayerID, rec.SecondPlayerName)
+	if err != nil {
+		return nil, err
+	}
+	if first.ID == second.ID {
+		return nil, &GameRecordError{Msg: "a player cannot play against themselves"}
+	}
+
+	id := uuid.NewString()
+	if rec.GameID != "" && uuid.Validate(rec.GameID) == nil {
+		existing, err := s.gameRepo.GetByID(ctx, rec.GameID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, &GameRecordError{Msg: "game " + rec.GameID + " already exists"}
+		}
+		id = rec.GameID
+	}
+	mode := rec.Mode
+	if mode == "" {
+		mode = models.ModeClassic
+	}
+	game := &models.Game{
+		ID:           id,
+		Solution:     rec.Solution,
+		Mode:         mode,
+		CreatedAt:    rec.Date,
+		UpdatedAt:    rec.Date,
+		FirstPlayer:  first.ID,
+		SecondPlayer: second.ID,
+		Guesses:      []string{},
+		GuessTimes:   []time.Time{},
+		Imported:     true,
+	}
+	for i, move := range rec.Moves {
+		game.Guesses = append(game.Guesses, move.Guess)
+		game.CurrentPlayer = game.GuessedBy(i)
+		if move.At != nil {
+			game.GuessTimes = append(game.GuessTimes, *move.At)
+			game.UpdatedAt = *move.At
+		}
+	}
+	switch rec.Result {
+	case RecordResultDraw:
+		game.Result = models.ResultDraw
+	case RecordResultFirstWins:
+		game.Result = models.ResultLosePrefix + second.ID
+	default:
+		game.Result = models.ResultLosePrefix + first.ID
+	}
+	return game, nil
+}
+
+func (s *GameRecordService) resolvePlayer(ctx context.Context, id, name string) (*models.Player, error) {
+	if id != "" {
+		player, err := s.playerRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if player != nil {
+			return player, nil
+		}
+	}
+	if name != "" {
+		player, err := s.playerRepo.GetByName(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if player != nil {
+			return player, nil
+		}
+	}
+	return nil, &GameRecordError{Msg: fmt.Sprintf("unknown player %q", name)}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+const sampleRecord = `[Event "Battle Wordle"]
+[GameID "not-a-uuid"]
+[Date "2025-08-14T12:00:00Z"]
+[FirstPlayer "alice"]
+[SecondPlayer "bob"]
+[Mode "classic"]
+[Solution "APPLE"]
+[Result "1-0"]
+
+; bob guesses the solution and loses
+1. CRANE ..Y.G {2025-08-14T12:00:09Z}
+2. APPLE GGGGG {2025-08-14T12:00:21Z}
+`
+
+func TestGameRecord_RoundTrip(t *testing.T) {
+	records, err := ParseGameRecords(strings.NewReader(sampleRecord))
+	if err != nil {
+		t.Fatalf("ParseGameRecords failed: %v", err)
+	}
+	if len(records) != 1 || len(records[0].Moves) != 2 || records[0].Solution != "APPLE" {
+		t.Fatalf("Unexpected records: %+v", records)
+	}
+	if err := ValidateGameRecord(records[0]); err != nil {
+		t.Fatalf("Expected a valid record, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGameRecord(&buf, records[0]); err != nil {
+		t.Fatalf("WriteGameRecord failed: %v", err)
+	}
+	reparsed, err := ParseGameRecords(strings.NewReader(buf.String() + buf.String()))
+	if err != nil {
+		t.Fatalf("Reparsing failed: %v", err)
+	}
+	if len(reparsed) != 2 || reparsed[1].Moves[1].Guess != "APPLE" || !reparsed[1].Moves[1].At.Equal(*records[0].Moves[1].At) {
+		t.Errorf("Round trip changed the record:\n%s", buf.String())
+	}
+}
+
+func TestValidateGameRecord_Rejects(t *testing.T) {
+	cases := map[string]string{
+		"wrong feedback": strings.Replace(sampleRecord, "..Y.G", ".YY.G", 1),
+		"wrong result":   strings.Replace(sampleRecord, `"1-0"`, `"0-1"`, 1),
+		"unfinished":     strings.Replace(sampleRecord, "2. APPLE GGGGG {2025-08-14T12:00:21Z}\n", "", 1),
+		"time travel":    strings.Replace(sampleRecord, "12:00:21Z", "11:00:21Z", 1),
+	}
+	for name, text := range cases {
+		records, err := ParseGameRecords(strings.NewReader(text))
+		if err != nil {
+			t.Fatalf("%s: ParseGameRecords failed: %v", name, err)
+		}
+		var recordErr *GameRecordError
+		if err := ValidateGameRecord(records[0]); !errors.As(err, &recordErr) {
+			t.Errorf("%s: expected a GameRecordError, got %v", name, err)
+		}
+	}
+
+	if _, err := ParseGameRecords(strings.NewReader("1. CRANE .....\n")); err == nil {
+		t.Error("Expected moves without headers to be rejected")
+	}
+
+	headers := map[string]string{
+		"non-ASCII solution": strings.Replace(sampleRecord, `"APPLE"`, `"ÄPPL"`, 1),
+		"short solution":     strings.Replace(sampleRecord, `"APPLE"`, `"APPL"`, 1),
+		"unknown mode":       strings.Replace(sampleRecord, `"classic"`, `"blitz"`, 1),
+	}
+	for name, text := range headers {
+		var recordErr *GameRecordError
+		if _, err := ParseGameRecords(strings.NewReader(text)); !errors.As(err, &recordErr) {
+			t.Errorf("%s: expected a GameRecordError, got %v", name, err)
+		}
+	}
+}
+
+func TestGameRecordService_ImportAndExport(t *testing.T) {
+	ctx := context.Background()
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p1", Name: "alice"})
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p2", Name: "bob"})
+	gameRepo := &mockGameRepo{}
+	service := NewGameRecordService(NewGameService(gameRepo, []string{"APPLE"}, nil), gameRepo, playerRepo)
+
+	ids, err := service.ImportGames(ctx, "p1", strings.NewReader(sampleRecord))
+	if err != nil {
+		t.Fatalf("ImportGames failed: %v", err)
+	}
+	game := gameRepo.created
+	if len(ids) != 1 || game == nil || game.ID != ids[0] {
+		t.Fatalf("Expected the game to be stored, got %v", ids)
+	}
+	if !game.Imported || game.Result != "lose:p2" || game.FirstPlayer != "p1" || len(game.GuessTimes) != 2 {
+		t.Errorf("Unexpected imported game: %+v", game)
+	}
+
+	var buf bytes.Buffer
+	if err := service.ExportGame(ctx, game.ID, &buf); err != nil {
+		t.Fatalf("ExportGame failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `[Result "1-0"]`) || !strings.Contains(buf.String(), "2. APPLE GGGGG") {
+		t.Errorf("Unexpected export:\n%s", buf.String())
+	}
+
+	// The solution of a game in progress stays hidden.
+	gameRepo.created = &models.Game{ID: "g2", Solution: "APPLE", FirstPlayer: "p1", SecondPlayer: "p2", Guesses: []string{"CRANE"}, CreatedAt: time.Now()}
+	buf.Reset()
+	if err := service.ExportGame(ctx, "g2", &buf); err != nil {
+		t.Fatalf("ExportGame failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "Solution") || !strings.Contains(buf.String(), `[Result "*"]`) {
+		t.Errorf("Expected an in-progress record without the solution:\n%s", buf.String())
+	}
+
+	if err := service.ExportGame(ctx, "missing", &buf); err == nil || err.Error() != "game_not_found" {
+		t.Errorf("Expected game_not_found, got %v", err)
+	}
+}
+
+func TestGameRecordService_ImportUnknownPlayer(t *testing.T) {
+	ctx := context.Background()
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p1", Name: "alice"})
+	gameRepo := &mockGameRepo{}
+	service := NewGameRecordService(NewGameService(gameRepo, []string{"APPLE"}, nil), gameRepo, playerRepo)
+	var recordErr *GameRecordError
+	if _, err := service.ImportGames(ctx, "p1", strings.NewReader(sampleRecord)); !errors.As(err, &recordErr) {
+		t.Errorf("Expected a GameRecordError, got %v", err)
+	}
+	if gameRepo.created != nil {
+		t.Error("Expected nothing to be stored")
+	}
+}
+
+func TestGameRecordService_ImportOthersGames(t *testing.T) {
+	ctx := context.Background()
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p1", Name: "alice"})
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p2", Name: "bob"})
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p3", Name: "carol"})
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p4", Name: "dave", Role: models.RoleAdmin})
+	gameRepo := &mockGameRepo{}
+	service := NewGameRecordService(NewGameService(gameRepo, []string{"APPLE"}, nil), gameRepo, playerRepo)
+
+	if _, err := service.ImportGames(ctx, "p3", strings.NewReader(sampleRecord)); err == nil || err.Error() != "forbidden" {
+		t.Errorf("Expected forbidden for a player who did not play the game, got %v", err)
+	}
+	if gameRepo.created != nil {
+		t.Error("Expected nothing to be stored")
+	}
+
+	if _, err := service.ImportGames(ctx, "p4", strings.NewReader(sampleRecord)); err != nil {
+		t.Errorf("Expected an admin to import any game, got %v", err)
+	}
+}
+
+func TestGameRecordService_ImportedGamesStayOutOfOpponentHistory(t *testing.T) {
+	ctx := context.Background()
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p1", Name: "alice"})
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p2", Name: "bob"})
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p4", Name: "dave", Role: models.RoleAdmin})
+	gameRepo := &mockGameRepo{}
+	games := NewGameService(gameRepo, []string{"APPLE"}, nil)
+	service := NewGameRecordService(games, gameRepo, playerRepo)
+
+	// alice imports a game she claims to have played against bob.
+	if _, err := service.ImportGames(ctx, "p1", strings.NewReader(sampleRecord)); err != nil {
+		t.Fatalf("ImportGames failed: %v", err)
+	}
+	if gameRepo.created.ImportedBy != "p1" {
+		t.Errorf("Expected the importer to be recorded, got %q", gameRepo.created.ImportedBy)
+	}
+	history, err := games.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: "p2"})
+	if err != nil {
+		t.Fatalf("ListPlayerGames failed: %v", err)
+	}
+	if len(history.Games) != 0 {
+		t.Errorf("Expected bob's history to leave out alice's import, got %d games", len(history.Games))
+	}
+	history, err = games.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: "p1"})
+	if err != nil {
+		t.Fatalf("ListPlayerGames failed: %v", err)
+	}
+	if len(history.Games) != 1 {
+		t.Errorf("Expected alice's history to show her import, got %d games", len(history.Games))
+	}
+
+	// An admin's import shows for both players.
+	if _, err := service.ImportGames(ctx, "p4", strings.NewReader(strings.Replace(sampleRecord, `[GameID "not-a-uuid"]`, "", 1))); err != nil {
+		t.Fatalf("ImportGames failed: %v", err)
+	}
+	history, err = games.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: "p2"})
+	if err != nil {
+		t.Fatalf("ListPlayerGames failed: %v", err)
+	}
+	if len(history.Games) != 1 || history.Games[0].Game.ID != gameRepo.created.ID {
+		t.Errorf("Expected bob's history to show the admin's import, got %+v", history.Games)
+	}
+}
@@ -2,10 +2,13 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"battle-wordle/server/models"
@@ -18,24 +21,70 @@ import (
 type GameService struct {
 	repo     repositories.GameRepositoryI
 	wordList []string
+	// wordListVersion identifies wordList in game records.
+	wordListVersion string
+	ratings         *RatingService
+	// mu guards the word list and listeners.
+	mu        sync.RWMutex
+	listeners []GameFinishedListener
+	// resultsMu is held for reading while a game's result is stored and for
+	// writing while RewriteResults runs.
+	resultsMu sync.RWMutex
 }
 
-// NewGameService creates a new GameService.
-func NewGameService(repo repositories.GameRepositoryI, wordList []string) *GameService {
-	return &GameService{repo: repo, wordList: wordList}
+// GameFinishedListener is notified after a game's result has been stored.
+type GameFinishedListener func(ctx context.Context, game *models.Game)
+
+// NewGameService creates a new GameService. If ratings is nil, finished games are left unrated.
+func NewGameService(repo repositories.GameRepositoryI, wordList []string, ratings *RatingService) *GameService {
+	return &GameService{repo: repo, wordList: wordList, wordListVersion: WordListVersion(wordList), ratings: ratings}
+}
+
+// OnGameFinished registers a listener that is called, in registration order,
+// every time a game finishes. Listeners run synchronously and handle their own errors.
+func (s *GameService) OnGameFinished(listener GameFinishedListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// WordList returns the words new games pick their solution from.
+func (s *GameService) WordList() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.wordList
+}
+
+// SetWordList replaces the words new games pick their solution from. Games in
+// progress keep their solution.
+func (s *GameService) SetWordList(wordList []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wordList = wordList
+	s.wordListVersion = WordListVersion(wordList)
+}
+
+// WordListVersion identifies the current word list by a short hash of its
+// contents.
+func (s *GameService) WordListVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.wordListVersion
 }
 
 func (s *GameService) CreateGame(ctx context.Context, PlayerOne string, PlayerTwo string) (*models.Game, error) {
-	if len(s.wordList) == 0 {
+	wordList := s.WordList()
+	if len(wordList) == 0 {
 		return nil, fmt.Errorf("word list is empty")
 	}
-	solution := s.wordList[rand.Intn(len(s.wordList))]
+	solution := wordList[rand.Intn(len(wordList))]
 
 	log.Printf("New game created: solution=%s, PlayerOne=%s, PlayerTwo=%s", solution, PlayerOne, PlayerTwo)
 
 	game := &models.Game{
 		ID:            uuid.NewString(),
 		Solution:      solution,
+		Mode:          models.ModeClassic,
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 		FirstPlayer:   PlayerOne,
@@ -43,6 +92,7 @@ func (s *GameService) CreateGame(ctx context.Context, PlayerOne string, PlayerTw
 		CurrentPlayer: PlayerOne,
 		Result:        "",
 		Guesses:       []string{},
+		GuessTimes:    []time.Time{},
 	}
 	if err := s.repo.CreateGame(ctx, game); err != nil {
 		return nil, err
@@ -58,6 +108,149 @@ func (s *GameService) GetByPlayer(ctx context.Context, playerID string) ([]*mode
 	return s.repo.GetByPlayer(ctx, playerID)
 }
 
+// maxGuesses is the number of guesses after which an unsolved game is a draw.
+const maxGuesses = 6
+
+// finishAttempts is how many times finishing a game is tried when another
+// game of one of its players is rated concurrently.
+const finishAttempts = 3
+
+const (
+	defaultGameHistoryLimit = 20
+	maxGameHistoryLimit     = 100
+)
+
+// GameHistoryQuery describes which page of a player's games to fetch.
+type GameHistoryQuery struct {
+	PlayerID string
+	// Status is "in_progress", "finished", or empty for both.
+	Status string
+	// OpponentID restricts the games to those against one player.
+	OpponentID string
+	// Result is "win", "loss" or "draw" from the player's point of view, or empty for any.
+	Result string
+	Mode   string
+	// From and To bound the games' creation time; zero values leave the range open.
+	From time.Time
+	To   time.Time
+	// Sort is "created_at" (the default) or "updated_at".
+	Sort string
+	// Order is "desc" (the default) or "asc".
+	Order string
+	// Limit is the page size; 0 uses the default.
+	Limit int
+	// Cursor is the NextCursor of the previous page, or empty for the first page.
+	Cursor string
+}
+
+// GameHistory is one page of a player's games.
+type GameHistory struct {
+	Games []*models.GameWithPlayers
+	// NextCursor fetches the following page; empty on the last page.
+	NextCursor string
+}
+
+// gameHistoryCursor is the opaque pagination state handed to clients. It
+// records the ordering so a cursor cannot be reused with a different one.
+type gameHistoryCursor struct {
+	Sort    string `json:"s"`
+	Asc     bool   `json:"a,omitempty"`
+	SortKey string `json:"k"`
+	GameID  string `json:"g"`
+}
+
+// ListPlayerGames returns a page of the player's games matching q.
+func (s *GameService) ListPlayerGames(ctx context.Context, q GameHistoryQuery) (*GameHistory, error) {
+	filter := repositories.GameFilter{
+		PlayerID:   q.PlayerID,
+		Status:     q.Status,
+		OpponentID: q.OpponentID,
+		Result:     q.Result,
+		Mode:       q.Mode,
+		From:       q.From,
+		To:         q.To,
+		Sort:       q.Sort,
+	}
+	switch q.Status {
+	case "", repositories.GameStatusInProgress, repositories.GameStatusFinished:
+	default:
+		return nil, fmt.Errorf("invalid_filter")
+	}
+	switch q.Result {
+	case "", models.OutcomeWin, models.OutcomeLoss, models.OutcomeDraw:
+	default:
+		return nil, fmt.Errorf("invalid_filter")
+	}
+	if q.Limit < 0 || (!q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To)) {
+		return nil, fmt.Errorf("invalid_filter")
+	}
+	switch q.Sort {
+	case "":
+		filter.Sort = repositories.GameSortCreatedAt
+	case repositories.GameSortCreatedAt, repositories.GameSortUpdatedAt:
+	default:
+		return nil, fmt.Errorf("invalid_sort")
+	}
+	switch q.Order {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return nil, fmt.Errorf("invalid_sort")
+	}
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultGameHistoryLimit
+	}
+	if limit > maxGameHistoryLimit {
+		limit = maxGameHistoryLimit
+	}
+
+	var after *repositories.GameCursor
+	if q.Cursor != "" {
+		cursor, err := decodeGameHistoryCursor(q.Cursor)
+		if err != nil || cursor.Sort != filter.Sort || cursor.Asc != filter.Ascending {
+			return nil, fmt.Errorf("invalid_cursor")
+		}
+		after = &repositories.GameCursor{SortKey: cursor.SortKey, GameID: cursor.GameID}
+	}
+
+	games, next, err := s.repo.ListByPlayer(ctx, filter, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	history := &GameHistory{Games: games}
+	if next != nil {
+		history.NextCursor = encodeGameHistoryCursor(gameHistoryCursor{
+			Sort:    filter.Sort,
+			Asc:     filter.Ascending,
+			SortKey: next.SortKey,
+			GameID:  next.GameID,
+		})
+	}
+	return history, nil
+}
+
+func encodeGameHistoryCursor(c gameHistoryCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeGameHistoryCursor(s string) (*gameHistoryCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c gameHistoryCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	if c.SortKey == "" || c.GameID == "" {
+		return nil, fmt.Errorf("incomplete cursor")
+	}
+	return &c, nil
+}
+
 func (s *GameService) SubmitGuess(ctx context.Context, gameID string, guess string, playerID string) (*models.Game, error) {
 	game, err := s.repo.GetByID(ctx, gameID)
 	if err != nil {
@@ -66,11 +259,13 @@ func (s *GameService) SubmitGuess(ctx context.Context, gameID string, guess stri
 	if game == nil {
 		return nil, fmt.Errorf("game not found")
 	}
-	if playerID != game.CurrentPlayer {
-		return game, nil // Not this player's turn, ignore
+	if game.Finished() || playerID != game.CurrentPlayer {
+		return game, nil // Game over or not this player's turn, ignore
 	}
+	now := time.Now()
 	game.Guesses = append(game.Guesses, guess)
-	game.UpdatedAt = time.Now()
+	game.GuessTimes = append(game.GuessTimes, now)
+	game.UpdatedAt = now
 	// Check if guess is correct
 	if strings.ToUpper(guess) == strings.ToUpper(game.Solution) {
 		game.Result = "lose:" + playerID
@@ -83,17 +278,94 @@ func (s *GameService) SubmitGuess(ctx context.Context, gameID string, guess stri
 			game.CurrentPlayer = game.FirstPlayer
 		}
 		// If max guesses reached and not solved, it's a draw
-		maxGuesses := 6
 		if len(game.Guesses) >= maxGuesses {
 			game.Result = "draw"
 		}
 	}
+	if game.Finished() {
+		if err := s.finishGame(ctx, game); err != nil {
+			return nil, err
+		}
+		return game, nil
+	}
 	if err := s.repo.UpdateGame(ctx, game); err != nil {
 		return nil, err
 	}
 	return game, nil
 }
 
+// ForfeitActiveGames ends every game the player has in progress as a loss for
+// the player, e.g. when their account is deleted.
+func (s *GameService) ForfeitActiveGames(ctx context.Context, playerID string) error {
+	for {
+		page, err := s.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: playerID, Status: repositories.GameStatusInProgress, Limit: maxGameHistoryLimit})
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Games {
+			game := item.Game
+			game.Result = models.ResultLosePrefix + playerID
+			game.UpdatedAt = time.Now()
+			if err := s.finishGame(ctx, game); err != nil {
+				return err
+			}
+		}
+		// Finished games drop out of the query, so start over until none are left.
+		if page.NextCursor == "" {
+			return nil
+		}
+	}
+}
+
+// finishGame stores the result of a game that just ended and notifies the
+// listeners.
+func (s *GameService) finishGame(ctx context.Context, game *models.Game) error {
+	if err := s.storeResult(ctx, game); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(ctx, game)
+	}
+	return nil
+}
+
+// storeResult rates a finished game and stores the result together with the
+// rating changes and the players' statistics. The ratings are read outside the
+// transaction that stores them, so when a player's other game finishes in
+// between the game is rated again from the new ratings.
+func (s *GameService) storeResult(ctx context.Context, game *models.Game) error {
+	s.resultsMu.RLock()
+	defer s.resultsMu.RUnlock()
+
+	var err error
+	for attempt := 0; attempt < finishAttempts; attempt++ {
+		var changes []*models.RatingChange
+		if s.ratings != nil {
+			changes, err = s.ratings.RateGame(ctx, game)
+			if err != nil {
+				return fmt.Errorf("failed to rate game: %w", err)
+			}
+		}
+		err = s.repo.FinishGame(ctx, game, changes, summarizeGame(game))
+		if err == nil || err.Error() != "rating_conflict" {
+			break
+		}
+	}
+	return err
+}
+
+// RewriteResults runs fn while no game can finish, so that fn can change
+// stored results and replay the finished games without one finishing in
+// between and being overwritten.
+func (s *GameService) RewriteResults(fn func() error) error {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	return fn()
+}
+
 type FeedbackType string
 
 const (
@@ -105,24 +377,24 @@ const (
 func getFeedback(guess, solution string) []FeedbackType {
 	guess = strings.ToUpper(guess)
 	solution = strings.ToUpper(solution)
-	feedback := make([]FeedbackType, len(solution))
 	targetArr := []rune(solution)
 	guessArr := []rune(guess)
-	used := make([]bool, len(solution))
+	feedback := make([]FeedbackType, len(targetArr))
+	used := make([]bool, len(targetArr))
 
-	for i := 0; i < len(solution); i++ {
+	for i := 0; i < len(targetArr) && i < len(guessArr); i++ {
 		if guessArr[i] == targetArr[i] {
 			feedback[i] = FeedbackCorrect
 			used[i] = true
 		}
 	}
 	// Second pass: present
-	for i := 0; i < len(solution); i++ {
+	for i := 0; i < len(targetArr) && i < len(guessArr); i++ {
 		if feedback[i] == FeedbackCorrect {
 			continue
 		}
 		found := false
-		for j := 0; j < len(solution); j++ {
+		for j := 0; j < len(targetArr); j++ {
 			if !used[j] && guessArr[i] == targetArr[j] {
 				feedback[i] = FeedbackPresent
 				used[j] = true
@@ -6,17 +6,38 @@ import (
 	"time"
 
 	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
 )
 
 type mockGameRepo struct {
-	created *models.Game
-	updated *models.Game
+	created   *models.Game
+	updated   *models.Game
+	changes   []*models.RatingChange
+	summaries []*models.PlayerGameSummary
+	// finishErrs are returned by successive FinishGame calls before it succeeds.
+	finishErrs []error
+	finished   []*models.Game
+	between    []*models.Game
+	// listFilter and listAfter record the last ListByPlayer call.
+	listFilter repositories.GameFilter
+	listAfter  *repositories.GameCursor
+	listNext   *repositories.GameCursor
+	// games are returned by ListByPlayer, filtered by player, importer and
+	// status. ImportGames adds to them.
+	games []*models.Game
 }
 
 func (m *mockGameRepo) CreateGame(ctx context.Context, game *models.Game) error {
 	m.created = game
 	return nil
 }
+func (m *mockGameRepo) ImportGames(ctx context.Context, games []*models.Game) error {
+	for _, game := range games {
+		m.created = game
+	}
+	m.games = append(m.games, games...)
+	return nil
+}
 func (m *mockGameRepo) GetByID(ctx context.Context, id string) (*models.Game, error) {
 	if m.created != nil && m.created.ID == id {
 		return m.created, nil
@@ -27,17 +48,97 @@ func (m *mockGameRepo) GetByID(ctx context.Context, id string) (*models.Game, er
 	return nil, nil
 }
 func (m *mockGameRepo) GetByPlayer(ctx context.Context, playerID string) ([]*models.Game, error) {
-	return nil, nil
+	var games []*models.Game
+	for _, g := range m.games {
+		if g.FirstPlayer == playerID || g.SecondPlayer == playerID {
+			games = append(games, g)
+		}
+	}
+	return games, nil
+}
+func (m *mockGameRepo) ListByPlayer(ctx context.Context, f repositories.GameFilter, after *repositories.GameCursor, limit int) ([]*models.GameWithPlayers, *repositories.GameCursor, error) {
+	m.listFilter = f
+	m.listAfter = after
+	page := []*models.GameWithPlayers{}
+	for _, g := range m.games {
+		if g.FirstPlayer != f.PlayerID && g.SecondPlayer != f.PlayerID {
+			continue
+		}
+		if g.Imported && g.ImportedBy != "" && g.ImportedBy != f.PlayerID {
+			continue
+		}
+		if (f.Status == repositories.GameStatusInProgress && g.Finished()) || (f.Status == repositories.GameStatusFinished && !g.Finished()) {
+			continue
+		}
+		page = append(page, &models.GameWithPlayers{Game: g})
+	}
+	return page, m.listNext, nil
+}
+func (m *mockGameRepo) GetBetween(ctx context.Context, firstPlayerID, secondPlayerID string) ([]*models.Game, error) {
+	return m.between, nil
+}
+func (m *mockGameRepo) GetRatingChangesBetween(ctx context.Context, firstPlayerID, secondPlayerID string) ([]*models.RatingChange, error) {
+	return m.changes, nil
+}
+func (m *mockGameRepo) GetRatingChanges(ctx context.Context, gameID string) ([]*models.RatingChange, error) {
+	var changes []*models.RatingChange
+	for _, c := range m.changes {
+		if c.GameID == gameID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+func (m *mockGameRepo) GetRatingChangesByPlayer(ctx context.Context, playerID string) ([]*models.RatingChange, error) {
+	var changes []*models.RatingChange
+	for _, c := range m.changes {
+		if c.PlayerID == playerID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
 }
 func (m *mockGameRepo) UpdateGame(ctx context.Context, game *models.Game) error {
 	m.updated = game
 	return nil
 }
+func (m *mockGameRepo) FinishGame(ctx context.Context, game *models.Game, changes []*models.RatingChange, summaries []*models.PlayerGameSummary) error {
+	if len(m.finishErrs) > 0 {
+		err := m.finishErrs[0]
+		m.finishErrs = m.finishErrs[1:]
+		return err
+	}
+	m.updated = game
+	m.changes = changes
+	m.summaries = summaries
+	return nil
+}
+func (m *mockGameRepo) GetFinished(ctx context.Context) ([]*models.Game, error) {
+	var finished []*models.Game
+	for _, g := range m.finished {
+		if !g.IsVoid() {
+			finished = append(finished, g)
+		}
+	}
+	return finished, nil
+}
+func (m *mockGameRepo) ReplaceRatings(ctx context.Context, changes []*models.RatingChange) error {
+	m.changes = changes
+	return nil
+}
+func (m *mockGameRepo) SetResult(ctx context.Context, gameID string, result string) error {
+	game, _ := m.GetByID(ctx, gameID)
+	if game == nil || !game.Finished() {
+		return &mockError{"game_not_finished"}
+	}
+	game.Result = result
+	return nil
+}
 
 func TestCreateGame(t *testing.T) {
 	repo := &mockGameRepo{}
 	wordList := []string{"APPLE", "BANJO"}
-	service := NewGameService(repo, wordList)
+	service := NewGameService(repo, wordList, nil)
 	ctx := context.Background()
 	game, err := service.CreateGame(ctx, "p1", "p2")
 	if err != nil {
@@ -53,7 +154,7 @@ func TestCreateGame(t *testing.T) {
 
 func TestCreateGame_EmptyWordList(t *testing.T) {
 	repo := &mockGameRepo{}
-	service := NewGameService(repo, []string{})
+	service := NewGameService(repo, []string{}, nil)
 	ctx := context.Background()
 	_, err := service.CreateGame(ctx, "p1", "p2")
 	if err == nil {
@@ -63,7 +164,7 @@ func TestCreateGame_EmptyWordList(t *testing.T) {
 
 func TestGetFeedbacks(t *testing.T) {
 	repo := &mockGameRepo{}
-	service := NewGameService(repo, []string{"APPLE"})
+	service := NewGameService(repo, []string{"APPLE"}, nil)
 	game := &models.Game{Solution: "APPLE", Guesses: []string{"APPLE", "GRAPE", "MANGO"}}
 	feedbacks := service.GetFeedbacks(game)
 	if len(feedbacks) != 3 {
@@ -77,7 +178,7 @@ func TestGetFeedbacks(t *testing.T) {
 func TestSubmitGuess(t *testing.T) {
 	repo := &mockGameRepo{}
 	wordList := []string{"APPLE"}
-	service := NewGameService(repo, wordList)
+	service := NewGameService(repo, wordList, nil)
 	ctx := context.Background()
 	// Create a game
 	game, err := service.CreateGame(ctx, "p1", "p2")
@@ -121,6 +222,9 @@ func TestSubmitGuess(t *testing.T) {
 	if updated.Result != "lose:p1" {
 		t.Errorf("Expected result 'lose:p1', got %s", updated.Result)
 	}
+	if len(repo.summaries) != 2 {
+		t.Errorf("Expected the finished game to carry a summary per player, got %d", len(repo.summaries))
+	}
 
 	// Test draw (6 wrong guesses)
 	game2, _ := service.CreateGame(ctx, "p1", "p2")
@@ -149,7 +253,7 @@ func TestSubmitGuess(t *testing.T) {
 func TestSubmitGuess_InvalidPlayer(t *testing.T) {
 	repo := &mockGameRepo{}
 	wordList := []string{"APPLE"}
-	service := NewGameService(repo, wordList)
+	service := NewGameService(repo, wordList, nil)
 	ctx := context.Background()
 	game, _ := service.CreateGame(ctx, "p1", "p2")
 	game.CurrentPlayer = "p1"
@@ -165,3 +269,38 @@ func TestSubmitGuess_InvalidPlayer(t *testing.T) {
 		t.Errorf("Expected no guesses recorded, got %v", updated.Guesses)
 	}
 }
+
+func TestListPlayerGames(t *testing.T) {
+	repo := &mockGameRepo{listNext: &repositories.GameCursor{SortKey: "2025-08-14 12:00:00+00:00", GameID: "g2"}}
+	service := NewGameService(repo, []string{"APPLE"}, nil)
+	ctx := context.Background()
+
+	page, err := service.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: "p1", Result: "win", Order: "asc"})
+	if err != nil {
+		t.Fatalf("ListPlayerGames failed: %v", err)
+	}
+	if repo.listFilter.Sort != repositories.GameSortCreatedAt || !repo.listFilter.Ascending || repo.listFilter.Result != "win" {
+		t.Errorf("Unexpected filter: %+v", repo.listFilter)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("Expected a next cursor")
+	}
+
+	if _, err := service.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: "p1", Order: "asc", Cursor: page.NextCursor}); err != nil {
+		t.Fatalf("ListPlayerGames with cursor failed: %v", err)
+	}
+	if repo.listAfter == nil || *repo.listAfter != *repo.listNext {
+		t.Errorf("Expected the cursor to be passed through, got %+v", repo.listAfter)
+	}
+
+	// A cursor is only valid for the ordering it was issued for.
+	if _, err := service.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: "p1", Cursor: page.NextCursor}); err == nil || err.Error() != "invalid_cursor" {
+		t.Errorf("Expected invalid_cursor, got %v", err)
+	}
+	if _, err := service.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: "p1", Status: "paused"}); err == nil || err.Error() != "invalid_filter" {
+		t.Errorf("Expected invalid_filter, got %v", err)
+	}
+	if _, err := service.ListPlayerGames(ctx, GameHistoryQuery{PlayerID: "p1", Sort: "name"}); err == nil || err.Error() != "invalid_sort" {
+		t.Errorf("Expected invalid_sort, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+const (
+	// LeaderboardPeriodAll ranks every game ever played.
+	LeaderboardPeriodAll = "all"
+	// LeaderboardPeriodSeason ranks games from the current season (calendar quarter).
+	LeaderboardPeriodSeason = "season"
+	// LeaderboardPeriodMonth ranks games from the current calendar month.
+	LeaderboardPeriodMonth = "month"
+
+	// LeaderboardSortRating orders players by their current rating.
+	LeaderboardSortRating = "rating"
+	// LeaderboardSortPoints orders players by points earned in the selected games.
+	LeaderboardSortPoints = "points"
+
+	defaultLeaderboardLimit = 25
+	maxLeaderboardLimit     = 100
+)
+
+// LeaderboardQuery describes which leaderboard page to fetch.
+type LeaderboardQuery struct {
+	// Period is one of the LeaderboardPeriod constants; empty means all time.
+	Period string
+	// Mode restricts the board to one game mode; empty counts every mode.
+	Mode string
+	// WordLength restricts the board to one solution length; 0 counts every length.
+	WordLength int
+	// MinGames is the minimum number of games a player needs to appear.
+	MinGames int
+	// Limit is the page size; 0 uses the default.
+	Limit int
+	// Cursor is the NextCursor of the previous page, or empty for the first page.
+	Cursor string
+	// PlayerID is the requesting player, whose own standing is always returned.
+	PlayerID string
+}
+
+// Leaderboard is one page of a leaderboard.
+type Leaderboard struct {
+	// Sort is how the board is ordered: LeaderboardSortRating or LeaderboardSortPoints.
+	Sort    string
+	Entries []*models.LeaderboardEntry
+	// NextCursor fetches the following page; empty on the last page.
+	NextCursor string
+	// Me is the requesting player's standing, or nil if they are not ranked.
+	Me *models.LeaderboardEntry
+}
+
+// leaderboardCursor is the opaque pagination state handed to clients.
+type leaderboardCursor struct {
+	Score    int    `json:"s"`
+	PlayerID string `json:"p"`
+	Rank     int    `json:"r"`
+	Position int    `json:"n"`
+}
+
+// LeaderboardService ranks players by rating or by results over a period.
+type LeaderboardService struct {
+	repo repositories.LeaderboardRepositoryI
+	now  func() time.Time
+}
+
+// NewLeaderboardService creates a new LeaderboardService.
+func NewLeaderboardService(repo repositories.LeaderboardRepositoryI) *LeaderboardService {
+	return &LeaderboardService{repo: repo, now: time.Now}
+}
+
+// GetLeaderboard returns a page of the leaderboard selected by q. The all-time
+// board without mode or word length filters is ordered by rating; any filter
+// switches to a points board (two per win, one per draw) over the matching
+// games, since ratings are not tracked per period or mode.
+func (s *LeaderboardService) GetLeaderboard(ctx context.Context, q LeaderboardQuery) (*Leaderboard, error) {
+	since, err := s.periodStart(q.Period)
+	if err != nil {
+		return nil, err
+	}
+	if q.MinGames < 0 || q.WordLength < 0 || q.Limit < 0 {
+		return nil, fmt.Errorf("invalid_filter")
+	}
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultLeaderboardLimit
+	}
+	if limit > maxLeaderboardLimit {
+		limit = maxLeaderboardLimit
+	}
+	cursor, err := decodeLeaderboardCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	var after *repositories.LeaderboardCursor
+	if cursor != nil {
+		after = &repositories.LeaderboardCursor{Score: cursor.Score, PlayerID: cursor.PlayerID}
+	}
+
+	board := &Leaderboard{}
+	// Fetch one extra entry to learn whether there is a next page.
+	var entries []*models.LeaderboardEntry
+	if since.IsZero() && q.Mode == "" && q.WordLength == 0 {
+		board.Sort = LeaderboardSortRating
+		entries, err = s.repo.RatingPage(ctx, q.MinGames, after, limit+1)
+		if err == nil && q.PlayerID != "" {
+			board.Me, err = s.repo.RatingStanding(ctx, q.MinGames, q.PlayerID)
+		}
+	} else {
+		board.Sort = LeaderboardSortPoints
+		filter := repositories.LeaderboardFilter{Since: since, Mode: q.Mode, WordLength: q.WordLength, MinGames: q.MinGames}
+		entries, err = s.repo.PointsPage(ctx, filter, after, limit+1)
+		if err == nil && q.PlayerID != "" {
+			board.Me, err = s.repo.PointsStanding(ctx, filter, q.PlayerID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+	state := leaderboardCursor{}
+	if cursor != nil {
+		state = *cursor
+	}
+	for _, e := range entries {
+		score := e.Points
+		if board.Sort == LeaderboardSortRating {
+			score = *e.Rating
+		}
+		state.Position++
+		if state.Position == 1 || score != state.Score {
+			state.Rank = state.Position
+		}
+		state.Score = score
+		state.PlayerID = e.PlayerID
+		e.Rank = state.Rank
+	}
+	board.Entries = entries
+	if hasMore {
+		board.NextCursor = encodeLeaderboardCursor(state)
+	}
+	return board, nil
+}
+
+// periodStart returns the earliest end time of games counted in the period,
+// or the zero time for all time. Seasons are calendar quarters. Periods start
+// at midnight UTC, whatever the server's time zone.
+func (s *LeaderboardService) periodStart(period string) (time.Time, error) {
+	now := s.now().UTC()
+	switch period {
+	case "", LeaderboardPeriodAll:
+		return time.Time{}, nil
+	case LeaderboardPeriodMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case LeaderboardPeriodSeason:
+		quarterStart := time.Month((int(now.Month())-1)/3*3 + 1)
+		return time.Date(now.Year(), quarterStart, 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid_period")
+	}
+}
+
+func encodeLeaderboardCursor(c leaderboardCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeLeaderboardCursor(s string) (*leaderboardCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid_cursor")
+	}
+	var c leaderboardCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.PlayerID == "" || c.Position < 1 {
+		return nil, fmt.Errorf("invalid_cursor")
+	}
+	return &c, nil
+}
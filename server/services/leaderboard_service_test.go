@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+// mockLeaderboardRepo serves a fixed, already ordered board.
+type mockLeaderboardRepo struct {
+	board      []*models.LeaderboardEntry
+	lastFilter repositories.LeaderboardFilter
+	usedPoints bool
+}
+
+func (m *mockLeaderboardRepo) page(after *repositories.LeaderboardCursor, limit int) []*models.LeaderboardEntry {
+	start := 0
+	if after != nil {
+		for i, e := range m.board {
+			if e.PlayerID == after.PlayerID {
+				start = i + 1
+			}
+		}
+	}
+	var result []*models.LeaderboardEntry
+	for _, e := range m.board[start:] {
+		if len(result) == limit {
+			break
+		}
+		copied := *e
+		result = append(result, &copied)
+	}
+	return result
+}
+
+func (m *mockLeaderboardRepo) RatingPage(ctx context.Context, minGames int, after *repositories.LeaderboardCursor, limit int) ([]*models.LeaderboardEntry, error) {
+	return m.page(after, limit), nil
+}
+func (m *mockLeaderboardRepo) RatingStanding(ctx context.Context, minGames int, playerID string) (*models.LeaderboardEntry, error) {
+	for _, e := range m.board {
+		if e.PlayerID == playerID {
+			copied := *e
+			copied.Rank = 99
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockLeaderboardRepo) PointsPage(ctx context.Context, f repositories.LeaderboardFilter, after *repositories.LeaderboardCursor, limit int) ([]*models.LeaderboardEntry, error) {
+	m.usedPoints = true
+	m.lastFilter = f
+	return m.page(after, limit), nil
+}
+func (m *mockLeaderboardRepo) PointsStanding(ctx context.Context, f repositories.LeaderboardFilter, playerID string) (*models.LeaderboardEntry, error) {
+	return m.RatingStanding(ctx, f.MinGames, playerID)
+}
+
+func ratedEntry(id string, rating int) *models.LeaderboardEntry {
+	return &models.LeaderboardEntry{PlayerID: id, Name: id, Rating: &rating}
+}
+
+func TestGetLeaderboard_RatingPagination(t *testing.T) {
+	repo := &mockLeaderboardRepo{board: []*models.LeaderboardEntry{
+		ratedEntry("a", 1600), ratedEntry("b", 1500), ratedEntry("c", 1500), ratedEntry("d", 1400),
+	}}
+	service := NewLeaderboardService(repo)
+	ctx := context.Background()
+
+	first, err := service.GetLeaderboard(ctx, LeaderboardQuery{Limit: 2, PlayerID: "d"})
+	if err != nil {
+		t.Fatalf("GetLeaderboard failed: %v", err)
+	}
+	if first.Sort != LeaderboardSortRating || len(first.Entries) != 2 || first.NextCursor == "" {
+		t.Fatalf("Unexpected first page: %+v", first)
+	}
+	if first.Entries[0].Rank != 1 || first.Entries[1].Rank != 2 {
+		t.Errorf("Unexpected ranks on first page: %d, %d", first.Entries[0].Rank, first.Entries[1].Rank)
+	}
+	if first.Me == nil || first.Me.PlayerID != "d" {
+		t.Errorf("Expected requesting player's standing off the page, got %+v", first.Me)
+	}
+
+	second, err := service.GetLeaderboard(ctx, LeaderboardQuery{Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("GetLeaderboard failed: %v", err)
+	}
+	if len(second.Entries) != 2 || second.NextCursor != "" {
+		t.Fatalf("Unexpected second page: %+v", second)
+	}
+	// c is tied with b from the previous page, so it shares rank 2.
+	if second.Entries[0].PlayerID != "c" || second.Entries[0].Rank != 2 {
+		t.Errorf("Expected c to share rank 2, got %+v", second.Entries[0])
+	}
+	if second.Entries[1].Rank != 4 {
+		t.Errorf("Expected d at rank 4, got %d", second.Entries[1].Rank)
+	}
+}
+
+func TestGetLeaderboard_FiltersUsePoints(t *testing.T) {
+	repo := &mockLeaderboardRepo{board: []*models.LeaderboardEntry{{PlayerID: "a", Points: 10}}}
+	service := NewLeaderboardService(repo)
+	service.now = func() time.Time { return time.Date(2025, time.August, 14, 12, 0, 0, 0, time.UTC) }
+
+	board, err := service.GetLeaderboard(context.Background(), LeaderboardQuery{Period: LeaderboardPeriodSeason, WordLength: 5, MinGames: 3})
+	if err != nil {
+		t.Fatalf("GetLeaderboard failed: %v", err)
+	}
+	if board.Sort != LeaderboardSortPoints || !repo.usedPoints {
+		t.Errorf("Expected a points board, got %q", board.Sort)
+	}
+	want := repositories.LeaderboardFilter{Since: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), WordLength: 5, MinGames: 3}
+	if repo.lastFilter != want {
+		t.Errorf("Expected filter %+v, got %+v", want, repo.lastFilter)
+	}
+
+	// It is already July east of UTC, but the season has not yet changed.
+	service.now = func() time.Time { return time.Date(2025, time.July, 1, 1, 0, 0, 0, time.FixedZone("UTC+3", 3*60*60)) }
+	if _, err := service.GetLeaderboard(context.Background(), LeaderboardQuery{Period: LeaderboardPeriodSeason, WordLength: 5, MinGames: 3}); err != nil {
+		t.Fatalf("GetLeaderboard failed: %v", err)
+	}
+	if want.Since = time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC); repo.lastFilter != want {
+		t.Errorf("Expected filter %+v, got %+v", want, repo.lastFilter)
+	}
+}
+
+func TestGetLeaderboard_InvalidQuery(t *testing.T) {
+	service := NewLeaderboardService(&mockLeaderboardRepo{})
+	if _, err := service.GetLeaderboard(context.Background(), LeaderboardQuery{Period: "week"}); err == nil || err.Error() != "invalid_period" {
+		t.Errorf("Expected invalid_period, got %v", err)
+	}
+	if _, err := service.GetLeaderboard(context.Background(), LeaderboardQuery{Cursor: "not-a-cursor"}); err == nil || err.Error() != "invalid_cursor" {
+		t.Errorf("Expected invalid_cursor, got %v", err)
+	}
+}
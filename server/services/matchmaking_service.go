@@ -6,13 +6,16 @@ import (
 	"sync"
 )
 
-// MatchmakingService handles player matchmaking and challenge invitations.
+// MatchmakingService handles player matchmaking and tracks the players'
+// notification connections. Challenges are handled by ChallengeService.
 type MatchmakingService struct {
-	gameService       GameServiceI
-	queue             []matchmakingClient
-	mu                sync.Mutex
-	online            map[string]interface{} // playerID -> connection
-	pendingChallenges map[string]string      // challengedID -> challengerID
+	gameService GameServiceI
+	queue       []matchmakingClient
+	mu          sync.Mutex
+	online      map[string]interface{} // playerID -> connection
+	// loadBlocks returns a function reporting whether two of the given
+	// players must not be matched.
+	loadBlocks func(playerIDs []string) func(playerID, otherID string) bool
 }
 
 type matchmakingClient struct {
@@ -23,13 +26,21 @@ type matchmakingClient struct {
 // NewMatchmakingService creates a new MatchmakingService.
 func NewMatchmakingService(gameService GameServiceI) *MatchmakingService {
 	return &MatchmakingService{
-		gameService:       gameService,
-		queue:             make([]matchmakingClient, 0),
-		online:            make(map[string]interface{}),
-		pendingChallenges: make(map[string]string),
+		gameService: gameService,
+		queue:       make([]matchmakingClient, 0),
+		online:      make(map[string]interface{}),
 	}
 }
 
+// SetBlockFilter makes TryMatch skip pairs of players who must not be
+// matched. TryMatch calls loadBlocks with the queued players before it locks
+// the queue, and skips pairs for which the returned function returns true.
+func (m *MatchmakingService) SetBlockFilter(loadBlocks func(playerIDs []string) func(playerID, otherID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loadBlocks = loadBlocks
+}
+
 // JoinQueue adds a player and their connection to the matchmaking queue.
 func (m *MatchmakingService) JoinQueue(playerID string, conn interface{}) {
 	m.mu.Lock()
@@ -50,24 +61,57 @@ func (m *MatchmakingService) LeaveQueue(playerID string) {
 }
 
 // TryMatch attempts to match two players. If successful, returns the game and the paired connections.
+// Players are paired in queue order, skipping pairs where one blocked the other.
+// Players who joined while the blocks were loaded wait for the next call.
 func (m *MatchmakingService) TryMatch(ctx context.Context) (gameID string, conns [2]interface{}, ok bool) {
+	m.mu.Lock()
+	loadBlocks := m.loadBlocks
+	checked := make(map[string]bool, len(m.queue))
+	playerIDs := make([]string, 0, len(m.queue))
+	for _, c := range m.queue {
+		checked[c.PlayerID] = true
+		playerIDs = append(playerIDs, c.PlayerID)
+	}
+	m.mu.Unlock()
+
+	blocked := func(playerID, otherID string) bool { return false }
+	if loadBlocks != nil {
+		blocked = loadBlocks(playerIDs)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if len(m.queue) < 2 {
+	i, j, found := m.nextPair(func(playerID, otherID string) bool {
+		return !checked[playerID] || !checked[otherID] || blocked(playerID, otherID)
+	})
+	if !found {
 		return "", [2]interface{}{}, false
 	}
-	c1 := m.queue[0]
-	c2 := m.queue[1]
-	m.queue = m.queue[2:]
+	c1 := m.queue[i]
+	c2 := m.queue[j]
 	game, err := m.gameService.CreateGame(ctx, c1.PlayerID, c2.PlayerID)
 	if err != nil {
-		// If game creation fails, requeue the clients
-		m.queue = append([]matchmakingClient{c1, c2}, m.queue...)
+		// If game creation fails, the clients stay queued
 		return "", [2]interface{}{}, false
 	}
+	m.queue = append(m.queue[:j], m.queue[j+1:]...)
+	m.queue = append(m.queue[:i], m.queue[i+1:]...)
 	return game.ID, [2]interface{}{c1.Conn, c2.Conn}, true
 }
 
+// nextPair returns the positions of the first two queued players for whom
+// skip returns false, with i < j.
+func (m *MatchmakingService) nextPair(skip func(playerID, otherID string) bool) (i, j int, ok bool) {
+	for i = 0; i < len(m.queue); i++ {
+		for j = i + 1; j < len(m.queue); j++ {
+			if !skip(m.queue[i].PlayerID, m.queue[j].PlayerID) {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
 // QueueLength returns the number of players waiting in the queue.
 func (m *MatchmakingService) QueueLength() int {
 	m.mu.Lock()
@@ -88,81 +132,41 @@ func (m *MatchmakingService) UnregisterConnection(playerID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.online, playerID)
-	delete(m.pendingChallenges, playerID)
-	// Remove as challenger if present
-	for challenged, challenger := range m.pendingChallenges {
-		if challenger == playerID {
-			delete(m.pendingChallenges, challenged)
-		}
-	}
 	log.Printf("[debug] UnregisterConnection: playerID=%s", playerID)
 }
 
-// SendChallengeInvite sends a challenge invite from challengerID to challengedID. Returns true if delivered.
-func (m *MatchmakingService) SendChallengeInvite(challengerID, challengedID string) bool {
+// OnlineConnection returns the connection for a given playerID, or false if not online.
+func (m *MatchmakingService) OnlineConnection(playerID string) (interface{}, bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	// Debug log: print all online player IDs
-	log.Printf("[debug] SendChallengeInvite: online keys: %v, challengedID: %s", keys(m.online), challengedID)
-	_, ok := m.online[challengedID]
+	conn, ok := m.online[playerID]
 	if !ok {
-		return false
-	}
-	// Mark as pending
-	m.pendingChallenges[challengedID] = challengerID
-	// The controller should call this and send the message
-	return true
-}
-
-// Helper function to get map keys as []string
-func keys(m map[string]interface{}) []string {
-	ks := make([]string, 0, len(m))
-	for k := range m {
-		ks = append(ks, k)
-	}
-	return ks
-}
-
-// AcceptChallenge handles a challenge response. If accepted, creates a game and returns (gameID, challengerConn, challengedConn, true). If declined, returns ("", challengerConn, challengedConn, false).
-func (m *MatchmakingService) AcceptChallenge(ctx context.Context, challengedID string, accepted bool) (gameID string, challengerConn, challengedConn interface{}, ok bool) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	challengerID, found := m.pendingChallenges[challengedID]
-	if !found {
-		return "", nil, nil, false
-	}
-	challengerConn = m.online[challengerID]
-	challengedConn = m.online[challengedID]
-	delete(m.pendingChallenges, challengedID)
-	if !accepted {
-		return "", challengerConn, challengedConn, false
-	}
-	// Create game
-	game, err := m.gameService.CreateGame(ctx, challengerID, challengedID)
-	if err != nil {
-		return "", challengerConn, challengedConn, false
+		log.Printf("[debug] OnlineConnection: playerID %s not found", playerID)
+	} else {
+		log.Printf("[debug] OnlineConnection: playerID %s found, type: %T", playerID, conn)
 	}
-	return game.ID, challengerConn, challengedConn, true
+	return conn, ok
 }
 
-// CancelChallenge removes a pending challenge from challenger to challenged.
-func (m *MatchmakingService) CancelChallenge(challengerID, challengedID string) {
+// IsOnline reports whether the player has a notification connection.
+func (m *MatchmakingService) IsOnline(playerID string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.pendingChallenges[challengedID] == challengerID {
-		delete(m.pendingChallenges, challengedID)
-	}
+	_, ok := m.online[playerID]
+	return ok
 }
 
-// OnlineConnection returns the connection for a given playerID, or false if not online.
-func (m *MatchmakingService) OnlineConnection(playerID string) (interface{}, bool) {
+// ReassignPlayer is called after player from was merged into player to, e.g.
+// a guest into their registered account. from leaves the queue, as their
+// connection is about to close. Their challenges are stored, so they move
+// with the merge.
+func (m *MatchmakingService) ReassignPlayer(from, to string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	conn, ok := m.online[playerID]
-	if !ok {
-		log.Printf("[debug] OnlineConnection: playerID %s not found", playerID)
-	} else {
-		log.Printf("[debug] OnlineConnection: playerID %s found, type: %T", playerID, conn)
+	for i, c := range m.queue {
+		if c.PlayerID == from {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			break
+		}
 	}
-	return conn, ok
 }
@@ -64,36 +64,6 @@ func TestTryMatch_GameCreationFails(t *testing.T) {
 	}
 }
 
-func TestSendChallengeInviteAndAccept(t *testing.T) {
-	mockGS := &mockGameService{}
-	mm := NewMatchmakingService(mockGS)
-	mm.RegisterConnection("p1", "conn1")
-	mm.RegisterConnection("p2", "conn2")
-	ok := mm.SendChallengeInvite("p1", "p2")
-	if !ok {
-		t.Errorf("Expected challenge invite to succeed")
-	}
-	gameID, challengerConn, challengedConn, accepted := mm.AcceptChallenge(context.Background(), "p2", true)
-	if !accepted || gameID == "" {
-		t.Errorf("Expected challenge to be accepted and game created")
-	}
-	if challengerConn != "conn1" || challengedConn != "conn2" {
-		t.Errorf("Expected correct connections returned")
-	}
-}
-
-func TestCancelChallenge(t *testing.T) {
-	mm := NewMatchmakingService(nil)
-	mm.RegisterConnection("p1", "conn1")
-	mm.RegisterConnection("p2", "conn2")
-	mm.SendChallengeInvite("p1", "p2")
-	mm.CancelChallenge("p1", "p2")
-	_, _, _, accepted := mm.AcceptChallenge(context.Background(), "p2", true)
-	if accepted {
-		t.Errorf("Expected challenge to be cancelled and not accepted")
-	}
-}
-
 func TestOnlineConnection(t *testing.T) {
 	mm := NewMatchmakingService(nil)
 	mm.RegisterConnection("p1", "conn1")
@@ -107,3 +77,60 @@ func TestOnlineConnection(t *testing.T) {
 		t.Errorf("Expected connection to be unregistered")
 	}
 }
+
+func TestReassignPlayer(t *testing.T) {
+	mm := NewMatchmakingService(&mockGameService{})
+	mm.JoinQueue("bob", "conn2")
+	mm.JoinQueue("guest", "conn1")
+
+	mm.ReassignPlayer("guest", "alice")
+
+	if mm.QueueLength() != 1 {
+		t.Errorf("Expected the guest to leave the queue, got length %d", mm.QueueLength())
+	}
+}
+
+func TestTryMatch_SkipsBlockedPairs(t *testing.T) {
+	mockGS := &mockGameService{}
+	mm := NewMatchmakingService(mockGS)
+	mm.SetBlockFilter(func(playerIDs []string) func(a, b string) bool {
+		return func(a, b string) bool {
+			return (a == "p1" && b == "p2") || (a == "p2" && b == "p1")
+		}
+	})
+	mm.JoinQueue("p1", "conn1")
+	mm.JoinQueue("p2", "conn2")
+	if _, _, ok := mm.TryMatch(context.Background()); ok {
+		t.Fatal("Expected blocked players not to be matched")
+	}
+	mm.JoinQueue("p3", "conn3")
+	if _, conns, ok := mm.TryMatch(context.Background()); !ok || conns[0] != "conn1" || conns[1] != "conn3" {
+		t.Fatalf("Expected p1 to be matched with p3, got %v", conns)
+	}
+	if mm.QueueLength() != 1 {
+		t.Errorf("Expected p2 to stay queued, got length %d", mm.QueueLength())
+	}
+}
+
+func TestTryMatch_LoadsBlocksUnlocked(t *testing.T) {
+	mockGS := &mockGameService{}
+	mm := NewMatchmakingService(mockGS)
+	var loaded []string
+	mm.SetBlockFilter(func(playerIDs []string) func(a, b string) bool {
+		loaded = playerIDs
+		// The queue is not locked while blocks load, so players can still join.
+		mm.JoinQueue("p2", "conn2")
+		return func(a, b string) bool { return false }
+	})
+	mm.JoinQueue("p1", "conn1")
+	if _, _, ok := mm.TryMatch(context.Background()); ok {
+		t.Fatal("Expected a player who joined while blocks loaded not to be matched yet")
+	}
+	if len(loaded) != 1 || loaded[0] != "p1" {
+		t.Errorf("Expected the blocks of the queued players to be loaded, got %v", loaded)
+	}
+	mm.SetBlockFilter(nil)
+	if _, conns, ok := mm.TryMatch(context.Background()); !ok || conns[0] != "conn1" || conns[1] != "conn2" {
+		t.Errorf("Expected p1 to be matched with p2 next time, got %v", conns)
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"battle-wordle/server/models"
+)
+
+const (
+	minNameLength = 3
+	maxNameLength = 20
+)
+
+// reservedNames cannot be taken by players because they would pass for the
+// game itself or its staff. They are stored in canonical form.
+var reservedNames = canonicalNames(
+	"admin", "administrator", "moderator", "mod",
+	"staff", "support", "system", "root", "official",
+	"battlewordle", "battle_wordle", "guest", "anonymous",
+	"deleted", "deleted_player", "null", "undefined",
+)
+
+func canonicalNames(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[models.CanonicalName(name)] = true
+	}
+	return set
+}
+
+// deletedNamePrefix starts the canonical names of deleted players, who are
+// named "deleted-" followed by their ID.
+const deletedNamePrefix = "deleted_"
+
+// NamePolicy decides which names players may use.
+type NamePolicy struct {
+	blocked *WordFilter
+}
+
+// NewNamePolicy creates a NamePolicy that also refuses names containing any
+// of the blocked words.
+func NewNamePolicy(blocked []string) *NamePolicy {
+	return &NamePolicy{blocked: NewWordFilter(blocked)}
+}
+
+// Check returns "invalid_name" unless name is 3 to 20 letters, digits, '_',
+// '-' or '.', starting and ending with a letter or digit, with letters from a
+// single script. It returns "name_not_allowed" for reserved names and names
+// with blocked words.
+func (p *NamePolicy) Check(name string) error {
+	if n := utf8.RuneCountInString(name); n < minNameLength || n > maxNameLength {
+		return fmt.Errorf("invalid_name")
+	}
+	script := ""
+	for i, r := range name {
+		switch {
+		case r >= '0' && r <= '9':
+		case r == '_' || r == '-' || r == '.':
+			if i == 0 || i == len(name)-1 {
+				return fmt.Errorf("invalid_name")
+			}
+		case unicode.IsLetter(r):
+			// Mixing scripts is how look-alike names are made, e.g. a
+			// Cyrillic "а" among Latin letters.
+			if s := scriptOf(r); script == "" {
+				script = s
+			} else if s != script {
+				return fmt.Errorf("invalid_name")
+			}
+		default:
+			return fmt.Errorf("invalid_name")
+		}
+	}
+
+	canonical := models.CanonicalName(name)
+	if reservedNames[canonical] || strings.HasPrefix(canonical, deletedNamePrefix) || p.blocked.Contains(name) {
+		return fmt.Errorf("name_not_allowed")
+	}
+	return nil
+}
+
+func scriptOf(r rune) string {
+	switch {
+	case unicode.Is(unicode.Latin, r):
+		return "latin"
+	case unicode.Is(unicode.Cyrillic, r):
+		return "cyrillic"
+	case unicode.Is(unicode.Greek, r):
+		return "greek"
+	}
+	return "other"
+}
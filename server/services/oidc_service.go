@@ -0,0 +1,372 @@
+package services
+
+import (
+	"battle-wordle/server/models"
+	"battle-wordle/server/oidc"
+	"battle-wordle/server/repositories"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// oidcLoginLifetime is how long a player has to log in at the provider.
+	oidcLoginLifetime = 10 * time.Minute
+	// oidcCodeLifetime is how long the UI has to redeem a finished login.
+	oidcCodeLifetime = time.Minute
+	// maxPendingOIDCLogins bounds the logins kept in memory.
+	maxPendingOIDCLogins = 10000
+)
+
+// OIDCProvider is a provider players can log in with.
+type OIDCProvider struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// oidcLogin is a login started at a provider, keyed by its state.
+type oidcLogin struct {
+	provider string
+	nonce    string
+	verifier string
+	// playerID and sessionID are set when a logged-in player started the
+	// login, to upgrade a guest or link the identity to their account.
+	playerID  string
+	sessionID string
+	expiresAt time.Time
+}
+
+// oidcResult is a finished login waiting to be redeemed, keyed by its code.
+type oidcResult struct {
+	player    *models.Player
+	tokens    *AuthTokens
+	expiresAt time.Time
+}
+
+// OIDCService logs players in with external OpenID Connect providers.
+//
+// The UI never sees the provider's tokens: after the provider redirects back,
+// the server verifies the ID token, starts a session and hands the UI a
+// short-lived one-time code, which it redeems for the session's tokens.
+type OIDCService struct {
+	providers     map[string]*oidc.Provider
+	order         []string
+	players       repositories.PlayerRepositoryI
+	identities    repositories.IdentityRepositoryI
+	playerService *PlayerService
+	now           func() time.Time
+
+	mu      sync.Mutex
+	logins  map[string]*oidcLogin
+	results map[string]*oidcResult
+}
+
+// NewOIDCService creates a new OIDCService for the providers, which are
+// offered in the given order.
+func NewOIDCService(providers []*oidc.Provider, players repositories.PlayerRepositoryI, identities repositories.IdentityRepositoryI, playerService *PlayerService) *OIDCService {
+	s := &OIDCService{
+		providers:     make(map[string]*oidc.Provider),
+		players:       players,
+		identities:    identities,
+		playerService: playerService,
+		now:           time.Now,
+		logins:        make(map[string]*oidcLogin),
+		results:       make(map[string]*oidcResult),
+	}
+	for _, p := range providers {
+		s.providers[p.ID()] = p
+		s.order = append(s.order, p.ID())
+	}
+	return s
+}
+
+// Providers returns the configured providers.
+func (s *OIDCService) Providers() []OIDCProvider {
+	providers := make([]OIDCProvider, 0, len(s.order))
+	for _, id := range s.order {
+		providers = append(providers, OIDCProvider{ID: id, Name: s.providers[id].Name()})
+	}
+	return providers
+}
+
+// StartLogin returns the URL of the provider's login page and a binding that
+// the browser starting the login must present to FinishLogin, so that a login
+// started by one browser cannot be finished by another. playerID and
+// sessionID identify the player who started the login, if any: a guest is
+// upgraded and a registered player gets the identity linked to their account.
+// It returns "provider_not_found" for unknown providers.
+func (s *OIDCService) StartLogin(ctx context.Context, providerID, playerID, sessionID string) (loginURL, binding string, err error) {
+	provider, ok := s.providers[providerID]
+	if !ok {
+		return "", "", fmt.Errorf("provider_not_found")
+	}
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	loginURL, err = provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	if len(s.logins) >= maxPendingOIDCLogins {
+		return "", "", fmt.Errorf("too_many_logins")
+	}
+	s.logins[state] = &oidcLogin{
+		provider:  providerID,
+		nonce:     nonce,
+		verifier:  verifier,
+		playerID:  playerID,
+		sessionID: sessionID,
+		expiresAt: s.now().Add(oidcLoginLifetime),
+	}
+	return loginURL, stateBinding(state), nil
+}
+
+// FinishLogin completes the login the provider redirected back from with
+// state and code, and returns a one-time code for RedeemLogin. binding is the
+// one StartLogin returned to the browser. It returns "invalid_login_state"
+// for unknown, expired or already used states or a binding that does not
+// match, and "identity_linked_elsewhere" when a logged-in player tries to
+// link an identity that belongs to another player.
+func (s *OIDCService) FinishLogin(ctx context.Context, providerID, state, binding, code string, client SessionClient) (string, error) {
+	s.mu.Lock()
+	login, ok := s.logins[state]
+	delete(s.logins, state)
+	s.mu.Unlock()
+	if !ok || login.provider != providerID || s.now().After(login.expiresAt) ||
+		subtle.ConstantTimeCompare([]byte(stateBinding(state)), []byte(binding)) != 1 {
+		return "", fmt.Errorf("invalid_login_state")
+	}
+	provider := s.providers[providerID]
+
+	rawIDToken, err := provider.Exchange(ctx, code, login.verifier)
+	if err != nil {
+		return "", err
+	}
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, login.nonce)
+	if err != nil {
+		return "", err
+	}
+
+	player, err := s.resolvePlayer(ctx, providerID, claims, login)
+	if err != nil {
+		return "", err
+	}
+	tokens, err := s.playerService.StartSession(ctx, player, client)
+	if err != nil {
+		return "", err
+	}
+
+	loginCode, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	s.results[loginCode] = &oidcResult{player: player, tokens: tokens, expiresAt: s.now().Add(oidcCodeLifetime)}
+	return loginCode, nil
+}
+
+// RedeemLogin exchanges a code from FinishLogin for the player and their new
+// session's tokens. Codes can be used once; it returns "invalid_login_code"
+// otherwise.
+func (s *OIDCService) RedeemLogin(code string) (*models.Player, *AuthTokens, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.results[code]
+	delete(s.results, code)
+	if !ok || s.now().After(result.expiresAt) {
+		return nil, nil, fmt.Errorf("invalid_login_code")
+	}
+	return result.player, result.tokens, nil
+}
+
+// resolvePlayer returns the player the identity logs in as, creating or
+// upgrading the player and linking the identity as needed.
+func (s *OIDCService) resolvePlayer(ctx context.Context, providerID string, claims *oidc.Claims, login *oidcLogin) (*models.Player, error) {
+	identity, err := s.identities.Get(ctx, providerID, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		player, err := s.players.GetByID(ctx, identity.PlayerID)
+		if err != nil {
+			return nil, err
+		}
+		if player != nil && player.DeletedAt == nil {
+			if login.playerID != "" && login.playerID != player.ID {
+				current, err := s.players.GetByID(ctx, login.playerID)
+				if err != nil {
+					return nil, err
+				}
+				// A guest simply switches to the account; a registered
+				// player cannot take over another player's identity.
+				if current != nil && current.Registered {
+					return nil, fmt.Errorf("identity_linked_elsewhere")
+				}
+			}
+			return player, nil
+		}
+		// The account was deleted; the identity starts afresh.
+		if err := s.identities.Delete(ctx, providerID, claims.Subject); err != nil {
+			return nil, err
+		}
+	}
+
+	var current *models.Player
+	if login.playerID != "" {
+		if current, err = s.players.GetByID(ctx, login.playerID); err != nil {
+			return nil, err
+		}
+		if current != nil && current.DeletedAt != nil {
+			current = nil
+		}
+	}
+
+	var player *models.Player
+	switch {
+	case current != nil && current.Registered:
+		player = current
+	case current != nil:
+		name, err := s.deriveName(ctx, claims, current.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.players.RegisterGuest(ctx, current.ID, name); err != nil {
+			return nil, err
+		}
+		if player, err = s.players.GetByID(ctx, current.ID); err != nil {
+			return nil, err
+		}
+		if err := s.playerService.revokeSession(ctx, login.sessionID); err != nil {
+			return nil, err
+		}
+	default:
+		name, err := s.deriveName(ctx, claims, "")
+		if err != nil {
+			return nil, err
+		}
+		player = &models.Player{
+			ID:         uuid.NewString(),
+			Name:       name,
+			Registered: true,
+			Role:       models.RolePlayer,
+			CreatedAt:  s.now().UTC(),
+		}
+		if err := s.players.CreatePlayer(ctx, player); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.identities.Create(ctx, &models.Identity{
+		Provider:  providerID,
+		Subject:   claims.Subject,
+		PlayerID:  player.ID,
+		Email:     claims.Email,
+		CreatedAt: s.now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	// Adopt a verified address for password resets unless the player has one.
+	if email := normalizeEmail(claims.Email); claims.EmailVerified && player.Email == nil && validEmail(email) {
+		if err := s.players.UpdateEmail(ctx, player.ID, &email); err == nil {
+			player.Email = &email
+		} else if err.Error() != "email_taken" {
+			log.Printf("error setting email of player %q: %v", player.ID, err)
+		}
+	}
+	return player, nil
+}
+
+// deriveName picks an unused player name from the identity's claims. The
+// player with ID self may keep their own name.
+func (s *OIDCService) deriveName(ctx context.Context, claims *oidc.Claims, self string) (string, error) {
+	base := ""
+	for _, candidate := range []string{claims.PreferredUsername, claims.Name, strings.SplitN(claims.Email, "@", 2)[0]} {
+		if name := sanitizeName(candidate); s.playerService.names.Check(name) == nil {
+			base = name
+			break
+		}
+	}
+	if base == "" {
+		base = "player"
+	}
+	for i := 1; i <= 100; i++ {
+		name := base
+		if i > 1 {
+			suffix := strconv.Itoa(i)
+			name = base[:min(len(base), maxNameLength-len(suffix))] + suffix
+		}
+		err := s.playerService.checkName(ctx, name, self)
+		if err == nil {
+			return name, nil
+		}
+		if err.Error() != "username_taken" {
+			return "", err
+		}
+	}
+	return base[:min(len(base), maxNameLength-9)] + strings.ReplaceAll(uuid.NewString()[:8], "-", ""), nil
+}
+
+// sanitizeName keeps the letters, digits and underscores of s, with spaces
+// and dots turned into underscores.
+func sanitizeName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r == ' ', r == '.', r == '-':
+			b.WriteByte('_')
+		}
+		if b.Len() >= maxNameLength {
+			break
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// stateBinding returns the binding of a login's state, a hash of it, so that
+// the browser does not hold the state itself.
+func stateBinding(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// pruneLocked drops expired logins and results. s.mu must be held.
+func (s *OIDCService) pruneLocked() {
+	now := s.now()
+	for state, login := range s.logins {
+		if now.After(login.expiresAt) {
+			delete(s.logins, state)
+		}
+	}
+	for code, result := range s.results {
+		if now.After(result.expiresAt) {
+			delete(s.results, code)
+		}
+	}
+}
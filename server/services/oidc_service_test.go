@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type mockIdentityRepo struct {
+	identities map[string]*models.Identity
+}
+
+func (m *mockIdentityRepo) Get(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	return m.identities[provider+"/"+subject], nil
+}
+func (m *mockIdentityRepo) Create(ctx context.Context, identity *models.Identity) error {
+	key := identity.Provider + "/" + identity.Subject
+	if _, exists := m.identities[key]; exists {
+		return &mockError{"identity_taken"}
+	}
+	m.identities[key] = identity
+	return nil
+}
+func (m *mockIdentityRepo) Delete(ctx context.Context, provider, subject string) error {
+	delete(m.identities, provider+"/"+subject)
+	return nil
+}
+func (m *mockIdentityRepo) ListByPlayer(ctx context.Context, playerID string) ([]*models.Identity, error) {
+	var result []*models.Identity
+	for _, identity := range m.identities {
+		if identity.PlayerID == playerID {
+			result = append(result, identity)
+		}
+	}
+	return result, nil
+}
+
+// mockIdP is an OpenID Connect provider that logs in the user in claims.
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+	// codes maps issued authorization codes to their PKCE challenge and nonce.
+	codes map[string][2]string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: map[string][2]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		issued, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != issued[0] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"aud":   "client",
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": issued[1],
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		signed, _ := token.SignedString(idp.key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// login follows the login URL like a browser would and returns the state and
+// code the provider redirects back with.
+func (idp *mockIdP) login(t *testing.T, loginURL string) (state, code string) {
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "client" {
+		t.Fatalf("Unexpected login URL %s", loginURL)
+	}
+	code = "code-" + q.Get("state")
+	idp.codes[code] = [2]string{q.Get("code_challenge"), q.Get("nonce")}
+	return q.Get("state"), code
+}
+
+func newTestOIDCService(t *testing.T) (*OIDCService, *mockIdP, *PlayerService, *mockPlayerRepo, *mockIdentityRepo) {
+	idp := newMockIdP(t)
+	provider := oidc.NewProvider(oidc.Config{
+		ID:          "test",
+		Name:        "Test",
+		Issuer:      idp.server.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost/api/auth/oidc/test/callback",
+	}, idp.server.Client())
+	players := newMockPlayerRepo()
+	identities := &mockIdentityRepo{identities: map[string]*models.Identity{}}
+	playerService := NewPlayerService(players, newMockSessionRepo(), "testsecret")
+	return NewOIDCService([]*oidc.Provider{provider}, players, identities, playerService), idp, playerService, players, identities
+}
+
+func TestOIDCService_LoginCreatesAndReusesPlayer(t *testing.T) {
+	ctx := context.Background()
+	service, idp, playerService, _, identities := newTestOIDCService(t)
+	idp.claims = jwt.MapClaims{"sub": "user-1", "preferred_username": "alice.smith", "email": "Alice@example.com", "email_verified": true}
+
+	loginURL, binding, err := service.StartLogin(ctx, "test", "", "")
+	if err != nil {
+		t.Fatalf("StartLogin failed: %v", err)
+	}
+	state, code := idp.login(t, loginURL)
+	loginCode, err := service.FinishLogin(ctx, "test", state, binding, code, SessionClient{})
+	if err != nil {
+		t.Fatalf("FinishLogin failed: %v", err)
+	}
+	player, tokens, err := service.RedeemLogin(loginCode)
+	if err != nil {
+		t.Fatalf("RedeemLogin failed: %v", err)
+	}
+	if player.Name != "alice_smith" || !player.Registered || player.PasswordHash != nil {
+		t.Errorf("Unexpected player %+v", player)
+	}
+	if player.Email == nil || *player.Email != "alice@example.com" {
+		t.Errorf("Expected the verified email to be adopted, got %v", player.Email)
+	}
+	if id, _, err := playerService.VerifyJWT(ctx, tokens.AccessToken); err != nil || id != player.ID {
+		t.Errorf("Expected a session for the player, got %q, %v", id, err)
+	}
+	if identity := identities.identities["test/user-1"]; identity == nil || identity.PlayerID != player.ID {
+		t.Errorf("Expected the identity to be linked, got %+v", identity)
+	}
+
+	if _, _, err := service.RedeemLogin(loginCode); err == nil || err.Error() != "invalid_login_code" {
+		t.Errorf("Expected login codes to be single-use, got %v", err)
+	}
+	if _, err := service.FinishLogin(ctx, "test", state, binding, code, SessionClient{}); err == nil || err.Error() != "invalid_login_state" {
+		t.Errorf("Expected states to be single-use, got %v", err)
+	}
+
+	loginURL, binding, _ = service.StartLogin(ctx, "test", "", "")
+	state, code = idp.login(t, loginURL)
+	loginCode, err = service.FinishLogin(ctx, "test", state, binding, code, SessionClient{})
+	if err != nil {
+		t.Fatalf("Second FinishLogin failed: %v", err)
+	}
+	again, _, _ := service.RedeemLogin(loginCode)
+	if again == nil || again.ID != player.ID {
+		t.Errorf("Expected to log in as the same player, got %+v", again)
+	}
+}
+
+func TestOIDCService_UpgradesGuest(t *testing.T) {
+	ctx := context.Background()
+	service, idp, playerService, _, _ := newTestOIDCService(t)
+	guest, guestTokens, _ := playerService.CreatePlayer(ctx, "guest1", nil, nil, SessionClient{})
+	idp.claims = jwt.MapClaims{"sub": "user-2", "name": "Bob"}
+
+	loginURL, binding, _ := service.StartLogin(ctx, "test", guest.ID, guestTokens.SessionID)
+	state, code := idp.login(t, loginURL)
+	loginCode, err := service.FinishLogin(ctx, "test", state, binding, code, SessionClient{})
+	if err != nil {
+		t.Fatalf("FinishLogin failed: %v", err)
+	}
+	player, _, _ := service.RedeemLogin(loginCode)
+	if player.ID != guest.ID || player.Name != "Bob" || !player.Registered {
+		t.Errorf("Expected the guest to be upgraded, got %+v", player)
+	}
+	if _, _, err := playerService.VerifyJWT(ctx, guestTokens.AccessToken); err == nil {
+		t.Error("Expected the guest's session to end")
+	}
+}
+
+func TestOIDCService_RequiresBrowserBinding(t *testing.T) {
+	ctx := context.Background()
+	service, idp, playerService, _, identities := newTestOIDCService(t)
+	attacker, attackerTokens, _ := playerService.CreatePlayer(ctx, "mallory", nil, nil, SessionClient{})
+	idp.claims = jwt.MapClaims{"sub": "victim"}
+
+	// A login started by one browser cannot be finished by another, e.g. a
+	// victim sent the attacker's provider URL.
+	loginURL, _, _ := service.StartLogin(ctx, "test", attacker.ID, attackerTokens.SessionID)
+	state, code := idp.login(t, loginURL)
+	if _, err := service.FinishLogin(ctx, "test", state, "", code, SessionClient{}); err == nil || err.Error() != "invalid_login_state" {
+		t.Errorf("Expected invalid_login_state without the binding, got %v", err)
+	}
+	loginURL, _, _ = service.StartLogin(ctx, "test", attacker.ID, attackerTokens.SessionID)
+	_, otherBinding, _ := service.StartLogin(ctx, "test", "", "")
+	state, code = idp.login(t, loginURL)
+	if _, err := service.FinishLogin(ctx, "test", state, otherBinding, code, SessionClient{}); err == nil || err.Error() != "invalid_login_state" {
+		t.Errorf("Expected invalid_login_state with another login's binding, got %v", err)
+	}
+	if identity := identities.identities["test/victim"]; identity != nil {
+		t.Errorf("Expected no identity to be linked, got %+v", identity)
+	}
+}
+
+func TestOIDCService_RejectsInvalidTokens(t *testing.T) {
+	ctx := context.Background()
+	service, idp, _, _, _ := newTestOIDCService(t)
+	idp.claims = jwt.MapClaims{"sub": "user-3", "nonce": "forged"}
+
+	loginURL, binding, _ := service.StartLogin(ctx, "test", "", "")
+	state, code := idp.login(t, loginURL)
+	if _, err := service.FinishLogin(ctx, "test", state, binding, code, SessionClient{}); err == nil {
+		t.Error("Expected a token with the wrong nonce to be rejected")
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	idp.key = otherKey
+	idp.claims = jwt.MapClaims{"sub": "user-3"}
+	loginURL, binding, _ = service.StartLogin(ctx, "test", "", "")
+	state, code = idp.login(t, loginURL)
+	if _, err := service.FinishLogin(ctx, "test", state, binding, code, SessionClient{}); err == nil {
+		t.Error("Expected a token with an invalid signature to be rejected")
+	}
+
+	if _, _, err := service.StartLogin(ctx, "unknown", "", ""); err == nil || err.Error() != "provider_not_found" {
+		t.Errorf("Expected provider_not_found, got %v", err)
+	}
+}
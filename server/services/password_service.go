@@ -0,0 +1,215 @@
+package services
+
+import (
+	"battle-wordle/server/mail"
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// passwordResetLifetime is how long a reset link can be used.
+	passwordResetLifetime = time.Hour
+	// maxPasswordResetsPerHour limits how many reset emails a player gets.
+	maxPasswordResetsPerHour = 3
+	minPasswordLength        = 8
+)
+
+// PasswordService changes and resets the passwords of registered players.
+type PasswordService struct {
+	players       repositories.PlayerRepositoryI
+	resets        repositories.PasswordResetRepositoryI
+	playerService *PlayerService
+	mailer        mail.Mailer
+	// resetURL is the UI page reset links point to; the token is appended as
+	// the "token" query parameter.
+	resetURL string
+	now      func() time.Time
+}
+
+// NewPasswordService creates a new PasswordService.
+func NewPasswordService(players repositories.PlayerRepositoryI, resets repositories.PasswordResetRepositoryI, playerService *PlayerService, mailer mail.Mailer, resetURL string) *PasswordService {
+	return &PasswordService{
+		players:       players,
+		resets:        resets,
+		playerService: playerService,
+		mailer:        mailer,
+		resetURL:      resetURL,
+		now:           time.Now,
+	}
+}
+
+// ChangePassword sets a new password after checking the current one, and ends
+// every other session of the player. It returns "invalid_password" if the
+// current password is wrong and "password_too_short" if the new one is.
+func (s *PasswordService) ChangePassword(ctx context.Context, playerID, sessionID, current, password string) error {
+	if _, err := s.checkPassword(ctx, playerID, current); err != nil {
+		return err
+	}
+	if err := s.setPassword(ctx, playerID, password); err != nil {
+		return err
+	}
+	_, err := s.playerService.RevokeOtherSessions(ctx, playerID, sessionID)
+	return err
+}
+
+// SetEmail sets or, when email is empty, removes the address reset links are
+// sent to. It checks the player's password, and returns "invalid_email" and
+// "email_taken" for unusable addresses.
+func (s *PasswordService) SetEmail(ctx context.Context, playerID, password, email string) error {
+	if _, err := s.checkPassword(ctx, playerID, password); err != nil {
+		return err
+	}
+	var address *string
+	if email = normalizeEmail(email); email != "" {
+		if !validEmail(email) {
+			return fmt.Errorf("invalid_email")
+		}
+		address = &email
+	}
+	return s.players.UpdateEmail(ctx, playerID, address)
+}
+
+// RequestReset emails a reset link to the registered player with the given
+// name or email address. To not reveal which players exist or have an
+// address, it succeeds without sending anything when there is no such player.
+func (s *PasswordService) RequestReset(ctx context.Context, nameOrEmail string) error {
+	nameOrEmail = strings.TrimSpace(nameOrEmail)
+	var player *models.Player
+	var err error
+	if strings.Contains(nameOrEmail, "@") {
+		player, err = s.players.GetByEmail(ctx, normalizeEmail(nameOrEmail))
+	} else {
+		player, err = s.players.GetByName(ctx, nameOrEmail)
+	}
+	if err != nil {
+		return err
+	}
+	if player == nil || !player.Registered || player.Email == nil {
+		return nil
+	}
+
+	now := s.now().UTC()
+	if err := s.resets.DeleteExpired(ctx, now); err != nil {
+		log.Printf("error deleting expired password resets: %v", err)
+	}
+	sent, err := s.resets.CountSince(ctx, player.ID, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if sent >= maxPasswordResetsPerHour {
+		log.Printf("too many password resets for player %s, not sending another", player.ID)
+		return nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	reset := &models.PasswordReset{
+		ID:        uuid.NewString(),
+		PlayerID:  player.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetLifetime),
+	}
+	if err := s.resets.Create(ctx, reset); err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mail.Message{
+		To:      *player.Email,
+		Subject: "Reset your Battle Wordle password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your Battle Wordle account. "+
+			"To choose a new password, open this link within the next hour:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email; your password stays the same.\n",
+			player.Name, s.resetLink(token)),
+	})
+}
+
+// ResetPassword sets a new password with a reset token and ends all of the
+// player's sessions. Each token works once; it returns "invalid_reset_token"
+// for unknown, used or expired tokens.
+func (s *PasswordService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password_too_short")
+	}
+	reset, err := s.resets.Consume(ctx, hashToken(token), s.now().UTC())
+	if err != nil {
+		return err
+	}
+	if reset == nil {
+		return fmt.Errorf("invalid_reset_token")
+	}
+	if err := s.setPassword(ctx, reset.PlayerID, password); err != nil {
+		if err.Error() == "player_not_found" {
+			return fmt.Errorf("invalid_reset_token")
+		}
+		return err
+	}
+	_, err = s.playerService.RevokeOtherSessions(ctx, reset.PlayerID, "")
+	return err
+}
+
+// checkPassword returns the registered player if password is theirs, and
+// "invalid_password" otherwise.
+func (s *PasswordService) checkPassword(ctx context.Context, playerID, password string) (*models.Player, error) {
+	player, err := s.players.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || !player.Registered || player.PasswordHash == nil {
+		return nil, fmt.Errorf("invalid_password")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(*player.PasswordHash), []byte(password)); err != nil {
+		return nil, fmt.Errorf("invalid_password")
+	}
+	return player, nil
+}
+
+func (s *PasswordService) setPassword(ctx context.Context, playerID, password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password_too_short")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.players.UpdatePassword(ctx, playerID, string(hash))
+}
+
+func (s *PasswordService) resetLink(token string) string {
+	u, err := url.Parse(s.resetURL)
+	if err != nil {
+		return s.resetURL + "?token=" + url.QueryEscape(token)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validEmail reports whether email is a bare address such as a@example.com.
+func validEmail(email string) bool {
+	if len(email) > 254 {
+		return false
+	}
+	addr, err := netmail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndex(email, "@"):], ".")
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"battle-wordle/server/mail"
+	"battle-wordle/server/models"
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type mockPasswordResetRepo struct {
+	resets []*models.PasswordReset
+}
+
+func (m *mockPasswordResetRepo) Create(ctx context.Context, reset *models.PasswordReset) error {
+	for _, r := range m.resets {
+		if r.PlayerID == reset.PlayerID && r.UsedAt == nil {
+			usedAt := reset.CreatedAt
+			r.UsedAt = &usedAt
+		}
+	}
+	m.resets = append(m.resets, reset)
+	return nil
+}
+func (m *mockPasswordResetRepo) Consume(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordReset, error) {
+	for _, r := range m.resets {
+		if r.TokenHash == tokenHash && r.UsedAt == nil && r.ExpiresAt.After(now) {
+			r.UsedAt = &now
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockPasswordResetRepo) CountSince(ctx context.Context, playerID string, since time.Time) (int, error) {
+	n := 0
+	for _, r := range m.resets {
+		if r.PlayerID == playerID && r.CreatedAt.After(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+func (m *mockPasswordResetRepo) DeleteExpired(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+type mockMailer struct {
+	sent []mail.Message
+}
+
+func (m *mockMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var resetLinkPattern = regexp.MustCompile(`https://example\.com/reset-password\?token=\S+`)
+
+func resetTokenFromMail(t *testing.T, msg mail.Message) string {
+	link := resetLinkPattern.FindString(msg.Body)
+	if link == "" {
+		t.Fatalf("No reset link in mail: %q", msg.Body)
+	}
+	u, _ := url.Parse(link)
+	return u.Query().Get("token")
+}
+
+func newTestPasswordService() (*PasswordService, *PlayerService, *mockMailer) {
+	players := newMockPlayerRepo()
+	playerService := NewPlayerService(players, newMockSessionRepo(), "testsecret")
+	mailer := &mockMailer{}
+	return NewPasswordService(players, &mockPasswordResetRepo{}, playerService, mailer, "https://example.com/reset-password"), playerService, mailer
+}
+
+func TestPasswordService_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+	service, playerService, mailer := newTestPasswordService()
+	pw := "old password"
+	player, tokens, _ := playerService.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
+	if err := service.SetEmail(ctx, player.ID, pw, " User1@Example.com "); err != nil {
+		t.Fatalf("SetEmail failed: %v", err)
+	}
+
+	if err := service.RequestReset(ctx, "nobody@example.com"); err != nil || len(mailer.sent) != 0 {
+		t.Fatalf("Expected no mail for an unknown address, got %v, %d sent", err, len(mailer.sent))
+	}
+	if err := service.RequestReset(ctx, "USER1@example.com"); err != nil {
+		t.Fatalf("RequestReset failed: %v", err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "user1@example.com" {
+		t.Fatalf("Expected one mail to user1@example.com, got %+v", mailer.sent)
+	}
+	token := resetTokenFromMail(t, mailer.sent[0])
+
+	if err := service.ResetPassword(ctx, token, "short"); err == nil || err.Error() != "password_too_short" {
+		t.Errorf("Expected password_too_short, got %v", err)
+	}
+	if err := service.ResetPassword(ctx, token, "new password"); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+	if err := service.ResetPassword(ctx, token, "another password"); err == nil || err.Error() != "invalid_reset_token" {
+		t.Errorf("Expected a used token to be rejected, got %v", err)
+	}
+	if _, _, err := playerService.VerifyJWT(ctx, tokens.AccessToken); err == nil {
+		t.Error("Expected sessions to end after a reset")
+	}
+	if p, _, err := playerService.Login(ctx, "user1", "new password", SessionClient{}); err != nil || p == nil {
+		t.Errorf("Expected login with the new password, got %v", err)
+	}
+}
+
+func TestPasswordService_RequestResetSupersedesAndLimits(t *testing.T) {
+	ctx := context.Background()
+	service, playerService, mailer := newTestPasswordService()
+	pw := "old password"
+	player, _, _ := playerService.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
+	service.SetEmail(ctx, player.ID, pw, "user1@example.com")
+
+	for i := 0; i < maxPasswordResetsPerHour+1; i++ {
+		if err := service.RequestReset(ctx, "user1"); err != nil {
+			t.Fatalf("RequestReset failed: %v", err)
+		}
+	}
+	if len(mailer.sent) != maxPasswordResetsPerHour {
+		t.Fatalf("Expected %d mails, got %d", maxPasswordResetsPerHour, len(mailer.sent))
+	}
+	if err := service.ResetPassword(ctx, resetTokenFromMail(t, mailer.sent[0]), "new password"); err == nil || err.Error() != "invalid_reset_token" {
+		t.Errorf("Expected a superseded token to be rejected, got %v", err)
+	}
+
+	latest := resetTokenFromMail(t, mailer.sent[len(mailer.sent)-1])
+	service.now = func() time.Time { return time.Now().Add(passwordResetLifetime + time.Minute) }
+	if err := service.ResetPassword(ctx, latest, "new password"); err == nil || err.Error() != "invalid_reset_token" {
+		t.Errorf("Expected an expired token to be rejected, got %v", err)
+	}
+}
+
+func TestPasswordService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	service, playerService, _ := newTestPasswordService()
+	pw := "old password"
+	player, current, _ := playerService.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
+	_, other, _ := playerService.Login(ctx, "user1", pw, SessionClient{})
+
+	if err := service.ChangePassword(ctx, player.ID, current.SessionID, "wrong", "new password"); err == nil || err.Error() != "invalid_password" {
+		t.Errorf("Expected invalid_password, got %v", err)
+	}
+	if err := service.ChangePassword(ctx, player.ID, current.SessionID, pw, "new password"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	if _, _, err := playerService.VerifyJWT(ctx, current.AccessToken); err != nil {
+		t.Errorf("Expected the current session to stay active, got %v", err)
+	}
+	if _, _, err := playerService.VerifyJWT(ctx, other.AccessToken); err == nil {
+		t.Error("Expected other sessions to end")
+	}
+	if p, _, _ := playerService.Login(ctx, "user1", pw, SessionClient{}); p != nil {
+		t.Error("Expected the old password to stop working")
+	}
+
+	guest, _, _ := playerService.CreatePlayer(ctx, "guest1", nil, nil, SessionClient{})
+	if err := service.SetEmail(ctx, guest.ID, "", "guest@example.com"); err == nil || err.Error() != "invalid_password" {
+		t.Errorf("Expected guests to be unable to set an email, got %v", err)
+	}
+	if err := service.SetEmail(ctx, player.ID, "new password", "not an email"); err == nil || err.Error() != "invalid_email" {
+		t.Errorf("Expected invalid_email, got %v", err)
+	}
+}
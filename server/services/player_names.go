@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+const (
+	// renameCooldown is how long players wait between renames.
+	renameCooldown = 30 * 24 * time.Hour
+	// nameReservation is how long a former name stays reserved for the player
+	// who gave it up, so that nobody else can pass for them.
+	nameReservation = 90 * 24 * time.Hour
+)
+
+// RenameCooldownError is returned by Rename when the player renamed too
+// recently.
+type RenameCooldownError struct {
+	Until time.Time
+}
+
+func (e *RenameCooldownError) Error() string { return "rename_cooldown" }
+
+// SetNamePolicy replaces the policy new names are checked against.
+func (s *PlayerService) SetNamePolicy(policy *NamePolicy) {
+	s.names = policy
+}
+
+// checkName checks name against the name policy, and returns
+// "username_taken" if another player has the name or gave it up less than
+// nameReservation ago. The player with ID self may take their own names.
+func (s *PlayerService) checkName(ctx context.Context, name, self string) error {
+	if err := s.names.Check(name); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != self {
+		return fmt.Errorf("username_taken")
+	}
+	former, err := s.repo.GetByFormerName(ctx, name, s.now().Add(-nameReservation))
+	if err != nil {
+		return err
+	}
+	if former != nil && former.ID != self {
+		return fmt.Errorf("username_taken")
+	}
+	return nil
+}
+
+// Rename changes a registered player's name, keeping the old one in their
+// name history. Players can rename once per renameCooldown; before then it
+// returns a *RenameCooldownError. It returns "invalid_name",
+// "name_not_allowed" and "username_taken" for unusable names,
+// "player_not_registered" for guests, who choose a name when they register,
+// and "player_not_found" for unknown or deleted players.
+func (s *PlayerService) Rename(ctx context.Context, playerID, name string) (*models.Player, error) {
+	player, err := s.repo.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.DeletedAt != nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+	if !player.Registered {
+		return nil, fmt.Errorf("player_not_registered")
+	}
+	history, err := s.repo.GetNameHistory(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > 0 {
+		if until := history[0].ChangedAt.Add(renameCooldown); s.now().Before(until) {
+			return nil, &RenameCooldownError{Until: until}
+		}
+	}
+	if err := s.rename(ctx, player, name); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, playerID)
+}
+
+// rename changes a player's name after checking it, without a cooldown.
+func (s *PlayerService) rename(ctx context.Context, player *models.Player, name string) error {
+	name = strings.TrimSpace(name)
+	if name == player.Name {
+		return nil
+	}
+	if err := s.checkName(ctx, name, player.ID); err != nil {
+		return err
+	}
+	return s.repo.Rename(ctx, player.ID, name, s.now())
+}
+
+// ResolveName returns the player with the given name, or else the player
+// who most recently gave it up, or nil if there is none. Names are compared
+// in canonical form.
+func (s *PlayerService) ResolveName(ctx context.Context, name string) (*models.Player, error) {
+	player, err := s.repo.GetByName(ctx, strings.TrimSpace(name))
+	if err != nil || player != nil {
+		return player, err
+	}
+	return s.repo.GetByFormerName(ctx, strings.TrimSpace(name), time.Time{})
+}
+
+// NameHistory returns the names the player used before, newest first.
+func (s *PlayerService) NameHistory(ctx context.Context, playerID string) ([]*models.NameChange, error) {
+	return s.repo.GetNameHistory(ctx, playerID)
+}
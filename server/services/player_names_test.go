@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+func TestNamePolicy_Check(t *testing.T) {
+	policy := NewNamePolicy([]string{"badword"})
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"alice", ""},
+		{"Bob_Smith.99", ""},
+		{"José", ""},
+		{"Иван", ""},
+		{"ab", "invalid_name"},
+		{"averyveryverylongname1", "invalid_name"},
+		{"has space", "invalid_name"},
+		{"_alice", "invalid_name"},
+		{"alice-", "invalid_name"},
+		{"аdmin", "invalid_name"}, // Cyrillic а among Latin letters
+		{"ADMIN", "name_not_allowed"},
+		{"Moderator", "name_not_allowed"},
+		{"AdmIn", "name_not_allowed"},
+		{"offlcial", "name_not_allowed"},
+		{"deleted-1234", "name_not_allowed"},
+		{"xBadWordx", "name_not_allowed"},
+		{"b4dw0rd", "name_not_allowed"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if err := policy.Check(tt.name); err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("Check(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalName_LookAlikes(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"Paypal", "PaypaI"},
+		{"Paypal", "Paypa|"},
+		{"Alice", "ALICE"},
+		{"alice", "aIice"},
+		{"bill", "b1ll"},
+	}
+	for _, tt := range tests {
+		if a, b := models.CanonicalName(tt.a), models.CanonicalName(tt.b); a != b {
+			t.Errorf("Expected %q and %q to share a canonical name, got %q and %q", tt.a, tt.b, a, b)
+		}
+	}
+}
+
+func TestPlayerService_Rename(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockPlayerRepo()
+	service := NewPlayerService(repo, newMockSessionRepo(), "testsecret")
+	pw := "pw"
+	player, _, _ := service.CreatePlayer(ctx, "alice", &pw, nil, SessionClient{})
+	guest, _, _ := service.CreatePlayer(ctx, "guest1", nil, nil, SessionClient{})
+
+	if _, err := service.Rename(ctx, guest.ID, "newguest"); err == nil || err.Error() != "player_not_registered" {
+		t.Errorf("Expected guests to be unable to rename, got %v", err)
+	}
+	if _, err := service.Rename(ctx, player.ID, "x"); err == nil || err.Error() != "invalid_name" {
+		t.Errorf("Expected invalid_name, got %v", err)
+	}
+	renamed, err := service.Rename(ctx, player.ID, "alicia")
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if renamed.Name != "alicia" {
+		t.Errorf("Expected the new name, got %q", renamed.Name)
+	}
+
+	// The old name resolves to the player and stays reserved for them.
+	if p, err := service.ResolveName(ctx, "alice"); err != nil || p == nil || p.ID != player.ID {
+		t.Errorf("Expected the old name to resolve to the player, got %+v, %v", p, err)
+	}
+	if _, _, err := service.CreatePlayer(ctx, "alice", &pw, nil, SessionClient{}); err == nil || err.Error() != "username_taken" {
+		t.Errorf("Expected the old name to be reserved, got %v", err)
+	}
+	if history, _ := service.NameHistory(ctx, player.ID); len(history) != 1 || history[0].Name != "alice" {
+		t.Errorf("Expected the old name in the history, got %+v", history)
+	}
+
+	_, err = service.Rename(ctx, player.ID, "alice")
+	var cooldown *RenameCooldownError
+	if !errors.As(err, &cooldown) {
+		t.Fatalf("Expected a cooldown, got %v", err)
+	}
+	service.now = func() time.Time { return cooldown.Until.Add(time.Minute) }
+	if _, err := service.Rename(ctx, player.ID, "alice"); err != nil {
+		t.Errorf("Expected the player to take back their old name after the cooldown, got %v", err)
+	}
+}
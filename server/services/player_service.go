@@ -2,41 +2,76 @@ package services
 
 import (
 	"battle-wordle/server/models"
+	"battle-wordle/server/ratelimit"
 	"battle-wordle/server/repositories"
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 // PlayerService provides business logic for managing players.
 type PlayerService struct {
 	repo      repositories.PlayerRepositoryI
+	sessions  repositories.SessionRepositoryI
 	jwtSecret string
+	// now is the clock tokens and sessions are checked against.
+	now              func() time.Time
+	revokedListeners []SessionRevokedListener
+	// logins locks names out after repeated failed logins.
+	logins *ratelimit.Lockout
+	// names is the policy new names are checked against.
+	names *NamePolicy
 }
 
+// loginLockout allows a few mistyped passwords before locking a name out,
+// then doubles the lock with every further failure.
+var loginLockout = ratelimit.LockoutPolicy{
+	FreeFailures: 5,
+	Base:         30 * time.Second,
+	Max:          time.Hour,
+	ResetAfter:   24 * time.Hour,
+}
+
+// LoginLockedError is returned by Login while a name is locked out after too
+// many failed logins.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string { return "login_locked" }
+
 // NewPlayerService creates a new PlayerService.
-func NewPlayerService(repo repositories.PlayerRepositoryI, jwtSecret string) *PlayerService {
-	return &PlayerService{repo: repo, jwtSecret: jwtSecret}
+func NewPlayerService(repo repositories.PlayerRepositoryI, sessions repositories.SessionRepositoryI, jwtSecret string) *PlayerService {
+	return &PlayerService{repo: repo, sessions: sessions, jwtSecret: jwtSecret, now: time.Now, logins: ratelimit.NewLockout(loginLockout), names: NewNamePolicy(nil)}
 }
 
 func (s *PlayerService) GetByID(ctx context.Context, playerID string) (*models.Player, error) {
 	return s.repo.GetByID(ctx, playerID)
 }
 
-func (s *PlayerService) CreatePlayer(ctx context.Context, name string, password *string, id *string) (*models.Player, *string, error) {
+// CreatePlayer creates a guest when password is nil and a registered player
+// otherwise, and starts a session for the player. When guestToken is given the
+// guest it was issued to is upgraded in place, keeping its history, and the
+// guest's session ends; it returns "invalid_guest_token" if the token does not
+// belong to a current guest. Names are checked like in Rename.
+func (s *PlayerService) CreatePlayer(ctx context.Context, name string, password *string, guestToken *string, client SessionClient) (*models.Player, *AuthTokens, error) {
+	name = strings.TrimSpace(name)
 	var player *models.Player
-	var jwtToken *string
 	if password == nil {
 		// Guest account
+		if err := s.checkName(ctx, name, ""); err != nil {
+			return nil, nil, err
+		}
 		player = &models.Player{
 			ID:         uuid.NewString(),
 			Name:       name,
 			Registered: false,
+			Role:       models.RolePlayer,
 			Elo:        nil,
 			CreatedAt:  time.Now().UTC(),
 		}
@@ -46,109 +81,134 @@ func (s *PlayerService) CreatePlayer(ctx context.Context, name string, password
 			}
 			return nil, nil, err
 		}
-		return player, nil, nil
-	} else {
-		// Logged-in account (new or upgrade)
-		var playerID string
-		if id != nil {
-			playerID = *id
-			// Check if this id exists and is a guest
-			existing, err := s.repo.GetByID(ctx, playerID)
-			if err != nil {
-				return nil, nil, err
-			}
-			if existing != nil && !existing.Registered {
-				// Upgrade guest to registered
-				// Check if new name is taken by another player
-				hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
-				if err != nil {
-					return nil, nil, err
-				}
-				if err := s.repo.UpdateGuestToRegistered(ctx, playerID, name, string(hash)); err != nil {
-					if err.Error() == "username_taken" {
-						return nil, nil, fmt.Errorf("username_taken")
-					}
-					return nil, nil, err
-				}
-				// Fetch updated player
-				player, err = s.repo.GetByID(ctx, playerID)
-				if err != nil {
-					return nil, nil, err
-				}
-				token, err := s.GenerateJWT(playerID)
-				if err != nil {
-					return nil, nil, err
-				}
-				jwtToken = &token
-				return player, jwtToken, nil
-			}
-		}
-		// If id is not a guest, treat as new registration
-		// Check if username is already taken
-		existingByName, err := s.repo.GetByName(ctx, name)
+		tokens, err := s.StartSession(ctx, player, client)
 		if err != nil {
 			return nil, nil, err
 		}
-		if existingByName != nil && existingByName.Registered {
-			return nil, nil, fmt.Errorf("username_taken")
+		return player, tokens, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, err
+	}
+	if guestToken != nil {
+		// Upgrade guest to registered
+		claims, err := s.verifyAccessToken(ctx, *guestToken)
+		if err != nil || !claims.Guest {
+			return nil, nil, fmt.Errorf("invalid_guest_token")
 		}
-		playerID = uuid.NewString()
-		hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+		existing, err := s.repo.GetByID(ctx, claims.PlayerID)
 		if err != nil {
 			return nil, nil, err
 		}
-		player = &models.Player{
-			ID:           playerID,
-			Name:         name,
-			Registered:   true,
-			PasswordHash: ptr(string(hash)),
-			Elo:          nil,
-			CreatedAt:    time.Now().UTC(),
+		if existing == nil || existing.Registered {
+			return nil, nil, fmt.Errorf("invalid_guest_token")
 		}
-		token, err := s.GenerateJWT(playerID)
-		if err != nil {
+		if err := s.checkName(ctx, name, existing.ID); err != nil {
 			return nil, nil, err
 		}
-		jwtToken = &token
-		if err := s.repo.CreatePlayer(ctx, player); err != nil {
+		if err := s.repo.UpdateGuestToRegistered(ctx, claims.PlayerID, name, string(hash)); err != nil {
 			if err.Error() == "username_taken" {
 				return nil, nil, fmt.Errorf("username_taken")
 			}
 			return nil, nil, err
 		}
-		return player, jwtToken, nil
+		player, err = s.repo.GetByID(ctx, claims.PlayerID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.revokeSession(ctx, claims.SessionID); err != nil {
+			return nil, nil, err
+		}
+		tokens, err := s.StartSession(ctx, player, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		return player, tokens, nil
+	}
+
+	// New registration
+	if err := s.checkName(ctx, name, ""); err != nil {
+		return nil, nil, err
+	}
+	player = &models.Player{
+		ID:           uuid.NewString(),
+		Name:         name,
+		Registered:   true,
+		Role:         models.RolePlayer,
+		PasswordHash: ptr(string(hash)),
+		Elo:          nil,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := s.repo.CreatePlayer(ctx, player); err != nil {
+		if err.Error() == "username_taken" {
+			return nil, nil, fmt.Errorf("username_taken")
+		}
+		return nil, nil, err
+	}
+	tokens, err := s.StartSession(ctx, player, client)
+	if err != nil {
+		return nil, nil, err
 	}
+	return player, tokens, nil
 }
 
-func (s *PlayerService) Login(ctx context.Context, name string, password string) (*models.Player, *string, error) {
+// Login checks a registered player's password and starts a session. Failed
+// logins count against the name whether or not a player has it, and after
+// too many it returns a *LoginLockedError until the lock expires.
+func (s *PlayerService) Login(ctx context.Context, name string, password string, client SessionClient) (*models.Player, *AuthTokens, error) {
+	// Names that look alike share their lockout.
+	key := models.CanonicalName(name)
+	if wait := s.logins.Locked(key); wait > 0 {
+		return nil, nil, &LoginLockedError{RetryAfter: wait}
+	}
 	// Find player by name (must be registered)
 	player, err := s.repo.GetByName(ctx, name)
-	if err != nil || player == nil || !player.Registered || player.PasswordHash == nil {
+	if err != nil {
 		return nil, nil, err
 	}
+	if player == nil || !player.Registered || player.PasswordHash == nil {
+		return nil, nil, s.loginFailed(key, nil)
+	}
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(*player.PasswordHash), []byte(password)); err != nil {
-		return nil, nil, err
+		return nil, nil, s.loginFailed(key, err)
 	}
-	token, err := s.GenerateJWT(player.ID)
+	s.logins.Reset(key)
+	tokens, err := s.StartSession(ctx, player, client)
 	if err != nil {
 		return nil, nil, err
 	}
-	return player, &token, nil
+	return player, tokens, nil
 }
 
-// SearchByName returns players whose names contain the substring (case-insensitive)
-func (s *PlayerService) SearchByName(ctx context.Context, name string) ([]*models.Player, error) {
-	return s.repo.SearchByName(ctx, name)
+// loginFailed records a failed login and returns err, or a *LoginLockedError
+// if the failure locked the name out.
+func (s *PlayerService) loginFailed(key string, err error) error {
+	if lock := s.logins.Fail(key); lock > 0 {
+		return &LoginLockedError{RetryAfter: lock}
+	}
+	return err
 }
 
-func (s *PlayerService) GenerateJWT(playerID string) (string, error) {
-	claims := jwt.MapClaims{
-		"player_id": playerID,
-		"exp":       time.Now().Add(24 * time.Hour).Unix(),
+// SearchByName returns players whose names contain the substring (case-insensitive),
+// and the player who had the name before renaming, if any.
+func (s *PlayerService) SearchByName(ctx context.Context, name string) ([]*models.Player, error) {
+	players, err := s.repo.SearchByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	former, err := s.repo.GetByFormerName(ctx, strings.TrimSpace(name), time.Time{})
+	if err != nil || former == nil {
+		return players, err
+	}
+	for _, p := range players {
+		if p.ID == former.ID {
+			return players, nil
+		}
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtSecret))
+	return append(players, former), nil
 }
 
 func ptr[T any](v T) *T { return &v }
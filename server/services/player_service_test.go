@@ -3,12 +3,20 @@ package services
 import (
 	"battle-wordle/server/models"
 	"context"
+	"errors"
 	"testing"
+	"time"
 )
 
 type mockPlayerRepo struct {
 	players map[string]*models.Player
 	byName  map[string]*models.Player
+	history []*models.NameChange
+	// mergeGames are the target's games once a guest is merged into them, as
+	// the repository selects them for the stats; merged holds the stats of the
+	// last merge.
+	mergeGames []*models.Game
+	merged     []*models.PlayerGameSummary
 }
 
 func newMockPlayerRepo() *mockPlayerRepo {
@@ -46,6 +54,127 @@ func (m *mockPlayerRepo) UpdateGuestToRegistered(ctx context.Context, id, newNam
 	m.byName[newName] = p
 	return nil
 }
+func (m *mockPlayerRepo) GetByEmail(ctx context.Context, email string) (*models.Player, error) {
+	for _, p := range m.players {
+		if p.Email != nil && *p.Email == email {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockPlayerRepo) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	p, ok := m.players[id]
+	if !ok || !p.Registered {
+		return &mockError{"player_not_found"}
+	}
+	p.PasswordHash = &passwordHash
+	return nil
+}
+func (m *mockPlayerRepo) UpdateEmail(ctx context.Context, id string, email *string) error {
+	p, ok := m.players[id]
+	if !ok {
+		return &mockError{"player_not_found"}
+	}
+	if email != nil {
+		if other, _ := m.GetByEmail(ctx, *email); other != nil && other.ID != id {
+			return &mockError{"email_taken"}
+		}
+	}
+	p.Email = email
+	return nil
+}
+func (m *mockPlayerRepo) Anonymise(ctx context.Context, id string, deletedAt time.Time) error {
+	p, ok := m.players[id]
+	if !ok || p.DeletedAt != nil {
+		return &mockError{"player_not_found"}
+	}
+	delete(m.byName, p.Name)
+	p.Name = "deleted-" + id
+	p.Registered = false
+	p.PasswordHash = nil
+	p.Email = nil
+	p.DeletedAt = &deletedAt
+	m.byName[p.Name] = p
+	return nil
+}
+func (m *mockPlayerRepo) RegisterGuest(ctx context.Context, id, newName string) error {
+	p, ok := m.players[id]
+	if !ok || p.Registered {
+		return &mockError{"guest_not_found_or_already_registered"}
+	}
+	if other, exists := m.byName[newName]; exists && other.ID != id {
+		return &mockError{"username_taken"}
+	}
+	delete(m.byName, p.Name)
+	p.Name = newName
+	p.Registered = true
+	m.byName[newName] = p
+	return nil
+}
+func (m *mockPlayerRepo) SetRole(ctx context.Context, id, role string) error {
+	p, ok := m.players[id]
+	if !ok {
+		return &mockError{"player_not_found"}
+	}
+	p.Role = role
+	return nil
+}
+func (m *mockPlayerRepo) SetBan(ctx context.Context, id string, bannedAt, until *time.Time, reason string) error {
+	p, ok := m.players[id]
+	if !ok {
+		return &mockError{"player_not_found"}
+	}
+	p.BannedAt, p.BannedUntil, p.BanReason = bannedAt, until, reason
+	return nil
+}
+func (m *mockPlayerRepo) Rename(ctx context.Context, id, newName string, at time.Time) error {
+	p, ok := m.players[id]
+	if !ok {
+		return &mockError{"player_not_found"}
+	}
+	if other, exists := m.byName[newName]; exists && other.ID != id {
+		return &mockError{"username_taken"}
+	}
+	m.history = append([]*models.NameChange{{PlayerID: id, Name: p.Name, ChangedAt: at}}, m.history...)
+	delete(m.byName, p.Name)
+	p.Name = newName
+	m.byName[newName] = p
+	return nil
+}
+func (m *mockPlayerRepo) GetNameHistory(ctx context.Context, id string) ([]*models.NameChange, error) {
+	var history []*models.NameChange
+	for _, c := range m.history {
+		if c.PlayerID == id {
+			history = append(history, c)
+		}
+	}
+	return history, nil
+}
+func (m *mockPlayerRepo) GetByFormerName(ctx context.Context, name string, since time.Time) (*models.Player, error) {
+	for _, c := range m.history {
+		if c.Name == name && !c.ChangedAt.Before(since) {
+			return m.players[c.PlayerID], nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockPlayerRepo) MergeGuest(ctx context.Context, guestID, targetID string, summarize func(game *models.Game) []*models.PlayerGameSummary) error {
+	guest, ok := m.players[guestID]
+	if !ok || guest.Registered {
+		return &mockError{"guest_not_found"}
+	}
+	m.merged = nil
+	for _, game := range m.mergeGames {
+		for _, summary := range summarize(game) {
+			if summary.PlayerID == targetID {
+				m.merged = append(m.merged, summary)
+			}
+		}
+	}
+	delete(m.players, guestID)
+	delete(m.byName, guest.Name)
+	return nil
+}
 func (m *mockPlayerRepo) SearchByName(ctx context.Context, name string) ([]*models.Player, error) {
 	var result []*models.Player
 	for _, p := range m.byName {
@@ -61,49 +190,146 @@ func (e *mockError) Error() string { return e.msg }
 
 type mockError struct{ msg string }
 
+type mockSessionRepo struct {
+	tokens map[string]*models.RefreshToken
+}
+
+func newMockSessionRepo() *mockSessionRepo {
+	return &mockSessionRepo{tokens: make(map[string]*models.RefreshToken)}
+}
+func (m *mockSessionRepo) Create(ctx context.Context, token *models.RefreshToken) error {
+	m.tokens[token.ID] = token
+	return nil
+}
+func (m *mockSessionRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	for _, t := range m.tokens {
+		if t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockSessionRepo) Rotate(ctx context.Context, previousID string, next *models.RefreshToken) (bool, error) {
+	previous, ok := m.tokens[previousID]
+	if !ok || previous.RotatedAt != nil || previous.RevokedAt != nil {
+		return false, nil
+	}
+	rotatedAt := next.CreatedAt
+	previous.RotatedAt = &rotatedAt
+	m.tokens[next.ID] = next
+	return true, nil
+}
+func (m *mockSessionRepo) RevokeSession(ctx context.Context, sessionID string, now time.Time) (bool, error) {
+	active, _ := m.IsActive(ctx, sessionID, now)
+	for _, t := range m.tokens {
+		if t.SessionID == sessionID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return active, nil
+}
+func (m *mockSessionRepo) IsActive(ctx context.Context, sessionID string, now time.Time) (bool, error) {
+	for _, t := range m.tokens {
+		if t.SessionID == sessionID && t.RotatedAt == nil && t.RevokedAt == nil && t.ExpiresAt.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *mockSessionRepo) ListActive(ctx context.Context, playerID string, now time.Time) ([]*models.Session, error) {
+	var sessions []*models.Session
+	for _, t := range m.tokens {
+		if t.PlayerID == playerID && t.RotatedAt == nil && t.RevokedAt == nil && t.ExpiresAt.After(now) {
+			sessions = append(sessions, &models.Session{ID: t.SessionID, PlayerID: t.PlayerID, LastUsedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt})
+		}
+	}
+	return sessions, nil
+}
+func (m *mockSessionRepo) DeleteExpired(ctx context.Context, now time.Time) error {
+	return nil
+}
+
 func TestPlayerService_GuestRegistration(t *testing.T) {
 	repo := newMockPlayerRepo()
-	service := NewPlayerService(repo, "testsecret")
+	service := NewPlayerService(repo, newMockSessionRepo(), "testsecret")
 	ctx := context.Background()
-	player, token, err := service.CreatePlayer(ctx, "guest1", nil, nil)
+	player, token, err := service.CreatePlayer(ctx, "guest1", nil, nil, SessionClient{})
 	if err != nil {
 		t.Fatalf("Guest registration failed: %v", err)
 	}
 	if player == nil || player.Registered {
 		t.Errorf("Expected guest player, got %+v", player)
 	}
-	if token != nil {
-		t.Errorf("Expected no token for guest, got %v", *token)
+	if token == nil {
+		t.Fatal("Expected a guest token")
+	}
+	if playerID, _, err := service.VerifyJWT(ctx, token.AccessToken); err != nil || playerID != player.ID {
+		t.Errorf("Expected the guest token to verify, got %q, %v", playerID, err)
+	}
+}
+
+func TestPlayerService_UpgradeGuestRequiresToken(t *testing.T) {
+	repo := newMockPlayerRepo()
+	sessions := newMockSessionRepo()
+	service := NewPlayerService(repo, sessions, "testsecret")
+	ctx := context.Background()
+	guest, guestTokens, _ := service.CreatePlayer(ctx, "guest1", nil, nil, SessionClient{})
+	guestToken := &guestTokens.AccessToken
+	pw := "pw"
+
+	// A registered player's token cannot upgrade anyone.
+	_, otherTokens, _ := service.CreatePlayer(ctx, "other", &pw, nil, SessionClient{})
+	if _, _, err := service.CreatePlayer(ctx, "user1", &pw, &otherTokens.AccessToken, SessionClient{}); err == nil || err.Error() != "invalid_guest_token" {
+		t.Errorf("Expected invalid_guest_token, got %v", err)
+	}
+	forgedTokens, _ := NewPlayerService(repo, sessions, "othersecret").StartSession(ctx, guest, SessionClient{})
+	if _, _, err := service.CreatePlayer(ctx, "user1", &pw, &forgedTokens.AccessToken, SessionClient{}); err == nil || err.Error() != "invalid_guest_token" {
+		t.Errorf("Expected invalid_guest_token for a forged token, got %v", err)
+	}
+
+	player, token, err := service.CreatePlayer(ctx, "user1", &pw, guestToken, SessionClient{})
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	if player.ID != guest.ID || !player.Registered || token == nil {
+		t.Errorf("Expected the guest to be upgraded in place, got %+v", player)
+	}
+	// The guest token stops working once the guest has registered.
+	if _, _, err := service.VerifyJWT(ctx, *guestToken); err == nil {
+		t.Error("Expected the guest token to be rejected after the upgrade")
+	}
+	if _, _, err := service.CreatePlayer(ctx, "user2", &pw, guestToken, SessionClient{}); err == nil || err.Error() != "invalid_guest_token" {
+		t.Errorf("Expected the guest token to be single use, got %v", err)
 	}
 }
 
 func TestPlayerService_RegisteredRegistration(t *testing.T) {
 	repo := newMockPlayerRepo()
-	service := NewPlayerService(repo, "testsecret")
+	service := NewPlayerService(repo, newMockSessionRepo(), "testsecret")
 	ctx := context.Background()
 	pw := "pw"
-	player, token, err := service.CreatePlayer(ctx, "user1", &pw, nil)
+	player, token, err := service.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
 	if err != nil {
 		t.Fatalf("Registered registration failed: %v", err)
 	}
 	if player == nil || !player.Registered {
 		t.Errorf("Expected registered player, got %+v", player)
 	}
-	if token == nil || *token == "" {
-		t.Errorf("Expected token for registered user, got %v", token)
+	if token == nil || token.AccessToken == "" || token.RefreshToken == "" {
+		t.Errorf("Expected tokens for registered user, got %v", token)
 	}
 }
 
 func TestPlayerService_DuplicateUsername(t *testing.T) {
 	repo := newMockPlayerRepo()
-	service := NewPlayerService(repo, "testsecret")
+	service := NewPlayerService(repo, newMockSessionRepo(), "testsecret")
 	ctx := context.Background()
 	pw := "pw"
-	_, _, err := service.CreatePlayer(ctx, "user1", &pw, nil)
+	_, _, err := service.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
 	if err != nil {
 		t.Fatalf("First registration failed: %v", err)
 	}
-	_, _, err = service.CreatePlayer(ctx, "user1", &pw, nil)
+	_, _, err = service.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
 	if err == nil || err.Error() != "username_taken" {
 		t.Errorf("Expected username_taken error, got %v", err)
 	}
@@ -111,36 +337,93 @@ func TestPlayerService_DuplicateUsername(t *testing.T) {
 
 func TestPlayerService_Login(t *testing.T) {
 	repo := newMockPlayerRepo()
-	service := NewPlayerService(repo, "testsecret")
+	service := NewPlayerService(repo, newMockSessionRepo(), "testsecret")
 	ctx := context.Background()
 	pw := "pw"
-	player, _, err := service.CreatePlayer(ctx, "user1", &pw, nil)
+	player, _, err := service.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
 	if err != nil {
 		t.Fatalf("Registration failed: %v", err)
 	}
-	p, tkn, err := service.Login(ctx, "user1", pw)
+	p, tkn, err := service.Login(ctx, "user1", pw, SessionClient{})
 	if err != nil {
 		t.Fatalf("Login failed: %v", err)
 	}
 	if p.ID != player.ID {
 		t.Errorf("Expected player ID %s, got %s", player.ID, p.ID)
 	}
-	if tkn == nil || *tkn == "" {
+	if tkn == nil || tkn.AccessToken == "" {
 		t.Errorf("Expected token, got %v", tkn)
 	}
-	_, _, err = service.Login(ctx, "user1", "wrongpw")
+	_, _, err = service.Login(ctx, "user1", "wrongpw", SessionClient{})
 	if err == nil {
 		t.Errorf("Expected login failure with wrong password")
 	}
 }
 
+func TestPlayerService_LoginLockout(t *testing.T) {
+	repo := newMockPlayerRepo()
+	service := NewPlayerService(repo, newMockSessionRepo(), "testsecret")
+	ctx := context.Background()
+	pw := "pw"
+	service.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
+
+	for i := 0; i < loginLockout.FreeFailures; i++ {
+		if _, _, err := service.Login(ctx, "user1", "wrongpw", SessionClient{}); err == nil || err.Error() == "login_locked" {
+			t.Fatalf("Expected failure %d to not lock the name, got %v", i+1, err)
+		}
+	}
+	_, _, err := service.Login(ctx, "user1", "wrongpw", SessionClient{})
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) || locked.RetryAfter != loginLockout.Base {
+		t.Fatalf("Expected a %v lock, got %v", loginLockout.Base, err)
+	}
+	if _, _, err := service.Login(ctx, "user1", pw, SessionClient{}); !errors.As(err, &locked) {
+		t.Errorf("Expected the right password to be refused while locked, got %v", err)
+	}
+	if _, _, err := service.Login(ctx, "user2", pw, SessionClient{}); errors.As(err, &locked) {
+		t.Errorf("Expected other names to not be locked, got %v", err)
+	}
+
+	// Unknown names are locked out the same way, to not reveal which exist.
+	for i := 0; i <= loginLockout.FreeFailures; i++ {
+		_, _, err = service.Login(ctx, "nobody", pw, SessionClient{})
+	}
+	if !errors.As(err, &locked) {
+		t.Errorf("Expected unknown names to be locked out, got %v", err)
+	}
+}
+
+func TestPlayerService_VerifyJWT(t *testing.T) {
+	ctx := context.Background()
+	sessions := newMockSessionRepo()
+	service := NewPlayerService(newMockPlayerRepo(), sessions, "testsecret")
+	tokens, err := service.StartSession(ctx, &models.Player{ID: "p1", Registered: true}, SessionClient{})
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	if playerID, sessionID, err := service.VerifyJWT(ctx, tokens.AccessToken); err != nil || playerID != "p1" || sessionID != tokens.SessionID {
+		t.Errorf("Expected p1, got %q, %q, %v", playerID, sessionID, err)
+	}
+	other := NewPlayerService(newMockPlayerRepo(), sessions, "othersecret")
+	if _, _, err := other.VerifyJWT(ctx, tokens.AccessToken); err == nil || err.Error() != "invalid_token" {
+		t.Errorf("Expected invalid_token for a foreign signature, got %v", err)
+	}
+	if _, _, err := service.VerifyJWT(ctx, "garbage"); err == nil {
+		t.Error("Expected garbage to be rejected")
+	}
+	service.now = func() time.Time { return time.Now().Add(accessTokenLifetime + time.Minute) }
+	if _, _, err := service.VerifyJWT(ctx, tokens.AccessToken); err == nil {
+		t.Error("Expected an expired access token to be rejected")
+	}
+}
+
 func TestPlayerService_SearchByName(t *testing.T) {
 	repo := newMockPlayerRepo()
-	service := NewPlayerService(repo, "testsecret")
+	service := NewPlayerService(repo, newMockSessionRepo(), "testsecret")
 	ctx := context.Background()
 	pw := "pw"
-	service.CreatePlayer(ctx, "alice", &pw, nil)
-	service.CreatePlayer(ctx, "bob", &pw, nil)
+	service.CreatePlayer(ctx, "alice", &pw, nil, SessionClient{})
+	service.CreatePlayer(ctx, "bob", &pw, nil, SessionClient{})
 	players, err := service.SearchByName(ctx, "alice")
 	if err != nil {
 		t.Fatalf("SearchByName failed: %v", err)
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"battle-wordle/server/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Token lifetimes. Access tokens are short-lived and renewed with a refresh
+// token, which is rotated on every use. Guests cannot log in again, so their
+// sessions last longer.
+const (
+	accessTokenLifetime       = 15 * time.Minute
+	refreshTokenLifetime      = 30 * 24 * time.Hour
+	guestRefreshTokenLifetime = 90 * 24 * time.Hour
+)
+
+// AuthTokens are the credentials of a session.
+type AuthTokens struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+	// ExpiresIn is how long the access token is valid for.
+	ExpiresIn time.Duration
+}
+
+// SessionClient describes the device a session is used from.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+// AccessClaims are the verified contents of an access token.
+type AccessClaims struct {
+	PlayerID  string
+	SessionID string
+	// Guest is set for tokens issued to guests.
+	Guest bool
+}
+
+// SessionRevokedListener is called with the ID of every revoked session.
+type SessionRevokedListener func(sessionID string)
+
+// OnSessionRevoked registers a listener for revoked sessions, e.g. to close
+// the session's open connections.
+func (s *PlayerService) OnSessionRevoked(listener SessionRevokedListener) {
+	s.revokedListeners = append(s.revokedListeners, listener)
+}
+
+// StartSession starts a new session for player on client. It returns
+// "player_banned" for banned players.
+func (s *PlayerService) StartSession(ctx context.Context, player *models.Player, client SessionClient) (*AuthTokens, error) {
+	now := s.now().UTC()
+	if player.Banned(now) {
+		return nil, fmt.Errorf("player_banned")
+	}
+	if err := s.sessions.DeleteExpired(ctx, now); err != nil {
+		log.Printf("error deleting expired sessions: %v", err)
+	}
+	token, refreshToken, err := s.newRefreshToken(player, uuid.NewString(), client, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.Create(ctx, token); err != nil {
+		return nil, err
+	}
+	return s.authTokens(player, token.SessionID, refreshToken, now)
+}
+
+// Refresh exchanges a refresh token for new tokens of the same session. The
+// refresh token can only be used once: presenting it again revokes the session
+// and returns "refresh_token_reused". It returns "invalid_refresh_token" for
+// unknown, expired or revoked tokens and "player_banned" for banned players.
+func (s *PlayerService) Refresh(ctx context.Context, refreshToken string, client SessionClient) (*models.Player, *AuthTokens, error) {
+	now := s.now().UTC()
+	previous, err := s.sessions.GetByTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	if previous == nil || previous.RevokedAt != nil || !now.Before(previous.ExpiresAt) {
+		return nil, nil, fmt.Errorf("invalid_refresh_token")
+	}
+	if previous.RotatedAt != nil {
+		return nil, nil, s.revokeReusedSession(ctx, previous)
+	}
+	player, err := s.repo.GetByID(ctx, previous.PlayerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if player == nil {
+		return nil, nil, fmt.Errorf("invalid_refresh_token")
+	}
+	if player.Banned(now) {
+		return nil, nil, fmt.Errorf("player_banned")
+	}
+
+	next, nextToken, err := s.newRefreshToken(player, previous.SessionID, client, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	rotated, err := s.sessions.Rotate(ctx, previous.ID, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rotated {
+		// Another request rotated the token first.
+		return nil, nil, s.revokeReusedSession(ctx, previous)
+	}
+	tokens, err := s.authTokens(player, previous.SessionID, nextToken, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return player, tokens, nil
+}
+
+func (s *PlayerService) revokeReusedSession(ctx context.Context, reused *models.RefreshToken) error {
+	log.Printf("refresh token reused for session %s of player %s, revoking it", reused.SessionID, reused.PlayerID)
+	if err := s.revokeSession(ctx, reused.SessionID); err != nil {
+		return err
+	}
+	return fmt.Errorf("refresh_token_reused")
+}
+
+// ListSessions returns the player's active sessions, marking currentSessionID.
+func (s *PlayerService) ListSessions(ctx context.Context, playerID, currentSessionID string) ([]*models.Session, error) {
+	sessions, err := s.sessions.ListActive(ctx, playerID, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the player's active sessions. It returns
+// "session_not_found" if the player has no such session.
+func (s *PlayerService) RevokeSession(ctx context.Context, playerID, sessionID string) error {
+	sessions, err := s.sessions.ListActive(ctx, playerID, s.now().UTC())
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			return s.revokeSession(ctx, sessionID)
+		}
+	}
+	return fmt.Errorf("session_not_found")
+}
+
+// RevokeOtherSessions ends every active session of the player except
+// currentSessionID and returns how many were ended.
+func (s *PlayerService) RevokeOtherSessions(ctx context.Context, playerID, currentSessionID string) (int, error) {
+	sessions, err := s.sessions.ListActive(ctx, playerID, s.now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+		if err := s.revokeSession(ctx, session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+func (s *PlayerService) revokeSession(ctx context.Context, sessionID string) error {
+	if _, err := s.sessions.RevokeSession(ctx, sessionID, s.now().UTC()); err != nil {
+		return err
+	}
+	for _, listener := range s.revokedListeners {
+		listener(sessionID)
+	}
+	return nil
+}
+
+// VerifyJWT returns the player and session an access token was issued for.
+// It returns "invalid_token" if the token is malformed, expired or not signed
+// with this server's secret, or if its session has ended.
+func (s *PlayerService) VerifyJWT(ctx context.Context, tokenString string) (playerID, sessionID string, err error) {
+	claims, err := s.verifyAccessToken(ctx, tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	return claims.PlayerID, claims.SessionID, nil
+}
+
+func (s *PlayerService) verifyAccessToken(ctx context.Context, tokenString string) (*AccessClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+		return []byte(s.jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithTimeFunc(s.now))
+	if err != nil {
+		return nil, fmt.Errorf("invalid_token")
+	}
+	var access AccessClaims
+	access.PlayerID, _ = claims["player_id"].(string)
+	access.SessionID, _ = claims["sid"].(string)
+	access.Guest, _ = claims["guest"].(bool)
+	if access.PlayerID == "" || access.SessionID == "" {
+		return nil, fmt.Errorf("invalid_token")
+	}
+	active, err := s.sessions.IsActive(ctx, access.SessionID, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, fmt.Errorf("invalid_token")
+	}
+	return &access, nil
+}
+
+func (s *PlayerService) authTokens(player *models.Player, sessionID, refreshToken string, now time.Time) (*AuthTokens, error) {
+	claims := jwt.MapClaims{
+		"player_id": player.ID,
+		"sid":       sessionID,
+		"exp":       now.Add(accessTokenLifetime).Unix(),
+	}
+	if !player.Registered {
+		claims["guest"] = true
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.jwtSecret))
+	if err != nil {
+		return nil, err
+	}
+	return &AuthTokens{
+		SessionID:    sessionID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    accessTokenLifetime,
+	}, nil
+}
+
+// newRefreshToken generates a refresh token and the record to store for it.
+func (s *PlayerService) newRefreshToken(player *models.Player, sessionID string, client SessionClient, now time.Time) (*models.RefreshToken, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	lifetime := refreshTokenLifetime
+	if !player.Registered {
+		lifetime = guestRefreshTokenLifetime
+	}
+	return &models.RefreshToken{
+		ID:        uuid.NewString(),
+		SessionID: sessionID,
+		PlayerID:  player.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+	}, token, nil
+}
+
+// hashToken returns the hex SHA-256 that is stored in place of a refresh or
+// password reset token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPlayerService_RefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	service := NewPlayerService(newMockPlayerRepo(), newMockSessionRepo(), "testsecret")
+	pw := "pw"
+	player, tokens, err := service.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
+	if err != nil {
+		t.Fatalf("CreatePlayer failed: %v", err)
+	}
+
+	refreshed, next, err := service.Refresh(ctx, tokens.RefreshToken, SessionClient{})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if refreshed.ID != player.ID || next.SessionID != tokens.SessionID {
+		t.Errorf("Expected the same player and session, got %s, %s", refreshed.ID, next.SessionID)
+	}
+	if next.RefreshToken == tokens.RefreshToken {
+		t.Error("Expected a new refresh token")
+	}
+	if _, _, err := service.VerifyJWT(ctx, next.AccessToken); err != nil {
+		t.Errorf("Expected the new access token to be valid, got %v", err)
+	}
+	if _, _, err := service.Refresh(ctx, "unknown", SessionClient{}); err == nil || err.Error() != "invalid_refresh_token" {
+		t.Errorf("Expected invalid_refresh_token, got %v", err)
+	}
+}
+
+func TestPlayerService_RefreshTokenReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	service := NewPlayerService(newMockPlayerRepo(), newMockSessionRepo(), "testsecret")
+	var revoked []string
+	service.OnSessionRevoked(func(sessionID string) { revoked = append(revoked, sessionID) })
+	_, tokens, _ := service.CreatePlayer(ctx, "guest1", nil, nil, SessionClient{})
+	_, next, err := service.Refresh(ctx, tokens.RefreshToken, SessionClient{})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	if _, _, err := service.Refresh(ctx, tokens.RefreshToken, SessionClient{}); err == nil || err.Error() != "refresh_token_reused" {
+		t.Fatalf("Expected refresh_token_reused, got %v", err)
+	}
+	if len(revoked) != 1 || revoked[0] != tokens.SessionID {
+		t.Errorf("Expected session %s to be revoked, got %v", tokens.SessionID, revoked)
+	}
+	if _, _, err := service.VerifyJWT(ctx, next.AccessToken); err == nil {
+		t.Error("Expected the access token of a revoked session to be rejected")
+	}
+	if _, _, err := service.Refresh(ctx, next.RefreshToken, SessionClient{}); err == nil || err.Error() != "invalid_refresh_token" {
+		t.Errorf("Expected invalid_refresh_token after revocation, got %v", err)
+	}
+}
+
+func TestPlayerService_RefreshTokenExpires(t *testing.T) {
+	ctx := context.Background()
+	service := NewPlayerService(newMockPlayerRepo(), newMockSessionRepo(), "testsecret")
+	pw := "pw"
+	_, tokens, _ := service.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
+	service.now = func() time.Time { return time.Now().Add(refreshTokenLifetime + time.Hour) }
+	if _, _, err := service.Refresh(ctx, tokens.RefreshToken, SessionClient{}); err == nil || err.Error() != "invalid_refresh_token" {
+		t.Errorf("Expected invalid_refresh_token, got %v", err)
+	}
+}
+
+func TestPlayerService_RevokeSessions(t *testing.T) {
+	ctx := context.Background()
+	service := NewPlayerService(newMockPlayerRepo(), newMockSessionRepo(), "testsecret")
+	pw := "pw"
+	player, first, _ := service.CreatePlayer(ctx, "user1", &pw, nil, SessionClient{})
+	_, second, _ := service.Login(ctx, "user1", pw, SessionClient{})
+	_, third, _ := service.Login(ctx, "user1", pw, SessionClient{})
+	other, otherTokens, _ := service.CreatePlayer(ctx, "user2", &pw, nil, SessionClient{})
+
+	sessions, err := service.ListSessions(ctx, player.ID, first.SessionID)
+	if err != nil || len(sessions) != 3 {
+		t.Fatalf("Expected 3 sessions, got %d, %v", len(sessions), err)
+	}
+	for _, session := range sessions {
+		if session.Current != (session.ID == first.SessionID) {
+			t.Errorf("Expected only %s to be current, got %+v", first.SessionID, session)
+		}
+	}
+
+	if err := service.RevokeSession(ctx, player.ID, otherTokens.SessionID); err == nil || err.Error() != "session_not_found" {
+		t.Errorf("Expected session_not_found for another player's session, got %v", err)
+	}
+	if err := service.RevokeSession(ctx, player.ID, second.SessionID); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if _, _, err := service.VerifyJWT(ctx, second.AccessToken); err == nil {
+		t.Error("Expected the revoked session's access token to be rejected")
+	}
+
+	n, err := service.RevokeOtherSessions(ctx, player.ID, first.SessionID)
+	if err != nil || n != 1 {
+		t.Errorf("Expected 1 other session revoked, got %d, %v", n, err)
+	}
+	if _, _, err := service.VerifyJWT(ctx, third.AccessToken); err == nil {
+		t.Error("Expected the other session's access token to be rejected")
+	}
+	if _, _, err := service.VerifyJWT(ctx, first.AccessToken); err != nil {
+		t.Errorf("Expected the current session to stay active, got %v", err)
+	}
+	if sessions, _ := service.ListSessions(ctx, other.ID, ""); len(sessions) != 1 {
+		t.Errorf("Expected the other player's session to be untouched, got %d", len(sessions))
+	}
+}
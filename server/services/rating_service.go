@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+// RatingService computes rating changes for finished games using a RatingSystem.
+type RatingService struct {
+	playerRepo repositories.PlayerRepositoryI
+	gameRepo   repositories.GameRepositoryI
+	system     RatingSystem
+}
+
+// NewRatingService creates a new RatingService.
+func NewRatingService(playerRepo repositories.PlayerRepositoryI, gameRepo repositories.GameRepositoryI, system RatingSystem) *RatingService {
+	return &RatingService{playerRepo: playerRepo, gameRepo: gameRepo, system: system}
+}
+
+// RateGame returns the rating changes produced by a finished game, one per player.
+// Games involving a guest are unrated and produce no changes.
+func (s *RatingService) RateGame(ctx context.Context, game *models.Game) ([]*models.RatingChange, error) {
+	if !game.Finished() {
+		return nil, fmt.Errorf("game_not_finished")
+	}
+	first, err := s.playerRepo.GetByID(ctx, game.FirstPlayer)
+	if err != nil {
+		return nil, err
+	}
+	second, err := s.playerRepo.GetByID(ctx, game.SecondPlayer)
+	if err != nil {
+		return nil, err
+	}
+	if first == nil || second == nil || !first.Registered || !second.Registered {
+		return nil, nil
+	}
+	changes, _, _ := s.rate(game, s.playerRating(first), s.playerRating(second), time.Now().UTC())
+	return changes, nil
+}
+
+// RecomputeAll replays every finished game in the order the games ended under
+// the configured rating system, and replaces all stored ratings with the
+// result. Games are rated if both players are registered now, so guests who
+// later registered get their earlier games rated. It returns the number of
+// games rated.
+func (s *RatingService) RecomputeAll(ctx context.Context) (int, error) {
+	games, err := s.gameRepo.GetFinished(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	registered := make(map[string]bool)
+	isRegistered := func(playerID string) (bool, error) {
+		if r, ok := registered[playerID]; ok {
+			return r, nil
+		}
+		p, err := s.playerRepo.GetByID(ctx, playerID)
+		if err != nil {
+			return false, err
+		}
+		registered[playerID] = p != nil && p.Registered
+		return registered[playerID], nil
+	}
+
+	ratings := make(map[string]PlayerRating)
+	ratingOf := func(playerID string) PlayerRating {
+		if r, ok := ratings[playerID]; ok {
+			return r
+		}
+		return s.system.Initial()
+	}
+
+	var changes []*models.RatingChange
+	rated := 0
+	for _, game := range games {
+		firstRegistered, err := isRegistered(game.FirstPlayer)
+		if err != nil {
+			return 0, err
+		}
+		secondRegistered, err := isRegistered(game.SecondPlayer)
+		if err != nil {
+			return 0, err
+		}
+		if !firstRegistered || !secondRegistered {
+			continue
+		}
+		gameChanges, first, second := s.rate(game, ratingOf(game.FirstPlayer), ratingOf(game.SecondPlayer), game.UpdatedAt.UTC())
+		ratings[game.FirstPlayer] = first
+		ratings[game.SecondPlayer] = second
+		changes = append(changes, gameChanges...)
+		rated++
+	}
+
+	if err := s.gameRepo.ReplaceRatings(ctx, changes); err != nil {
+		return 0, err
+	}
+	return rated, nil
+}
+
+// rate applies the rating system to a finished game and returns the resulting
+// changes along with both players' new rating states. Ratings are stored as
+// whole numbers, so the new states are rounded to match what is persisted.
+func (s *RatingService) rate(game *models.Game, first, second PlayerRating, at time.Time) ([]*models.RatingChange, PlayerRating, PlayerRating) {
+	firstScore := 0.5
+	switch game.Loser() {
+	case game.FirstPlayer:
+		firstScore = 0
+	case game.SecondPlayer:
+		firstScore = 1
+	}
+
+	newFirst, newSecond := s.system.Rate(first, second, firstScore, at)
+	newFirst.Rating = math.Round(newFirst.Rating)
+	newSecond.Rating = math.Round(newSecond.Rating)
+	changes := []*models.RatingChange{
+		s.change(game.ID, game.FirstPlayer, first, newFirst, at),
+		s.change(game.ID, game.SecondPlayer, second, newSecond, at),
+	}
+	return changes, newFirst, newSecond
+}
+
+func (s *RatingService) change(gameID, playerID string, before, after PlayerRating, at time.Time) *models.RatingChange {
+	change := &models.RatingChange{
+		GameID:     gameID,
+		PlayerID:   playerID,
+		Before:     int(math.Round(before.Rating)),
+		After:      int(after.Rating),
+		RatedGames: before.Games,
+		CreatedAt:  at,
+	}
+	if s.system.TracksDeviation() {
+		change.Deviation = &after.Deviation
+		change.Volatility = &after.Volatility
+	}
+	return change
+}
+
+// playerRating converts a stored player into the rating system's view of them.
+func (s *RatingService) playerRating(p *models.Player) PlayerRating {
+	r := s.system.Initial()
+	if p.Elo != nil {
+		r.Rating = float64(*p.Elo)
+	}
+	if p.RatingDeviation != nil {
+		r.Deviation = *p.RatingDeviation
+	}
+	if p.RatingVolatility != nil {
+		r.Volatility = *p.RatingVolatility
+	}
+	r.Games = p.RatedGames
+	r.RatedAt = p.RatedAt
+	return r
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+var testEloConfig = EloConfig{
+	InitialRating:      1200,
+	KFactor:            32,
+	ProvisionalKFactor: 64,
+	ProvisionalGames:   10,
+}
+
+func newRatedPlayer(repo *mockPlayerRepo, id string, elo int, ratedGames int) *models.Player {
+	p := &models.Player{ID: id, Name: id, Registered: true, Elo: &elo, RatedGames: ratedGames}
+	repo.CreatePlayer(context.Background(), p)
+	return p
+}
+
+func TestRateGame_Win(t *testing.T) {
+	repo := newMockPlayerRepo()
+	newRatedPlayer(repo, "p1", 1500, 20)
+	newRatedPlayer(repo, "p2", 1500, 20)
+	service := NewRatingService(repo, &mockGameRepo{}, NewEloSystem(testEloConfig))
+	game := &models.Game{ID: "g1", FirstPlayer: "p1", SecondPlayer: "p2", Result: "lose:p2"}
+	changes, err := service.RateGame(context.Background(), game)
+	if err != nil {
+		t.Fatalf("RateGame failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d", len(changes))
+	}
+	if changes[0].PlayerID != "p1" || changes[0].Before != 1500 || changes[0].After != 1516 {
+		t.Errorf("Unexpected winner change: %+v", changes[0])
+	}
+	if changes[1].PlayerID != "p2" || changes[1].Before != 1500 || changes[1].After != 1484 {
+		t.Errorf("Unexpected loser change: %+v", changes[1])
+	}
+}
+
+func TestRateGame_DrawAndProvisional(t *testing.T) {
+	repo := newMockPlayerRepo()
+	newRatedPlayer(repo, "p1", 1400, 20)
+	repo.CreatePlayer(context.Background(), &models.Player{ID: "p2", Name: "p2", Registered: true})
+	service := NewRatingService(repo, &mockGameRepo{}, NewEloSystem(testEloConfig))
+	game := &models.Game{ID: "g1", FirstPlayer: "p1", SecondPlayer: "p2", Result: "draw"}
+	changes, err := service.RateGame(context.Background(), game)
+	if err != nil {
+		t.Fatalf("RateGame failed: %v", err)
+	}
+	// p2 is new: starts at 1200 and uses the provisional K-factor.
+	if changes[1].Before != 1200 || changes[1].After != 1217 {
+		t.Errorf("Unexpected provisional change: %+v", changes[1])
+	}
+	if changes[0].After != 1392 {
+		t.Errorf("Unexpected established change: %+v", changes[0])
+	}
+}
+
+func TestRateGame_GuestUnrated(t *testing.T) {
+	repo := newMockPlayerRepo()
+	newRatedPlayer(repo, "p1", 1500, 20)
+	repo.CreatePlayer(context.Background(), &models.Player{ID: "guest", Name: "guest", Registered: false})
+	service := NewRatingService(repo, &mockGameRepo{}, NewEloSystem(testEloConfig))
+	game := &models.Game{ID: "g1", FirstPlayer: "p1", SecondPlayer: "guest", Result: "lose:guest"}
+	changes, err := service.RateGame(context.Background(), game)
+	if err != nil {
+		t.Fatalf("RateGame failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes for a game with a guest, got %+v", changes)
+	}
+}
+
+func TestSubmitGuess_RatesFinishedGame(t *testing.T) {
+	playerRepo := newMockPlayerRepo()
+	newRatedPlayer(playerRepo, "p1", 1500, 20)
+	newRatedPlayer(playerRepo, "p2", 1500, 20)
+	repo := &mockGameRepo{}
+	service := NewGameService(repo, []string{"APPLE"}, NewRatingService(playerRepo, repo, NewEloSystem(testEloConfig)))
+	ctx := context.Background()
+	game, _ := service.CreateGame(ctx, "p1", "p2")
+	game.CreatedAt = time.Now()
+	repo.created = game
+	if _, err := service.SubmitGuess(ctx, game.ID, "APPLE", "p1"); err != nil {
+		t.Fatalf("SubmitGuess failed: %v", err)
+	}
+	if len(repo.changes) != 2 {
+		t.Fatalf("Expected rating changes to be stored with the result, got %+v", repo.changes)
+	}
+	if repo.changes[0].PlayerID != "p1" || repo.changes[0].After >= 1500 {
+		t.Errorf("Expected p1 to lose rating, got %+v", repo.changes[0])
+	}
+
+	// Guesses after the game is over are ignored.
+	repo.changes = nil
+	updated, err := service.SubmitGuess(ctx, game.ID, "APPLE", "p1")
+	if err != nil {
+		t.Fatalf("SubmitGuess failed: %v", err)
+	}
+	if len(updated.Guesses) != 1 || repo.changes != nil {
+		t.Errorf("Expected finished game to be left alone, got %+v", updated)
+	}
+}
+
+func TestSubmitGuess_RetriesRatingConflict(t *testing.T) {
+	playerRepo := newMockPlayerRepo()
+	newRatedPlayer(playerRepo, "p1", 1500, 20)
+	newRatedPlayer(playerRepo, "p2", 1500, 20)
+	repo := &mockGameRepo{finishErrs: []error{fmt.Errorf("rating_conflict")}}
+	service := NewGameService(repo, []string{"APPLE"}, NewRatingService(playerRepo, repo, NewEloSystem(testEloConfig)))
+	ctx := context.Background()
+	game, _ := service.CreateGame(ctx, "p1", "p2")
+	game.CreatedAt = time.Now()
+	repo.created = game
+	if _, err := service.SubmitGuess(ctx, game.ID, "APPLE", "p1"); err != nil {
+		t.Fatalf("SubmitGuess failed: %v", err)
+	}
+	if len(repo.changes) != 2 || repo.changes[0].RatedGames != 20 {
+		t.Fatalf("Expected the game to be rated again and stored, got %+v", repo.changes)
+	}
+
+	// A finish that keeps conflicting gives up rather than retrying forever.
+	repo.finishErrs = []error{fmt.Errorf("rating_conflict"), fmt.Errorf("rating_conflict"), fmt.Errorf("rating_conflict")}
+	game2, _ := service.CreateGame(ctx, "p1", "p2")
+	game2.CreatedAt = time.Now()
+	repo.created = game2
+	if _, err := service.SubmitGuess(ctx, game2.ID, "APPLE", "p1"); err == nil || err.Error() != "rating_conflict" {
+		t.Errorf("Expected rating_conflict, got %v", err)
+	}
+}
+
+func TestGlicko2_Rate(t *testing.T) {
+	system := NewGlicko2System(Glicko2Config{Tau: 0.5, RatingPeriod: 7 * 24 * time.Hour})
+	now := time.Now()
+	winner, loser := system.Rate(system.Initial(), system.Initial(), 1, now)
+	if winner.Rating <= 1500 || loser.Rating >= 1500 {
+		t.Errorf("Expected winner to gain and loser to drop, got %.1f and %.1f", winner.Rating, loser.Rating)
+	}
+	if winner.Deviation >= 350 || loser.Deviation >= 350 {
+		t.Errorf("Expected deviations to shrink, got %.1f and %.1f", winner.Deviation, loser.Deviation)
+	}
+	if winner.Games != 1 || winner.RatedAt == nil {
+		t.Errorf("Expected game count and rated time to be set, got %+v", winner)
+	}
+
+	// A player returning after a long break has a larger deviation, so the same result moves them further.
+	active, _ := system.Rate(winner, loser, 1, now.Add(24*time.Hour))
+	idle, _ := system.Rate(winner, loser, 1, now.Add(365*24*time.Hour))
+	if idle.Rating-winner.Rating <= active.Rating-winner.Rating {
+		t.Errorf("Expected idle player to move further: active %.1f, idle %.1f", active.Rating, idle.Rating)
+	}
+}
+
+func TestRecomputeAll(t *testing.T) {
+	playerRepo := newMockPlayerRepo()
+	newRatedPlayer(playerRepo, "p1", 1800, 50)
+	newRatedPlayer(playerRepo, "p2", 1100, 50)
+	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: "guest", Name: "guest"})
+	start := time.Now().Add(-time.Hour)
+	gameRepo := &mockGameRepo{finished: []*models.Game{
+		{ID: "g1", FirstPlayer: "p1", SecondPlayer: "p2", Result: "lose:p2", UpdatedAt: start},
+		{ID: "g2", FirstPlayer: "p1", SecondPlayer: "guest", Result: "lose:guest", UpdatedAt: start.Add(time.Minute)},
+		{ID: "g3", FirstPlayer: "p2", SecondPlayer: "p1", Result: "draw", UpdatedAt: start.Add(2 * time.Minute)},
+	}}
+	service := NewRatingService(playerRepo, gameRepo, NewEloSystem(testEloConfig))
+	rated, err := service.RecomputeAll(context.Background())
+	if err != nil {
+		t.Fatalf("RecomputeAll failed: %v", err)
+	}
+	if rated != 2 {
+		t.Errorf("Expected 2 rated games, got %d", rated)
+	}
+	if len(gameRepo.changes) != 4 {
+		t.Fatalf("Expected 4 rating changes, got %d", len(gameRepo.changes))
+	}
+	// Stored ratings are ignored: everyone replays from the initial rating.
+	if gameRepo.changes[0].Before != 1200 || gameRepo.changes[0].After != 1232 {
+		t.Errorf("Unexpected first change: %+v", gameRepo.changes[0])
+	}
+	if gameRepo.changes[3].GameID != "g3" || gameRepo.changes[3].Before != 1232 {
+		t.Errorf("Expected g3 to start from p1's replayed rating, got %+v", gameRepo.changes[3])
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PlayerRating is a player's rating state as seen by a RatingSystem.
+type PlayerRating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+	// Games is the number of rated games played so far.
+	Games int
+	// RatedAt is the time of the last rated game, or nil if there is none.
+	RatedAt *time.Time
+}
+
+// RatingSystem computes new ratings for the two players of a finished game.
+type RatingSystem interface {
+	// Name returns the identifier used to select the system in configuration.
+	Name() string
+	// Initial returns the rating state of a player with no rated games.
+	Initial() PlayerRating
+	// Rate returns both players' new ratings, given the first player's score
+	// (1 for a win, 0.5 for a draw, 0 for a loss) and the time the game ended.
+	Rate(first, second PlayerRating, firstScore float64, at time.Time) (PlayerRating, PlayerRating)
+	// TracksDeviation reports whether the system uses deviation and volatility.
+	TracksDeviation() bool
+}
+
+// NewRatingSystem returns the rating system with the given name ("elo" or "glicko2").
+func NewRatingSystem(name string, elo EloConfig, glicko Glicko2Config) (RatingSystem, error) {
+	switch name {
+	case "elo":
+		return NewEloSystem(elo), nil
+	case "glicko2":
+		return NewGlicko2System(glicko), nil
+	default:
+		return nil, fmt.Errorf("unknown rating system %q", name)
+	}
+}
+
+// EloConfig holds the tuning parameters for Elo rating updates.
+type EloConfig struct {
+	// InitialRating is the rating a player starts from before their first rated game.
+	InitialRating int
+	// KFactor is the K-factor applied to established players.
+	KFactor int
+	// ProvisionalKFactor is the K-factor applied during the provisional period.
+	ProvisionalKFactor int
+	// ProvisionalGames is the number of rated games a player stays provisional for.
+	ProvisionalGames int
+}
+
+// EloSystem is the classic Elo rating system with a provisional K-factor for new players.
+type EloSystem struct {
+	cfg EloConfig
+}
+
+// NewEloSystem creates a new EloSystem.
+func NewEloSystem(cfg EloConfig) *EloSystem {
+	return &EloSystem{cfg: cfg}
+}
+
+func (e *EloSystem) Name() string { return "elo" }
+
+func (e *EloSystem) TracksDeviation() bool { return false }
+
+func (e *EloSystem) Initial() PlayerRating {
+	return PlayerRating{Rating: float64(e.cfg.InitialRating)}
+}
+
+func (e *EloSystem) Rate(first, second PlayerRating, firstScore float64, at time.Time) (PlayerRating, PlayerRating) {
+	newFirst := e.update(first, second, firstScore, at)
+	newSecond := e.update(second, first, 1-firstScore, at)
+	return newFirst, newSecond
+}
+
+func (e *EloSystem) update(p, opponent PlayerRating, score float64, at time.Time) PlayerRating {
+	k := e.cfg.KFactor
+	if p.Games < e.cfg.ProvisionalGames {
+		k = e.cfg.ProvisionalKFactor
+	}
+	expected := 1 / (1 + math.Pow(10, (opponent.Rating-p.Rating)/400))
+	p.Rating += math.Round(float64(k) * (score - expected))
+	p.Games++
+	p.RatedAt = &at
+	return p
+}
+
+// Glicko2Config holds the tuning parameters for Glicko-2 rating updates.
+type Glicko2Config struct {
+	// Tau constrains how quickly volatility can change; typical values are 0.3 to 1.2.
+	Tau float64
+	// RatingPeriod is how long a player must be idle for their deviation to grow by one period.
+	RatingPeriod time.Duration
+}
+
+const (
+	glickoScale             = 173.7178
+	glickoInitialRating     = 1500
+	glickoInitialDeviation  = 350
+	glickoInitialVolatility = 0.06
+	glickoConvergence       = 0.000001
+)
+
+// Glicko2System implements Mark Glickman's Glicko-2 rating system, treating
+// every game as its own rating period and growing a player's deviation for
+// each idle period since their last rated game.
+type Glicko2System struct {
+	cfg Glicko2Config
+}
+
+// NewGlicko2System creates a new Glicko2System.
+func NewGlicko2System(cfg Glicko2Config) *Glicko2System {
+	return &Glicko2System{cfg: cfg}
+}
+
+func (g *Glicko2System) Name() string { return "glicko2" }
+
+func (g *Glicko2System) TracksDeviation() bool { return true }
+
+func (g *Glicko2System) Initial() PlayerRating {
+	return PlayerRating{
+		Rating:     glickoInitialRating,
+		Deviation:  glickoInitialDeviation,
+		Volatility: glickoInitialVolatility,
+	}
+}
+
+func (g *Glicko2System) Rate(first, second PlayerRating, firstScore float64, at time.Time) (PlayerRating, PlayerRating) {
+	first = g.age(first, at)
+	second = g.age(second, at)
+	newFirst := g.update(first, second, firstScore, at)
+	newSecond := g.update(second, first, 1-firstScore, at)
+	return newFirst, newSecond
+}
+
+// age grows the deviation of a player for every rating period they sat out.
+func (g *Glicko2System) age(p PlayerRating, at time.Time) PlayerRating {
+	if p.RatedAt == nil || g.cfg.RatingPeriod <= 0 {
+		return p
+	}
+	periods := math.Floor(at.Sub(*p.RatedAt).Hours() / g.cfg.RatingPeriod.Hours())
+	if periods <= 0 {
+		return p
+	}
+	phi := p.Deviation / glickoScale
+	phi = math.Sqrt(phi*phi + periods*p.Volatility*p.Volatility)
+	p.Deviation = math.Min(phi*glickoScale, glickoInitialDeviation)
+	return p
+}
+
+func (g *Glicko2System) update(p, opponent PlayerRating, score float64, at time.Time) PlayerRating {
+	mu := (p.Rating - glickoInitialRating) / glickoScale
+	phi := p.Deviation / glickoScale
+	muJ := (opponent.Rating - glickoInitialRating) / glickoScale
+	phiJ := opponent.Deviation / glickoScale
+
+	gPhi := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+	expected := 1 / (1 + math.Exp(-gPhi*(mu-muJ)))
+	v := 1 / (gPhi * gPhi * expected * (1 - expected))
+	delta := v * gPhi * (score - expected)
+
+	sigma := g.volatility(phi, p.Volatility, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*gPhi*(score-expected)
+
+	p.Rating = newMu*glickoScale + glickoInitialRating
+	p.Deviation = newPhi * glickoScale
+	p.Volatility = sigma
+	p.Games++
+	p.RatedAt = &at
+	return p
+}
+
+// volatility finds the new volatility using the Illinois algorithm from step 5 of the Glicko-2 paper.
+func (g *Glicko2System) volatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	tau := g.cfg.Tau
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoConvergence {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"battle-wordle/server/models"
+	"battle-wordle/server/repositories"
+)
+
+// ShareService builds shareable summaries of games: an emoji grid for chat
+// and a rendered board image. Neither reveals the solution of a game in progress.
+type ShareService struct {
+	gameRepo   repositories.GameRepositoryI
+	playerRepo repositories.PlayerRepositoryI
+}
+
+// ShareCard is the shareable summary of a game.
+type ShareCard struct {
+	Game             *models.Game
+	FirstPlayerName  string
+	SecondPlayerName string
+	// Title names the players, e.g. "alice vs bob".
+	Title string
+	// Summary describes the state or outcome of the game.
+	Summary string
+	// Grid has one line of emoji per guess, labelled with the guessing player.
+	Grid string
+}
+
+var feedbackEmoji = map[FeedbackType]string{
+	FeedbackCorrect: "🟩",
+	FeedbackPresent: "🟨",
+	FeedbackAbsent:  "⬛",
+}
+
+// NewShareService creates a new ShareService.
+func NewShareService(gameRepo repositories.GameRepositoryI, playerRepo repositories.PlayerRepositoryI) *ShareService {
+	return &ShareService{gameRepo: gameRepo, playerRepo: playerRepo}
+}
+
+// GetShareCard returns the share card of a game, or "game_not_found".
+func (s *ShareService) GetShareCard(ctx context.Context, gameID string) (*ShareCard, error) {
+	game, err := s.gameRepo.GetByID(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, fmt.Errorf("game_not_found")
+	}
+	card := &ShareCard{Game: game, FirstPlayerName: "Player 1", SecondPlayerName: "Player 2"}
+	for _, p := range []struct {
+		id   string
+		name *string
+	}{{game.FirstPlayer, &card.FirstPlayerName}, {game.SecondPlayer, &card.SecondPlayerName}} {
+		player, err := s.playerRepo.GetByID(ctx, p.id)
+		if err != nil {
+			return nil, err
+		}
+		if player != nil && player.Name != "" {
+			*p.name = player.DisplayName()
+		}
+	}
+
+	card.Title = card.FirstPlayerName + " vs " + card.SecondPlayerName
+	card.Summary = card.summary()
+	var grid strings.Builder
+	for i, guess := range game.Guesses {
+		for _, f := range getFeedback(guess, game.Solution) {
+			grid.WriteString(feedbackEmoji[f])
+		}
+		grid.WriteString(" " + card.playerName(game.GuessedBy(i)) + "\n")
+	}
+	card.Grid = strings.TrimSuffix(grid.String(), "\n")
+	return card, nil
+}
+
+func (c *ShareCard) playerName(playerID string) string {
+	if playerID == c.Game.FirstPlayer {
+		return c.FirstPlayerName
+	}
+	return c.SecondPlayerName
+}
+
+func (c *ShareCard) summary() string {
+	guesses := len(c.Game.Guesses)
+	plural := "es"
+	if guesses == 1 {
+		plural = ""
+	}
+	switch {
+	case !c.Game.Finished():
+		return fmt.Sprintf("In progress after %d guess%s", guesses, plural)
+	case c.Game.IsDraw():
+		return fmt.Sprintf("Draw after %d guess%s", guesses, plural)
+	case c.Game.IsVoid():
+		return fmt.Sprintf("Voided after %d guess%s", guesses, plural)
+	default:
+		return fmt.Sprintf("%s won in %d guess%s", c.playerName(c.Game.Winner()), guesses, plural)
+	}
+}
+
+// Text returns the card as a chat message, ending with a link to the game.
+func (c *ShareCard) Text(url string) string {
+	text := "Battle Wordle: " + c.Title + "\n" + c.Summary + "\n"
+	if c.Grid != "" {
+		text += "\n" + c.Grid + "\n"
+	}
+	return text + "\n" + url
+}
+
+// Board image layout, in pixels.
+const (
+	boardPadding   = 24
+	boardTile      = 60
+	boardGap       = 8
+	boardMarker    = 8
+	boardTextScale = 3
+	boardTileScale = 4
+)
+
+var (
+	boardBackground   = color.RGBA{0x12, 0x12, 0x13, 0xff}
+	boardEmptyTile    = color.RGBA{0x3a, 0x3a, 0x3c, 0xff}
+	boardText         = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	boardFirstPlayer  = color.RGBA{0x4f, 0x8e, 0xf7, 0xff}
+	boardSecondPlayer = color.RGBA{0xf7, 0x9b, 0x4f, 0xff}
+	boardTileColors   = map[FeedbackType]color.RGBA{
+		FeedbackCorrect: {0x6a, 0xaa, 0x64, 0xff},
+		FeedbackPresent: {0xc9, 0xb4, 0x58, 0xff},
+		FeedbackAbsent:  boardEmptyTile,
+	}
+)
+
+// RenderBoard draws the card's board: the players above it, one row of tiles
+// per guess with a marker in the guessing player's colour, and the summary
+// below it. Guessed letters are shown; a game in progress has no guess equal
+// to its solution, so the solution is not revealed.
+func RenderBoard(c *ShareCard) image.Image {
+	wordLength := len([]rune(c.Game.Solution))
+	rows := maxGuesses
+	if len(c.Game.Guesses) > rows {
+		rows = len(c.Game.Guesses)
+	}
+	lineHeight := glyphHeight*boardTextScale + boardPadding
+	boardLeft := boardPadding + boardMarker + boardGap
+	width := boardLeft + wordLength*boardTile + (wordLength-1)*boardGap + boardPadding
+	height := boardPadding + lineHeight + rows*boardTile + (rows-1)*boardGap + boardPadding + lineHeight
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(boardBackground), image.Point{}, draw.Src)
+
+	// Names are coloured like the row markers so that no legend is needed.
+	x := boardPadding
+	for _, part := range []struct {
+		text  string
+		color color.Color
+	}{{c.FirstPlayerName, boardFirstPlayer}, {" vs ", boardText}, {c.SecondPlayerName, boardSecondPlayer}} {
+		text := fitText(part.text, width-boardPadding-x, boardTextScale)
+		if text == "" {
+			break
+		}
+		drawText(img, text, x, boardPadding, boardTextScale, part.color)
+		// textWidth leaves out the blank column after the last glyph.
+		x += textWidth(text, boardTextScale) + boardTextScale
+	}
+
+	top := boardPadding + lineHeight
+	for row := 0; row < rows; row++ {
+		y := top + row*(boardTile+boardGap)
+		var feedback []FeedbackType
+		var guess []rune
+		if row < len(c.Game.Guesses) {
+			guess = []rune(strings.ToUpper(c.Game.Guesses[row]))
+			feedback = getFeedback(c.Game.Guesses[row], c.Game.Solution)
+			marker := boardSecondPlayer
+			if c.Game.GuessedBy(row) == c.Game.FirstPlayer {
+				marker = boardFirstPlayer
+			}
+			draw.Draw(img, image.Rect(boardPadding, y, boardPadding+boardMarker, y+boardTile), image.NewUniform(marker), image.Point{}, draw.Src)
+		}
+		for col := 0; col < wordLength; col++ {
+			tx := boardLeft + col*(boardTile+boardGap)
+			tile := image.Rect(tx, y, tx+boardTile, y+boardTile)
+			if feedback == nil {
+				drawOutline(img, tile, boardEmptyTile, 2)
+				continue
+			}
+			draw.Draw(img, tile, image.NewUniform(boardTileColors[feedback[col]]), image.Point{}, draw.Src)
+			if col < len(guess) {
+				letter := string(guess[col])
+				lx := tx + (boardTile-textWidth(letter, boardTileScale))/2
+				ly := y + (boardTile-glyphHeight*boardTileScale)/2
+				drawText(img, letter, lx, ly, boardTileScale, boardText)
+			}
+		}
+	}
+
+	summary := fitText(c.Summary, width-2*boardPadding, boardTextScale-1)
+	drawText(img, summary, boardPadding, height-lineHeight, boardTextScale-1, boardText)
+	return img
+}
+
+// fitText shortens text, with a trailing "-", until it fits in width pixels at scale.
+func fitText(text string, width, scale int) string {
+	runes := []rune(text)
+	if textWidth(text, scale) <= width {
+		return text
+	}
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if candidate := string(runes) + "-"; textWidth(candidate, scale) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// drawOutline draws a border of the given thickness just inside r.
+func drawOutline(img draw.Image, r image.Rectangle, c color.Color, thickness int) {
+	src := image.NewUniform(c)
+	draw.Draw(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+thickness), src, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(r.Min.X, r.Max.Y-thickness, r.Max.X, r.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(r.Min.X, r.Min.Y, r.Min.X+thickness, r.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(r.Max.X-thickness, r.Min.Y, r.Max.X, r.Max.Y), src, image.Point{}, draw.Src)
+}
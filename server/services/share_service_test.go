@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"image/color"
+	"strings"
+	"testing"
+
+	"battle-wordle/server/models"
+)
+
+func TestShareService_GetShareCard(t *testing.T) {
+	ctx := context.Background()
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p1", Name: "alice"})
+	playerRepo.CreatePlayer(ctx, &models.Player{ID: "p2", Name: "bob"})
+	gameRepo := &mockGameRepo{created: &models.Game{
+		ID: "g1", Solution: "APPLE", FirstPlayer: "p1", SecondPlayer: "p2",
+		Guesses: []string{"CRANE", "APPLE"}, Result: "lose:p2",
+	}}
+	service := NewShareService(gameRepo, playerRepo)
+
+	card, err := service.GetShareCard(ctx, "g1")
+	if err != nil {
+		t.Fatalf("GetShareCard failed: %v", err)
+	}
+	if card.Title != "alice vs bob" || card.Summary != "alice won in 2 guesses" {
+		t.Errorf("Unexpected title or summary: %q, %q", card.Title, card.Summary)
+	}
+	if want := "⬛⬛🟨⬛🟩 alice\n🟩🟩🟩🟩🟩 bob"; card.Grid != want {
+		t.Errorf("Expected grid\n%s\ngot\n%s", want, card.Grid)
+	}
+
+	img := RenderBoard(card)
+	// The first tile of the solved row is green.
+	top := boardPadding + glyphHeight*boardTextScale + boardPadding + boardTile + boardGap
+	left := boardPadding + boardMarker + boardGap
+	if got := color.RGBAModel.Convert(img.At(left+1, top+1)); got != boardTileColors[FeedbackCorrect] {
+		t.Errorf("Expected a green tile, got %v", got)
+	}
+
+	if _, err := service.GetShareCard(ctx, "missing"); err == nil || err.Error() != "game_not_found" {
+		t.Errorf("Expected game_not_found, got %v", err)
+	}
+}
+
+func TestShareService_HidesSolutionInProgress(t *testing.T) {
+	gameRepo := &mockGameRepo{created: &models.Game{
+		ID: "g1", Solution: "APPLE", FirstPlayer: "p1", SecondPlayer: "p2", Guesses: []string{"CRANE"},
+	}}
+	card, err := NewShareService(gameRepo, newMockPlayerRepo()).GetShareCard(context.Background(), "g1")
+	if err != nil {
+		t.Fatalf("GetShareCard failed: %v", err)
+	}
+	text := card.Text("http://example.com/game/g1")
+	if strings.Contains(strings.ToUpper(text), "APPLE") || card.Summary != "In progress after 1 guess" {
+		t.Errorf("Unexpected share text:\n%s", text)
+	}
+	if !strings.Contains(text, "⬛⬛🟨⬛🟩 Player 1") {
+		t.Errorf("Expected unknown players to be labelled by seat:\n%s", text)
+	}
+}
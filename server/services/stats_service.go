@@ -2,28 +2,340 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"time"
 
+	"battle-wordle/server/models"
 	"battle-wordle/server/repositories"
 )
 
 // StatsService provides logic for player and game statistics.
 type StatsService struct {
-	gameRepo   *repositories.GameRepository
-	playerRepo *repositories.PlayerRepository
+	gameRepo   repositories.GameRepositoryI
+	playerRepo repositories.PlayerRepositoryI
+	statsRepo  repositories.PlayerStatsRepositoryI
 }
 
+// HeadToHeadStats summarises every game played between two players. Wins are
+// attributed from the perspective of the players as requested, not by who
+// moved first in each game.
 type HeadToHeadStats struct {
 	FirstPlayerWins  int `json:"first_player_wins"`
 	SecondPlayerWins int `json:"second_player_wins"`
 	Draws            int `json:"draws"`
+	// GamesPlayed is the number of finished games between the pair.
+	GamesPlayed int `json:"games_played"`
+	// AverageGuesses is the mean number of guesses it took to finish a game.
+	AverageGuesses float64 `json:"average_guesses"`
+	// CurrentStreak is the run of identical results ending with the latest game, if any.
+	CurrentStreak *HeadToHeadStreak `json:"current_streak,omitempty"`
+	// RecentGames are the latest games between the pair, newest first.
+	RecentGames []HeadToHeadGame `json:"recent_games"`
+	// RatingHistory tracks both ratings across their rated games, oldest first.
+	RatingHistory []HeadToHeadRatingPoint `json:"rating_history"`
 }
 
+// HeadToHeadStreak is a run of consecutive wins by one player, or of draws.
+type HeadToHeadStreak struct {
+	// PlayerID is the player on the winning run, or empty for a run of draws.
+	PlayerID string `json:"player_id,omitempty"`
+	Result   string `json:"result"`
+	Length   int    `json:"length"`
+}
+
+// HeadToHeadGame is a summary of one game between the pair.
+type HeadToHeadGame struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	Result    string    `json:"result"`
+	// WinnerID is the winning player, or empty for draws and unfinished games.
+	WinnerID string `json:"winner_id,omitempty"`
+	Guesses  int    `json:"guesses"`
+}
+
+// HeadToHeadRatingPoint is both players' ratings after a rated game between them.
+type HeadToHeadRatingPoint struct {
+	GameID             string    `json:"game_id"`
+	At                 time.Time `json:"at"`
+	FirstPlayerRating  int       `json:"first_player_rating"`
+	FirstPlayerChange  int       `json:"first_player_change"`
+	SecondPlayerRating int       `json:"second_player_rating"`
+	SecondPlayerChange int       `json:"second_player_change"`
+}
+
+// PlayerStatsProfile is a player's statistics across all their finished games.
+type PlayerStatsProfile struct {
+	PlayerID string `json:"player_id"`
+	ModeStats
+	// GuessDistribution maps a number of guesses to how many of the player's
+	// solved games took that many.
+	GuessDistribution map[int]int `json:"guess_distribution"`
+	// TopOpenings are the player's most used opening words, most used first.
+	TopOpenings []*models.OpeningStats `json:"top_openings"`
+	// ByMode breaks the totals down by game mode.
+	ByMode map[string]ModeStats `json:"by_mode"`
+}
+
+// ModeStats are a player's results in one game mode, or in all of them.
+type ModeStats struct {
+	Games  int `json:"games"`
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+	Draws  int `json:"draws"`
+	// WinRate is the share of games won, between 0 and 1.
+	WinRate       float64 `json:"win_rate"`
+	CurrentStreak int     `json:"current_streak"`
+	BestStreak    int     `json:"best_streak"`
+	// AverageTurnSeconds is the mean time the player took per turn, 0 if unknown.
+	AverageTurnSeconds float64 `json:"average_turn_seconds"`
+}
+
+const (
+	streakResultWin  = "win"
+	streakResultDraw = "draw"
+
+	topOpeningsLimit = 5
+)
+
 // NewStatsService creates a new StatsService.
-func NewStatsService(gameRepo *repositories.GameRepository, playerRepo *repositories.PlayerRepository) *StatsService {
-	return &StatsService{gameRepo: gameRepo, playerRepo: playerRepo}
+func NewStatsService(gameRepo repositories.GameRepositoryI, playerRepo repositories.PlayerRepositoryI, statsRepo repositories.PlayerStatsRepositoryI) *StatsService {
+	return &StatsService{gameRepo: gameRepo, playerRepo: playerRepo, statsRepo: statsRepo}
+}
+
+// summarizeGame splits a finished game into each player's part of it, which
+// GameService stores with the game's result.
+func summarizeGame(game *models.Game) []*models.PlayerGameSummary {
+	mode := game.Mode
+	if mode == "" {
+		mode = models.ModeClassic
+	}
+	summaries := make(map[string]*models.PlayerGameSummary, 2)
+	var order []*models.PlayerGameSummary
+	for _, playerID := range []string{game.FirstPlayer, game.SecondPlayer} {
+		summary := &models.PlayerGameSummary{PlayerID: playerID, Mode: mode}
+		switch {
+		case game.IsDraw():
+			summary.Outcome = models.OutcomeDraw
+		case game.Loser() == playerID:
+			summary.Outcome = models.OutcomeLoss
+		default:
+			summary.Outcome = models.OutcomeWin
+		}
+		if !game.IsDraw() {
+			summary.SolvedIn = len(game.Guesses)
+		}
+		summaries[playerID] = summary
+		order = append(order, summary)
+	}
+
+	// Guess times are only trusted when every guess has one; older games lack them.
+	timed := len(game.GuessTimes) == len(game.Guesses)
+	turnStart := game.CreatedAt
+	for i, guess := range game.Guesses {
+		summary := summaries[game.GuessedBy(i)]
+		if summary.Opening == "" {
+			summary.Opening = guess
+		}
+		if timed {
+			summary.Turns++
+			summary.TurnTime += game.GuessTimes[i].Sub(turnStart)
+			turnStart = game.GuessTimes[i]
+		}
+	}
+	return order
 }
 
-func (s *StatsService) GetHeadToHeadStats(ctx context.Context, firstPlayerID string, secondPlayerID string) (HeadToHeadStats, error) {
-	// TODO: Implement StatsService logic or remove if not needed. Currently a stub.
-	return HeadToHeadStats{FirstPlayerWins: 0, SecondPlayerWins: 0, Draws: 0}, nil
+// countedGames returns the player's games that count towards their statistics
+// and achievements, in the order they finished. Voided and imported games do
+// not count.
+func countedGames(ctx context.Context, gameRepo repositories.GameRepositoryI, playerID string) ([]*models.Game, error) {
+	games, err := gameRepo.GetByPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	counted := make([]*models.Game, 0, len(games))
+	for _, game := range games {
+		if game.Finished() && !game.IsVoid() && !game.Imported {
+			counted = append(counted, game)
+		}
+	}
+	sort.SliceStable(counted, func(i, j int) bool {
+		if !counted[i].UpdatedAt.Equal(counted[j].UpdatedAt) {
+			return counted[i].UpdatedAt.Before(counted[j].UpdatedAt)
+		}
+		return counted[i].ID < counted[j].ID
+	})
+	return counted, nil
+}
+
+// RecomputePlayer rebuilds the player's aggregate statistics from their
+// finished games, e.g. after the result of one of them was changed.
+func (s *StatsService) RecomputePlayer(ctx context.Context, playerID string) error {
+	games, err := countedGames(ctx, s.gameRepo, playerID)
+	if err != nil {
+		return err
+	}
+	var summaries []*models.PlayerGameSummary
+	for _, game := range games {
+		summaries = append(summaries, summarizeGame(game)...)
+	}
+	return s.statsRepo.ReplacePlayer(ctx, playerID, summaries)
+}
+
+// GetPlayerStats returns the player's statistics profile. Players who have not
+// finished a game get an empty profile.
+func (s *StatsService) GetPlayerStats(ctx context.Context, playerID string) (*PlayerStatsProfile, error) {
+	player, err := s.playerRepo.GetByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil {
+		return nil, fmt.Errorf("player_not_found")
+	}
+
+	stats, err := s.statsRepo.GetStats(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	distribution, err := s.statsRepo.GetGuessDistribution(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	openings, err := s.statsRepo.GetTopOpenings(ctx, playerID, topOpeningsLimit)
+	if err != nil {
+		return nil, err
+	}
+	if openings == nil {
+		openings = []*models.OpeningStats{}
+	}
+
+	profile := &PlayerStatsProfile{
+		PlayerID:          playerID,
+		GuessDistribution: distribution,
+		TopOpenings:       openings,
+		ByMode:            make(map[string]ModeStats),
+	}
+	for _, st := range stats {
+		if st.Mode == models.StatsModeAll {
+			profile.ModeStats = modeStats(st)
+		} else {
+			profile.ByMode[st.Mode] = modeStats(st)
+		}
+	}
+	return profile, nil
+}
+
+func modeStats(st *models.PlayerStats) ModeStats {
+	ms := ModeStats{
+		Games:         st.Games,
+		Wins:          st.Wins,
+		Losses:        st.Losses,
+		Draws:         st.Draws,
+		CurrentStreak: st.CurrentStreak,
+		BestStreak:    st.BestStreak,
+	}
+	if st.Games > 0 {
+		ms.WinRate = float64(st.Wins) / float64(st.Games)
+	}
+	if st.Turns > 0 {
+		ms.AverageTurnSeconds = st.TurnTime.Seconds() / float64(st.Turns)
+	}
+	return ms
+}
+
+// GetHeadToHeadStats returns the record between two players along with their
+// recentGames most recent games.
+func (s *StatsService) GetHeadToHeadStats(ctx context.Context, firstPlayerID string, secondPlayerID string, recentGames int) (HeadToHeadStats, error) {
+	for _, id := range []string{firstPlayerID, secondPlayerID} {
+		player, err := s.playerRepo.GetByID(ctx, id)
+		if err != nil {
+			return HeadToHeadStats{}, err
+		}
+		if player == nil {
+			return HeadToHeadStats{}, fmt.Errorf("player_not_found")
+		}
+	}
+
+	games, err := s.gameRepo.GetBetween(ctx, firstPlayerID, secondPlayerID)
+	if err != nil {
+		return HeadToHeadStats{}, err
+	}
+
+	stats := HeadToHeadStats{
+		RecentGames:   []HeadToHeadGame{},
+		RatingHistory: []HeadToHeadRatingPoint{},
+	}
+	totalGuesses := 0
+	streakOpen := true
+	// Games are newest first, so the streak is counted until the first different result.
+	for _, game := range games {
+		if len(stats.RecentGames) < recentGames {
+			stats.RecentGames = append(stats.RecentGames, HeadToHeadGame{
+				ID:        game.ID,
+				URL:       "/game/" + game.ID,
+				CreatedAt: game.CreatedAt,
+				Result:    game.Result,
+				WinnerID:  game.Winner(),
+				Guesses:   len(game.Guesses),
+			})
+		}
+		if !game.Finished() || game.IsVoid() {
+			continue
+		}
+		stats.GamesPlayed++
+		totalGuesses += len(game.Guesses)
+		switch game.Winner() {
+		case firstPlayerID:
+			stats.FirstPlayerWins++
+		case secondPlayerID:
+			stats.SecondPlayerWins++
+		default:
+			stats.Draws++
+		}
+
+		if !streakOpen {
+			continue
+		}
+		result := streakResultWin
+		if game.IsDraw() {
+			result = streakResultDraw
+		}
+		if stats.CurrentStreak == nil {
+			stats.CurrentStreak = &HeadToHeadStreak{PlayerID: game.Winner(), Result: result}
+		}
+		if stats.CurrentStreak.Result != result || stats.CurrentStreak.PlayerID != game.Winner() {
+			streakOpen = false
+			continue
+		}
+		stats.CurrentStreak.Length++
+	}
+	if stats.GamesPlayed > 0 {
+		stats.AverageGuesses = float64(totalGuesses) / float64(stats.GamesPlayed)
+	}
+
+	changes, err := s.gameRepo.GetRatingChangesBetween(ctx, firstPlayerID, secondPlayerID)
+	if err != nil {
+		return HeadToHeadStats{}, err
+	}
+	pointIndex := make(map[string]int)
+	for _, change := range changes {
+		i, ok := pointIndex[change.GameID]
+		if !ok {
+			stats.RatingHistory = append(stats.RatingHistory, HeadToHeadRatingPoint{GameID: change.GameID, At: change.CreatedAt})
+			i = len(stats.RatingHistory) - 1
+			pointIndex[change.GameID] = i
+		}
+		point := &stats.RatingHistory[i]
+		if change.PlayerID == firstPlayerID {
+			point.FirstPlayerRating = change.After
+			point.FirstPlayerChange = change.After - change.Before
+		} else {
+			point.SecondPlayerRating = change.After
+			point.SecondPlayerChange = change.After - change.Before
+		}
+	}
+
+	return stats, nil
 }
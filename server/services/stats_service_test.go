@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"battle-wordle/server/models"
+)
+
+func TestGetHeadToHeadStats(t *testing.T) {
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: "p1", Name: "alice"})
+	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: "p2", Name: "bob"})
+	now := time.Now()
+	gameRepo := &mockGameRepo{
+		// Newest first, as returned by the repository.
+		between: []*models.Game{
+			{ID: "g5", FirstPlayer: "p1", SecondPlayer: "p2", Guesses: []string{"CRANE"}, CreatedAt: now},
+			{ID: "g4", FirstPlayer: "p2", SecondPlayer: "p1", Result: "lose:p1", Guesses: []string{"CRANE", "APPLE"}, CreatedAt: now.Add(-time.Hour)},
+			{ID: "g3", FirstPlayer: "p1", SecondPlayer: "p2", Result: "lose:p1", Guesses: []string{"CRANE", "SLATE", "APPLE"}, CreatedAt: now.Add(-2 * time.Hour)},
+			{ID: "g2", FirstPlayer: "p1", SecondPlayer: "p2", Result: "draw", Guesses: []string{"A", "B", "C", "D", "E", "F"}, CreatedAt: now.Add(-3 * time.Hour)},
+			{ID: "g1", FirstPlayer: "p2", SecondPlayer: "p1", Result: "lose:p2", Guesses: []string{"APPLE"}, CreatedAt: now.Add(-4 * time.Hour)},
+		},
+		changes: []*models.RatingChange{
+			{GameID: "g1", PlayerID: "p1", Before: 1200, After: 1232},
+			{GameID: "g1", PlayerID: "p2", Before: 1200, After: 1168},
+			{GameID: "g3", PlayerID: "p2", Before: 1168, After: 1200},
+			{GameID: "g3", PlayerID: "p1", Before: 1232, After: 1200},
+		},
+	}
+	service := NewStatsService(gameRepo, playerRepo, &mockStatsRepo{})
+	stats, err := service.GetHeadToHeadStats(context.Background(), "p1", "p2", 2)
+	if err != nil {
+		t.Fatalf("GetHeadToHeadStats failed: %v", err)
+	}
+	if stats.FirstPlayerWins != 1 || stats.SecondPlayerWins != 2 || stats.Draws != 1 || stats.GamesPlayed != 4 {
+		t.Errorf("Unexpected record: %+v", stats)
+	}
+	if stats.AverageGuesses != 3 {
+		t.Errorf("Expected average of 3 guesses, got %v", stats.AverageGuesses)
+	}
+	if stats.CurrentStreak == nil || stats.CurrentStreak.PlayerID != "p2" || stats.CurrentStreak.Length != 2 {
+		t.Errorf("Expected p2 on a 2 game streak, got %+v", stats.CurrentStreak)
+	}
+	if len(stats.RecentGames) != 2 || stats.RecentGames[0].ID != "g5" || stats.RecentGames[0].URL != "/game/g5" {
+		t.Errorf("Unexpected recent games: %+v", stats.RecentGames)
+	}
+	if len(stats.RatingHistory) != 2 {
+		t.Fatalf("Expected 2 rating points, got %+v", stats.RatingHistory)
+	}
+	if p := stats.RatingHistory[1]; p.GameID != "g3" || p.FirstPlayerRating != 1200 || p.FirstPlayerChange != -32 || p.SecondPlayerChange != 32 {
+		t.Errorf("Unexpected rating point: %+v", p)
+	}
+}
+
+func TestGetHeadToHeadStats_UnknownPlayer(t *testing.T) {
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: "p1", Name: "alice"})
+	service := NewStatsService(&mockGameRepo{}, playerRepo, &mockStatsRepo{})
+	_, err := service.GetHeadToHeadStats(context.Background(), "p1", "nobody", 10)
+	if err == nil || err.Error() != "player_not_found" {
+		t.Errorf("Expected player_not_found, got %v", err)
+	}
+}
+
+// mockStatsRepo serves fixed aggregates and records replacements.
+type mockStatsRepo struct {
+	// replaced maps a player to the summaries their aggregates were rebuilt from.
+	replaced     map[string][]*models.PlayerGameSummary
+	stats        []*models.PlayerStats
+	distribution map[int]int
+	openings     []*models.OpeningStats
+}
+
+func (m *mockStatsRepo) ReplacePlayer(ctx context.Context, playerID string, summaries []*models.PlayerGameSummary) error {
+	if m.replaced == nil {
+		m.replaced = make(map[string][]*models.PlayerGameSummary)
+	}
+	m.replaced[playerID] = summaries
+	return nil
+}
+func (m *mockStatsRepo) GetStats(ctx context.Context, playerID string) ([]*models.PlayerStats, error) {
+	return m.stats, nil
+}
+func (m *mockStatsRepo) GetGuessDistribution(ctx context.Context, playerID string) (map[int]int, error) {
+	if m.distribution == nil {
+		return map[int]int{}, nil
+	}
+	return m.distribution, nil
+}
+func (m *mockStatsRepo) GetTopOpenings(ctx context.Context, playerID string, limit int) ([]*models.OpeningStats, error) {
+	return m.openings, nil
+}
+
+func TestSummarizeGame(t *testing.T) {
+	start := time.Now()
+	game := &models.Game{
+		ID: "g1", Mode: models.ModeClassic, FirstPlayer: "p1", SecondPlayer: "p2", Result: "lose:p1",
+		CreatedAt:  start,
+		Guesses:    []string{"CRANE", "SLATE", "APPLE"},
+		GuessTimes: []time.Time{start.Add(10 * time.Second), start.Add(30 * time.Second), start.Add(34 * time.Second)},
+	}
+	summaries := summarizeGame(game)
+
+	if len(summaries) != 2 {
+		t.Fatalf("Expected a summary per player, got %d", len(summaries))
+	}
+	first, second := summaries[0], summaries[1]
+	if first.Outcome != models.OutcomeLoss || first.Opening != "CRANE" || first.Turns != 2 || first.TurnTime != 14*time.Second {
+		t.Errorf("Unexpected first player summary: %+v", first)
+	}
+	if second.Outcome != models.OutcomeWin || second.Opening != "SLATE" || second.Turns != 1 || second.TurnTime != 20*time.Second {
+		t.Errorf("Unexpected second player summary: %+v", second)
+	}
+	if first.SolvedIn != 3 || second.SolvedIn != 3 {
+		t.Errorf("Expected both players to record a 3 guess game, got %d and %d", first.SolvedIn, second.SolvedIn)
+	}
+}
+
+func TestGetPlayerStats(t *testing.T) {
+	playerRepo := newMockPlayerRepo()
+	playerRepo.CreatePlayer(context.Background(), &models.Player{ID: "p1", Name: "alice"})
+	statsRepo := &mockStatsRepo{
+		stats: []*models.PlayerStats{
+			{PlayerID: "p1", Mode: models.StatsModeAll, Games: 4, Wins: 3, Losses: 1, CurrentStreak: 2, BestStreak: 2, Turns: 8, TurnTime: 40 * time.Second},
+			{PlayerID: "p1", Mode: models.ModeClassic, Games: 4, Wins: 3, Losses: 1, CurrentStreak: 2, BestStreak: 2, Turns: 8, TurnTime: 40 * time.Second},
+		},
+		distribution: map[int]int{3: 2, 4: 2},
+		openings:     []*models.OpeningStats{{Word: "CRANE", Uses: 3, Wins: 2}},
+	}
+	service := NewStatsService(&mockGameRepo{}, playerRepo, statsRepo)
+
+	profile, err := service.GetPlayerStats(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPlayerStats failed: %v", err)
+	}
+	if profile.Games != 4 || profile.WinRate != 0.75 || profile.AverageTurnSeconds != 5 {
+		t.Errorf("Unexpected totals: %+v", profile.ModeStats)
+	}
+	if classic, ok := profile.ByMode[models.ModeClassic]; !ok || classic.Wins != 3 {
+		t.Errorf("Expected a classic breakdown, got %+v", profile.ByMode)
+	}
+	if _, ok := profile.ByMode[models.StatsModeAll]; ok {
+		t.Errorf("Did not expect the all-modes row in the breakdown")
+	}
+	if len(profile.TopOpenings) != 1 || profile.GuessDistribution[3] != 2 {
+		t.Errorf("Unexpected openings or distribution: %+v %+v", profile.TopOpenings, profile.GuessDistribution)
+	}
+
+	if _, err := service.GetPlayerStats(context.Background(), "nobody"); err == nil || err.Error() != "player_not_found" {
+		t.Errorf("Expected player_not_found, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"battle-wordle/server/models"
+)
+
+// leetLetters maps digits and symbols to the letters they stand in for.
+var leetLetters = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '!': 'i', '+': 't', '|': 'l',
+}
+
+// WordFilter finds blocked words, e.g. slurs, in text. Text and words are
+// compared in their canonical forms, read as letters only, so that variants
+// such as "B.4.D" match "bad". Contains suits names, ContainsWord running text.
+type WordFilter struct {
+	words []string
+}
+
+// NewWordFilter creates a WordFilter for words. Empty words are ignored.
+func NewWordFilter(words []string) *WordFilter {
+	f := &WordFilter{}
+	for _, w := range words {
+		if w = filterFold(w); w != "" {
+			f.words = append(f.words, w)
+		}
+	}
+	return f
+}
+
+// Contains reports whether text contains a blocked word.
+func (f *WordFilter) Contains(text string) bool {
+	folded := filterFold(text)
+	for _, w := range f.words {
+		if strings.Contains(folded, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsWord reports whether a word of text, folded on its own, is a
+// blocked word. Unlike Contains, it never matches inside a longer word or
+// across spaces, so it suits running text such as chat messages.
+func (f *WordFilter) ContainsWord(text string) bool {
+	for _, word := range strings.Fields(text) {
+		folded := filterFold(strings.Trim(word, sentencePunctuation))
+		for _, w := range f.words {
+			if folded == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sentencePunctuation is trimmed from the ends of words in running text,
+// where it ends a sentence or quotes a word rather than standing in for a
+// letter.
+const sentencePunctuation = `.,;:!?"'()`
+
+// filterFold returns the letters of text's canonical form, with digits and
+// symbols read as letters.
+func filterFold(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if l, ok := leetLetters[r]; ok {
+			r = l
+		}
+		for _, c := range models.CanonicalName(string(r)) {
+			if unicode.IsLetter(c) {
+				b.WriteRune(c)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package ws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeWait is how long a write may take before it fails, so that a stalled
+// client does not hold up the other writers of its connection for long.
+const writeWait = 10 * time.Second
+
+// Conn is a WebSocket connection that may be written from several goroutines,
+// e.g. its own handler and another player's. gorilla/websocket allows only one
+// writer at a time, so messages must be sent with WriteMessage, which
+// serializes them. Close and WriteControl are safe to call concurrently.
+type Conn struct {
+	*websocket.Conn
+	writeMu sync.Mutex
+}
+
+// NewConn wraps conn. Every writer must use the same Conn.
+func NewConn(conn *websocket.Conn) *Conn {
+	return &Conn{Conn: conn}
+}
+
+// WriteMessage writes a message once any write in progress has finished.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.Conn.WriteMessage(messageType, data)
+}
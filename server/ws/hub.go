@@ -2,6 +2,7 @@ package ws
 
 import (
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -9,28 +10,28 @@ import (
 // Hub manages WebSocket connections by key (e.g., gameID, playerID).
 type Hub struct {
 	mu    sync.RWMutex
-	conns map[string]map[*websocket.Conn]bool
+	conns map[string]map[*Conn]bool
 }
 
 // NewHub creates a new Hub.
 func NewHub() *Hub {
 	return &Hub{
-		conns: make(map[string]map[*websocket.Conn]bool),
+		conns: make(map[string]map[*Conn]bool),
 	}
 }
 
 // AddConnection adds a connection for a given key.
-func (h *Hub) AddConnection(key string, conn *websocket.Conn) {
+func (h *Hub) AddConnection(key string, conn *Conn) {
 	h.mu.Lock()
 	if _, ok := h.conns[key]; !ok {
-		h.conns[key] = make(map[*websocket.Conn]bool)
+		h.conns[key] = make(map[*Conn]bool)
 	}
 	h.conns[key][conn] = true
 	h.mu.Unlock()
 }
 
 // RemoveConnection removes a connection for a given key.
-func (h *Hub) RemoveConnection(key string, conn *websocket.Conn) {
+func (h *Hub) RemoveConnection(key string, conn *Conn) {
 	h.mu.Lock()
 	if _, ok := h.conns[key]; ok {
 		delete(h.conns[key], conn)
@@ -43,21 +44,28 @@ func (h *Hub) RemoveConnection(key string, conn *websocket.Conn) {
 
 // Broadcast sends a message to all connections for a given key.
 func (h *Hub) Broadcast(key string, message []byte) {
-	h.mu.RLock()
-	conns := h.conns[key]
-	h.mu.RUnlock()
-	for conn := range conns {
+	for _, conn := range h.Connections(key) {
 		conn.WriteMessage(websocket.TextMessage, message)
 	}
 }
 
 // Connections returns all connections for a given key.
-func (h *Hub) Connections(key string) []*websocket.Conn {
+func (h *Hub) Connections(key string) []*Conn {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	var result []*websocket.Conn
+	var result []*Conn
 	for conn := range h.conns[key] {
 		result = append(result, conn)
 	}
 	return result
 }
+
+// CloseConnections closes all connections for a given key, telling the
+// clients why. The connections' readers then fail and clean up as usual.
+func (h *Hub) CloseConnections(key string, reason string) {
+	message := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	for _, conn := range h.Connections(key) {
+		conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+		conn.Close()
+	}
+}
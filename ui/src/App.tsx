@@ -5,12 +5,15 @@ import Game from './pages/Game';
 import Matchmaking from './pages/Matchmaking';
 import ChallengePage from './pages/Challenge';
 import Leaderboard from './pages/Leaderboard';
+import ResetPassword from './pages/ResetPassword';
 import NavBar from './components/NavBar';
 import ChallengePopover from './components/ChallengePopover';
 import type { Challenge as ChallengeNotification } from './components/ChallengePopover';
 import './App.css';
 import { NotificationWebSocketProvider } from './components/NotificationWebSocketContext';
 import { useNavigate } from 'react-router-dom';
+import { wsProtocols, storeSession, refreshSession, msUntilRefresh, logout, oidcProviders, startOidcLogin, finishOidcLogin, OIDC_ERRORS } from './auth';
+import type { OidcProvider } from './auth';
 
 // Define the Player type
 interface Player {
@@ -50,7 +53,8 @@ function App() {
     const wsInstance = new WebSocket(
       window.location.protocol === 'https:'
         ? `wss://${window.location.host}/ws/notifications`
-        : `ws://${window.location.host}/ws/notifications`
+        : `ws://${window.location.host}/ws/notifications`,
+      wsProtocols()
     );
     setWs(wsInstance);
     wsInstance.onopen = () => {
@@ -77,10 +81,11 @@ function App() {
               return;
             }
           }
-          setChallenges((prev) => [
+          // Pending invites are sent again on reconnect; show each once.
+          setChallenges((prev) => prev.some((c) => c.id === msg.challenge_id) ? prev : [
             ...prev,
             {
-              id: `${msg.from}-${isRematch ? 'rematch-' : ''}-${Date.now()}`,
+              id: msg.challenge_id,
               fromName: msg.from_name || msg.from,
               fromId: msg.from,
               rematch: isRematch,
@@ -89,12 +94,16 @@ function App() {
         }
         if (msg.type === 'challenge_result') {
           console.log('[Notification WS] challenge_result:', msg);
+          setChallenges((prev) => prev.filter((c) => c.id !== msg.challenge_id));
           if (msg.accepted && msg.game_id) {
             if (player && (msg.to === player.id || msg.from === player.id)) {
               navigate(`/game/${msg.game_id}`);
             }
           }
         }
+        if (msg.type === 'challenge_cancelled' || msg.type === 'challenge_expired') {
+          setChallenges((prev) => prev.filter((c) => c.id !== msg.challenge_id));
+        }
         // Handle other notification types here
       } catch {}
     };
@@ -105,34 +114,23 @@ function App() {
     };
   }, [player, location.pathname]);
 
-  const handleAcceptChallenge = (id: string) => {
+  // Challenges are answered over the API, so that the answer is not lost if
+  // the notification socket is reconnecting. Both players then get a
+  // challenge_result, which opens the game.
+  const respondToChallenge = (id: string, action: 'accept' | 'decline') => {
     setChallenges((prev) => prev.filter((c) => c.id !== id));
-    const challenge = challenges.find((c) => c.id === id);
-    if (!challenge) return;
-    if (ws && ws.readyState === WebSocket.OPEN) {
-      ws.send(JSON.stringify({
-        type: 'challenge_response',
-        from: player?.id,
-        to: challenge.fromId,
-        accepted: true,
-        rematch: challenge.rematch,
-      }));
-    }
-  };
-  const handleRejectChallenge = (id: string) => {
-    setChallenges((prev) => prev.filter((c) => c.id !== id));
-    const challenge = challenges.find((c) => c.id === id);
-    if (!challenge) return;
-    if (ws && ws.readyState === WebSocket.OPEN) {
-      ws.send(JSON.stringify({
-        type: 'challenge_response',
-        from: player?.id,
-        to: challenge.fromId,
-        accepted: false,
-        rematch: challenge.rematch,
-      }));
-    }
+    fetch(`/api/player/me/challenges/${id}/${action}`, {
+      method: 'POST',
+      headers: { Authorization: `Bearer ${localStorage.getItem('token')}` },
+    })
+      .then((res) => (res.ok ? res.json() : null))
+      .then((challenge) => {
+        if (challenge?.game_id) navigate(`/game/${challenge.game_id}`);
+      })
+      .catch(() => {});
   };
+  const handleAcceptChallenge = (id: string) => respondToChallenge(id, 'accept');
+  const handleRejectChallenge = (id: string) => respondToChallenge(id, 'decline');
 
   // Accept/Reject rematch handlers
   const handleAcceptRematch = () => {
@@ -156,15 +154,88 @@ function App() {
     setRematchOffer(null);
   };
 
-  // Register guest player on first visit
+  // Finish an external login, restore the session or register a guest on first visit
   useEffect(() => {
-    const storedPlayer = localStorage.getItem('player');
-    if (storedPlayer) {
-      setPlayer(JSON.parse(storedPlayer));
-      setLoading(false);
+    if (location.pathname === '/auth/callback') {
+      const params = new URLSearchParams(location.search);
+      const code = params.get('code');
+      navigate('/', { replace: true });
+      if (code) {
+        finishOidcLogin(code)
+          .then((loggedIn) => {
+            if (loggedIn) {
+              setPlayer(loggedIn);
+              setLoading(false);
+            } else {
+              restoreSession();
+            }
+          })
+          .catch(() => restoreSession());
+        return;
+      }
+      setAuthError(OIDC_ERRORS[params.get('error') || ''] || OIDC_ERRORS.login_failed);
+      setShowLoginModal(true);
+    }
+    restoreSession();
+  }, []);
+
+  function restoreSession() {
+    // Players stored without a refresh token predate sessions and cannot act any more.
+    if (localStorage.getItem('player') && localStorage.getItem('refresh_token')) {
+      refreshSession()
+        .then((refreshed) => {
+          if (refreshed) {
+            setPlayer(refreshed);
+            setLoading(false);
+          } else {
+            registerGuest();
+          }
+        })
+        .catch(() => {
+          setError('Could not restore your session. Please try again.');
+          setLoading(false);
+        });
       return;
     }
-    // No player found, register a guest
+    registerGuest();
+  }
+
+  // External login providers for the login modal
+  const [providers, setProviders] = useState<OidcProvider[]>([]);
+  useEffect(() => {
+    oidcProviders().then(setProviders).catch(() => setProviders([]));
+  }, []);
+  const handleOidcLogin = async (providerId: string) => {
+    setAuthError(null);
+    try {
+      await startOidcLogin(providerId);
+    } catch {
+      setAuthError(OIDC_ERRORS.login_failed);
+    }
+  };
+
+  // Refresh the access token shortly before it expires
+  useEffect(() => {
+    if (!player) return;
+    let timer: ReturnType<typeof setTimeout>;
+    const schedule = () => {
+      timer = setTimeout(() => {
+        refreshSession()
+          .then((refreshed) => {
+            if (refreshed) schedule();
+            else window.location.reload();
+          })
+          .catch(() => {
+            timer = setTimeout(schedule, 30 * 1000);
+          });
+      }, msUntilRefresh());
+    };
+    schedule();
+    return () => clearTimeout(timer);
+  }, [player?.id]);
+
+  // No player found, register a guest
+  function registerGuest() {
     const adjectives = [
       'Silly', 'Bouncy', 'Wiggly', 'Giggly', 'Wobbly', 'Fluffy', 'Bumpy', 'Jumpy',
       'Wacky', 'Zippy', 'Dizzy', 'Fuzzy', 'Squishy', 'Bouncy', 'Wiggly', 'Giggly'
@@ -187,15 +258,15 @@ function App() {
       .then(async (res) => {
         if (!res.ok) throw new Error('Failed to register player');
         const data = await res.json();
-        setPlayer(data);
-        localStorage.setItem('player', JSON.stringify(data));
+        setPlayer(data.player);
+        storeSession(data);
         setLoading(false);
       })
       .catch((err) => {
         setError('Could not register player. Please try again.');
         setLoading(false);
       });
-  }, []);
+  }
 
   // Modal handlers
   const openLoginModal = () => {
@@ -220,22 +291,21 @@ function App() {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
-          id: player?.id,
+          guest_token: player && !player.registered ? localStorage.getItem('token') : undefined,
           name: registerForm.name.trim(),
           password: registerForm.password
         })
       });
-      if (res.status === 409) {
-        // Username taken
-        const data = await res.json();
-        setAuthError(data.error || 'Username is already taken.');
+      if (res.status === 409 || res.status === 400) {
+        // Username taken or not allowed
+        const data = await res.json().catch(() => ({}));
+        setAuthError(data.error || (res.status === 409 ? 'Username is already taken.' : 'This name cannot be used.'));
         return;
       }
       if (!res.ok) throw new Error('Registration failed');
       const data = await res.json();
       setPlayer(data.player);
-      localStorage.setItem('player', JSON.stringify(data.player));
-      localStorage.setItem('token', data.token);
+      storeSession(data);
       setShowLoginModal(false);
     } catch (err) {
       setAuthError('Registration failed. Try a different name.');
@@ -257,19 +327,32 @@ function App() {
           password: loginForm.password
         })
       });
+      if (res.status === 429) {
+        const minutes = Math.ceil(Number(res.headers.get('Retry-After') || 60) / 60);
+        setAuthError(`Too many failed logins. Try again in ${minutes} minute${minutes === 1 ? '' : 's'}.`);
+        return;
+      }
       if (!res.ok) throw new Error('Login failed');
       const data = await res.json();
+      // Bring the games played as a guest along into the account.
+      const guestToken = player && !player.registered ? localStorage.getItem('token') : null;
+      if (guestToken) {
+        await fetch('/api/player/me/merge', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${data.token}` },
+          body: JSON.stringify({ guest_token: guestToken })
+        }).catch(() => undefined);
+      }
       setPlayer(data.player);
-      localStorage.setItem('player', JSON.stringify(data.player));
-      localStorage.setItem('token', data.token);
+      storeSession(data);
       setShowLoginModal(false);
     } catch (err) {
       setAuthError('Login failed. Check your credentials.');
     }
   };
-  const handleLogout = () => {
+  const handleLogout = async () => {
+    await logout();
     setPlayer(null);
-    localStorage.removeItem('player');
     window.location.reload();
   };
 
@@ -312,6 +395,11 @@ function App() {
               </>
             )}
             {authError && <div style={{ color: 'red', fontSize: '0.95rem' }}>{authError}</div>}
+            {providers.map(p => (
+              <button key={p.id} type="button" onClick={() => handleOidcLogin(p.id)} style={{ background: '#3a3a3c', color: '#f3f3f3', border: 'none', borderRadius: 4, padding: '0.5rem 1rem', cursor: 'pointer' }}>
+                Continue with {p.name}
+              </button>
+            ))}
             <div style={{ display: 'flex', gap: '1rem', justifyContent: 'flex-end', marginTop: 8 }}>
               <button type="button" onClick={closeLoginModal} style={{ background: '#3a3a3c', color: '#f3f3f3', border: 'none', borderRadius: 4, padding: '0.5rem 1rem', cursor: 'pointer' }}>Cancel</button>
               <button type="submit" style={{ background: '#538d4e', color: '#f3f3f3', border: 'none', borderRadius: 4, padding: '0.5rem 1rem', fontWeight: 'bold', cursor: 'pointer' }}>{showRegister ? 'Sign Up' : 'Login'}</button>
@@ -322,9 +410,14 @@ function App() {
                   Already have an account? Login
                 </button>
               ) : (
-                <button type="button" onClick={() => setShowRegister(true)} style={{ background: 'none', border: 'none', color: '#f3f3f3', cursor: 'pointer', textDecoration: 'underline', fontSize: '1rem' }}>
-                  Don&apos;t have an account? Sign up
-                </button>
+                <>
+                  <button type="button" onClick={() => setShowRegister(true)} style={{ background: 'none', border: 'none', color: '#f3f3f3', cursor: 'pointer', textDecoration: 'underline', fontSize: '1rem' }}>
+                    Don&apos;t have an account? Sign up
+                  </button>
+                  <button type="button" onClick={() => { setShowLoginModal(false); navigate('/reset-password'); }} style={{ background: 'none', border: 'none', color: '#f3f3f3', cursor: 'pointer', textDecoration: 'underline', fontSize: '1rem' }}>
+                    Forgot password?
+                  </button>
+                </>
               )}
             </div>
           </form>
@@ -336,6 +429,7 @@ function App() {
         <Route path="/matchmaking" element={<Matchmaking />} />
         <Route path="/challenge" element={<ChallengePage />} />
         <Route path="/leaderboard" element={<Leaderboard />} />
+        <Route path="/reset-password" element={<ResetPassword />} />
       </Routes>
     </NotificationWebSocketProvider>
   );
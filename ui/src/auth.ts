@@ -0,0 +1,125 @@
+// Browsers cannot set headers on WebSocket requests, so the token is offered
+// as a subprotocol after "bearer"; the server selects "bearer".
+export function wsProtocols(): string[] | undefined {
+  const token = localStorage.getItem('token');
+  return token ? ['bearer', token] : undefined;
+}
+
+// Access tokens expire after expires_in seconds; refresh them this long before.
+const REFRESH_MARGIN_MS = 60 * 1000;
+
+// storeSession saves the player and tokens of a register, login or refresh
+// response.
+export function storeSession(data: { player: any; token: string; refresh_token: string; expires_in: number }) {
+  localStorage.setItem('player', JSON.stringify(data.player));
+  localStorage.setItem('token', data.token);
+  localStorage.setItem('refresh_token', data.refresh_token);
+  localStorage.setItem('token_expires_at', String(Date.now() + data.expires_in * 1000));
+}
+
+export function clearSession() {
+  localStorage.removeItem('player');
+  localStorage.removeItem('token');
+  localStorage.removeItem('refresh_token');
+  localStorage.removeItem('token_expires_at');
+}
+
+// refreshSession exchanges the stored refresh token for new tokens and returns
+// the player, or null if the session has ended. Refresh tokens are single-use,
+// so concurrent calls share one request.
+let refreshing: Promise<any | null> | null = null;
+export function refreshSession(): Promise<any | null> {
+  if (refreshing) return refreshing;
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) return Promise.resolve(null);
+  refreshing = fetch('/api/player/refresh', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ refresh_token: refreshToken }),
+  })
+    .then(async (res) => {
+      if (res.status === 401) {
+        clearSession();
+        return null;
+      }
+      if (!res.ok) throw new Error('Failed to refresh session');
+      const data = await res.json();
+      storeSession(data);
+      return data.player;
+    })
+    .finally(() => {
+      refreshing = null;
+    });
+  return refreshing;
+}
+
+// msUntilRefresh returns how long until the access token should be refreshed.
+export function msUntilRefresh(): number {
+  const expiresAt = Number(localStorage.getItem('token_expires_at') || 0);
+  return Math.max(0, expiresAt - Date.now() - REFRESH_MARGIN_MS);
+}
+
+// logout ends the session on the server and forgets it locally.
+export async function logout() {
+  const token = localStorage.getItem('token');
+  if (token) {
+    try {
+      await fetch('/api/player/logout', {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${token}` },
+      });
+    } catch {
+      // The session expires on its own.
+    }
+  }
+  clearSession();
+}
+
+export interface OidcProvider {
+  id: string;
+  name: string;
+}
+
+// oidcProviders lists the external providers players can log in with.
+export async function oidcProviders(): Promise<OidcProvider[]> {
+  const res = await fetch('/api/auth/oidc/providers');
+  if (!res.ok) return [];
+  return res.json();
+}
+
+// startOidcLogin sends the browser to the provider's login page. The current
+// session is sent along so that a guest is upgraded rather than replaced.
+export async function startOidcLogin(providerId: string) {
+  const token = localStorage.getItem('token');
+  const res = await fetch(`/api/auth/oidc/${encodeURIComponent(providerId)}/start`, {
+    method: 'POST',
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+  });
+  if (!res.ok) throw new Error('Failed to start login');
+  const data = await res.json();
+  window.location.assign(data.url);
+}
+
+// finishOidcLogin redeems the one-time code the provider's callback redirected
+// back with and returns the player, or null if the code is invalid.
+export async function finishOidcLogin(code: string): Promise<any | null> {
+  const res = await fetch('/api/auth/oidc/exchange', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ code }),
+  });
+  if (res.status === 401) return null;
+  if (!res.ok) throw new Error('Failed to finish login');
+  const data = await res.json();
+  storeSession(data);
+  return data.player;
+}
+
+// OIDC_ERRORS describes the errors the provider's callback redirects back with.
+export const OIDC_ERRORS: Record<string, string> = {
+  login_cancelled: 'Login was cancelled.',
+  login_expired: 'The login took too long. Please try again.',
+  identity_linked_elsewhere: 'That account is already linked to another player.',
+  player_banned: 'This account is banned.',
+  login_failed: 'Login failed. Please try again.',
+};
@@ -0,0 +1,108 @@
+import React, { useState } from 'react';
+
+export type ChatMessage = {
+  id: number;
+  player_id: string;
+  text: string;
+  quick_phrase: boolean;
+  created_at: string;
+};
+
+export type ChatMode = 'all' | 'quick_phrases' | 'muted';
+
+type GameChatProps = {
+  messages: ChatMessage[];
+  quickPhrases: string[];
+  mode: ChatMode;
+  playerId?: string;
+  // canSend is false for spectators, who may only read the chat.
+  canSend: boolean;
+  names: Record<string, string>;
+  notice: string;
+  onSend: (text: string) => void;
+  onModeChange: (mode: ChatMode) => void;
+};
+
+const MAX_MESSAGE_LENGTH = 200;
+
+const rejectionText: Record<string, string> = {
+  message_too_long: `Messages can be at most ${MAX_MESSAGE_LENGTH} characters.`,
+  message_not_allowed: 'That message is not allowed.',
+  empty_message: 'Type a message first.',
+  not_a_participant: 'Only the players can chat.',
+  unauthorized: 'Log in to chat.',
+};
+
+export const chatRejectionText = (reason: string) => rejectionText[reason] || 'Message not sent.';
+
+const GameChat: React.FC<GameChatProps> = ({ messages, quickPhrases, mode, playerId, canSend, names, notice, onSend, onModeChange }) => {
+  const [text, setText] = useState('');
+
+  const send = (message: string) => {
+    if (!message.trim()) return;
+    onSend(message);
+    setText('');
+  };
+
+  return (
+    <div style={{ background: 'rgba(255,255,255,0.08)', borderRadius: 10, padding: 12, marginBottom: 16 }}>
+      <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', marginBottom: 8 }}>
+        <b>Chat</b>
+        {canSend && (
+          <select
+            value={mode}
+            onChange={e => onModeChange(e.target.value as ChatMode)}
+            style={{ background: '#2a2a2c', color: 'white', border: 'none', borderRadius: 6, padding: '4px 8px' }}
+          >
+            <option value="all">Show all messages</option>
+            <option value="quick_phrases">Quick phrases only</option>
+            <option value="muted">Mute opponent</option>
+          </select>
+        )}
+      </div>
+      <div style={{ maxHeight: 160, overflowY: 'auto', fontSize: '0.95rem', marginBottom: 8 }}>
+        {messages.length === 0 && <div style={{ color: '#888' }}>No messages yet.</div>}
+        {messages.map(m => (
+          <div key={m.id} style={{ marginBottom: 4, wordBreak: 'break-word' }}>
+            <b style={{ color: m.player_id === playerId ? '#538d4e' : '#b59f3b' }}>
+              {m.player_id === playerId ? 'You' : names[m.player_id] || 'Opponent'}:
+            </b>{' '}
+            {m.text}
+          </div>
+        ))}
+      </div>
+      {notice && <div style={{ color: '#f44336', fontSize: '0.9rem', marginBottom: 6 }}>{notice}</div>}
+      {canSend && (
+        <>
+          <div style={{ display: 'flex', flexWrap: 'wrap', gap: 6, marginBottom: 8 }}>
+            {quickPhrases.map(phrase => (
+              <button
+                key={phrase}
+                onClick={() => send(phrase)}
+                style={{ padding: '4px 10px', borderRadius: 12, border: 'none', background: '#3a3a3c', color: 'white', cursor: 'pointer', fontSize: '0.85rem' }}
+              >{phrase}</button>
+            ))}
+          </div>
+          <form
+            onSubmit={e => { e.preventDefault(); send(text); }}
+            style={{ display: 'flex', gap: 6 }}
+          >
+            <input
+              value={text}
+              maxLength={MAX_MESSAGE_LENGTH}
+              onChange={e => setText(e.target.value)}
+              placeholder="Say something..."
+              style={{ flex: 1, padding: '6px 10px', borderRadius: 6, border: 'none', background: '#2a2a2c', color: 'white' }}
+            />
+            <button
+              type="submit"
+              style={{ padding: '6px 14px', borderRadius: 6, border: 'none', background: '#538d4e', color: 'white', fontWeight: 600, cursor: 'pointer' }}
+            >Send</button>
+          </form>
+        </>
+      )}
+    </div>
+  );
+};
+
+export default GameChat;
@@ -6,6 +6,30 @@ interface Player {
   name: string;
 }
 
+interface BlockedPlayer {
+  player_id: string;
+  name: string;
+}
+
+interface Friend {
+  player_id: string;
+  name: string;
+  status: 'pending' | 'accepted';
+  incoming: boolean;
+  online: boolean;
+}
+
+const friendButtonStyle = (background: string): React.CSSProperties => ({
+  background,
+  color: 'white',
+  border: 'none',
+  borderRadius: 6,
+  padding: '7px 14px',
+  fontWeight: 600,
+  fontSize: 15,
+  cursor: 'pointer',
+});
+
 const Challenge: React.FC = () => {
   const [search, setSearch] = useState('');
   const [players, setPlayers] = useState<Player[]>([]);
@@ -22,6 +46,97 @@ const Challenge: React.FC = () => {
   })();
   const ws = useNotificationWebSocket();
   const debounceRef = useRef<number | null>(null);
+  const [friends, setFriends] = useState<Friend[]>([]);
+  const [blocked, setBlocked] = useState<BlockedPlayer[]>([]);
+
+  const friendsRequest = (path: string, method: string, body?: unknown) =>
+    fetch(`/api/player/me/friends${path}`, {
+      method,
+      headers: {
+        'Content-Type': 'application/json',
+        Authorization: `Bearer ${localStorage.getItem('token')}`,
+      },
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+
+  const loadFriends = () => {
+    if (!player?.registered) return;
+    friendsRequest('', 'GET')
+      .then((res) => (res.ok ? res.json() : []))
+      .then(setFriends)
+      .catch(() => setFriends([]));
+  };
+
+  const blocksRequest = (path: string, method: string, body?: unknown) =>
+    fetch(`/api/player/me/blocks${path}`, {
+      method,
+      headers: {
+        'Content-Type': 'application/json',
+        Authorization: `Bearer ${localStorage.getItem('token')}`,
+      },
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+
+  const loadBlocked = () => {
+    if (!player) return;
+    blocksRequest('', 'GET')
+      .then((res) => (res.ok ? res.json() : []))
+      .then(setBlocked)
+      .catch(() => setBlocked([]));
+  };
+
+  useEffect(() => {
+    loadFriends();
+    loadBlocked();
+  }, []);
+
+  // Blocked players can no longer challenge or message each other.
+  const handleBlock = async (target: Player) => {
+    const res = await blocksRequest('', 'POST', { player_id: target.id });
+    if (!res.ok) {
+      setError('Could not block player.');
+      return;
+    }
+    setPlayers((prev) => prev.filter((p) => p.id !== target.id));
+    loadFriends();
+    loadBlocked();
+  };
+  const handleUnblock = async (target: BlockedPlayer) => {
+    await blocksRequest(`/${encodeURIComponent(target.player_id)}`, 'DELETE');
+    loadBlocked();
+  };
+
+  // Friend requests and acceptances arrive live, as do answers to challenges.
+  useEffect(() => {
+    if (!ws) return;
+    const onMessage = (event: MessageEvent) => {
+      try {
+        const msg = JSON.parse(event.data);
+        if (msg.type === 'friend_request' || msg.type === 'friend_accepted') loadFriends();
+        if (['challenge_result', 'challenge_cancelled', 'challenge_expired'].includes(msg.type)) {
+          setSent((current) => (current === msg.from || current === msg.to ? null : current));
+        }
+      } catch {}
+    };
+    ws.addEventListener('message', onMessage);
+    return () => ws.removeEventListener('message', onMessage);
+  }, [ws]);
+
+  const handleAddFriend = async (target: Player) => {
+    const res = await friendsRequest('', 'POST', { player_id: target.id });
+    if (!res.ok && res.status !== 409) {
+      setError((await res.text()) || 'Could not send friend request.');
+    }
+    loadFriends();
+  };
+  const handleFriendAction = async (friend: Friend, action: 'accept' | 'decline' | 'remove') => {
+    if (action === 'remove') {
+      await friendsRequest(`/${encodeURIComponent(friend.player_id)}`, 'DELETE');
+    } else {
+      await friendsRequest(`/${encodeURIComponent(friend.player_id)}/${action}`, 'POST');
+    }
+    loadFriends();
+  };
 
   // Debounced search
   const handleSearchChange = (e: React.ChangeEvent<HTMLInputElement>) => {
@@ -41,7 +156,7 @@ const Challenge: React.FC = () => {
         .then(async (res) => {
           if (!res.ok) throw new Error('Failed to search players');
           const data = await res.json();
-          setPlayers(data.filter((p: Player) => p.id !== player?.id));
+          setPlayers(data.filter((p: Player) => p.id !== player?.id && !blocked.some((b) => b.player_id === p.id)));
           setLoading(false);
         })
         .catch(() => {
@@ -86,6 +201,45 @@ const Challenge: React.FC = () => {
   return (
     <div style={{ color: 'white', textAlign: 'center', marginTop: '4rem', minHeight: '60vh' }}>
       <h1>Challenge a Player</h1>
+      {friends.length > 0 && (
+        <div style={{ margin: '2rem auto', maxWidth: 400, display: 'flex', flexDirection: 'column', gap: 12 }}>
+          <h2 style={{ margin: 0, fontSize: 20 }}>Friends</h2>
+          {friends.map((f) => (
+            <div key={f.player_id} style={{ display: 'flex', alignItems: 'center', justifyContent: 'space-between', background: '#23233a', borderRadius: 8, padding: '12px 18px', gap: 8 }}>
+              <span style={{ fontWeight: 500, fontSize: 17, display: 'flex', alignItems: 'center', gap: 8 }}>
+                <span
+                  title={f.online ? 'Online' : 'Offline'}
+                  style={{ width: 10, height: 10, borderRadius: '50%', background: f.online ? '#538d4e' : '#555', display: 'inline-block' }}
+                />
+                {f.name}
+              </span>
+              <span style={{ display: 'flex', gap: 8 }}>
+                {f.status === 'accepted' && (
+                  <>
+                    <button
+                      disabled={!f.online}
+                      onClick={() => handleChallenge({ id: f.player_id, name: f.name })}
+                      style={{ ...friendButtonStyle(f.online ? '#538d4e' : '#444'), cursor: f.online ? 'pointer' : 'default' }}
+                    >
+                      {sent === f.player_id ? 'Sent' : 'Challenge'}
+                    </button>
+                    <button onClick={() => handleFriendAction(f, 'remove')} style={friendButtonStyle('#3a3a3c')}>Remove</button>
+                  </>
+                )}
+                {f.status === 'pending' && f.incoming && (
+                  <>
+                    <button onClick={() => handleFriendAction(f, 'accept')} style={friendButtonStyle('#538d4e')}>Accept</button>
+                    <button onClick={() => handleFriendAction(f, 'decline')} style={friendButtonStyle('#3a3a3c')}>Decline</button>
+                  </>
+                )}
+                {f.status === 'pending' && !f.incoming && (
+                  <button onClick={() => handleFriendAction(f, 'remove')} style={friendButtonStyle('#3a3a3c')}>Cancel request</button>
+                )}
+              </span>
+            </div>
+          ))}
+        </div>
+      )}
       <div style={{ margin: '2rem auto', maxWidth: 400 }}>
         <input
           type="text"
@@ -113,6 +267,10 @@ const Challenge: React.FC = () => {
           {players.map((p) => (
             <div key={p.id} style={{ display: 'flex', alignItems: 'center', justifyContent: 'space-between', background: '#23233a', borderRadius: 8, padding: '12px 18px' }}>
               <span style={{ fontWeight: 500, fontSize: 17 }}>{p.name}</span>
+              {player?.registered && !friends.some((f) => f.player_id === p.id) && (
+                <button onClick={() => handleAddFriend(p)} style={friendButtonStyle('#3a3a3c')}>Add friend</button>
+              )}
+              <button onClick={() => handleBlock(p)} style={friendButtonStyle('#7a2e2e')}>Block</button>
               {sent === p.id ? (
                 <button
                   onClick={() => handleCancel(p)}
@@ -152,6 +310,17 @@ const Challenge: React.FC = () => {
           ))}
         </div>
       </div>
+      {blocked.length > 0 && (
+        <div style={{ margin: '2rem auto', maxWidth: 400, display: 'flex', flexDirection: 'column', gap: 12 }}>
+          <h2 style={{ margin: 0, fontSize: 20 }}>Blocked players</h2>
+          {blocked.map((b) => (
+            <div key={b.player_id} style={{ display: 'flex', alignItems: 'center', justifyContent: 'space-between', background: '#23233a', borderRadius: 8, padding: '12px 18px' }}>
+              <span style={{ fontWeight: 500, fontSize: 17 }}>{b.name}</span>
+              <button onClick={() => handleUnblock(b)} style={friendButtonStyle('#3a3a3c')}>Unblock</button>
+            </div>
+          ))}
+        </div>
+      )}
     </div>
   );
 };
@@ -3,6 +3,8 @@ import Board from '../components/Board';
 import Keyboard from '../components/Keyboard';
 import { useParams, useNavigate } from 'react-router-dom';
 import { useNotificationWebSocket } from '../components/NotificationWebSocketContext';
+import { wsProtocols } from '../auth';
+import GameChat, { ChatMessage, ChatMode, chatRejectionText } from '../components/GameChat';
 
 const MAX_GUESSES = 6;
 const WORD_LENGTH = 5;
@@ -35,6 +37,11 @@ const Game: React.FC = () => {
   const [rematchState, setRematchState] = useState<'idle' | 'pending' | 'offered' | 'declined' | 'accepted'>('idle');
   const [rematchFrom, setRematchFrom] = useState<string | null>(null);
   const [rematchOfferId, setRematchOfferId] = useState<string | null>(null);
+  // Chat
+  const [chatMessages, setChatMessages] = useState<ChatMessage[]>([]);
+  const [chatMode, setChatMode] = useState<ChatMode>('all');
+  const [quickPhrases, setQuickPhrases] = useState<string[]>([]);
+  const [chatNotice, setChatNotice] = useState('');
 
   // Define opponent for display, stats, and sessionStorage
   type Opponent = { id: string; name: string };
@@ -55,7 +62,8 @@ const Game: React.FC = () => {
     const ws = new WebSocket(
       window.location.protocol === 'https:'
         ? `wss://${window.location.host}/ws/game/${gameId}`
-        : `ws://${window.location.host}/ws/game/${gameId}`
+        : `ws://${window.location.host}/ws/game/${gameId}`,
+      wsProtocols()
     );
     wsRef.current = ws;
     ws.onopen = () => {
@@ -65,6 +73,35 @@ const Game: React.FC = () => {
       if (!isMounted) return;
       console.log('WebSocket message received:', event.data);
       const data = JSON.parse(event.data);
+      if (data.type === 'rate_limited') {
+        // The server dropped the message; let the player try again.
+        if (data.message_type === 'chat') {
+          setChatNotice(`Slow down! Try again in ${data.retry_after}s.`);
+        } else {
+          setPendingGuess(null);
+        }
+        return;
+      }
+      if (data.type === 'chat_history') {
+        setChatMessages(data.messages || []);
+        setQuickPhrases(data.quick_phrases || []);
+        if (data.mode) setChatMode(data.mode);
+        return;
+      }
+      if (data.type === 'chat') {
+        setChatMessages(prev => [...prev.filter(m => m.id !== data.id), data]);
+        setChatNotice('');
+        return;
+      }
+      if (data.type === 'chat_settings') {
+        setChatMode(data.mode);
+        return;
+      }
+      if (data.type === 'chat_rejected' || data.type === 'chat_settings_rejected') {
+        setChatNotice(chatRejectionText(data.reason));
+        return;
+      }
+      if (data.type !== 'game_state') return;
       receivedGameState.current = true;
       setGame(data);
       setLoading(false);
@@ -108,6 +145,8 @@ const Game: React.FC = () => {
   useEffect(() => {
     setGameOver(false);
     setError('');
+    setChatMessages([]);
+    setChatNotice('');
     setCurrent('');
     setPendingGuess(null);
     // Optionally reset other state if needed
@@ -118,6 +157,8 @@ const Game: React.FC = () => {
       if (gameOver || pendingGuess) return;
       if (!playerId || game?.current_player !== playerId) return;
       if (e.ctrlKey || e.metaKey || e.altKey) return;
+      // Typing in the chat is not a guess.
+      if (e.target instanceof HTMLInputElement || e.target instanceof HTMLSelectElement) return;
       const key = e.key.toUpperCase();
       if (/^[A-Z]$/.test(key)) {
         handleKey(key);
@@ -240,12 +281,12 @@ const Game: React.FC = () => {
           setRematchFrom(msg.from_name || msg.from);
           setRematchOfferId('rematch');
           console.log('[Rematch] Received challenge_invite (rematch)', msg);
-        } else if ((msg.type === 'challenge_cancel' || msg.type === 'challenge_cancelled') && msg.rematch && (msg.to === playerId || msg.type === 'challenge_cancelled')) {
+        } else if ((msg.type === 'challenge_cancelled' || msg.type === 'challenge_expired') && msg.rematch) {
           setRematchState('idle');
           setRematchFrom(null);
           setRematchOfferId(null);
           console.log('[Rematch] Received challenge_cancel (rematch)', msg);
-        } else if (msg.type === 'challenge_response' && msg.rematch && msg.to === playerId && msg.accepted === false) {
+        } else if (msg.type === 'challenge_result' && msg.rematch && msg.to === playerId && !msg.accepted) {
           setRematchState('declined');
           setTimeout(() => setRematchState('idle'), 2000);
           setRematchOfferId(null);
@@ -292,6 +333,14 @@ const Game: React.FC = () => {
     console.log('[Rematch] Sent challenge_response declined (rematch)', { from: player.id, to: opponent.id });
   };
 
+  const handleSendChat = (text: string) => {
+    wsRef.current?.send(JSON.stringify({ type: 'chat', text }));
+  };
+
+  const handleChatModeChange = (mode: ChatMode) => {
+    wsRef.current?.send(JSON.stringify({ type: 'chat_settings', mode }));
+  };
+
   const handleFindMatch = () => {
     navigate('/matchmaking');
   };
@@ -456,6 +505,19 @@ const Game: React.FC = () => {
             )}
           </div>
         )}
+        {game && (
+          <GameChat
+            messages={chatMessages}
+            quickPhrases={quickPhrases}
+            mode={chatMode}
+            playerId={playerId}
+            canSend={!!playerId && (game.first_player.id === playerId || game.second_player.id === playerId)}
+            names={{ [game.first_player.id]: game.first_player.name, [game.second_player.id]: game.second_player.name }}
+            notice={chatNotice}
+            onSend={handleSendChat}
+            onModeChange={handleChatModeChange}
+          />
+        )}
         {/* Keyboard - no card background */}
         <div style={{
           margin: '2rem 0',
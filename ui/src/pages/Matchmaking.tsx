@@ -1,5 +1,6 @@
 import React, { useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
+import { wsProtocols } from '../auth';
 
 const Matchmaking: React.FC = () => {
   const navigate = useNavigate();
@@ -12,7 +13,8 @@ const Matchmaking: React.FC = () => {
     const ws = new WebSocket(
       window.location.protocol === 'https:'
         ? `wss://${window.location.host}/ws/matchmaking`
-        : `ws://${window.location.host}/ws/matchmaking`
+        : `ws://${window.location.host}/ws/matchmaking`,
+      wsProtocols()
     );
     ws.onopen = () => {
       ws.send(JSON.stringify({ type: 'join', player_id: player.id }));
@@ -0,0 +1,74 @@
+import React, { useState } from 'react';
+import { useSearchParams } from 'react-router-dom';
+
+const inputStyle: React.CSSProperties = { padding: '0.5rem', borderRadius: 4, border: '1px solid #3a3a3c', background: '#1a1a1a', color: '#f3f3f3' };
+const buttonStyle: React.CSSProperties = { background: '#538d4e', color: '#f3f3f3', border: 'none', borderRadius: 4, padding: '0.5rem 1rem', fontWeight: 'bold', cursor: 'pointer' };
+const formStyle: React.CSSProperties = { background: '#222', padding: '2rem', borderRadius: 8, width: 320, margin: '4rem auto', display: 'flex', flexDirection: 'column', gap: '1rem' };
+
+// ResetPassword requests a reset link or, when opened from one, sets a new password.
+const ResetPassword: React.FC = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [nameOrEmail, setNameOrEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [confirm, setConfirm] = useState('');
+  const [message, setMessage] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const requestReset = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError(null);
+    const value = nameOrEmail.trim();
+    if (!value) return;
+    const res = await fetch('/api/player/password/reset', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(value.includes('@') ? { email: value } : { name: value }),
+    });
+    if (!res.ok) {
+      setError('Could not request a reset. Please try again.');
+      return;
+    }
+    setMessage('If the account has an email address, a reset link is on its way.');
+  };
+
+  const resetPassword = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError(null);
+    if (password.length < 8 || password !== confirm) {
+      setError('Use at least 8 characters and make sure the passwords match.');
+      return;
+    }
+    const res = await fetch('/api/player/password/reset/confirm', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token, password }),
+    });
+    if (!res.ok) {
+      setError(res.status === 400 ? 'This reset link is invalid or has expired.' : 'Could not reset the password. Please try again.');
+      return;
+    }
+    setMessage('Your password has been changed. You can now log in with it.');
+  };
+
+  if (message) {
+    return <div style={{ color: 'white', textAlign: 'center', marginTop: '4rem' }}>{message}</div>;
+  }
+  return (
+    <form onSubmit={token ? resetPassword : requestReset} style={formStyle}>
+      <h2 style={{ color: '#f3f3f3', margin: 0, textAlign: 'center' }}>Reset Password</h2>
+      {token ? (
+        <>
+          <input type="password" value={password} onChange={e => setPassword(e.target.value)} placeholder="New Password" style={inputStyle} />
+          <input type="password" value={confirm} onChange={e => setConfirm(e.target.value)} placeholder="Confirm Password" style={inputStyle} />
+        </>
+      ) : (
+        <input type="text" value={nameOrEmail} onChange={e => setNameOrEmail(e.target.value)} placeholder="Username or email" style={inputStyle} />
+      )}
+      {error && <div style={{ color: 'red', fontSize: '0.95rem' }}>{error}</div>}
+      <button type="submit" style={buttonStyle}>{token ? 'Set Password' : 'Send Reset Link'}</button>
+    </form>
+  );
+};
+
+export default ResetPassword;